@@ -1,18 +1,409 @@
-CREATE TABLE IF NOT EXISTS accounts (
+CREATE TABLE IF NOT EXISTS customers (
 	id serial PRIMARY KEY,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
 	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
 	name text NOT NULL,
-	cpf text UNIQUE NOT NULL,
+	cpf text UNIQUE CONSTRAINT customers_cpf_digits CHECK (cpf ~ '^[0-9]{11}$'),
 	secret text NOT NULL,
+	role text DEFAULT 'customer' NOT NULL CHECK (role IN ('customer', 'admin')),
+	secret_changed_at timestamptz,
+	secret_failed_attempts integer DEFAULT 0 NOT NULL,
+	secret_locked_until timestamptz,
+	mfa_secret text,
+	mfa_enabled boolean DEFAULT false NOT NULL,
+	mfa_last_step bigint,
+	mfa_failed_attempts integer DEFAULT 0 NOT NULL,
+	mfa_locked_until timestamptz,
+	anonymized_at timestamptz,
+	active boolean DEFAULT true NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS account_products (
+	code text PRIMARY KEY,
+	name text NOT NULL,
+	interest_rate_bps integer DEFAULT 0 NOT NULL CHECK (interest_rate_bps >= 0),
+	overdraft_rate_bps integer DEFAULT 0 NOT NULL CHECK (overdraft_rate_bps >= 0)
+);
+
+INSERT INTO account_products (code, name, interest_rate_bps, overdraft_rate_bps) VALUES
+	('checking', 'Conta corrente', 0, 9600),
+	('savings', 'Conta poupança', 600, 0)
+ON CONFLICT (code) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS accounts (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	product text DEFAULT 'checking' NOT NULL REFERENCES account_products(code),
 	balance bigint DEFAULT 0 NOT NULL,
+	overdraft_limit bigint DEFAULT 0 NOT NULL CHECK (overdraft_limit >= 0),
+	limit_per_transaction bigint DEFAULT 500000 NOT NULL CHECK (limit_per_transaction >= 0),
+	limit_daily bigint DEFAULT 1000000 NOT NULL CHECK (limit_daily >= 0),
+	limit_monthly bigint DEFAULT 5000000 NOT NULL CHECK (limit_monthly >= 0),
+	limit_nightly bigint DEFAULT 100000 NOT NULL CHECK (limit_nightly >= 0),
+	approval_threshold bigint CHECK (approval_threshold >= 0),
+	required_approvals integer DEFAULT 0 NOT NULL CHECK (required_approvals >= 0),
+	pin_hash text,
+	pin_failed_attempts integer DEFAULT 0 NOT NULL,
+	pin_locked_until timestamptz,
+	closed_at timestamptz,
 	active boolean DEFAULT true NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS accounts_customer_id_idx ON accounts (customer_id);
+
 CREATE TABLE IF NOT EXISTS transfers (
 	id serial PRIMARY KEY,
 	account_origin_id bigint REFERENCES accounts(id), 
 	account_destination_id bigint REFERENCES accounts(id),
 	amount bigint,
+	kind text DEFAULT 'transfer' NOT NULL,
+	related_id bigint REFERENCES transfers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS transfers_origin_created_at_idx ON transfers (account_origin_id, created_at);
+
+-- Accounts owned by the bank itself, the other side of interest, fees and
+-- other postings. The bank customer can not log in.
+CREATE TABLE IF NOT EXISTS system_accounts (
+	purpose text PRIMARY KEY,
+	account_id bigint UNIQUE NOT NULL REFERENCES accounts(id)
+);
+
+INSERT INTO customers (name, cpf, secret, active) VALUES ('Banco', '00000000000', '', false)
+ON CONFLICT (cpf) DO NOTHING;
+
+DO $$
+DECLARE
+	p text;
+	bank_id bigint;
+	new_id bigint;
+BEGIN
+	SELECT id INTO bank_id FROM customers WHERE cpf = '00000000000';
+
+	FOREACH p IN ARRAY ARRAY['interest_expense', 'overdraft_revenue', 'fee_revenue'] LOOP
+		IF NOT EXISTS (SELECT 1 FROM system_accounts WHERE purpose = p) THEN
+			INSERT INTO accounts (customer_id) VALUES (bank_id) RETURNING id INTO new_id;
+			INSERT INTO system_accounts (purpose, account_id) VALUES (p, new_id);
+		END IF;
+	END LOOP;
+END
+$$;
+
+CREATE TABLE IF NOT EXISTS interest_accruals (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	accrual_date date NOT NULL,
+	balance bigint NOT NULL,
+	rate_bps integer NOT NULL,
+	amount bigint NOT NULL,
+	paid_at TIMESTAMP WITH TIME ZONE,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (account_id, accrual_date)
+);
+
+CREATE INDEX IF NOT EXISTS interest_accruals_unpaid_idx ON interest_accruals (account_id) WHERE paid_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS overdraft_charges (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	charge_date date NOT NULL,
+	balance bigint NOT NULL,
+	rate_bps integer NOT NULL,
+	amount bigint NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (account_id, charge_date)
+);
+
+-- Raises of transfer limits, applied only once effective_at is reached.
+CREATE TABLE IF NOT EXISTS limit_change_requests (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	kind text NOT NULL CHECK (kind IN ('perTransaction', 'daily', 'monthly', 'nightly')),
+	amount bigint NOT NULL CHECK (amount >= 0),
+	effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	applied_at TIMESTAMP WITH TIME ZONE,
+	cancelled_at TIMESTAMP WITH TIME ZONE,
 	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
-);
\ No newline at end of file
+);
+
+CREATE INDEX IF NOT EXISTS limit_change_requests_pending_idx ON limit_change_requests (effective_at) WHERE applied_at IS NULL AND cancelled_at IS NULL;
+
+-- Fees charged per service. A NULL product applies to the products without a
+-- tariff of their own. The first free_per_month uses in a month are free.
+CREATE TABLE IF NOT EXISTS tariffs (
+	id serial PRIMARY KEY,
+	service text NOT NULL,
+	product text REFERENCES account_products(code),
+	free_per_month integer DEFAULT 0 NOT NULL CHECK (free_per_month >= 0),
+	fee bigint NOT NULL CHECK (fee >= 0),
+	active boolean DEFAULT true NOT NULL,
+	UNIQUE (service, product)
+);
+
+INSERT INTO tariffs (service, product, free_per_month, fee)
+SELECT v.service, v.product, v.free_per_month, v.fee
+FROM (VALUES
+	('transfer', 'checking', 4, 150),
+	('transfer', 'savings', 2, 200)
+) AS v (service, product, free_per_month, fee)
+WHERE NOT EXISTS (SELECT 1 FROM tariffs);
+
+CREATE TABLE IF NOT EXISTS transfer_batches (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	mode text NOT NULL CHECK (mode IN ('atomic', 'bestEffort')),
+	status text NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	finished_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS transfer_batch_items (
+	batch_id bigint NOT NULL REFERENCES transfer_batches(id),
+	position integer NOT NULL,
+	destination bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	fee bigint DEFAULT 0 NOT NULL,
+	status text NOT NULL,
+	error text DEFAULT '' NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	PRIMARY KEY (batch_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS holds (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	destination bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	captured_amount bigint DEFAULT 0 NOT NULL CHECK (captured_amount >= 0 AND captured_amount <= amount),
+	reference text DEFAULT '' NOT NULL,
+	status text NOT NULL CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+	expires_at timestamptz NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	finished_at timestamptz,
+	transfer_id bigint REFERENCES transfers(id)
+);
+
+CREATE INDEX IF NOT EXISTS holds_active_idx ON holds (account_id) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS account_approvers (
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	PRIMARY KEY (account_id, customer_id)
+);
+
+CREATE TABLE IF NOT EXISTS pending_transfers (
+	id serial PRIMARY KEY,
+	account_origin_id bigint NOT NULL REFERENCES accounts(id),
+	account_destination_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	requested_by bigint NOT NULL REFERENCES customers(id),
+	required_approvals integer NOT NULL,
+	status text NOT NULL CHECK (status IN ('pending', 'approved', 'rejected', 'executed', 'failed')),
+	error text DEFAULT '' NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	decided_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS transfer_approvals (
+	pending_transfer_id bigint NOT NULL REFERENCES pending_transfers(id),
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	approved boolean NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (pending_transfer_id, customer_id)
+);
+
+CREATE TABLE IF NOT EXISTS risk_assessments (
+	id serial PRIMARY KEY,
+	account_origin_id bigint NOT NULL REFERENCES accounts(id),
+	account_destination_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL,
+	decision text NOT NULL CHECK (decision IN ('allow', 'challenge', 'block')),
+	reasons text[] NOT NULL,
+	passed_at timestamptz,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	code_hash text NOT NULL,
+	used_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS mfa_recovery_codes_customer_id_idx ON mfa_recovery_codes (customer_id);
+
+CREATE TABLE IF NOT EXISTS secret_reset_tokens (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	token_hash text UNIQUE NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS api_clients (
+	id serial PRIMARY KEY,
+	client_id text UNIQUE NOT NULL,
+	secret_hash text NOT NULL,
+	name text NOT NULL,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	scopes text[] NOT NULL,
+	active boolean DEFAULT true NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	user_agent text NOT NULL DEFAULT '',
+	ip text NOT NULL DEFAULT '',
+	created_at timestamptz DEFAULT now() NOT NULL,
+	last_seen_at timestamptz DEFAULT now() NOT NULL,
+	expires_at timestamptz NOT NULL,
+	terminated_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS sessions_customer_id_idx ON sessions (customer_id);
+
+CREATE TABLE IF NOT EXISTS account_freezes (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	block_credits boolean DEFAULT false NOT NULL,
+	reason text NOT NULL,
+	reference text NOT NULL,
+	created_by bigint NOT NULL REFERENCES customers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	lifted_by bigint REFERENCES customers(id),
+	lifted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS account_freezes_account_id_idx ON account_freezes (account_id);
+
+CREATE TABLE IF NOT EXISTS judicial_blocks (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	reason text NOT NULL,
+	reference text NOT NULL,
+	created_by bigint NOT NULL REFERENCES customers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	released_by bigint REFERENCES customers(id),
+	released_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS judicial_blocks_account_id_idx ON judicial_blocks (account_id);
+
+CREATE TABLE IF NOT EXISTS consents (
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	purpose text NOT NULL,
+	granted boolean NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (customer_id, purpose)
+);
+
+-- Append-only audit log, each event chained to the previous one by its hash.
+CREATE TABLE IF NOT EXISTS audit_events (
+	id bigserial PRIMARY KEY,
+	actor_id bigint REFERENCES customers(id),
+	client_id text DEFAULT '' NOT NULL,
+	action text NOT NULL,
+	target text NOT NULL,
+	request_id text DEFAULT '' NOT NULL,
+	ip text DEFAULT '' NOT NULL,
+	before jsonb NOT NULL,
+	after jsonb NOT NULL,
+	created_at timestamptz NOT NULL,
+	prev_hash text NOT NULL,
+	hash text UNIQUE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_actor_id_idx ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (target);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+
+-- Domain events, delivered at least once to the outbox sinks.
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id bigserial PRIMARY KEY,
+	type text NOT NULL,
+	subject text NOT NULL,
+	payload jsonb NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS outbox_offsets (
+	sink text PRIMARY KEY,
+	last_event_id bigint DEFAULT 0 NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_transfer_origin_idx ON outbox_events (((payload->>'origin')::bigint), id) WHERE type = 'TransferCompleted';
+CREATE INDEX IF NOT EXISTS outbox_events_transfer_destination_idx ON outbox_events (((payload->>'destination')::bigint), id) WHERE type = 'TransferCompleted';
+CREATE INDEX IF NOT EXISTS outbox_events_posting_origin_idx ON outbox_events (((payload->>'origin')::bigint), id) WHERE type = 'PostingCompleted';
+CREATE INDEX IF NOT EXISTS outbox_events_posting_destination_idx ON outbox_events (((payload->>'destination')::bigint), id) WHERE type = 'PostingCompleted';
+CREATE INDEX IF NOT EXISTS outbox_events_account_idx ON outbox_events (((payload->>'accountId')::bigint), id) WHERE type IN ('HoldChanged', 'JudicialBlockChanged');
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+	id bigserial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	client_id text DEFAULT '' NOT NULL,
+	url text NOT NULL,
+	event_types text[] NOT NULL,
+	secret text NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	deleted_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_account_id_idx ON webhook_subscriptions (account_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id bigserial PRIMARY KEY,
+	subscription_id bigint NOT NULL REFERENCES webhook_subscriptions(id),
+	event_id bigint NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	status text DEFAULT 'pending' NOT NULL,
+	attempts int DEFAULT 0 NOT NULL,
+	next_attempt_at timestamptz,
+	last_status_code int,
+	last_error text DEFAULT '' NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	delivered_at timestamptz,
+	UNIQUE (subscription_id, event_id, event_type)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	customer_id bigint PRIMARY KEY REFERENCES customers(id),
+	language text DEFAULT 'pt-BR' NOT NULL,
+	email text DEFAULT '' NOT NULL,
+	phone text DEFAULT '' NOT NULL,
+	push_token text DEFAULT '' NOT NULL,
+	channels text[] DEFAULT '{}' NOT NULL,
+	muted text[] DEFAULT '{}' NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notifications_sent (
+	event_id bigint NOT NULL,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	kind text NOT NULL,
+	sent_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (event_id, customer_id, kind)
+);
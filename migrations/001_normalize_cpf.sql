@@ -0,0 +1,5 @@
+-- Stores every CPF in its canonical, digits only, form.
+-- Fails on the unique constraint if the same CPF was registered twice with
+-- different punctuation; those rows must be merged by hand before running it.
+UPDATE accounts SET cpf = regexp_replace(cpf, '[^0-9]', '', 'g') WHERE cpf ~ '[^0-9]';
+ALTER TABLE accounts ADD CONSTRAINT accounts_cpf_digits CHECK (cpf ~ '^[0-9]{11}$');
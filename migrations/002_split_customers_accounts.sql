@@ -0,0 +1,28 @@
+-- Moves the person data (name, cpf, secret) out of accounts into customers.
+-- Every existing account becomes the single account of a customer with the
+-- same id, so tokens and transfers keep pointing to the same rows.
+BEGIN;
+
+CREATE TABLE customers (
+	id serial PRIMARY KEY,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	name text NOT NULL,
+	cpf text UNIQUE NOT NULL CONSTRAINT customers_cpf_digits CHECK (cpf ~ '^[0-9]{11}$'),
+	secret text NOT NULL,
+	active boolean DEFAULT true NOT NULL
+);
+
+INSERT INTO customers (id, created_at, updated_at, name, cpf, secret, active)
+SELECT id, created_at, updated_at, name, cpf, secret, active FROM accounts;
+
+SELECT setval(pg_get_serial_sequence('customers', 'id'), COALESCE((SELECT max(id) FROM customers), 0) + 1, false);
+
+ALTER TABLE accounts ADD COLUMN customer_id bigint REFERENCES customers(id);
+UPDATE accounts SET customer_id = id;
+ALTER TABLE accounts ALTER COLUMN customer_id SET NOT NULL;
+ALTER TABLE accounts DROP COLUMN name, DROP COLUMN cpf, DROP COLUMN secret;
+
+CREATE INDEX accounts_customer_id_idx ON accounts (customer_id);
+
+COMMIT;
@@ -0,0 +1,45 @@
+-- Account products with savings interest, ledger movement kinds, the bank
+-- system accounts and the daily interest accruals.
+BEGIN;
+
+CREATE TABLE account_products (
+	code text PRIMARY KEY,
+	name text NOT NULL,
+	interest_rate_bps integer DEFAULT 0 NOT NULL CHECK (interest_rate_bps >= 0)
+);
+
+INSERT INTO account_products (code, name, interest_rate_bps) VALUES
+	('checking', 'Conta corrente', 0),
+	('savings', 'Conta poupança', 600);
+
+ALTER TABLE accounts ADD COLUMN product text DEFAULT 'checking' NOT NULL REFERENCES account_products(code);
+ALTER TABLE transfers ADD COLUMN kind text DEFAULT 'transfer' NOT NULL;
+
+CREATE TABLE system_accounts (
+	purpose text PRIMARY KEY,
+	account_id bigint UNIQUE NOT NULL REFERENCES accounts(id)
+);
+
+INSERT INTO customers (name, cpf, secret, active) VALUES ('Banco', '00000000000', '', false);
+
+WITH new_account AS (
+	INSERT INTO accounts (customer_id) SELECT id FROM customers WHERE cpf = '00000000000' RETURNING id
+)
+INSERT INTO system_accounts (purpose, account_id) SELECT 'interest_expense', id FROM new_account;
+
+CREATE TABLE interest_accruals (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	accrual_date date NOT NULL,
+	balance bigint NOT NULL,
+	rate_bps integer NOT NULL,
+	amount bigint NOT NULL,
+	paid_at TIMESTAMP WITH TIME ZONE,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (account_id, accrual_date)
+);
+
+CREATE INDEX interest_accruals_unpaid_idx ON interest_accruals (account_id) WHERE paid_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- Customer roles, per account overdraft limits and the daily overdraft
+-- interest charges.
+BEGIN;
+
+ALTER TABLE customers ADD COLUMN role text DEFAULT 'customer' NOT NULL CHECK (role IN ('customer', 'admin'));
+ALTER TABLE accounts ADD COLUMN overdraft_limit bigint DEFAULT 0 NOT NULL CHECK (overdraft_limit >= 0);
+ALTER TABLE account_products ADD COLUMN overdraft_rate_bps integer DEFAULT 0 NOT NULL CHECK (overdraft_rate_bps >= 0);
+
+UPDATE account_products SET overdraft_rate_bps = 9600 WHERE code = 'checking';
+
+WITH new_account AS (
+	INSERT INTO accounts (customer_id) SELECT id FROM customers WHERE cpf = '00000000000' RETURNING id
+)
+INSERT INTO system_accounts (purpose, account_id) SELECT 'overdraft_revenue', id FROM new_account;
+
+CREATE TABLE overdraft_charges (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	charge_date date NOT NULL,
+	balance bigint NOT NULL,
+	rate_bps integer NOT NULL,
+	amount bigint NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL,
+	UNIQUE (account_id, charge_date)
+);
+
+COMMIT;
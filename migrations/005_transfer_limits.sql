@@ -0,0 +1,26 @@
+-- Per transaction, daily, monthly and nightly transfer limits, and the
+-- delayed requests to raise them.
+BEGIN;
+
+ALTER TABLE accounts
+	ADD COLUMN limit_per_transaction bigint DEFAULT 500000 NOT NULL CHECK (limit_per_transaction >= 0),
+	ADD COLUMN limit_daily bigint DEFAULT 1000000 NOT NULL CHECK (limit_daily >= 0),
+	ADD COLUMN limit_monthly bigint DEFAULT 5000000 NOT NULL CHECK (limit_monthly >= 0),
+	ADD COLUMN limit_nightly bigint DEFAULT 100000 NOT NULL CHECK (limit_nightly >= 0);
+
+CREATE INDEX transfers_origin_created_at_idx ON transfers (account_origin_id, created_at);
+
+CREATE TABLE limit_change_requests (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	kind text NOT NULL CHECK (kind IN ('perTransaction', 'daily', 'monthly', 'nightly')),
+	amount bigint NOT NULL CHECK (amount >= 0),
+	effective_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	applied_at TIMESTAMP WITH TIME ZONE,
+	cancelled_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+CREATE INDEX limit_change_requests_pending_idx ON limit_change_requests (effective_at) WHERE applied_at IS NULL AND cancelled_at IS NULL;
+
+COMMIT;
@@ -0,0 +1,26 @@
+-- Tariffs per service and account product, fee movements related to the
+-- transfer they were charged for and the bank fee revenue account.
+BEGIN;
+
+ALTER TABLE transfers ADD COLUMN related_id bigint REFERENCES transfers(id);
+
+WITH new_account AS (
+	INSERT INTO accounts (customer_id) SELECT id FROM customers WHERE cpf = '00000000000' RETURNING id
+)
+INSERT INTO system_accounts (purpose, account_id) SELECT 'fee_revenue', id FROM new_account;
+
+CREATE TABLE tariffs (
+	id serial PRIMARY KEY,
+	service text NOT NULL,
+	product text REFERENCES account_products(code),
+	free_per_month integer DEFAULT 0 NOT NULL CHECK (free_per_month >= 0),
+	fee bigint NOT NULL CHECK (fee >= 0),
+	active boolean DEFAULT true NOT NULL,
+	UNIQUE (service, product)
+);
+
+INSERT INTO tariffs (service, product, free_per_month, fee) VALUES
+	('transfer', 'checking', 4, 150),
+	('transfer', 'savings', 2, 200);
+
+COMMIT;
@@ -0,0 +1,25 @@
+-- Batch transfers and the outcome of each of their items.
+BEGIN;
+
+CREATE TABLE transfer_batches (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	mode text NOT NULL CHECK (mode IN ('atomic', 'bestEffort')),
+	status text NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	finished_at timestamptz
+);
+
+CREATE TABLE transfer_batch_items (
+	batch_id bigint NOT NULL REFERENCES transfer_batches(id),
+	position integer NOT NULL,
+	destination bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	fee bigint DEFAULT 0 NOT NULL,
+	status text NOT NULL,
+	error text DEFAULT '' NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	PRIMARY KEY (batch_id, position)
+);
+
+COMMIT;
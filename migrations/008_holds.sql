@@ -0,0 +1,20 @@
+-- Holds reserving account funds until captured, voided or expired.
+BEGIN;
+
+CREATE TABLE holds (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	destination bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	captured_amount bigint DEFAULT 0 NOT NULL CHECK (captured_amount >= 0 AND captured_amount <= amount),
+	reference text DEFAULT '' NOT NULL,
+	status text NOT NULL CHECK (status IN ('active', 'captured', 'voided', 'expired')),
+	expires_at timestamptz NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	finished_at timestamptz,
+	transfer_id bigint REFERENCES transfers(id)
+);
+
+CREATE INDEX holds_active_idx ON holds (account_id) WHERE status = 'active';
+
+COMMIT;
@@ -0,0 +1,36 @@
+-- Maker-checker approval of transfers above an account threshold.
+BEGIN;
+
+ALTER TABLE accounts
+	ADD COLUMN approval_threshold bigint CHECK (approval_threshold >= 0),
+	ADD COLUMN required_approvals integer DEFAULT 0 NOT NULL CHECK (required_approvals >= 0);
+
+CREATE TABLE account_approvers (
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	PRIMARY KEY (account_id, customer_id)
+);
+
+CREATE TABLE pending_transfers (
+	id serial PRIMARY KEY,
+	account_origin_id bigint NOT NULL REFERENCES accounts(id),
+	account_destination_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	requested_by bigint NOT NULL REFERENCES customers(id),
+	required_approvals integer NOT NULL,
+	status text NOT NULL CHECK (status IN ('pending', 'approved', 'rejected', 'executed', 'failed')),
+	error text DEFAULT '' NOT NULL,
+	transfer_id bigint REFERENCES transfers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	decided_at timestamptz
+);
+
+CREATE TABLE transfer_approvals (
+	pending_transfer_id bigint NOT NULL REFERENCES pending_transfers(id),
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	approved boolean NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (pending_transfer_id, customer_id)
+);
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Risk screening of transfers: assessments kept for review and the last
+-- secret change of each customer, which the rules look at.
+BEGIN;
+
+ALTER TABLE customers ADD COLUMN secret_changed_at timestamptz;
+
+CREATE TABLE risk_assessments (
+	id serial PRIMARY KEY,
+	account_origin_id bigint NOT NULL REFERENCES accounts(id),
+	account_destination_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL,
+	decision text NOT NULL CHECK (decision IN ('allow', 'challenge', 'block')),
+	reasons text[] NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Transaction PINs, hashed, with their own wrong attempts lockout.
+BEGIN;
+
+ALTER TABLE accounts
+	ADD COLUMN pin_hash text,
+	ADD COLUMN pin_failed_attempts integer DEFAULT 0 NOT NULL,
+	ADD COLUMN pin_locked_until timestamptz;
+
+COMMIT;
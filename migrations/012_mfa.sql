@@ -0,0 +1,22 @@
+-- Optional TOTP two-factor authentication of customers: the secret, whether
+-- enrollment was confirmed, the last time step used, so a code can not be
+-- replayed, its own wrong attempts lockout, and the hashed recovery codes.
+BEGIN;
+
+ALTER TABLE customers
+	ADD COLUMN mfa_secret text,
+	ADD COLUMN mfa_enabled boolean DEFAULT false NOT NULL,
+	ADD COLUMN mfa_last_step bigint,
+	ADD COLUMN mfa_failed_attempts integer DEFAULT 0 NOT NULL,
+	ADD COLUMN mfa_locked_until timestamptz;
+
+CREATE TABLE mfa_recovery_codes (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	code_hash text NOT NULL,
+	used_at timestamptz
+);
+
+CREATE INDEX mfa_recovery_codes_customer_id_idx ON mfa_recovery_codes (customer_id);
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Secret reset tokens, hashed, each usable once until it expires.
+BEGIN;
+
+CREATE TABLE secret_reset_tokens (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	token_hash text UNIQUE NOT NULL,
+	expires_at timestamptz NOT NULL,
+	used_at timestamptz,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,16 @@
+-- API clients of server to server integrations, acting on one account with
+-- the scopes granted to them.
+BEGIN;
+
+CREATE TABLE api_clients (
+	id serial PRIMARY KEY,
+	client_id text UNIQUE NOT NULL,
+	secret_hash text NOT NULL,
+	name text NOT NULL,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	scopes text[] NOT NULL,
+	active boolean DEFAULT true NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- Sessions, one per login, so customers can see where they are logged in and
+-- log out of any of them.
+BEGIN;
+
+CREATE TABLE sessions (
+	id serial PRIMARY KEY,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	user_agent text NOT NULL DEFAULT '',
+	ip text NOT NULL DEFAULT '',
+	created_at timestamptz DEFAULT now() NOT NULL,
+	last_seen_at timestamptz DEFAULT now() NOT NULL,
+	expires_at timestamptz NOT NULL,
+	terminated_at timestamptz
+);
+
+CREATE INDEX sessions_customer_id_idx ON sessions (customer_id);
+
+COMMIT;
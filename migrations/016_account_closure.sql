@@ -0,0 +1,6 @@
+-- Closed accounts: inactive for good, taking no more credits.
+BEGIN;
+
+ALTER TABLE accounts ADD COLUMN closed_at timestamptz;
+
+COMMIT;
@@ -0,0 +1,33 @@
+-- Account freezes and judicial blocks (bloqueios judiciais), with who made
+-- and undid them and why.
+BEGIN;
+
+CREATE TABLE account_freezes (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	block_credits boolean DEFAULT false NOT NULL,
+	reason text NOT NULL,
+	reference text NOT NULL,
+	created_by bigint NOT NULL REFERENCES customers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	lifted_by bigint REFERENCES customers(id),
+	lifted_at timestamptz
+);
+
+CREATE INDEX account_freezes_account_id_idx ON account_freezes (account_id);
+
+CREATE TABLE judicial_blocks (
+	id serial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	amount bigint NOT NULL CHECK (amount > 0),
+	reason text NOT NULL,
+	reference text NOT NULL,
+	created_by bigint NOT NULL REFERENCES customers(id),
+	created_at timestamptz DEFAULT now() NOT NULL,
+	released_by bigint REFERENCES customers(id),
+	released_at timestamptz
+);
+
+CREATE INDEX judicial_blocks_account_id_idx ON judicial_blocks (account_id);
+
+COMMIT;
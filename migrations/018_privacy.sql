@@ -0,0 +1,16 @@
+-- LGPD: customer consents, and anonymization of customers whose accounts are
+-- all closed, which leaves them without a CPF.
+BEGIN;
+
+ALTER TABLE customers ALTER COLUMN cpf DROP NOT NULL;
+ALTER TABLE customers ADD COLUMN anonymized_at timestamptz;
+
+CREATE TABLE consents (
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	purpose text NOT NULL,
+	granted boolean NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (customer_id, purpose)
+);
+
+COMMIT;
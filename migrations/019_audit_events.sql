@@ -0,0 +1,33 @@
+-- Append-only audit log of state-changing operations. Each event carries the
+-- hash of the previous one, so tampering with the chain can be detected.
+BEGIN;
+
+CREATE TABLE audit_events (
+	id bigserial PRIMARY KEY,
+	actor_id bigint REFERENCES customers(id),
+	client_id text DEFAULT '' NOT NULL,
+	action text NOT NULL,
+	target text NOT NULL,
+	request_id text DEFAULT '' NOT NULL,
+	ip text DEFAULT '' NOT NULL,
+	before jsonb NOT NULL,
+	after jsonb NOT NULL,
+	created_at timestamptz NOT NULL,
+	prev_hash text NOT NULL,
+	hash text UNIQUE NOT NULL
+);
+
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id);
+CREATE INDEX audit_events_target_idx ON audit_events (target);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE PROCEDURE audit_events_append_only();
+
+COMMIT;
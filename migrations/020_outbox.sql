@@ -0,0 +1,18 @@
+-- Transactional outbox of domain events and how far each sink got.
+BEGIN;
+
+CREATE TABLE outbox_events (
+	id bigserial PRIMARY KEY,
+	type text NOT NULL,
+	subject text NOT NULL,
+	payload jsonb NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL
+);
+
+CREATE TABLE outbox_offsets (
+	sink text PRIMARY KEY,
+	last_event_id bigint DEFAULT 0 NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMIT;
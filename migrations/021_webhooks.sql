@@ -0,0 +1,35 @@
+-- Webhook subscriptions of accounts and API clients and their deliveries.
+BEGIN;
+
+CREATE TABLE webhook_subscriptions (
+	id bigserial PRIMARY KEY,
+	account_id bigint NOT NULL REFERENCES accounts(id),
+	client_id text DEFAULT '' NOT NULL,
+	url text NOT NULL,
+	event_types text[] NOT NULL,
+	secret text NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	deleted_at timestamptz
+);
+
+CREATE INDEX webhook_subscriptions_account_id_idx ON webhook_subscriptions (account_id);
+
+CREATE TABLE webhook_deliveries (
+	id bigserial PRIMARY KEY,
+	subscription_id bigint NOT NULL REFERENCES webhook_subscriptions(id),
+	event_id bigint NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	status text DEFAULT 'pending' NOT NULL,
+	attempts int DEFAULT 0 NOT NULL,
+	next_attempt_at timestamptz,
+	last_status_code int,
+	last_error text DEFAULT '' NOT NULL,
+	created_at timestamptz DEFAULT now() NOT NULL,
+	delivered_at timestamptz,
+	UNIQUE (subscription_id, event_id, event_type)
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+COMMIT;
@@ -0,0 +1,7 @@
+-- Lookup of the transfer events of an account, to resume event streams.
+BEGIN;
+
+CREATE INDEX outbox_events_transfer_origin_idx ON outbox_events (((payload->>'origin')::bigint), id) WHERE type = 'TransferCompleted';
+CREATE INDEX outbox_events_transfer_destination_idx ON outbox_events (((payload->>'destination')::bigint), id) WHERE type = 'TransferCompleted';
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- Where, in which language and about what each customer is notified.
+BEGIN;
+
+CREATE TABLE notification_preferences (
+	customer_id bigint PRIMARY KEY REFERENCES customers(id),
+	language text DEFAULT 'pt-BR' NOT NULL,
+	email text DEFAULT '' NOT NULL,
+	phone text DEFAULT '' NOT NULL,
+	push_token text DEFAULT '' NOT NULL,
+	channels text[] DEFAULT '{}' NOT NULL,
+	muted text[] DEFAULT '{}' NOT NULL,
+	updated_at timestamptz DEFAULT now() NOT NULL
+);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Wrong attempts lockout of the customer secret where it confirms changes of a
+-- logged in customer, as the secret change and the PIN reset.
+BEGIN;
+
+ALTER TABLE customers
+	ADD COLUMN secret_failed_attempts integer DEFAULT 0 NOT NULL,
+	ADD COLUMN secret_locked_until timestamptz;
+
+COMMIT;
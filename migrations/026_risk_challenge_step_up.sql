@@ -0,0 +1,7 @@
+-- When a challenged screening was answered, by the customer secret or by the
+-- approvals of the transfer, and let through.
+BEGIN;
+
+ALTER TABLE risk_assessments ADD COLUMN passed_at timestamptz;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- Notifications already sent, so that outbox events delivered again do not
+-- notify the customer twice.
+BEGIN;
+
+CREATE TABLE notifications_sent (
+	event_id bigint NOT NULL,
+	customer_id bigint NOT NULL REFERENCES customers(id),
+	kind text NOT NULL,
+	sent_at timestamptz DEFAULT now() NOT NULL,
+	PRIMARY KEY (event_id, customer_id, kind)
+);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Lookup of the posting, hold and judicial block events of an account, to
+-- resume event streams.
+BEGIN;
+
+CREATE INDEX outbox_events_posting_origin_idx ON outbox_events (((payload->>'origin')::bigint), id) WHERE type = 'PostingCompleted';
+CREATE INDEX outbox_events_posting_destination_idx ON outbox_events (((payload->>'destination')::bigint), id) WHERE type = 'PostingCompleted';
+CREATE INDEX outbox_events_account_idx ON outbox_events (((payload->>'accountId')::bigint), id) WHERE type IN ('HoldChanged', 'JudicialBlockChanged');
+
+COMMIT;
@@ -4,13 +4,30 @@ import "time"
 
 type UserId uint64
 
+// Account products, matching the account_products table.
+const (
+	Checking = "checking"
+	Savings  = "savings"
+)
+
+// Account is a bank account. Name and Cpf belong to the customer that owns it.
 type Account struct {
 	Id         uint64
+	CustomerId uint64
+	Product    string
 	Name       string
 	Cpf        string
 	Balance    int64
-	Secret     string
-	Active     bool
+	// OverdraftLimit is how far below zero the balance is allowed to go.
+	OverdraftLimit int64
+	// ApprovalThreshold, when set, is the amount above which transfers from
+	// the account wait for RequiredApprovals approvals.
+	ApprovalThreshold *int64
+	RequiredApprovals int
+	Active            bool
+	// ClosedAt is set once the customer closes the account, which is then
+	// inactive for good and takes no more credits.
+	ClosedAt   *time.Time
 	Created_at time.Duration
 	Updated_at time.Duration
 }
@@ -33,19 +50,97 @@ type ListAccountQuery struct {
 	Page     int
 }
 
+type CustomerAccount struct {
+	Id        uint64     `json:"id"`
+	Product   string     `json:"product"`
+	Balance   int64      `json:"balance"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ClosedAt  *time.Time `json:"closedAt,omitempty"`
+}
+
+type OpenAccountRequest struct {
+	Product string `json:"product"`
+}
+
+type ListCustomerAccountsResponse struct {
+	Data []CustomerAccount `json:"data"`
+}
+
+// CloseAccountRequest closes an account of the customer. An account with money
+// left needs a PayoutDestination, that gets the whole balance, and the PIN.
+type CloseAccountRequest struct {
+	PayoutDestination *uint64 `json:"payoutDestination"`
+	Pin               string  `json:"pin"`
+}
+
+// CloseAccountResponse tells when the account was closed and, if there was a
+// payout, its transfer and amount.
+type CloseAccountResponse struct {
+	Id               uint64    `json:"id"`
+	ClosedAt         time.Time `json:"closedAt"`
+	PayoutTransferId *uint64   `json:"payoutTransferId,omitempty"`
+	PayoutAmount     int64     `json:"payoutAmount,omitempty"`
+}
+
+// UpdateProfileRequest changes the customer data that can be changed, only the
+// name for now.
+type UpdateProfileRequest struct {
+	Name *string `json:"name"`
+}
+
+type Profile struct {
+	Id   uint64 `json:"id"`
+	Name string `json:"name"`
+	Cpf  string `json:"cpf"`
+}
+
 type BalanceRequest struct {
 	ID string `json:"id"`
 }
 
+// BalanceResponse carries the ledger balance, what can be spent from it
+// counting the overdraft limit, the funds reserved by holds and by judicial
+// blocks, and the savings interest accrued but not credited yet. Nothing is
+// available while the account is frozen.
 type BalanceResponse struct {
-	Balance int64 `json:"balance"`
+	Balance         int64 `json:"balance"`
+	OverdraftLimit  int64 `json:"overdraftLimit"`
+	Held            int64 `json:"held"`
+	Blocked         int64 `json:"blocked"`
+	Available       int64 `json:"available"`
+	AccruedInterest int64 `json:"accruedInterest"`
+	Frozen          bool  `json:"frozen"`
+	CreditsFrozen   bool  `json:"creditsFrozen"`
+}
+
+// Restrictions are the freezes and judicial blocks in force on an account.
+type Restrictions struct {
+	Frozen        bool
+	CreditsFrozen bool
+	Blocked       int64
+}
+
+type OverdraftLimitRequest struct {
+	Limit *int64 `json:"limit"`
+}
+
+// ApprovalPolicyRequest sets which customers approve the account transfers
+// above Threshold and how many of them must. A nil Threshold turns approvals
+// off.
+type ApprovalPolicyRequest struct {
+	Threshold         *int64   `json:"threshold"`
+	RequiredApprovals int      `json:"requiredApprovals"`
+	Approvers         []uint64 `json:"approvers"`
 }
 
+// NewAccountRequest registers a new customer together with its first account.
 type NewAccountRequest struct {
 	Name    string `json:"name"`
 	Cpf     string `json:"cpf"`
 	Secret  string `json:"secret"`
 	Balance int64  `json:"balance"`
+	Product string `json:"product"`
 }
 
 type AccountResponse struct {
@@ -14,21 +14,50 @@ import (
 type Repository interface {
 	ListAccount(context.Context, ListAccountQuery) (ListAccountsReponse, error)
 	AddAccount(context.Context, NewAccountRequest) error
-	GetAccountBalance(context.Context, uint64) (int64, error)
+	GetAccountById(context.Context, uint64) (Account, error)
+	SetOverdraftLimit(context.Context, uint64, int64) error
+	SetApprovalPolicy(context.Context, uint64, ApprovalPolicyRequest) error
+	ListCustomerAccounts(context.Context, uint64) ([]CustomerAccount, error)
+	AddCustomerAccount(context.Context, uint64, string) (CustomerAccount, error)
+	GetAccruedInterest(context.Context, uint64) (int64, error)
+	GetHeldAmount(context.Context, uint64) (int64, error)
+	GetAccountRestrictions(context.Context, uint64) (Restrictions, error)
+	UpdateCustomerName(context.Context, uint64, string) (Profile, error)
+	SetAccountActive(context.Context, uint64, bool) error
+	CloseAccount(context.Context, uint64, *uint64) (CloseAccountResponse, error)
 }
 
 type Service interface {
 	List(context.Context, ListAccountQuery) (ListAccountsReponse, error)
 	NewAccount(context.Context, NewAccountRequest) (NewAccountResponse, error)
 	GetBalance(context.Context, uint64) (BalanceResponse, error)
+	ListOwn(context.Context, uint64) (ListCustomerAccountsResponse, error)
+	OpenAccount(context.Context, uint64, OpenAccountRequest) (CustomerAccount, error)
+	SetOverdraftLimit(context.Context, uint64, OverdraftLimitRequest) error
+	SetApprovalPolicy(context.Context, uint64, ApprovalPolicyRequest) error
+	UpdateProfile(context.Context, uint64, UpdateProfileRequest) (Profile, error)
+	Deactivate(context.Context, uint64) error
+	Reactivate(context.Context, uint64) error
+	Close(context.Context, uint64, uint64, CloseAccountRequest) (CloseAccountResponse, error)
 }
 
+// PinVerifier checks the transaction PIN of an account.
+type PinVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+// MaxNameLength bounds customer names.
+const MaxNameLength = 120
+
 type service struct {
-	r Repository
+	r    Repository
+	pins PinVerifier
 }
 
-func New(r Repository) *service {
-	return &service{r}
+// New builds the account service. A nil pins does not ask for the transaction
+// PIN to pay out the balance of accounts being closed.
+func New(r Repository, pins PinVerifier) *service {
+	return &service{r, pins}
 }
 
 func (s *service) List(ctx context.Context, q ListAccountQuery) (ListAccountsReponse, error) {
@@ -46,6 +75,9 @@ func (s *service) List(ctx context.Context, q ListAccountQuery) (ListAccountsRep
 
 	select {
 	case accounts := <-accountsCh:
+		for i := range accounts.Data {
+			accounts.Data[i].Cpf = validators.FormatCPF(accounts.Data[i].Cpf)
+		}
 		return accounts, nil
 	case err := <-errCh:
 		return ListAccountsReponse{}, err
@@ -65,6 +97,12 @@ func (s *service) NewAccount(ctx context.Context, newAccount NewAccountRequest)
 			return
 		}
 
+		newAccount.Cpf, _ = validators.NormalizeCPF(newAccount.Cpf)
+
+		if newAccount.Product == "" {
+			newAccount.Product = Checking
+		}
+
 		newAccount.Secret = fmt.Sprintf("%x", sha256.Sum256([]byte(newAccount.Secret+os.Getenv("SALT"))))
 
 		if err := s.r.AddAccount(ctx, newAccount); err != nil {
@@ -88,21 +126,54 @@ func (s *service) NewAccount(ctx context.Context, newAccount NewAccountRequest)
 
 func (s *service) GetBalance(ctx context.Context, userId uint64) (BalanceResponse, error) {
 	var balanceResponse BalanceResponse
-	balanceCh := make(chan int64)
+	balanceCh := make(chan BalanceResponse)
 	errCh := make(chan error)
 
 	go func() {
-		balance, err := s.r.GetAccountBalance(ctx, userId)
+		var balance BalanceResponse
+
+		account, err := s.r.GetAccountById(ctx, userId)
 		if err != nil {
 			errCh <- err
 			return
 		}
+
+		balance.Balance = account.Balance
+		balance.OverdraftLimit = account.OverdraftLimit
+
+		balance.Held, err = s.r.GetHeldAmount(ctx, userId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		restrictions, err := s.r.GetAccountRestrictions(ctx, userId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		balance.Blocked = restrictions.Blocked
+		balance.Frozen = restrictions.Frozen
+		balance.CreditsFrozen = restrictions.CreditsFrozen
+		balance.Available = account.Balance + account.OverdraftLimit - balance.Held - balance.Blocked
+
+		if balance.Frozen && balance.Available > 0 {
+			balance.Available = 0
+		}
+
+		balance.AccruedInterest, err = s.r.GetAccruedInterest(ctx, userId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
 		balanceCh <- balance
 	}()
 
 	select {
 	case balance := <-balanceCh:
-		balanceResponse.Balance = balance
+		balanceResponse = balance
 		return balanceResponse, nil
 	case err := <-errCh:
 		return balanceResponse, err
@@ -111,6 +182,329 @@ func (s *service) GetBalance(ctx context.Context, userId uint64) (BalanceRespons
 	}
 }
 
+func (s *service) ListOwn(ctx context.Context, customerId uint64) (ListCustomerAccountsResponse, error) {
+	accountsCh := make(chan []CustomerAccount)
+	errCh := make(chan error)
+
+	go func() {
+		accounts, err := s.r.ListCustomerAccounts(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		accountsCh <- accounts
+	}()
+
+	select {
+	case accounts := <-accountsCh:
+		return ListCustomerAccountsResponse{Data: accounts}, nil
+	case err := <-errCh:
+		return ListCustomerAccountsResponse{}, err
+	case <-ctx.Done():
+		return ListCustomerAccountsResponse{}, ctx.Err()
+	}
+}
+
+func (s *service) OpenAccount(ctx context.Context, customerId uint64, o OpenAccountRequest) (CustomerAccount, error) {
+	accountCh := make(chan CustomerAccount)
+	errCh := make(chan error)
+
+	go func() {
+		if o.Product == "" {
+			o.Product = Checking
+		}
+
+		if err := validateProduct(o.Product); err != nil {
+			errCh <- err
+			return
+		}
+
+		account, err := s.r.AddCustomerAccount(ctx, customerId, o.Product)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		accountCh <- account
+	}()
+
+	select {
+	case account := <-accountCh:
+		return account, nil
+	case err := <-errCh:
+		return CustomerAccount{}, err
+	case <-ctx.Done():
+		return CustomerAccount{}, ctx.Err()
+	}
+}
+
+func (s *service) SetOverdraftLimit(ctx context.Context, accountId uint64, o OverdraftLimitRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if o.Limit == nil {
+			errCh <- apperrors.NewArgumentError("limit")
+			return
+		}
+
+		if *o.Limit < 0 {
+			errCh <- apperrors.NewArgumentError("limit must not be negative")
+			return
+		}
+
+		if err := s.r.SetOverdraftLimit(ctx, accountId, *o.Limit); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetApprovalPolicy replaces the account approvers and approval threshold.
+func (s *service) SetApprovalPolicy(ctx context.Context, accountId uint64, p ApprovalPolicyRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := validateApprovalPolicyValues(p); err != nil {
+			errCh <- err
+			return
+		}
+
+		if p.Threshold == nil {
+			p.RequiredApprovals = 0
+			p.Approvers = nil
+		}
+
+		if err := s.r.SetApprovalPolicy(ctx, accountId, p); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UpdateProfile changes the customer name.
+func (s *service) UpdateProfile(ctx context.Context, customerId uint64, u UpdateProfileRequest) (Profile, error) {
+	var profile Profile
+	profileCh := make(chan Profile)
+	errCh := make(chan error)
+
+	go func() {
+		if u.Name == nil {
+			errCh <- apperrors.NewArgumentError("nothing to update")
+			return
+		}
+
+		name := strings.TrimSpace(*u.Name)
+
+		if name == "" || len(name) > MaxNameLength {
+			errCh <- apperrors.NewArgumentError("name must have 1 to 120 characters")
+			return
+		}
+
+		profile, err := s.r.UpdateCustomerName(ctx, customerId, name)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		profileCh <- profile
+	}()
+
+	select {
+	case profile = <-profileCh:
+		profile.Cpf = validators.FormatCPF(profile.Cpf)
+		return profile, nil
+	case err := <-errCh:
+		return profile, err
+	case <-ctx.Done():
+		return profile, ctx.Err()
+	}
+}
+
+// Deactivate suspends an account: its tokens stop working and no one can log
+// into it until it is reactivated.
+func (s *service) Deactivate(ctx context.Context, accountId uint64) error {
+	return s.setActive(ctx, accountId, false)
+}
+
+// Reactivate lifts a deactivation. Closed accounts cannot be reactivated.
+func (s *service) Reactivate(ctx context.Context, accountId uint64) error {
+	return s.setActive(ctx, accountId, true)
+}
+
+func (s *service) setActive(ctx context.Context, accountId uint64, active bool) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		account, err := s.r.GetAccountById(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if account.ClosedAt != nil {
+			errCh <- apperrors.NewArgumentError("account is closed")
+			return
+		}
+
+		if err := s.r.SetAccountActive(ctx, accountId, active); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes an account of the customer for good. Its balance must be zero
+// or be paid out, with the account PIN, to PayoutDestination, another account
+// of the customer.
+func (s *service) Close(ctx context.Context, customerId uint64, accountId uint64, c CloseAccountRequest) (CloseAccountResponse, error) {
+	var response CloseAccountResponse
+	responseCh := make(chan CloseAccountResponse)
+	errCh := make(chan error)
+
+	go func() {
+		account, err := s.r.GetAccountById(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if account.CustomerId != customerId {
+			errCh <- apperrors.NewAccountNotFoundError("account not found")
+			return
+		}
+
+		if account.ClosedAt != nil {
+			errCh <- apperrors.NewArgumentError("account already closed")
+			return
+		}
+
+		if c.PayoutDestination != nil {
+			if err := s.checkPayout(ctx, customerId, accountId, *c.PayoutDestination, c.Pin); err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		response, err := s.r.CloseAccount(ctx, accountId, c.PayoutDestination)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- response
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// checkPayout checks the PIN of the account being closed and that the payout
+// destination is an account of the customer that can take the balance. Paying
+// out to anyone else would be a transfer without the transfer checks.
+func (s *service) checkPayout(ctx context.Context, customerId uint64, accountId uint64, destination uint64, pin string) error {
+	if s.pins != nil {
+		if pin == "" {
+			return apperrors.NewArgumentError("pin")
+		}
+
+		if err := s.pins.Verify(ctx, accountId, pin); err != nil {
+			return err
+		}
+	}
+
+	if destination == accountId {
+		return apperrors.NewArgumentError("payoutDestination must be another account")
+	}
+
+	payout, err := s.r.GetAccountById(ctx, destination)
+	if err != nil {
+		if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+			return apperrors.NewArgumentError("payoutDestination account not found")
+		}
+		return err
+	}
+
+	if payout.CustomerId != customerId {
+		return apperrors.NewArgumentError("payoutDestination must be an account of the customer")
+	}
+
+	if !payout.Active {
+		return apperrors.NewArgumentError("payoutDestination account is not active")
+	}
+
+	return nil
+}
+
+func validateApprovalPolicyValues(p ApprovalPolicyRequest) error {
+	if p.Threshold == nil {
+		return nil
+	}
+
+	if *p.Threshold < 0 {
+		return apperrors.NewArgumentError("threshold must not be negative")
+	}
+
+	if p.RequiredApprovals < 1 {
+		return apperrors.NewArgumentError("requiredApprovals must be at least 1")
+	}
+
+	seen := map[uint64]bool{}
+
+	for _, id := range p.Approvers {
+		if seen[id] {
+			return apperrors.NewArgumentError("approvers must not repeat")
+		}
+		seen[id] = true
+	}
+
+	if len(p.Approvers) < p.RequiredApprovals {
+		return apperrors.NewArgumentError("approvers must be at least requiredApprovals")
+	}
+
+	return nil
+}
+
 func validateAccountValues(a NewAccountRequest) error {
 	var invalid []string
 
@@ -143,9 +537,24 @@ func validateAccountValues(a NewAccountRequest) error {
 		invalid = append(invalid, "invalid balance")
 	}
 
+	if a.Product != "" {
+		if err := validateProduct(a.Product); err != nil {
+			invalid = append(invalid, "invalid product")
+		}
+	}
+
 	if len(invalid) > 0 {
 		return apperrors.NewArgumentError(strings.Join(invalid, ", "))
 	}
 
 	return nil
 }
+
+func validateProduct(product string) error {
+	switch product {
+	case Checking, Savings:
+		return nil
+	default:
+		return apperrors.NewArgumentError("product must be one of", strings.Join([]string{Checking, Savings}, ", "))
+	}
+}
@@ -12,6 +12,7 @@ const CONFIG_ERROR_PREFIX string = "configuration error"
 const AUTH_ERROR_PREFIX string = "authentication error"
 const VALIDATOR_ERROR_PREFIX string = "validator error"
 const INTERNAL_ERROR_PREFIX string = "server error"
+const CHALLENGE_ERROR_PREFIX string = "additional verification required"
 
 type ArgumentError struct {
 	Context string
@@ -53,6 +54,11 @@ type InternalServerError struct {
 	Err     string
 }
 
+type ChallengeError struct {
+	Context string
+	Err     string
+}
+
 type RestError struct {
 	Err string `json:"error"`
 }
@@ -89,6 +95,10 @@ func (e *InternalServerError) Error() string {
 	return fmt.Sprintf("%s: %s", e.Err, e.Context)
 }
 
+func (e *ChallengeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Err, e.Context)
+}
+
 func NewArgumentError(context ...string) error {
 	return &ArgumentError{Context: strings.Join(context, ": "), Err: ARGUMENT_ERROR_PREFIX}
 }
@@ -120,3 +130,7 @@ func NewValidatorError(context ...string) error {
 func NewInternalServerError(context ...string) error {
 	return &InternalServerError{Context: strings.Join(context, ": "), Err: INTERNAL_ERROR_PREFIX}
 }
+
+func NewChallengeError(context ...string) error {
+	return &ChallengeError{Context: strings.Join(context, ": "), Err: CHALLENGE_ERROR_PREFIX}
+}
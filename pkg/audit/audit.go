@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionAccountCreate        = "account.create"
+	ActionAccountOpen          = "account.open"
+	ActionAccountClose         = "account.close"
+	ActionLogin                = "login"
+	ActionTransferCreate       = "transfer.create"
+	ActionLimitLower           = "limits.lower"
+	ActionLimitRaiseRequest    = "limits.raise_request"
+	ActionLimitRaiseApply      = "limits.raise_apply"
+	ActionOverdraftLimitSet    = "admin.overdraft_limit.set"
+	ActionApprovalPolicySet    = "admin.approval_policy.set"
+	ActionAccountDeactivate    = "admin.account.deactivate"
+	ActionAccountReactivate    = "admin.account.reactivate"
+	ActionAccountFreeze        = "admin.freeze.create"
+	ActionAccountFreezeLift    = "admin.freeze.lift"
+	ActionJudicialBlock        = "admin.judicial_block.create"
+	ActionJudicialBlockRelease = "admin.judicial_block.release"
+	ActionApiClientCreate      = "admin.client.create"
+	ActionApiClientRevoke      = "admin.client.revoke"
+	ActionCustomerAnonymize    = "admin.customer.anonymize"
+	ActionCustomerUpdate       = "customer.update"
+	ActionSecretChange         = "secret.change"
+	ActionSecretReset          = "secret.reset"
+	ActionPinSet               = "pin.set"
+	ActionMfaEnable            = "mfa.enable"
+	ActionMfaDisable           = "mfa.disable"
+	ActionTransferRequest      = "transfer.request"
+	ActionTransferApprove      = "transfer.approve"
+	ActionTransferReject       = "transfer.reject"
+	ActionTransferFinish       = "transfer.finish"
+	ActionHoldAuthorize        = "hold.authorize"
+	ActionHoldCapture          = "hold.capture"
+	ActionHoldVoid             = "hold.void"
+	ActionWebhookCreate        = "webhook.create"
+	ActionWebhookDelete        = "webhook.delete"
+)
+
+// GenesisHash is the previous hash of the first event of the chain.
+var GenesisHash = strings.Repeat("0", 64)
+
+// Actor is who asked for an operation: a customer, an API client acting for
+// one, or the system itself when both are empty, as in background jobs.
+type Actor struct {
+	CustomerId *uint64
+	ClientId   string
+	RequestId  string
+	Ip         string
+}
+
+// Event is an entry of the audit log. Hash covers every other field and the
+// hash of the previous event, so changing or removing an event breaks the
+// chain from it on.
+type Event struct {
+	Id        uint64          `json:"id"`
+	ActorId   *uint64         `json:"actorId"`
+	ClientId  string          `json:"clientId,omitempty"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target"`
+	RequestId string          `json:"requestId,omitempty"`
+	Ip        string          `json:"ip,omitempty"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	CreatedAt time.Time       `json:"createdAt"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+// ListEventsQuery filters the audit log. Events are listed newest first,
+// BeforeId pages through older ones.
+type ListEventsQuery struct {
+	ActorId   *uint64
+	Action    string
+	Target    string
+	RequestId string
+	From      *time.Time
+	To        *time.Time
+	BeforeId  *uint64
+	Limit     int
+}
+
+type ListEventsResponse struct {
+	Events []Event `json:"events"`
+}
+
+// VerifyResponse tells whether the chain is intact. BrokenAt is the first
+// event whose hash does not match.
+type VerifyResponse struct {
+	Valid    bool    `json:"valid"`
+	Checked  int     `json:"checked"`
+	BrokenAt *uint64 `json:"brokenAt,omitempty"`
+}
+
+type actorContextKey struct{}
+
+// NewContext returns a copy of ctx carrying a.
+func NewContext(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, a)
+}
+
+// ActorFrom returns the actor carried by ctx, the system when there is none.
+func ActorFrom(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorContextKey{}).(Actor)
+	return a
+}
+
+// WithCustomer returns a copy of ctx whose actor is the customer, acting
+// through client when it is not empty.
+func WithCustomer(ctx context.Context, customerId uint64, client string) context.Context {
+	a := ActorFrom(ctx)
+	a.CustomerId = &customerId
+	a.ClientId = client
+
+	return NewContext(ctx, a)
+}
+
+// Target names the object of an event, as in account:42.
+func Target(kind string, id uint64) string {
+	return kind + ":" + strconv.FormatUint(id, 10)
+}
+
+// NewEvent returns the event of actor doing action on target at now, the
+// state before and after it encoded as JSON. The hash is left to be chained.
+func NewEvent(actor Actor, action string, target string, before interface{}, after interface{}, now time.Time) (Event, error) {
+	e := Event{
+		ActorId:   actor.CustomerId,
+		ClientId:  actor.ClientId,
+		Action:    action,
+		Target:    target,
+		RequestId: actor.RequestId,
+		Ip:        actor.Ip,
+		// The database keeps microseconds, the hash must survive the round trip.
+		CreatedAt: now.UTC().Truncate(time.Microsecond),
+	}
+
+	var err error
+
+	if e.Before, err = encode(before); err != nil {
+		return e, err
+	}
+
+	if e.After, err = encode(after); err != nil {
+		return e, err
+	}
+
+	return e, nil
+}
+
+// ComputeHash returns the hash of e chained to prevHash.
+func (e Event) ComputeHash(prevHash string) (string, error) {
+	before, err := Canonical(e.Before)
+	if err != nil {
+		return "", err
+	}
+
+	after, err := Canonical(e.After)
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(struct {
+		PrevHash  string
+		ActorId   *uint64
+		ClientId  string
+		Action    string
+		Target    string
+		RequestId string
+		Ip        string
+		Before    json.RawMessage
+		After     json.RawMessage
+		CreatedAt string
+	}{prevHash, e.ActorId, e.ClientId, e.Action, e.Target, e.RequestId, e.Ip, before, after,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(payload)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks that events, in chain order, follow prevHash. It returns how
+// many events are good and the first bad one, if any.
+func Verify(prevHash string, events []Event) (int, *Event) {
+	for i, e := range events {
+		hash, err := e.ComputeHash(prevHash)
+
+		if err != nil || e.PrevHash != prevHash || e.Hash != hash {
+			return i, &events[i]
+		}
+
+		prevHash = e.Hash
+	}
+
+	return len(events), nil
+}
+
+// Canonical re-encodes a JSON document so that it does not depend on the key
+// order and spacing the database returns it with.
+func Canonical(raw json.RawMessage) (json.RawMessage, error) {
+	if len(raw) == 0 {
+		return json.RawMessage("null"), nil
+	}
+
+	var v interface{}
+
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(v)
+}
+
+func encode(v interface{}) (json.RawMessage, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return Canonical(raw)
+}
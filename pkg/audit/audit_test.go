@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func chain(t *testing.T, n int) []Event {
+	t.Helper()
+
+	customerId := uint64(7)
+	actor := Actor{CustomerId: &customerId, RequestId: "req-1", Ip: "10.0.0.1"}
+	prevHash := GenesisHash
+	events := make([]Event, 0, n)
+
+	for i := 0; i < n; i++ {
+		e, err := NewEvent(actor, ActionTransferCreate, Target("account", uint64(i+1)),
+			nil, map[string]interface{}{"amount": 100 * (i + 1), "kind": "transfer"}, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		e.Id = uint64(i + 1)
+		e.PrevHash = prevHash
+
+		if e.Hash, err = e.ComputeHash(prevHash); err != nil {
+			t.Fatal(err)
+		}
+
+		prevHash = e.Hash
+		events = append(events, e)
+	}
+
+	return events
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("intact chain", func(t *testing.T) {
+		events := chain(t, 3)
+
+		if checked, broken := Verify(GenesisHash, events); broken != nil || checked != 3 {
+			t.Errorf("got %d checked, broken at %v", checked, broken)
+		}
+	})
+
+	t.Run("changed event", func(t *testing.T) {
+		events := chain(t, 3)
+		events[1].After = json.RawMessage(`{"amount":1,"kind":"transfer"}`)
+
+		if checked, broken := Verify(GenesisHash, events); broken == nil || broken.Id != 2 || checked != 1 {
+			t.Errorf("got %d checked, broken at %v", checked, broken)
+		}
+	})
+
+	t.Run("removed event", func(t *testing.T) {
+		events := chain(t, 3)
+		events = append(events[:1], events[2:]...)
+
+		if _, broken := Verify(GenesisHash, events); broken == nil || broken.Id != 3 {
+			t.Errorf("got broken at %v", broken)
+		}
+	})
+
+	t.Run("changed actor", func(t *testing.T) {
+		events := chain(t, 3)
+		other := uint64(8)
+		events[2].ActorId = &other
+
+		if _, broken := Verify(GenesisHash, events); broken == nil || broken.Id != 3 {
+			t.Errorf("got broken at %v", broken)
+		}
+	})
+}
+
+func TestCanonical(t *testing.T) {
+	a, err := Canonical(json.RawMessage(`{"kind": "transfer", "amount": 100}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Canonical(json.RawMessage(`{"amount":100,"kind":"transfer"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(a) != string(b) {
+		t.Errorf("got %s and %s", a, b)
+	}
+
+	if empty, _ := Canonical(nil); string(empty) != "null" {
+		t.Errorf("got %s for an empty document", empty)
+	}
+}
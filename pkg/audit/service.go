@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+const (
+	DefaultListLimit = 100
+	MaxListLimit     = 1000
+
+	// verifyBatchSize is how many events Verify reads at a time.
+	verifyBatchSize = 1000
+)
+
+type Repository interface {
+	ListAuditEvents(context.Context, ListEventsQuery) ([]Event, error)
+	ListAuditChain(context.Context, uint64, int) ([]Event, error)
+}
+
+type Service interface {
+	List(context.Context, ListEventsQuery) (ListEventsResponse, error)
+	Verify(context.Context) (VerifyResponse, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+func (s *service) List(ctx context.Context, q ListEventsQuery) (ListEventsResponse, error) {
+	var response ListEventsResponse
+	eventsCh := make(chan []Event)
+	errCh := make(chan error)
+
+	go func() {
+		if q.Limit == 0 {
+			q.Limit = DefaultListLimit
+		}
+
+		if q.Limit < 1 || q.Limit > MaxListLimit {
+			errCh <- apperrors.NewArgumentError("limit")
+			return
+		}
+
+		if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+			errCh <- apperrors.NewArgumentError("to")
+			return
+		}
+
+		events, err := s.r.ListAuditEvents(ctx, q)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		eventsCh <- events
+	}()
+
+	select {
+	case response.Events = <-eventsCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// Verify walks the whole chain from the first event, checking every hash.
+func (s *service) Verify(ctx context.Context) (VerifyResponse, error) {
+	var response VerifyResponse
+	responseCh := make(chan VerifyResponse)
+	errCh := make(chan error)
+
+	go func() {
+		v := VerifyResponse{Valid: true}
+		prevHash := GenesisHash
+		var lastId uint64
+
+		for {
+			events, err := s.r.ListAuditChain(ctx, lastId, verifyBatchSize)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			checked, broken := Verify(prevHash, events)
+			v.Checked += checked
+
+			if broken != nil {
+				v.Valid = false
+				v.BrokenAt = &broken.Id
+				break
+			}
+
+			if len(events) < verifyBatchSize {
+				break
+			}
+
+			prevHash = events[len(events)-1].Hash
+			lastId = events[len(events)-1].Id
+		}
+
+		responseCh <- v
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
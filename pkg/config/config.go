@@ -12,7 +12,6 @@ import (
 )
 
 var requiredEnvs []string = []string{
-	"JWT_SECRET",
 	"APP_PORT",
 	"DB_PW",
 	"DB_PORT",
@@ -35,6 +34,8 @@ var (
 	ServerIdleTimeout       time.Duration
 	ServerReadHeaderTimeout time.Duration
 	RequestTimeout          time.Duration
+	JobsInterval            time.Duration  = time.Hour
+	Location                *time.Location = time.UTC
 )
 
 func Load(path string) error {
@@ -49,6 +50,10 @@ func Load(path string) error {
 		return err
 	}
 
+	if err := fillJobsValues(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -61,6 +66,12 @@ func verifyEnv() error {
 		}
 	}
 
+	// Tokens are signed with the keys in JWT_KEYS_DIR or, without it, the
+	// JWT_SECRET HMAC secret.
+	if os.Getenv("JWT_KEYS_DIR") == "" && os.Getenv("JWT_SECRET") == "" {
+		missing = append(missing, "JWT_SECRET or JWT_KEYS_DIR")
+	}
+
 	if len(missing) > 0 {
 		return apperrors.NewEnvVarError("Missing required variables", strings.Join(missing, ", "))
 	}
@@ -113,3 +124,35 @@ func fillTimeOutValues() error {
 
 	return nil
 }
+
+// fillJobsValues reads the optional background jobs settings, keeping the
+// defaults for the ones not set.
+func fillJobsValues() error {
+	var invalid []string
+
+	if v := os.Getenv("JOBS_INTERVAL_S"); v != "" {
+		ji, err := strconv.Atoi(v)
+
+		if err != nil || ji < 1 {
+			invalid = append(invalid, "JOBS_INTERVAL_S")
+		} else {
+			JobsInterval = (time.Duration(ji) * time.Second)
+		}
+	}
+
+	if v := os.Getenv("TIMEZONE"); v != "" {
+		loc, err := time.LoadLocation(v)
+
+		if err != nil {
+			invalid = append(invalid, "TIMEZONE")
+		} else {
+			Location = loc
+		}
+	}
+
+	if len(invalid) > 0 {
+		return apperrors.NewEnvVarError("invalid env value", strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package freeze
+
+import "time"
+
+// Freeze stops all debits from an account, and also its credits when
+// BlockCredits is set, until it is lifted. CreatedBy and LiftedBy are the
+// admins that did it.
+type Freeze struct {
+	Id           uint64     `json:"id"`
+	AccountId    uint64     `json:"accountId"`
+	BlockCredits bool       `json:"blockCredits"`
+	Reason       string     `json:"reason"`
+	Reference    string     `json:"reference"`
+	CreatedBy    uint64     `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LiftedBy     *uint64    `json:"liftedBy,omitempty"`
+	LiftedAt     *time.Time `json:"liftedAt,omitempty"`
+}
+
+// JudicialBlock (bloqueio judicial) reserves Amount of the account funds, by
+// court order, until it is released. The account may not have the whole
+// amount: it can still take credits, but no debit is allowed while what is
+// left would not cover the blocks.
+type JudicialBlock struct {
+	Id         uint64     `json:"id"`
+	AccountId  uint64     `json:"accountId"`
+	Amount     int64      `json:"amount"`
+	Reason     string     `json:"reason"`
+	Reference  string     `json:"reference"`
+	CreatedBy  uint64     `json:"createdBy"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ReleasedBy *uint64    `json:"releasedBy,omitempty"`
+	ReleasedAt *time.Time `json:"releasedAt,omitempty"`
+}
+
+// FreezeRequest freezes an account. Reference is the ticket, case or court
+// order number behind it.
+type FreezeRequest struct {
+	BlockCredits bool   `json:"blockCredits"`
+	Reason       string `json:"reason"`
+	Reference    string `json:"reference"`
+}
+
+// JudicialBlockRequest blocks Amount of an account by court order, Reference
+// being the case number.
+type JudicialBlockRequest struct {
+	Amount    *int64 `json:"amount"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference"`
+}
+
+// ListResponse carries every freeze and judicial block of an account, lifted
+// and released ones included.
+type ListResponse struct {
+	Freezes        []Freeze        `json:"freezes"`
+	JudicialBlocks []JudicialBlock `json:"judicialBlocks"`
+}
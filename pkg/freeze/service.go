@@ -0,0 +1,232 @@
+package freeze
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// maxTextLength bounds reasons and references.
+const maxTextLength = 500
+
+type Repository interface {
+	AddAccountFreeze(context.Context, Freeze) (Freeze, error)
+	LiftAccountFreeze(context.Context, uint64, uint64, uint64) (Freeze, error)
+	ListAccountFreezes(context.Context, uint64) ([]Freeze, error)
+	AddJudicialBlock(context.Context, JudicialBlock) (JudicialBlock, error)
+	ReleaseJudicialBlock(context.Context, uint64, uint64, uint64) (JudicialBlock, error)
+	ListJudicialBlocks(context.Context, uint64) ([]JudicialBlock, error)
+	GetAccountById(context.Context, uint64) (account.Account, error)
+}
+
+type Service interface {
+	Freeze(context.Context, uint64, uint64, FreezeRequest) (Freeze, error)
+	Lift(context.Context, uint64, uint64, uint64) (Freeze, error)
+	Block(context.Context, uint64, uint64, JudicialBlockRequest) (JudicialBlock, error)
+	Release(context.Context, uint64, uint64, uint64) (JudicialBlock, error)
+	List(context.Context, uint64) (ListResponse, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+// Freeze freezes an account on behalf of the admin author.
+func (s *service) Freeze(ctx context.Context, accountId uint64, author uint64, f FreezeRequest) (Freeze, error) {
+	var freeze Freeze
+	freezeCh := make(chan Freeze)
+	errCh := make(chan error)
+
+	go func() {
+		if err := validateText(f.Reason, f.Reference); err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.r.GetAccountById(ctx, accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		freeze, err := s.r.AddAccountFreeze(ctx, Freeze{
+			AccountId:    accountId,
+			BlockCredits: f.BlockCredits,
+			Reason:       strings.TrimSpace(f.Reason),
+			Reference:    strings.TrimSpace(f.Reference),
+			CreatedBy:    author,
+		})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		freezeCh <- freeze
+	}()
+
+	select {
+	case freeze = <-freezeCh:
+		return freeze, nil
+	case err := <-errCh:
+		return freeze, err
+	case <-ctx.Done():
+		return freeze, ctx.Err()
+	}
+}
+
+// Lift lifts a freeze of the account on behalf of the admin author.
+func (s *service) Lift(ctx context.Context, accountId uint64, id uint64, author uint64) (Freeze, error) {
+	var freeze Freeze
+	freezeCh := make(chan Freeze)
+	errCh := make(chan error)
+
+	go func() {
+		freeze, err := s.r.LiftAccountFreeze(ctx, accountId, id, author)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		freezeCh <- freeze
+	}()
+
+	select {
+	case freeze = <-freezeCh:
+		return freeze, nil
+	case err := <-errCh:
+		return freeze, err
+	case <-ctx.Done():
+		return freeze, ctx.Err()
+	}
+}
+
+// Block blocks an amount of the account funds on behalf of the admin author.
+func (s *service) Block(ctx context.Context, accountId uint64, author uint64, b JudicialBlockRequest) (JudicialBlock, error) {
+	var block JudicialBlock
+	blockCh := make(chan JudicialBlock)
+	errCh := make(chan error)
+
+	go func() {
+		if b.Amount == nil || *b.Amount < 1 {
+			errCh <- apperrors.NewArgumentError("amount")
+			return
+		}
+
+		if err := validateText(b.Reason, b.Reference); err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.r.GetAccountById(ctx, accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		block, err := s.r.AddJudicialBlock(ctx, JudicialBlock{
+			AccountId: accountId,
+			Amount:    *b.Amount,
+			Reason:    strings.TrimSpace(b.Reason),
+			Reference: strings.TrimSpace(b.Reference),
+			CreatedBy: author,
+		})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		blockCh <- block
+	}()
+
+	select {
+	case block = <-blockCh:
+		return block, nil
+	case err := <-errCh:
+		return block, err
+	case <-ctx.Done():
+		return block, ctx.Err()
+	}
+}
+
+// Release releases a judicial block of the account on behalf of the admin
+// author.
+func (s *service) Release(ctx context.Context, accountId uint64, id uint64, author uint64) (JudicialBlock, error) {
+	var block JudicialBlock
+	blockCh := make(chan JudicialBlock)
+	errCh := make(chan error)
+
+	go func() {
+		block, err := s.r.ReleaseJudicialBlock(ctx, accountId, id, author)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		blockCh <- block
+	}()
+
+	select {
+	case block = <-blockCh:
+		return block, nil
+	case err := <-errCh:
+		return block, err
+	case <-ctx.Done():
+		return block, ctx.Err()
+	}
+}
+
+func (s *service) List(ctx context.Context, accountId uint64) (ListResponse, error) {
+	var response ListResponse
+	responseCh := make(chan ListResponse)
+	errCh := make(chan error)
+
+	go func() {
+		var list ListResponse
+		var err error
+
+		if list.Freezes, err = s.r.ListAccountFreezes(ctx, accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		if list.JudicialBlocks, err = s.r.ListJudicialBlocks(ctx, accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- list
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// validateText requires a reason and a reference, so every freeze and block
+// can be traced back to why it was made.
+func validateText(reason string, reference string) error {
+	var invalid []string
+
+	if r := strings.TrimSpace(reason); r == "" || len(r) > maxTextLength {
+		invalid = append(invalid, "reason")
+	}
+
+	if r := strings.TrimSpace(reference); r == "" || len(r) > maxTextLength {
+		invalid = append(invalid, "reference")
+	}
+
+	if len(invalid) > 0 {
+		return apperrors.NewArgumentError(strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
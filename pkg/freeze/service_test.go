@@ -0,0 +1,37 @@
+package freeze
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateText(t *testing.T) {
+	tests := []struct {
+		name      string
+		reason    string
+		reference string
+		invalid   string
+	}{
+		{"valid", "suspeita de fraude", "CHAMADO-123", ""},
+		{"missing reason", " ", "CHAMADO-123", "reason"},
+		{"missing both", "", "", "reason, reference"},
+		{"long reference", "ordem judicial", strings.Repeat("a", maxTextLength+1), "reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateText(tt.reason, tt.reference)
+
+			if tt.invalid == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.invalid) {
+				t.Errorf("got error %v want one about %s", err, tt.invalid)
+			}
+		})
+	}
+}
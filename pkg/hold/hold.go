@@ -0,0 +1,60 @@
+package hold
+
+import "time"
+
+// Hold statuses. Only active holds that have not expired reduce the
+// available balance.
+const (
+	StatusActive   = "active"
+	StatusCaptured = "captured"
+	StatusVoided   = "voided"
+	StatusExpired  = "expired"
+)
+
+// DefaultExpiry is used when an authorization does not ask for one, and
+// MaxExpiry is the longest a hold may reserve funds for.
+const (
+	DefaultExpiry = 7 * 24 * time.Hour
+	MaxExpiry     = 30 * 24 * time.Hour
+)
+
+// Hold reserves Amount of the account funds in favor of Destination until it
+// is captured, voided or expires.
+type Hold struct {
+	Id             uint64     `json:"id"`
+	AccountId      uint64     `json:"accountId"`
+	Destination    uint64     `json:"destination"`
+	Amount         int64      `json:"amount"`
+	CapturedAmount int64      `json:"capturedAmount"`
+	Reference      string     `json:"reference"`
+	Status         string     `json:"status"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	TransferId     *uint64    `json:"transferId,omitempty"`
+}
+
+// AuthorizeRequest asks for a hold of Amount in favor of Destination,
+// expiring after ExpiresIn seconds.
+type AuthorizeRequest struct {
+	Destination *uint64 `json:"destination"`
+	Amount      *int64  `json:"amount"`
+	Reference   string  `json:"reference"`
+	ExpiresIn   *int64  `json:"expiresIn"`
+	// Pin is the origin account transaction PIN.
+	Pin string `json:"pin"`
+	// Secret is the customer secret, sent again to answer a risk challenge.
+	Secret string `json:"secret,omitempty"`
+	// RequestedBy is the authenticated customer, never read from the body.
+	RequestedBy uint64 `json:"-"`
+}
+
+// CaptureRequest settles a hold. A nil Amount captures it in full, a smaller
+// one captures it partially and releases the rest.
+type CaptureRequest struct {
+	Amount *int64 `json:"amount"`
+}
+
+type ListHoldsResponse struct {
+	Holds []Hold `json:"holds"`
+}
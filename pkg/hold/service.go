@@ -0,0 +1,324 @@
+package hold
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/risk"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// maxReferenceLength bounds the free text reference of a hold.
+const maxReferenceLength = 140
+
+type Repository interface {
+	AddHold(context.Context, Hold, transfer.LimitWindows) (Hold, error)
+	GetHold(context.Context, uint64) (Hold, error)
+	CaptureHold(context.Context, uint64, int64, transfer.LimitWindows) (Hold, error)
+	VoidHold(context.Context, uint64) (Hold, error)
+	ListAccountHolds(context.Context, uint64) ([]Hold, error)
+	ExpireHolds(context.Context, time.Time) (int, error)
+	GetAccountById(context.Context, uint64) (account.Account, error)
+}
+
+type Service interface {
+	Authorize(context.Context, uint64, AuthorizeRequest) (Hold, error)
+	Capture(context.Context, uint64, uint64, CaptureRequest) (Hold, error)
+	Void(context.Context, uint64, uint64) (Hold, error)
+	List(context.Context, uint64) (ListHoldsResponse, error)
+	ExpireDue(context.Context, time.Time) error
+}
+
+// Screener assesses the risk of a hold as of a transfer.
+type Screener interface {
+	Screen(context.Context, risk.Screening) (risk.Assessment, error)
+}
+
+// PinVerifier checks the transaction PIN of an account.
+type PinVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+type service struct {
+	r        Repository
+	screener Screener
+	pins     PinVerifier
+}
+
+// New builds the hold service. A nil screener lets every hold through and a
+// nil pins does not ask for the transaction PIN to authorize holds.
+func New(r Repository, screener Screener, pins PinVerifier) *service {
+	return &service{r, screener, pins}
+}
+
+// Authorize reserves funds of origin in favor of the request destination,
+// after checking the origin transaction PIN and screening it as a transfer
+// is. The origin must be able to afford the amount and stay within its
+// transfer limits, as if it was transferred right away.
+func (s *service) Authorize(ctx context.Context, origin uint64, a AuthorizeRequest) (Hold, error) {
+	holdCh := make(chan Hold)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.verifyPin(ctx, origin, a.Pin); err != nil {
+			errCh <- err
+			return
+		}
+
+		now := time.Now()
+
+		expiresAt, err := validateAuthorizeValues(origin, a, now)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.r.GetAccountById(ctx, *a.Destination); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.screen(ctx, risk.Screening{
+			Origin:      origin,
+			Destination: *a.Destination,
+			Amount:      *a.Amount,
+			CustomerId:  a.RequestedBy,
+			Secret:      a.Secret,
+		}); err != nil {
+			errCh <- err
+			return
+		}
+
+		h, err := s.r.AddHold(ctx, Hold{
+			AccountId:   origin,
+			Destination: *a.Destination,
+			Amount:      *a.Amount,
+			Reference:   a.Reference,
+			Status:      StatusActive,
+			ExpiresAt:   expiresAt,
+		}, transfer.Windows(now.In(config.Location)))
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		holdCh <- h
+	}()
+
+	select {
+	case h := <-holdCh:
+		return h, nil
+	case err := <-errCh:
+		return Hold{}, err
+	case <-ctx.Done():
+		return Hold{}, ctx.Err()
+	}
+}
+
+// Capture settles a hold as a transfer to its destination, which is the only
+// account allowed to capture it.
+func (s *service) Capture(ctx context.Context, accountId uint64, id uint64, c CaptureRequest) (Hold, error) {
+	holdCh := make(chan Hold)
+	errCh := make(chan error)
+
+	go func() {
+		h, err := s.getOwnHold(ctx, accountId, id)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		amount := h.Amount
+
+		if c.Amount != nil {
+			amount = *c.Amount
+		}
+
+		if amount < 1 || amount > h.Amount {
+			errCh <- apperrors.NewArgumentError("amount must be positive and at most the hold amount")
+			return
+		}
+
+		h, err = s.r.CaptureHold(ctx, id, amount, transfer.Windows(time.Now().In(config.Location)))
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		holdCh <- h
+	}()
+
+	select {
+	case h := <-holdCh:
+		return h, nil
+	case err := <-errCh:
+		return Hold{}, err
+	case <-ctx.Done():
+		return Hold{}, ctx.Err()
+	}
+}
+
+// Void releases a hold without moving any funds. Like Capture, only the hold
+// destination may do it.
+func (s *service) Void(ctx context.Context, accountId uint64, id uint64) (Hold, error) {
+	holdCh := make(chan Hold)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.getOwnHold(ctx, accountId, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		h, err := s.r.VoidHold(ctx, id)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		holdCh <- h
+	}()
+
+	select {
+	case h := <-holdCh:
+		return h, nil
+	case err := <-errCh:
+		return Hold{}, err
+	case <-ctx.Done():
+		return Hold{}, ctx.Err()
+	}
+}
+
+// List returns the holds placed on the account and the ones in its favor.
+func (s *service) List(ctx context.Context, accountId uint64) (ListHoldsResponse, error) {
+	holdsCh := make(chan ListHoldsResponse)
+	errCh := make(chan error)
+
+	go func() {
+		holds, err := s.r.ListAccountHolds(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		holdsCh <- ListHoldsResponse{Holds: holds}
+	}()
+
+	select {
+	case holds := <-holdsCh:
+		return holds, nil
+	case err := <-errCh:
+		return ListHoldsResponse{}, err
+	case <-ctx.Done():
+		return ListHoldsResponse{}, ctx.Err()
+	}
+}
+
+// ExpireDue releases every active hold past its expiry.
+func (s *service) ExpireDue(ctx context.Context, now time.Time) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.r.ExpireHolds(ctx, now); err != nil {
+			errCh <- err
+			return
+		}
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// verifyPin checks the origin transaction PIN, before anything else about the
+// hold is looked at.
+func (s *service) verifyPin(ctx context.Context, origin uint64, pin string) error {
+	if s.pins == nil {
+		return nil
+	}
+
+	if pin == "" {
+		return apperrors.NewArgumentError("pin")
+	}
+
+	return s.pins.Verify(ctx, origin, pin)
+}
+
+// screen refuses sc when the screener blocks it or asks for a challenge that
+// sc did not answer.
+func (s *service) screen(ctx context.Context, sc risk.Screening) error {
+	if s.screener == nil {
+		return nil
+	}
+
+	a, err := s.screener.Screen(ctx, sc)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a.Decision == risk.Block:
+		return apperrors.NewTransferRequestError("hold blocked by risk screening", strings.Join(a.Reasons, ", "))
+	case a.Decision == risk.Challenge && a.PassedAt == nil:
+		return apperrors.NewChallengeError(strings.Join(a.Reasons, ", "), "send the customer secret to confirm")
+	}
+
+	return nil
+}
+
+func (s *service) getOwnHold(ctx context.Context, accountId uint64, id uint64) (Hold, error) {
+	h, err := s.r.GetHold(ctx, id)
+	if err != nil {
+		return h, err
+	}
+
+	if h.Destination != accountId {
+		return Hold{}, apperrors.NewAccountNotFoundError("hold not found")
+	}
+
+	return h, nil
+}
+
+// validateAuthorizeValues checks a and returns when the hold expires.
+func validateAuthorizeValues(origin uint64, a AuthorizeRequest, now time.Time) (time.Time, error) {
+	var invalid []string
+
+	if a.Amount == nil || *a.Amount < 1 {
+		invalid = append(invalid, "amount")
+	}
+
+	if a.Destination == nil || *a.Destination == origin {
+		invalid = append(invalid, "destination")
+	}
+
+	if len(a.Reference) > maxReferenceLength {
+		invalid = append(invalid, "reference")
+	}
+
+	expiry := DefaultExpiry
+
+	if a.ExpiresIn != nil {
+		// Checked in seconds: a huge expiresIn would overflow the duration.
+		if *a.ExpiresIn < 1 || *a.ExpiresIn > int64(MaxExpiry/time.Second) {
+			invalid = append(invalid, "expiresIn")
+		}
+
+		expiry = time.Duration(*a.ExpiresIn) * time.Second
+	}
+
+	if len(invalid) > 0 {
+		return time.Time{}, apperrors.NewArgumentError(strings.Join(invalid, ", "))
+	}
+
+	return now.Add(expiry), nil
+}
@@ -0,0 +1,56 @@
+package hold
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateAuthorizeValues(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+	destination := uint64(2)
+	origin := uint64(1)
+	amount := int64(100)
+	zero := int64(0)
+	hour := int64(3600)
+	tooLong := int64(MaxExpiry/time.Second) + 1
+	overflowing := int64(1e10)
+
+	tests := []struct {
+		name      string
+		request   AuthorizeRequest
+		expiresAt time.Time
+		invalid   string
+	}{
+		{"default expiry", AuthorizeRequest{Destination: &destination, Amount: &amount}, now.Add(DefaultExpiry), ""},
+		{"requested expiry", AuthorizeRequest{Destination: &destination, Amount: &amount, ExpiresIn: &hour}, now.Add(time.Hour), ""},
+		{"expiry too long", AuthorizeRequest{Destination: &destination, Amount: &amount, ExpiresIn: &tooLong}, time.Time{}, "expiresIn"},
+		{"expiry overflowing a duration", AuthorizeRequest{Destination: &destination, Amount: &amount, ExpiresIn: &overflowing}, time.Time{}, "expiresIn"},
+		{"zero amount", AuthorizeRequest{Destination: &destination, Amount: &zero}, time.Time{}, "amount"},
+		{"missing values", AuthorizeRequest{}, time.Time{}, "amount, destination"},
+		{"hold on itself", AuthorizeRequest{Destination: &origin, Amount: &amount}, time.Time{}, "destination"},
+		{"long reference", AuthorizeRequest{Destination: &destination, Amount: &amount, Reference: strings.Repeat("a", maxReferenceLength+1)}, time.Time{}, "reference"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt, err := validateAuthorizeValues(origin, tt.request, now)
+
+			if tt.invalid == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+
+				if !expiresAt.Equal(tt.expiresAt) {
+					t.Errorf("got expiry %v want %v", expiresAt, tt.expiresAt)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.invalid) {
+				t.Errorf("got error %v want one about %s", err, tt.invalid)
+			}
+		})
+	}
+}
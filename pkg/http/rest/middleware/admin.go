@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+// Admin only lets requests through when the token, validated by Auth, belongs
+// to an admin.
+func Admin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if role, _ := r.Context().Value(RoleContextKey("role")).(string); role != login.RoleAdmin {
+			respondWithError(w, http.StatusForbidden, apperrors.NewAuthError("admin only"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
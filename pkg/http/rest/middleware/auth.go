@@ -4,50 +4,149 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
 	"github.com/golang-jwt/jwt"
 )
 
 const BEARER_SCHEMA = "Bearer "
 
-type UserIdContextKey string
+type CustomerIdContextKey string
+type AccountIdContextKey string
+type RoleContextKey string
+type MfaContextKey string
+type IssuedAtContextKey string
+type ScopesContextKey string
+type ClientIdContextKey string
+type SessionIdContextKey string
 
-func Auth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		splitToken := strings.Split(r.Header.Get("Authorization"), BEARER_SCHEMA)
+// TokenParser validates a token for an audience and returns its claims.
+type TokenParser interface {
+	Parse(string, string) (jwt.MapClaims, error)
+}
+
+// SessionValidator tells whether a token issued at some time to a customer,
+// for an account and in a session, is still good.
+type SessionValidator interface {
+	ValidateSession(context.Context, uint64, uint64, uint64, time.Time) error
+}
+
+// ClientValidator tells whether a token of an API client, for an account, is
+// still good.
+type ClientValidator interface {
+	ValidateClient(context.Context, string, uint64) error
+}
 
-		if len(splitToken) != 2 {
-			respondWithError(w, http.StatusBadRequest, apperrors.NewAuthError("invalid authentication token"))
-			return
-		}
+// Auth only lets requests through with a valid access token, of a customer
+// and account still active and not revoked since, and puts who the token is
+// for in the request context. Customer tokens must belong to a session not
+// terminated and client tokens to a client not revoked. Every token problem
+// is a 401.
+func Auth(tokens TokenParser, sessions SessionValidator, clients ClientValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
 
-		token, err := jwt.Parse(splitToken[1], func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				respondWithError(w, http.StatusBadRequest, apperrors.NewAuthError("invalid signing method"))
+			if !strings.HasPrefix(header, BEARER_SCHEMA) {
+				unauthorized(w, apperrors.NewAuthError("missing authentication token"))
+				return
+			}
+
+			claims, err := tokens.Parse(strings.TrimPrefix(header, BEARER_SCHEMA), login.Audience)
+
+			if err != nil {
+				logger.Log.Debug("Invalid authentication token:", err)
+				unauthorized(w, apperrors.NewAuthError("invalid authentication token"))
+				return
+			}
+
+			sub, _ := claims["sub"].(string)
+			customerId, errCustomer := strconv.ParseUint(sub, 10, 64)
+			accountId, okAccount := claims["accountId"].(float64)
+
+			if errCustomer != nil || !okAccount || accountId < 0 || accountId != float64(uint64(accountId)) {
+				logger.Log.Debug("Invalid authentication token: bad sub or accountId")
+				unauthorized(w, apperrors.NewAuthError("invalid authentication token"))
+				return
 			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
 
-		if err != nil {
-			respondWithError(w, http.StatusUnauthorized, apperrors.NewAuthError("invalid authentication token"))
-			return
-		}
+			clientId, client := claims[oauth.ClaimClientId].(string)
+			sessionId, okSession := claims[login.ClaimSession].(float64)
+
+			if !client && (!okSession || sessionId < 1 || sessionId != float64(uint64(sessionId))) {
+				logger.Log.Debug("Invalid authentication token: bad sid")
+				unauthorized(w, apperrors.NewAuthError("invalid authentication token"))
+				return
+			}
+
+			if client {
+				sessionId = 0
+			}
 
-		if !token.Valid {
-			respondWithError(w, http.StatusUnauthorized, apperrors.NewAuthError("invalid authentication token"))
-		}
+			iat, _ := claims["iat"].(float64)
+			issuedAt := time.Unix(int64(iat), 0)
 
-		claims := token.Claims.(jwt.MapClaims)
-		userId := claims["userId"].(float64)
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, UserIdContextKey("userId"), uint64(userId))
-		ro := r.Clone(ctx)
+			if err := sessions.ValidateSession(r.Context(), customerId, uint64(accountId), uint64(sessionId), issuedAt); err != nil {
+				if _, ok := err.(*apperrors.AuthError); ok {
+					unauthorized(w, err)
+					return
+				}
+
+				logger.Log.Error("Validate session error:", err)
+				respondWithError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			if client {
+				if err := clients.ValidateClient(r.Context(), clientId, uint64(accountId)); err != nil {
+					if _, ok := err.(*apperrors.AuthError); ok {
+						unauthorized(w, err)
+						return
+					}
+
+					logger.Log.Error("Validate client error:", err)
+					respondWithError(w, http.StatusInternalServerError, err)
+					return
+				}
+			}
+
+			ctx := r.Context()
+			ctx = context.WithValue(ctx, CustomerIdContextKey("customerId"), customerId)
+			ctx = context.WithValue(ctx, AccountIdContextKey("accountId"), uint64(accountId))
+
+			if role, ok := claims["role"].(string); ok {
+				ctx = context.WithValue(ctx, RoleContextKey("role"), role)
+			}
+
+			mfa, _ := claims[login.ClaimMfa].(bool)
+			ctx = context.WithValue(ctx, MfaContextKey("mfa"), mfa)
+			ctx = context.WithValue(ctx, IssuedAtContextKey("issuedAt"), issuedAt)
+			ctx = context.WithValue(ctx, SessionIdContextKey("sessionId"), uint64(sessionId))
+
+			// Tokens of API clients are limited to their scopes.
+			if client {
+				scope, _ := claims[oauth.ClaimScope].(string)
+				ctx = context.WithValue(ctx, ClientIdContextKey("clientId"), clientId)
+				ctx = context.WithValue(ctx, ScopesContextKey("scopes"), strings.Fields(scope))
+			}
+
+			ctx = audit.WithCustomer(ctx, customerId, clientId)
+
+			next.ServeHTTP(w, r.Clone(ctx))
+		})
+	}
+}
 
-		next.ServeHTTP(w, ro)
-	})
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="go-banking"`)
+	respondWithError(w, http.StatusUnauthorized, err)
 }
 
 func respondWithError(w http.ResponseWriter, code int, err error) {
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+type mockSessions struct {
+	err error
+}
+
+func (m *mockSessions) ValidateSession(ctx context.Context, c uint64, a uint64, s uint64, i time.Time) error {
+	if s == 9 {
+		return apperrors.NewAuthError("session terminated, log in again")
+	}
+	return m.err
+}
+
+// mockClients has cli_1 active and every other client revoked.
+type mockClients struct{}
+
+func (m *mockClients) ValidateClient(ctx context.Context, clientId string, accountId uint64) error {
+	if clientId != "cli_1" {
+		return apperrors.NewAuthError("api client revoked")
+	}
+	return nil
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+
+	return jwt.MapClaims{
+		"iss":       keys.Issuer,
+		"aud":       login.Audience,
+		"sub":       "7",
+		"accountId": 3,
+		"role":      login.RoleCustomer,
+		"mfa":       true,
+		"sid":       5,
+		"iat":       now.Unix(),
+		"exp":       now.Add(login.TokenExpiry).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, change func(jwt.MapClaims)) string {
+	claims := validClaims()
+	change(claims)
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestAuth(t *testing.T) {
+	secret := []byte("secret")
+	k, err := keys.NewSet("", string(secret), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unchanged := func(c jwt.MapClaims) {}
+
+	tests := []struct {
+		name     string
+		header   string
+		sessions mockSessions
+		status   int
+	}{
+		{"valid token", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, unchanged), mockSessions{}, http.StatusOK},
+		{"missing header", "", mockSessions{}, http.StatusUnauthorized},
+		{"other scheme", "Basic " + sign(t, jwt.SigningMethodHS256, secret, unchanged), mockSessions{}, http.StatusUnauthorized},
+		{"empty token", "Bearer ", mockSessions{}, http.StatusUnauthorized},
+		{"malformed token", "Bearer not.a.token", mockSessions{}, http.StatusUnauthorized},
+		{"not a jwt", "Bearer abc", mockSessions{}, http.StatusUnauthorized},
+		{"forged with another secret", "Bearer " + sign(t, jwt.SigningMethodHS256, []byte("guess"), unchanged), mockSessions{}, http.StatusUnauthorized},
+		{"forged with alg none", "Bearer " + sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, unchanged), mockSessions{}, http.StatusUnauthorized},
+		{"algorithm not allowed", "Bearer " + sign(t, jwt.SigningMethodHS512, secret, unchanged), mockSessions{}, http.StatusUnauthorized},
+		{"expired", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+		}), mockSessions{}, http.StatusUnauthorized},
+		{"expired within clock skew", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) {
+			c["exp"] = time.Now().Add(-10 * time.Second).Unix()
+		}), mockSessions{}, http.StatusOK},
+		{"missing exp", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { delete(c, "exp") }), mockSessions{}, http.StatusUnauthorized},
+		{"missing iat", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { delete(c, "iat") }), mockSessions{}, http.StatusUnauthorized},
+		{"other issuer", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["iss"] = "evil" }), mockSessions{}, http.StatusUnauthorized},
+		{"mfa pending token", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["aud"] = login.MfaAudience }), mockSessions{}, http.StatusUnauthorized},
+		{"missing sub", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { delete(c, "sub") }), mockSessions{}, http.StatusUnauthorized},
+		{"sub not a customer id", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["sub"] = "admin" }), mockSessions{}, http.StatusUnauthorized},
+		{"missing accountId", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { delete(c, "accountId") }), mockSessions{}, http.StatusUnauthorized},
+		{"accountId not a number", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["accountId"] = "3" }), mockSessions{}, http.StatusUnauthorized},
+		{"negative accountId", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["accountId"] = -3 }), mockSessions{}, http.StatusUnauthorized},
+		{"missing sid", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { delete(c, "sid") }), mockSessions{}, http.StatusUnauthorized},
+		{"sid not a number", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["sid"] = "5" }), mockSessions{}, http.StatusUnauthorized},
+		{"terminated session", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) { c["sid"] = 9 }), mockSessions{}, http.StatusUnauthorized},
+		{"inactive account", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, unchanged), mockSessions{apperrors.NewAuthError("this account is inactive")}, http.StatusUnauthorized},
+		{"session check failure", "Bearer " + sign(t, jwt.SigningMethodHS256, secret, unchanged), mockSessions{errors.New("database down")}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var customerId, accountId, sessionId uint64
+			var mfa bool
+
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				customerId = r.Context().Value(CustomerIdContextKey("customerId")).(uint64)
+				accountId = r.Context().Value(AccountIdContextKey("accountId")).(uint64)
+				mfa = r.Context().Value(MfaContextKey("mfa")).(bool)
+				sessionId = r.Context().Value(SessionIdContextKey("sessionId")).(uint64)
+			})
+
+			req, err := http.NewRequest(http.MethodGet, "/accounts/balance", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			sessions := tt.sessions
+			Auth(k, &sessions, &mockClients{})(next).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+
+			if tt.status == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("401 without WWW-Authenticate header")
+			}
+
+			if tt.status == http.StatusOK && (customerId != 7 || accountId != 3 || sessionId != 5 || !mfa) {
+				t.Errorf("unexpected context: customer %d account %d session %d mfa %v", customerId, accountId, sessionId, mfa)
+			}
+		})
+	}
+}
+
+func TestAuthClientToken(t *testing.T) {
+	secret := []byte("secret")
+	k, err := keys.NewSet("", string(secret), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientToken := func(clientId string) string {
+		return sign(t, jwt.SigningMethodHS256, secret, func(c jwt.MapClaims) {
+			delete(c, "role")
+			delete(c, "mfa")
+			delete(c, "sid")
+			c[oauth.ClaimClientId] = clientId
+			c[oauth.ClaimScope] = "accounts:read transfers:write"
+		})
+	}
+
+	var scopes []string
+	var clientId string
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes, _ = r.Context().Value(ScopesContextKey("scopes")).([]string)
+		clientId, _ = r.Context().Value(ClientIdContextKey("clientId")).(string)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/accounts/balance", nil)
+	req.Header.Set("Authorization", "Bearer "+clientToken("cli_1"))
+	rr := httptest.NewRecorder()
+	Auth(k, &mockSessions{}, &mockClients{})(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	if clientId != "cli_1" || len(scopes) != 2 || scopes[0] != oauth.ScopeAccountsRead || scopes[1] != oauth.ScopeTransfersWrite {
+		t.Errorf("unexpected context: client %q scopes %v", clientId, scopes)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+clientToken("cli_2"))
+	rr = httptest.NewRecorder()
+	Auth(k, &mockSessions{}, &mockClients{})(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("revoked client: handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// RequireMFA only lets requests through when the token, validated by Auth,
+// was issued after an MFA code. Customers without MFA have to enroll first.
+func RequireMFA(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mfa, _ := r.Context().Value(MfaContextKey("mfa")).(bool); !mfa {
+			respondWithError(w, http.StatusForbidden, apperrors.NewAuthError("multi-factor authentication required"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+)
+
+const RequestIdHeader = "X-Request-Id"
+
+// requestIdRegex bounds the request ids accepted from clients, as they end
+// up in logs and in the audit log.
+var requestIdRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestId tags the request with the X-Request-Id the client sent, or a new
+// one, and echoes it in the response. The id and the client IP start the
+// audit actor of the request, Auth adds who it is.
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIdHeader)
+
+		if !requestIdRegex.MatchString(id) {
+			id = newRequestId()
+		}
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		w.Header().Set(RequestIdHeader, id)
+		ctx := audit.NewContext(r.Context(), audit.Actor{RequestId: id, Ip: ip})
+
+		next.ServeHTTP(w, r.Clone(ctx))
+	})
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+)
+
+func TestRequestId(t *testing.T) {
+	tests := []struct {
+		name    string
+		inbound string
+		keep    bool
+	}{
+		{"no inbound id", "", false},
+		{"inbound id kept", "req-42.a_b", true},
+		{"inbound id with spaces", "req 42", false},
+		{"inbound id too long", strings.Repeat("a", 65), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:5555"
+
+			if tt.inbound != "" {
+				req.Header.Set(RequestIdHeader, tt.inbound)
+			}
+
+			var actor audit.Actor
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				actor = audit.ActorFrom(r.Context())
+			})
+
+			rr := httptest.NewRecorder()
+			RequestId(next).ServeHTTP(rr, req)
+
+			id := rr.Header().Get(RequestIdHeader)
+
+			if id == "" || id != actor.RequestId {
+				t.Errorf("got response id %q and actor id %q", id, actor.RequestId)
+			}
+
+			if (id == tt.inbound) != tt.keep {
+				t.Errorf("got id %q for inbound %q", id, tt.inbound)
+			}
+
+			if actor.Ip != "10.0.0.1" {
+				t.Errorf("got actor ip %q", actor.Ip)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// Scopes only lets API client tokens through when they carry the read scope,
+// for GET requests, or the write scope, for the others. An empty scope means
+// clients get no access at all. Customer tokens are not limited by scopes.
+func Scopes(read string, write string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, client := r.Context().Value(ScopesContextKey("scopes")).([]string)
+
+			if client {
+				required := write
+				if r.Method == http.MethodGet || r.Method == http.MethodHead {
+					required = read
+				}
+
+				if !hasScope(scopes, required) {
+					respondWithError(w, http.StatusForbidden, apperrors.NewAuthError("insufficient scope", required))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CustomersOnly refuses API client tokens, for routes about the customer
+// rather than the account.
+func CustomersOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, client := r.Context().Value(ScopesContextKey("scopes")).([]string); client {
+			respondWithError(w, http.StatusForbidden, apperrors.NewAuthError("customers only"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasScope(scopes []string, scope string) bool {
+	if scope == "" {
+		return false
+	}
+
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+)
+
+func TestScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		scopes []string
+		client bool
+		status int
+	}{
+		{"customer token", http.MethodPost, nil, false, http.StatusOK},
+		{"read scope on GET", http.MethodGet, []string{oauth.ScopeTransfersRead}, true, http.StatusOK},
+		{"read scope on POST", http.MethodPost, []string{oauth.ScopeTransfersRead}, true, http.StatusForbidden},
+		{"write scope on POST", http.MethodPost, []string{oauth.ScopeTransfersWrite}, true, http.StatusOK},
+		{"write scope on GET", http.MethodGet, []string{oauth.ScopeTransfersWrite}, true, http.StatusForbidden},
+		{"other scopes", http.MethodGet, []string{oauth.ScopeHoldsRead}, true, http.StatusForbidden},
+		{"no scopes", http.MethodGet, []string{}, true, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/transfers", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.client {
+				req = req.WithContext(context.WithValue(req.Context(), ScopesContextKey("scopes"), tt.scopes))
+			}
+
+			rr := httptest.NewRecorder()
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			Scopes(oauth.ScopeTransfersRead, oauth.ScopeTransfersWrite)(next).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestCustomersOnly(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req, _ := http.NewRequest(http.MethodGet, "/me/limits", nil)
+	rr := httptest.NewRecorder()
+	CustomersOnly(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("customer token refused: got %v", rr.Code)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), ScopesContextKey("scopes"), []string{oauth.ScopeAccountsRead}))
+	rr = httptest.NewRecorder()
+	CustomersOnly(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("client token allowed: got %v", rr.Code)
+	}
+}
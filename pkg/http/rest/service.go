@@ -1,52 +1,163 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 
 	"github.com/GilbertoVGL/go-banking/pkg/account"
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
 	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/freeze"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
 	"github.com/GilbertoVGL/go-banking/pkg/http/rest/middleware"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
 	"github.com/GilbertoVGL/go-banking/pkg/logger"
 	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/mfa"
+	"github.com/GilbertoVGL/go-banking/pkg/notification"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/pin"
+	"github.com/GilbertoVGL/go-banking/pkg/privacy"
+	"github.com/GilbertoVGL/go-banking/pkg/risk"
+	"github.com/GilbertoVGL/go-banking/pkg/secret"
+	"github.com/GilbertoVGL/go-banking/pkg/stream"
 	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+	"github.com/GilbertoVGL/go-banking/pkg/webhook"
 )
 
-func NewRouter(l login.Service, a account.Service, t transfer.Service) http.Handler {
+const (
+	// streamHeartbeat is how often an idle event stream gets a comment, so
+	// proxies keep it open.
+	streamHeartbeat = 10 * time.Second
+
+	// streamRetry is how long EventSource clients wait to reconnect.
+	streamRetry = time.Second
+)
+
+func NewRouter(l login.Service, a account.Service, t transfer.Service, lm limits.Service, h hold.Service, rs risk.Service, p pin.Service, m mfa.Service, sc secret.Service, o oauth.Service, f freeze.Service, pv privacy.Service, au audit.Service, wh webhook.Service, es stream.Service, n notification.Service, k *keys.Set) http.Handler {
 	r := mux.NewRouter()
+	auth := middleware.Auth(k, l, o)
 
 	// Open routes \/
 	r.HandleFunc("/", healthCheck).Methods("GET").Name("Health Check")
+	r.HandleFunc("/.well-known/jwks.json", getJWKS(k)).Methods("GET").Name("Token validation keys")
 	r.HandleFunc("/login", doLogin(l)).Methods("POST").Name("Login")
+	r.HandleFunc("/login/mfa", doMfaLogin(l)).Methods("POST").Name("Complete login with MFA code")
 	r.HandleFunc("/accounts", newAccount(a)).Methods("POST").Name("Create account")
-	r.Use(middleware.ReqTimeout)
+	r.HandleFunc("/secret/reset", requestSecretReset(sc)).Methods("POST").Name("Request secret reset")
+	r.HandleFunc("/secret/reset/confirm", resetSecret(sc)).Methods("POST").Name("Reset secret")
+	r.HandleFunc("/oauth/token", requestToken(o)).Methods("POST").Name("Client credentials token")
+	r.Use(middleware.RequestId, middleware.ReqTimeout)
 
 	// Needs auth \/
 	transferRouter := r.PathPrefix("/transfers").Subrouter()
 	transferRouter.HandleFunc("", doTransfer(t)).Methods("POST").Name("Create transfer")
 	transferRouter.HandleFunc("", listTransfer(t)).Methods("GET").Name("Read transfer")
-	transferRouter.Use(middleware.Auth)
+	transferRouter.HandleFunc("/batch", doBatchTransfer(t)).Methods("POST").Name("Create batch transfer")
+	transferRouter.HandleFunc("/batch/{id}", getBatchTransfer(t)).Methods("GET").Name("Read batch transfer")
+	transferRouter.HandleFunc("/approvals", listPendingTransfers(t)).Methods("GET").Name("List transfers pending approval")
+	transferRouter.Handle("/{id}/approve", middleware.CustomersOnly(decideTransfer(t, true))).Methods("POST").Name("Approve transfer")
+	transferRouter.Handle("/{id}/reject", middleware.CustomersOnly(decideTransfer(t, false))).Methods("POST").Name("Reject transfer")
+	transferRouter.Use(auth, middleware.Scopes(oauth.ScopeTransfersRead, oauth.ScopeTransfersWrite))
+
+	holdRouter := r.PathPrefix("/holds").Subrouter()
+	holdRouter.HandleFunc("", authorizeHold(h)).Methods("POST").Name("Authorize hold")
+	holdRouter.HandleFunc("", listHolds(h)).Methods("GET").Name("List holds")
+	holdRouter.HandleFunc("/{id}/capture", captureHold(h)).Methods("POST").Name("Capture hold")
+	holdRouter.HandleFunc("/{id}/void", voidHold(h)).Methods("POST").Name("Void hold")
+	holdRouter.Use(auth, middleware.Scopes(oauth.ScopeHoldsRead, oauth.ScopeHoldsWrite))
+
+	webhookRouter := r.PathPrefix("/webhooks").Subrouter()
+	webhookRouter.HandleFunc("", createWebhook(wh)).Methods("POST").Name("Create webhook")
+	webhookRouter.HandleFunc("", listWebhooks(wh)).Methods("GET").Name("List webhooks")
+	webhookRouter.HandleFunc("/{id}", deleteWebhook(wh)).Methods("DELETE").Name("Delete webhook")
+	webhookRouter.HandleFunc("/{id}/deliveries", listWebhookDeliveries(wh)).Methods("GET").Name("List webhook deliveries")
+	webhookRouter.HandleFunc("/{id}/deliveries/{deliveryId}/replay", replayWebhookDelivery(wh)).Methods("POST").Name("Replay webhook delivery")
+	webhookRouter.Use(auth, middleware.Scopes(oauth.ScopeWebhooksRead, oauth.ScopeWebhooksWrite))
 
 	accountRouter := r.PathPrefix("/accounts").Subrouter()
-	accountRouter.HandleFunc("", listAccounts(a)).Methods("GET").Name("List accounts")
+	accountRouter.Handle("", middleware.CustomersOnly(listAccounts(a))).Methods("GET").Name("List accounts")
 	accountRouter.HandleFunc("/balance", getSelfBalance(a)).Methods("GET").Name("Get current user balance")
-	accountRouter.HandleFunc("/{id}/balance", getBalance(a)).Methods("GET").Name("Get some user balance")
-	accountRouter.Use(middleware.Auth)
-
-	headersOk := handlers.AllowedHeaders([]string{"Origin", "Content-Type", "Authorization"})
+	accountRouter.Handle("/{id}/balance", middleware.CustomersOnly(getBalance(a))).Methods("GET").Name("Get some user balance")
+	accountRouter.Use(auth, middleware.Scopes(oauth.ScopeAccountsRead, ""))
+
+	meRouter := r.PathPrefix("/me").Subrouter()
+	meRouter.HandleFunc("", updateProfile(a)).Methods("PATCH").Name("Update current customer profile")
+	meRouter.HandleFunc("/accounts", listOwnAccounts(a)).Methods("GET").Name("List current customer accounts")
+	meRouter.HandleFunc("/accounts", openAccount(a)).Methods("POST").Name("Open account for current customer")
+	meRouter.HandleFunc("/accounts/{id}/select", selectAccount(l)).Methods("POST").Name("Select current customer active account")
+	meRouter.HandleFunc("/accounts/{id}/close", closeAccount(a)).Methods("POST").Name("Close current customer account")
+	meRouter.HandleFunc("/transfers", doOwnAccountsTransfer(t)).Methods("POST").Name("Create transfer between current customer accounts")
+	meRouter.HandleFunc("/limits", getLimits(lm)).Methods("GET").Name("Get current account transfer limits")
+	meRouter.Handle("/limits", middleware.RequireMFA(updateLimits(lm))).Methods("PUT").Name("Update current account transfer limits")
+	meRouter.HandleFunc("/pin", setPin(p)).Methods("PUT").Name("Set current account transaction PIN")
+	meRouter.HandleFunc("/secret", changeSecret(sc)).Methods("PUT").Name("Change current customer secret")
+	meRouter.HandleFunc("/mfa", enrollMfa(m)).Methods("POST").Name("Start current customer MFA enrollment")
+	meRouter.HandleFunc("/mfa/confirm", confirmMfa(m)).Methods("POST").Name("Confirm current customer MFA enrollment")
+	meRouter.HandleFunc("/mfa", disableMfa(m)).Methods("DELETE").Name("Disable current customer MFA")
+	meRouter.HandleFunc("/sessions", listSessions(l)).Methods("GET").Name("List current customer sessions")
+	meRouter.HandleFunc("/sessions/{id}", terminateSession(l)).Methods("DELETE").Name("Terminate current customer session")
+	meRouter.HandleFunc("/data-export", exportData(pv)).Methods("GET").Name("Export current customer data")
+	meRouter.HandleFunc("/consents", listConsents(pv)).Methods("GET").Name("List current customer consents")
+	meRouter.HandleFunc("/consents", setConsent(pv)).Methods("PUT").Name("Set current customer consent")
+	meRouter.HandleFunc("/notification-preferences", getNotificationPreferences(n)).Methods("GET").Name("Get current customer notification preferences")
+	meRouter.HandleFunc("/notification-preferences", setNotificationPreferences(n)).Methods("PUT").Name("Set current customer notification preferences")
+	meRouter.Use(auth, middleware.CustomersOnly)
+
+	adminRouter := r.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/accounts/{id}/overdraft", setOverdraftLimit(a)).Methods("PUT").Name("Set account overdraft limit")
+	adminRouter.HandleFunc("/accounts/{id}/approval", setApprovalPolicy(a)).Methods("PUT").Name("Set account transfer approval policy")
+	adminRouter.HandleFunc("/accounts/{id}/deactivate", setAccountActive(a, false)).Methods("POST").Name("Deactivate account")
+	adminRouter.HandleFunc("/accounts/{id}/reactivate", setAccountActive(a, true)).Methods("POST").Name("Reactivate account")
+	adminRouter.HandleFunc("/accounts/{id}/freezes", freezeAccount(f)).Methods("POST").Name("Freeze account")
+	adminRouter.HandleFunc("/accounts/{id}/freezes", listAccountFreezes(f)).Methods("GET").Name("List account freezes and judicial blocks")
+	adminRouter.HandleFunc("/accounts/{id}/freezes/{freezeId}/lift", liftAccountFreeze(f)).Methods("POST").Name("Lift account freeze")
+	adminRouter.HandleFunc("/accounts/{id}/judicial-blocks", blockAccountAmount(f)).Methods("POST").Name("Add judicial block")
+	adminRouter.HandleFunc("/accounts/{id}/judicial-blocks/{blockId}/release", releaseJudicialBlock(f)).Methods("POST").Name("Release judicial block")
+	adminRouter.HandleFunc("/risk/assessments", listRiskAssessments(rs)).Methods("GET").Name("List risk assessments")
+	adminRouter.HandleFunc("/clients", createApiClient(o)).Methods("POST").Name("Create API client")
+	adminRouter.HandleFunc("/clients", listApiClients(o)).Methods("GET").Name("List API clients")
+	adminRouter.HandleFunc("/clients/{id}", revokeApiClient(o)).Methods("DELETE").Name("Revoke API client")
+	adminRouter.HandleFunc("/anonymizations", anonymizeCustomers(pv)).Methods("POST").Name("Anonymize customers with closed accounts")
+	adminRouter.HandleFunc("/audit-events", listAuditEvents(au)).Methods("GET").Name("List audit events")
+	adminRouter.HandleFunc("/audit-events/verify", verifyAuditChain(au)).Methods("GET").Name("Verify audit chain")
+	adminRouter.Use(auth, middleware.Admin)
+
+	headersOk := handlers.AllowedHeaders([]string{"Origin", "Content-Type", "Authorization", "X-Transaction-Pin", "X-Customer-Secret", "Last-Event-ID", middleware.RequestIdHeader})
 	originsOk := handlers.AllowedOrigins([]string{os.Getenv("ORIGIN_ALLOWED")})
-	methodsOk := handlers.AllowedMethods([]string{"GET", "POST", "OPTIONS"})
+	methodsOk := handlers.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+
+	// Event streams outlive the request timeouts, which would also buffer
+	// them, so they get a router of their own \/
+	streamRouter := mux.NewRouter()
+	streamRouter.HandleFunc("/me/events", streamEvents(es, a)).Methods("GET").Name("Stream current account events")
+	streamRouter.Use(middleware.RequestId, auth, middleware.CustomersOnly)
 
 	walkRoutes(r)
+	walkRoutes(streamRouter)
+
+	cors := handlers.CORS(originsOk, headersOk, methodsOk)
 
-	return http.TimeoutHandler(handlers.CORS(originsOk, headersOk, methodsOk)(r), config.ServerReadTimeout, "Timeout")
+	root := http.NewServeMux()
+	root.Handle("/me/events", cors(streamRouter))
+	root.Handle("/", http.TimeoutHandler(cors(r), config.ServerReadTimeout, "Timeout"))
+
+	return root
 }
 
 func walkRoutes(r *mux.Router) {
@@ -75,6 +186,13 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, http.StatusOK, ok)
 }
 
+func getJWKS(k *keys.Set) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		respondWithJSON(w, http.StatusOK, k.JWKS())
+	}
+}
+
 func doLogin(s login.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var newLogin login.LoginRequest
@@ -85,6 +203,7 @@ func doLogin(s login.Service) http.HandlerFunc {
 			return
 		}
 
+		newLogin.Device = requestDevice(r)
 		logger.Log.Debug("Trying to login:", newLogin.Cpf)
 
 		loginCh := make(chan login.LoginReponse)
@@ -114,10 +233,53 @@ func doLogin(s login.Service) http.HandlerFunc {
 	}
 }
 
+func doMfaLogin(s login.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mfaLogin login.MfaLoginRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&mfaLogin); err != nil {
+			logger.Log.Error("Error while decoding do mfa login body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		mfaLogin.Device = requestDevice(r)
+
+		loginCh := make(chan login.LoginReponse)
+		errorCh := make(chan error)
+
+		go func() {
+			login, err := s.CompleteMfaLogin(r.Context(), mfaLogin)
+			if err != nil {
+				errorCh <- err
+				return
+			}
+			loginCh <- login
+		}()
+
+		select {
+		case loginResponse := <-loginCh:
+			logger.Log.Debug("User succesfully logged in with mfa")
+			respondWithJSON(w, http.StatusOK, loginResponse)
+		case err := <-errorCh:
+			logger.Log.Error("Do mfa login error", err)
+			switch err.(type) {
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusUnauthorized, err)
+			default:
+				respondWithError(w, http.StatusBadRequest, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Do mfa login", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
 func doTransfer(s transfer.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var newTransfer transfer.TransferRequest
-		newTransfer.Origin = r.Context().Value(middleware.UserIdContextKey("userId")).(uint64)
 
 		if err := json.NewDecoder(r.Body).Decode(&newTransfer); err != nil {
 			logger.Log.Error("Error while decoding do transfer body", err)
@@ -125,29 +287,43 @@ func doTransfer(s transfer.Service) http.HandlerFunc {
 			return
 		}
 
-		logger.Log.Debug("Trying to do transfer from", newTransfer.Origin, "to", *newTransfer.Destination, "of value", *newTransfer.Amount)
+		newTransfer.Origin = r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+		newTransfer.RequestedBy = r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
 
-		transferCh := make(chan bool)
+		logger.Log.Debug("Trying to do transfer from", newTransfer.Origin, "to", newTransfer.Destination, "of value", newTransfer.Amount)
+
+		transferCh := make(chan transfer.TransferResponse)
 		errCh := make(chan error)
 
 		go func() {
-			if err := s.DoTransfer(r.Context(), newTransfer); err != nil {
+			response, err := s.DoTransfer(r.Context(), newTransfer)
+			if err != nil {
 				errCh <- err
 				return
 			}
 
-			transferCh <- true
+			transferCh <- response
 		}()
 
 		select {
-		case <-transferCh:
-			logger.Log.Debug("Transfer successfully made from account", newTransfer.Origin, "to", *newTransfer.Destination, "of value", *newTransfer.Amount)
-			respondWithJSON(w, http.StatusCreated, newTransfer)
+		case response := <-transferCh:
+			if response.Status == transfer.TransferPendingApproval {
+				logger.Log.Debug("Transfer from account", response.Origin, "to", response.Destination, "of value", response.Amount, "waiting for approval as", response.Id)
+				respondWithJSON(w, http.StatusAccepted, response)
+				return
+			}
+
+			logger.Log.Debug("Transfer successfully made from account", response.Origin, "to", response.Destination, "of value", response.Amount, "with fee", response.Fee)
+			respondWithJSON(w, http.StatusCreated, response)
 		case err := <-errCh:
 			logger.Log.Error("Do Transfer error", err)
 			switch err.(type) {
 			case *apperrors.ArgumentError, *apperrors.TransferRequestError:
 				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			case *apperrors.ChallengeError:
+				respondWithError(w, http.StatusForbidden, err)
 			default:
 				respondWithError(w, http.StatusInternalServerError, err)
 			}
@@ -160,247 +336,2934 @@ func doTransfer(s transfer.Service) http.HandlerFunc {
 	}
 }
 
-func listTransfer(s transfer.Service) http.HandlerFunc {
+func doOwnAccountsTransfer(s transfer.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var invalid []string
-		id := r.Context().Value(middleware.UserIdContextKey("userId")).(uint64)
-		query := transfer.ListTransferQuery{
-			PageSize: 15,
-			Page:     0,
-		}
-
-		logger.Log.Debug("List transfers from user", id)
-
-		if v := r.FormValue("pageSize"); v != "" {
-			page, err := strconv.Atoi(v)
+		var newTransfer transfer.TransferRequest
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
 
-			if err != nil {
-				logger.Log.Debug("List transfers invalid pageSize", v)
-				invalid = append(invalid, "pageSize")
-			} else {
-				query.PageSize = page
-			}
+		if err := json.NewDecoder(r.Body).Decode(&newTransfer); err != nil {
+			logger.Log.Error("Error while decoding do own accounts transfer body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
 		}
 
-		if v := r.FormValue("page"); v != "" {
-			page, err := strconv.Atoi(v)
-
-			if err != nil || page < 1 {
-				logger.Log.Debug("List transfers invalid page", v)
-				invalid = append(invalid, "page")
-			} else {
-				query.Page = page - 1
-			}
+		if newTransfer.Origin == 0 {
+			newTransfer.Origin = r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
 		}
 
-		if len(invalid) > 0 {
-			err := apperrors.NewArgumentError("invalid query params", strings.Join(invalid, ", "))
-			logger.Log.Error("List transfers invalid params", err)
-			respondWithError(w, http.StatusBadRequest, err)
-			return
-		}
+		logger.Log.Debug("Trying to do own accounts transfer for customer", customerId, "from", newTransfer.Origin, "to", newTransfer.Destination)
 
-		transferListCh := make(chan transfer.ListTransferResponse)
+		transferCh := make(chan transfer.TransferResponse)
 		errCh := make(chan error)
 
 		go func() {
-			transferList, err := s.GetTransfers(r.Context(), id, query)
-
+			response, err := s.DoOwnAccountsTransfer(r.Context(), customerId, newTransfer)
 			if err != nil {
 				errCh <- err
 				return
 			}
-			transferListCh <- transferList
+
+			transferCh <- response
 		}()
 
 		select {
-		case transferList := <-transferListCh:
-			logger.Log.Debug("Successfully listed transfers", transferList)
-			respondWithJSON(w, http.StatusOK, transferList)
+		case response := <-transferCh:
+			logger.Log.Debug("Own accounts transfer successfully made from account", response.Origin, "to", response.Destination, "of value", response.Amount, "with fee", response.Fee)
+			respondWithJSON(w, http.StatusCreated, response)
 		case err := <-errCh:
-			logger.Log.Error("List transfer error", err)
-			respondWithError(w, http.StatusInternalServerError, err)
+			logger.Log.Error("Do own accounts transfer error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError, *apperrors.TransferRequestError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError, *apperrors.ChallengeError:
+				respondWithError(w, http.StatusForbidden, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
 		case <-r.Context().Done():
 			err := apperrors.NewInternalServerError("request timeout")
-			logger.Log.Error("List transfer", err)
+			logger.Log.Error("Do own accounts transfer", err)
 			respondWithError(w, http.StatusRequestTimeout, err)
 		}
 	}
 }
 
-func newAccount(s account.Service) http.HandlerFunc {
+func doBatchTransfer(s transfer.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var newAccount account.NewAccountRequest
+		batchRequest, err := decodeBatchRequest(r)
 
-		if err := json.NewDecoder(r.Body).Decode(&newAccount); err != nil {
-			logger.Log.Error("Error while decoding new account body", err)
-			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+		if err != nil {
+			logger.Log.Error("Error while decoding do batch transfer body", err)
+			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		logger.Log.Debug("Trying to create new account for", newAccount.Cpf)
+		origin := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+		batchRequest.RequestedBy = r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
 
-		newAccountCh := make(chan account.NewAccountResponse)
+		logger.Log.Debug("Trying to do batch transfer from", origin, "with", len(batchRequest.Items), "items in mode", batchRequest.Mode)
+
+		batchCh := make(chan transfer.Batch)
 		errCh := make(chan error)
 
 		go func() {
-			message, err := s.NewAccount(r.Context(), newAccount)
+			batch, err := s.DoBatchTransfer(r.Context(), origin, batchRequest)
 			if err != nil {
 				errCh <- err
 				return
 			}
-			newAccountCh <- message
+
+			batchCh <- batch
 		}()
 
 		select {
-		case message := <-newAccountCh:
-			logger.Log.Debug("New account created with balance", newAccount.Balance, "CPF", newAccount.Cpf, "and name", newAccount.Name)
-			respondWithJSON(w, http.StatusCreated, message)
+		case batch := <-batchCh:
+			logger.Log.Debug("Batch transfer", batch.Id, "accepted from account", origin)
+			respondWithJSON(w, http.StatusAccepted, batch)
 		case err := <-errCh:
-			logger.Log.Error("New account error", err)
-			respondWithError(w, http.StatusBadRequest, err)
+			logger.Log.Error("Do batch transfer error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError, *apperrors.TransferRequestError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError, *apperrors.ChallengeError:
+				respondWithError(w, http.StatusForbidden, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
 		case <-r.Context().Done():
 			err := apperrors.NewInternalServerError("request timeout")
-			logger.Log.Error("New account", err)
+			logger.Log.Error("Do batch transfer", err)
 			respondWithError(w, http.StatusRequestTimeout, err)
 		}
-
 	}
 }
 
-func listAccounts(s account.Service) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var invalid []string
-		query := account.ListAccountQuery{
-			PageSize: 15,
-			Page:     0,
+// decodeBatchRequest reads a batch from a JSON body, a text/csv body with the
+// mode in the query string and the PIN and secret in the X-Transaction-Pin and
+// X-Customer-Secret headers, or a multipart form with a "file" CSV and "mode",
+// "pin" and "secret" fields.
+func decodeBatchRequest(r *http.Request) (transfer.BatchRequest, error) {
+	var batchRequest transfer.BatchRequest
+
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		file, _, err := r.FormFile("file")
+
+		if err != nil {
+			return batchRequest, apperrors.NewArgumentError("file", err.Error())
 		}
 
-		if v := r.FormValue("pageSize"); v != "" {
-			page, err := strconv.Atoi(v)
+		defer file.Close()
 
-			if err != nil {
-				logger.Log.Debug("List accounts invalid pageSize", v)
-				invalid = append(invalid, "pageSize")
-			} else {
-				query.PageSize = page
-			}
+		items, err := transfer.ParseBatchCSV(file)
+
+		if err != nil {
+			return batchRequest, err
 		}
 
-		if v := r.FormValue("page"); v != "" {
-			page, err := strconv.Atoi(v)
+		batchRequest.Mode = r.FormValue("mode")
+		batchRequest.Pin = r.FormValue("pin")
+		batchRequest.Secret = r.FormValue("secret")
+		batchRequest.Items = items
+	case strings.HasPrefix(contentType, "text/csv"):
+		items, err := transfer.ParseBatchCSV(r.Body)
 
-			if err != nil || page < 1 {
-				logger.Log.Debug("List accounts invalid page", v)
-				invalid = append(invalid, "page")
-			} else {
-				query.Page = page - 1
-			}
+		if err != nil {
+			return batchRequest, err
 		}
 
-		if len(invalid) > 0 {
-			err := apperrors.NewArgumentError("invalid query params", strings.Join(invalid, ", "))
-			logger.Log.Error("List transfers invalid params", err)
+		batchRequest.Mode = r.URL.Query().Get("mode")
+		batchRequest.Pin = r.Header.Get("X-Transaction-Pin")
+		batchRequest.Secret = r.Header.Get("X-Customer-Secret")
+		batchRequest.Items = items
+	default:
+		if err := json.NewDecoder(r.Body).Decode(&batchRequest); err != nil {
+			return batchRequest, apperrors.NewArgumentError(err.Error())
+		}
+	}
+
+	return batchRequest, nil
+}
+
+func getBatchTransfer(s transfer.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		batchId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding get batch transfer id", err)
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		accountsListCh := make(chan account.ListAccountsReponse)
+		origin := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to get batch transfer", batchId, "of account", origin)
+
+		batchCh := make(chan transfer.Batch)
 		errCh := make(chan error)
 
 		go func() {
-			accountsList, err := s.List(r.Context(), query)
+			batch, err := s.GetBatch(r.Context(), origin, batchId)
 			if err != nil {
 				errCh <- err
 				return
 			}
-			accountsListCh <- accountsList
+
+			batchCh <- batch
 		}()
 
 		select {
-		case accountsList := <-accountsListCh:
-			logger.Log.Debug("Successfully listed accounts", accountsList)
-			respondWithJSON(w, http.StatusOK, accountsList)
+		case batch := <-batchCh:
+			logger.Log.Debug("Successfully got batch transfer", batch.Id, "with status", batch.Status)
+			respondWithJSON(w, http.StatusOK, batch)
 		case err := <-errCh:
-			logger.Log.Error("List accounts error", err)
-			respondWithError(w, http.StatusBadRequest, err)
+			logger.Log.Error("Get batch transfer error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
 		case <-r.Context().Done():
 			err := apperrors.NewInternalServerError("request timeout")
-			logger.Log.Error("List transfer", err)
+			logger.Log.Error("Get batch transfer", err)
 			respondWithError(w, http.StatusRequestTimeout, err)
 		}
 	}
 }
 
-func getBalance(s account.Service) http.HandlerFunc {
+func decideTransfer(s transfer.Service, approve bool) http.HandlerFunc {
+	decision := "reject"
+	if approve {
+		decision = "approve"
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := mux.Vars(r)["id"]
-		userId, err := strconv.Atoi(id)
+		pendingId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
 
 		if err != nil {
 			err := apperrors.NewArgumentError("invalid id format")
-			logger.Log.Error("Error while decoding get balance user id", err)
+			logger.Log.Error("Error while decoding", decision, "transfer id", err)
 			respondWithError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		logger.Log.Debug("Trying to get balance from", userId)
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
 
-		balanceCh := make(chan account.BalanceResponse)
+		logger.Log.Debug("Trying to", decision, "transfer", pendingId, "by customer", customerId)
+
+		pendingCh := make(chan transfer.PendingTransfer)
 		errCh := make(chan error)
 
 		go func() {
-			balance, err := s.GetBalance(r.Context(), uint64(userId))
+			var p transfer.PendingTransfer
+			var err error
+
+			if approve {
+				p, err = s.ApproveTransfer(r.Context(), customerId, pendingId)
+			} else {
+				p, err = s.RejectTransfer(r.Context(), customerId, pendingId)
+			}
+
 			if err != nil {
 				errCh <- err
 				return
 			}
-			balanceCh <- balance
+
+			pendingCh <- p
 		}()
 
 		select {
-		case balance := <-balanceCh:
-			logger.Log.Debug("Got balance from", userId, "balance", balance)
-			respondWithJSON(w, http.StatusOK, balance)
+		case p := <-pendingCh:
+			logger.Log.Debug("Transfer", p.Id, "decided by customer", customerId, "now", p.Status)
+			respondWithJSON(w, http.StatusOK, p)
 		case err := <-errCh:
-			logger.Log.Error("Get balance error", err)
-			respondWithError(w, http.StatusInternalServerError, err)
+			logger.Log.Error("Decide transfer error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError, *apperrors.TransferRequestError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
 		case <-r.Context().Done():
 			err := apperrors.NewInternalServerError("request timeout")
-			logger.Log.Error("Get balance", err)
+			logger.Log.Error("Decide transfer", err)
 			respondWithError(w, http.StatusRequestTimeout, err)
 		}
 	}
 }
 
-func getSelfBalance(s account.Service) http.HandlerFunc {
+func listPendingTransfers(s transfer.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userId := r.Context().Value(middleware.UserIdContextKey("userId")).(uint64)
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
 
-		logger.Log.Debug("Trying to get self balance from", userId)
+		logger.Log.Debug("List transfers pending approval of customer", customerId)
 
-		balanceCh := make(chan account.BalanceResponse)
+		pendingCh := make(chan transfer.ListPendingTransfersResponse)
 		errCh := make(chan error)
 
 		go func() {
-			balance, err := s.GetBalance(r.Context(), userId)
+			transfers, err := s.ListPendingTransfers(r.Context(), customerId)
 			if err != nil {
 				errCh <- err
 				return
 			}
-			balanceCh <- balance
+
+			pendingCh <- transfers
 		}()
 
 		select {
-		case balance := <-balanceCh:
-			logger.Log.Debug("Got self balance from", userId, "balance", balance)
-			respondWithJSON(w, http.StatusOK, balance)
+		case transfers := <-pendingCh:
+			logger.Log.Debug("Successfully listed transfers pending approval", transfers)
+			respondWithJSON(w, http.StatusOK, transfers)
 		case err := <-errCh:
-			logger.Log.Error("Get self balance error", err)
+			logger.Log.Error("List transfers pending approval error", err)
 			respondWithError(w, http.StatusInternalServerError, err)
 		case <-r.Context().Done():
 			err := apperrors.NewInternalServerError("request timeout")
-			logger.Log.Error("Get self balance", err)
+			logger.Log.Error("List transfers pending approval", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listTransfer(s transfer.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var invalid []string
+		id := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+		query := transfer.ListTransferQuery{
+			PageSize: 15,
+			Page:     0,
+		}
+
+		logger.Log.Debug("List transfers from user", id)
+
+		if v := r.FormValue("pageSize"); v != "" {
+			page, err := strconv.Atoi(v)
+
+			if err != nil {
+				logger.Log.Debug("List transfers invalid pageSize", v)
+				invalid = append(invalid, "pageSize")
+			} else {
+				query.PageSize = page
+			}
+		}
+
+		if v := r.FormValue("page"); v != "" {
+			page, err := strconv.Atoi(v)
+
+			if err != nil || page < 1 {
+				logger.Log.Debug("List transfers invalid page", v)
+				invalid = append(invalid, "page")
+			} else {
+				query.Page = page - 1
+			}
+		}
+
+		if len(invalid) > 0 {
+			err := apperrors.NewArgumentError("invalid query params", strings.Join(invalid, ", "))
+			logger.Log.Error("List transfers invalid params", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		transferListCh := make(chan transfer.ListTransferResponse)
+		errCh := make(chan error)
+
+		go func() {
+			transferList, err := s.GetTransfers(r.Context(), id, query)
+
+			if err != nil {
+				errCh <- err
+				return
+			}
+			transferListCh <- transferList
+		}()
+
+		select {
+		case transferList := <-transferListCh:
+			logger.Log.Debug("Successfully listed transfers", transferList)
+			respondWithJSON(w, http.StatusOK, transferList)
+		case err := <-errCh:
+			logger.Log.Error("List transfer error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List transfer", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func authorizeHold(s hold.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var authorizeRequest hold.AuthorizeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&authorizeRequest); err != nil {
+			logger.Log.Error("Error while decoding authorize hold body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		origin := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+		authorizeRequest.RequestedBy = r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Trying to authorize hold on account", origin, "to", authorizeRequest.Destination, "of value", authorizeRequest.Amount)
+
+		holdCh := make(chan hold.Hold)
+		errCh := make(chan error)
+
+		go func() {
+			h, err := s.Authorize(r.Context(), origin, authorizeRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			holdCh <- h
+		}()
+
+		select {
+		case h := <-holdCh:
+			logger.Log.Debug("Hold", h.Id, "authorized on account", h.AccountId, "of value", h.Amount)
+			respondWithJSON(w, http.StatusCreated, h)
+		case err := <-errCh:
+			logger.Log.Error("Authorize hold error", err)
+			respondWithHoldError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Authorize hold", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listHolds(s hold.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("List holds of account", accountId)
+
+		holdsCh := make(chan hold.ListHoldsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			holds, err := s.List(r.Context(), accountId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			holdsCh <- holds
+		}()
+
+		select {
+		case holds := <-holdsCh:
+			logger.Log.Debug("Successfully listed holds", holds)
+			respondWithJSON(w, http.StatusOK, holds)
+		case err := <-errCh:
+			logger.Log.Error("List holds error", err)
+			respondWithHoldError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List holds", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func captureHold(s hold.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var captureRequest hold.CaptureRequest
+		holdId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding capture hold id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&captureRequest); err != nil {
+				logger.Log.Error("Error while decoding capture hold body", err)
+				respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+				return
+			}
+		}
+
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to capture hold", holdId, "by account", accountId)
+
+		holdCh := make(chan hold.Hold)
+		errCh := make(chan error)
+
+		go func() {
+			h, err := s.Capture(r.Context(), accountId, holdId, captureRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			holdCh <- h
+		}()
+
+		select {
+		case h := <-holdCh:
+			logger.Log.Debug("Hold", h.Id, "captured with value", h.CapturedAmount)
+			respondWithJSON(w, http.StatusOK, h)
+		case err := <-errCh:
+			logger.Log.Error("Capture hold error", err)
+			respondWithHoldError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Capture hold", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func voidHold(s hold.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		holdId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding void hold id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to void hold", holdId, "by account", accountId)
+
+		holdCh := make(chan hold.Hold)
+		errCh := make(chan error)
+
+		go func() {
+			h, err := s.Void(r.Context(), accountId, holdId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			holdCh <- h
+		}()
+
+		select {
+		case h := <-holdCh:
+			logger.Log.Debug("Hold", h.Id, "voided")
+			respondWithJSON(w, http.StatusOK, h)
+		case err := <-errCh:
+			logger.Log.Error("Void hold error", err)
+			respondWithHoldError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Void hold", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func respondWithHoldError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *apperrors.ArgumentError, *apperrors.TransferRequestError:
+		respondWithError(w, http.StatusBadRequest, err)
+	case *apperrors.AuthError, *apperrors.ChallengeError:
+		respondWithError(w, http.StatusForbidden, err)
+	case *apperrors.AccountNotFoundError:
+		respondWithError(w, http.StatusNotFound, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func newAccount(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var newAccount account.NewAccountRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&newAccount); err != nil {
+			logger.Log.Error("Error while decoding new account body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to create new account for", newAccount.Cpf)
+
+		newAccountCh := make(chan account.NewAccountResponse)
+		errCh := make(chan error)
+
+		go func() {
+			message, err := s.NewAccount(r.Context(), newAccount)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			newAccountCh <- message
+		}()
+
+		select {
+		case message := <-newAccountCh:
+			logger.Log.Debug("New account created with balance", newAccount.Balance, "CPF", newAccount.Cpf, "and name", newAccount.Name)
+			respondWithJSON(w, http.StatusCreated, message)
+		case err := <-errCh:
+			logger.Log.Error("New account error", err)
+			respondWithError(w, http.StatusBadRequest, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("New account", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+
+	}
+}
+
+func listAccounts(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var invalid []string
+		query := account.ListAccountQuery{
+			PageSize: 15,
+			Page:     0,
+		}
+
+		if v := r.FormValue("pageSize"); v != "" {
+			page, err := strconv.Atoi(v)
+
+			if err != nil {
+				logger.Log.Debug("List accounts invalid pageSize", v)
+				invalid = append(invalid, "pageSize")
+			} else {
+				query.PageSize = page
+			}
+		}
+
+		if v := r.FormValue("page"); v != "" {
+			page, err := strconv.Atoi(v)
+
+			if err != nil || page < 1 {
+				logger.Log.Debug("List accounts invalid page", v)
+				invalid = append(invalid, "page")
+			} else {
+				query.Page = page - 1
+			}
+		}
+
+		if len(invalid) > 0 {
+			err := apperrors.NewArgumentError("invalid query params", strings.Join(invalid, ", "))
+			logger.Log.Error("List transfers invalid params", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		accountsListCh := make(chan account.ListAccountsReponse)
+		errCh := make(chan error)
+
+		go func() {
+			accountsList, err := s.List(r.Context(), query)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			accountsListCh <- accountsList
+		}()
+
+		select {
+		case accountsList := <-accountsListCh:
+			logger.Log.Debug("Successfully listed accounts", accountsList)
+			respondWithJSON(w, http.StatusOK, accountsList)
+		case err := <-errCh:
+			logger.Log.Error("List accounts error", err)
+			respondWithError(w, http.StatusBadRequest, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List transfer", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func getBalance(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		userId, err := strconv.Atoi(id)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding get balance user id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Trying to get balance from", userId)
+
+		balanceCh := make(chan account.BalanceResponse)
+		errCh := make(chan error)
+
+		go func() {
+			balance, err := s.GetBalance(r.Context(), uint64(userId))
+			if err != nil {
+				errCh <- err
+				return
+			}
+			balanceCh <- balance
+		}()
+
+		select {
+		case balance := <-balanceCh:
+			logger.Log.Debug("Got balance from", userId, "balance", balance)
+			respondWithJSON(w, http.StatusOK, balance)
+		case err := <-errCh:
+			logger.Log.Error("Get balance error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Get balance", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func getSelfBalance(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to get self balance from", userId)
+
+		balanceCh := make(chan account.BalanceResponse)
+		errCh := make(chan error)
+
+		go func() {
+			balance, err := s.GetBalance(r.Context(), userId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			balanceCh <- balance
+		}()
+
+		select {
+		case balance := <-balanceCh:
+			logger.Log.Debug("Got self balance from", userId, "balance", balance)
+			respondWithJSON(w, http.StatusOK, balance)
+		case err := <-errCh:
+			logger.Log.Error("Get self balance error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Get self balance", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// streamEvents streams, as Server-Sent Events, the balance of the current
+// account and what changes it as it is committed: transfers received,
+// interest postings, holds and judicial blocks. A client resuming with
+// Last-Event-ID first gets the events it missed or, when it missed more than
+// can be replayed, a reset event that ends the stream, so it reloads the
+// account and resumes from the current events. The stream ends before the
+// server write timeout, EventSource clients reconnect on their own.
+func streamEvents(b stream.Service, s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			err := apperrors.NewInternalServerError("streaming unsupported")
+			logger.Log.Error("Stream events", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		var lastEventId uint64
+		resume := r.Header.Get("Last-Event-ID") != ""
+
+		if resume {
+			id, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+			if err != nil {
+				err := apperrors.NewArgumentError("invalid Last-Event-ID")
+				logger.Log.Error("Error while decoding stream events last event id", err)
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			lastEventId = id
+		}
+
+		sub := b.Subscribe(accountId)
+		defer b.Unsubscribe(sub)
+
+		missed := []outbox.Event{}
+		truncated := false
+
+		if resume {
+			events, more, err := b.Since(r.Context(), accountId, lastEventId)
+			if err != nil {
+				logger.Log.Error("Stream events replay error", err)
+				respondWithError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			missed, truncated = events, more
+		} else {
+			lastEventId = sub.Head
+		}
+
+		logger.Log.Debug("Streaming events of", accountId, "after", lastEventId)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+
+		if truncated {
+			logger.Log.Debug("Stream events of", accountId, "missed more than", stream.MaxReplay, "events")
+			writeEvent(w, sub.Head, "reset", streamReset{stream.MaxReplay})
+			flusher.Flush()
+			return
+		}
+
+		for _, e := range missed {
+			if err := writeAccountEvent(w, accountId, e); err != nil {
+				return
+			}
+
+			lastEventId = e.Id
+		}
+
+		if err := writeBalanceEvent(r.Context(), w, s, accountId, lastEventId); err != nil {
+			logger.Log.Error("Stream events balance error", err)
+			return
+		}
+
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		var end <-chan time.Time
+
+		if config.ServerWriteTimeout > streamRetry {
+			timer := time.NewTimer(config.ServerWriteTimeout - streamRetry)
+			defer timer.Stop()
+			end = timer.C
+		}
+
+		for {
+			select {
+			case e, ok := <-sub.C:
+				if !ok {
+					logger.Log.Debug("Stream events of", accountId, "fell behind")
+					return
+				}
+
+				if e.Id <= lastEventId {
+					continue
+				}
+
+				if err := writeAccountEvent(w, accountId, e); err != nil {
+					return
+				}
+
+				lastEventId = e.Id
+
+				if err := writeBalanceEvent(r.Context(), w, s, accountId, lastEventId); err != nil {
+					logger.Log.Error("Stream events balance error", err)
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+			case <-end:
+				return
+			case <-r.Context().Done():
+				return
+			}
+
+			flusher.Flush()
+		}
+	}
+}
+
+// streamReset tells a client resuming the stream that it missed more than
+// MissedMoreThan events, which are not replayed.
+type streamReset struct {
+	MissedMoreThan int `json:"missedMoreThan"`
+}
+
+// writeAccountEvent writes the event of e for the account: transfer.received
+// when it is the destination of the transfer, interest.paid and
+// overdraft_interest.charged for postings, hold.<status> and
+// judicial_block.placed or .released. Transfers the account sent only show
+// in its balance.
+func writeAccountEvent(w io.Writer, accountId uint64, e outbox.Event) error {
+	switch e.Type {
+	case outbox.TypeTransferCompleted:
+		var t outbox.TransferPayload
+
+		if err := json.Unmarshal(e.Payload, &t); err != nil {
+			logger.Log.Error("Stream events decode error", err)
+			return nil
+		}
+
+		if t.Destination != accountId || t.Origin == accountId {
+			return nil
+		}
+
+		return writeEvent(w, e.Id, "transfer.received", t)
+	case outbox.TypePostingCompleted:
+		var p outbox.PostingPayload
+
+		if err := json.Unmarshal(e.Payload, &p); err != nil {
+			logger.Log.Error("Stream events decode error", err)
+			return nil
+		}
+
+		switch {
+		case p.Kind == transfer.KindInterest && p.Destination == accountId:
+			return writeEvent(w, e.Id, "interest.paid", p)
+		case p.Kind == transfer.KindOverdraftInterest && p.Origin == accountId:
+			return writeEvent(w, e.Id, "overdraft_interest.charged", p)
+		}
+
+		return nil
+	case outbox.TypeHoldChanged:
+		var h outbox.HoldPayload
+
+		if err := json.Unmarshal(e.Payload, &h); err != nil {
+			logger.Log.Error("Stream events decode error", err)
+			return nil
+		}
+
+		return writeEvent(w, e.Id, "hold."+h.Status, h)
+	case outbox.TypeJudicialBlockChanged:
+		var b outbox.JudicialBlockPayload
+
+		if err := json.Unmarshal(e.Payload, &b); err != nil {
+			logger.Log.Error("Stream events decode error", err)
+			return nil
+		}
+
+		if b.Released {
+			return writeEvent(w, e.Id, "judicial_block.released", b)
+		}
+
+		return writeEvent(w, e.Id, "judicial_block.placed", b)
+	default:
+		return nil
+	}
+}
+
+func writeBalanceEvent(ctx context.Context, w io.Writer, s account.Service, accountId uint64, eventId uint64) error {
+	balance, err := s.GetBalance(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	return writeEvent(w, eventId, "balance", balance)
+}
+
+// writeEvent writes a Server-Sent Event, without an id when eventId is 0.
+func writeEvent(w io.Writer, eventId uint64, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if eventId > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", eventId); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+
+	return err
+}
+
+func listOwnAccounts(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Trying to list accounts from customer", customerId)
+
+		accountsCh := make(chan account.ListCustomerAccountsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			accounts, err := s.ListOwn(r.Context(), customerId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			accountsCh <- accounts
+		}()
+
+		select {
+		case accounts := <-accountsCh:
+			logger.Log.Debug("Listed accounts from customer", customerId, accounts)
+			respondWithJSON(w, http.StatusOK, accounts)
+		case err := <-errCh:
+			logger.Log.Error("List own accounts error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List own accounts", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func openAccount(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var openRequest account.OpenAccountRequest
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		if err := json.NewDecoder(r.Body).Decode(&openRequest); err != nil && !errors.Is(err, io.EOF) {
+			logger.Log.Error("Error while decoding open account body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to open", openRequest.Product, "account for customer", customerId)
+
+		accountCh := make(chan account.CustomerAccount)
+		errCh := make(chan error)
+
+		go func() {
+			newAccount, err := s.OpenAccount(r.Context(), customerId, openRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			accountCh <- newAccount
+		}()
+
+		select {
+		case newAccount := <-accountCh:
+			logger.Log.Debug("Opened account", newAccount.Id, "for customer", customerId)
+			respondWithJSON(w, http.StatusCreated, newAccount)
+		case err := <-errCh:
+			logger.Log.Error("Open account error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Open account", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func closeAccount(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var closeRequest account.CloseAccountRequest
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding close account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&closeRequest); err != nil && !errors.Is(err, io.EOF) {
+			logger.Log.Error("Error while decoding close account body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to close account", accountId)
+
+		closeCh := make(chan account.CloseAccountResponse)
+		errCh := make(chan error)
+
+		go func() {
+			closed, err := s.Close(r.Context(), customerId, accountId, closeRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			closeCh <- closed
+		}()
+
+		select {
+		case closed := <-closeCh:
+			logger.Log.Debug("Customer", customerId, "closed account", accountId)
+			respondWithJSON(w, http.StatusOK, closed)
+		case err := <-errCh:
+			logger.Log.Error("Close account error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError, *apperrors.TransferRequestError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Close account", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func updateProfile(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var updateRequest account.UpdateProfileRequest
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+			logger.Log.Error("Error while decoding update profile body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to update profile")
+
+		profileCh := make(chan account.Profile)
+		errCh := make(chan error)
+
+		go func() {
+			profile, err := s.UpdateProfile(r.Context(), customerId, updateRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			profileCh <- profile
+		}()
+
+		select {
+		case profile := <-profileCh:
+			logger.Log.Debug("Customer", customerId, "updated profile")
+			respondWithJSON(w, http.StatusOK, profile)
+		case err := <-errCh:
+			logger.Log.Error("Update profile error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Update profile", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func selectAccount(s login.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		mfaDone, _ := r.Context().Value(middleware.MfaContextKey("mfa")).(bool)
+		sessionId := r.Context().Value(middleware.SessionIdContextKey("sessionId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding select account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to select account", accountId)
+
+		loginCh := make(chan login.LoginReponse)
+		errCh := make(chan error)
+
+		go func() {
+			login, err := s.SelectAccount(r.Context(), customerId, accountId, sessionId, mfaDone)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			loginCh <- login
+		}()
+
+		select {
+		case loginResponse := <-loginCh:
+			logger.Log.Debug("Customer", customerId, "selected account", accountId)
+			respondWithJSON(w, http.StatusOK, loginResponse)
+		case err := <-errCh:
+			logger.Log.Error("Select account error", err)
+			respondWithError(w, http.StatusBadRequest, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Select account", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listSessions(s login.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		sessionId := r.Context().Value(middleware.SessionIdContextKey("sessionId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "listing sessions")
+
+		sessionsCh := make(chan login.ListSessionsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			sessions, err := s.ListSessions(r.Context(), customerId, sessionId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			sessionsCh <- sessions
+		}()
+
+		select {
+		case sessions := <-sessionsCh:
+			logger.Log.Debug("Customer", customerId, "has", len(sessions.Sessions), "sessions")
+			respondWithJSON(w, http.StatusOK, sessions)
+		case err := <-errCh:
+			logger.Log.Error("List sessions error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List sessions", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func terminateSession(s login.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding terminate session id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to terminate session", id)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.TerminateSession(r.Context(), customerId, id); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Customer", customerId, "terminated session", id)
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Terminate session error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Terminate session", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// requestDevice describes where a request comes from, for the session it
+// logs in. The address is the one of the connection, proxy headers can be
+// set by anyone.
+func requestDevice(r *http.Request) login.Device {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	userAgent := r.UserAgent()
+
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	return login.Device{UserAgent: userAgent, Ip: ip}
+}
+
+func setOverdraftLimit(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var limitRequest account.OverdraftLimitRequest
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding set overdraft limit account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&limitRequest); err != nil {
+			logger.Log.Error("Error while decoding set overdraft limit body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to set overdraft limit of account", accountId, "to", limitRequest.Limit)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.SetOverdraftLimit(r.Context(), accountId, limitRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Overdraft limit of account", accountId, "set to", *limitRequest.Limit)
+			respondWithJSON(w, http.StatusOK, limitRequest)
+		case err := <-errCh:
+			logger.Log.Error("Set overdraft limit error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set overdraft limit", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// setAccountActive deactivates, or reactivates when active is set, an account.
+func setAccountActive(s account.Service, active bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding set account active id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Trying to set account", accountId, "active to", active)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			var err error
+			if active {
+				err = s.Reactivate(r.Context(), accountId)
+			} else {
+				err = s.Deactivate(r.Context(), accountId)
+			}
+			if err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Account", accountId, "active set to", active)
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Set account active error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set account active", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func setApprovalPolicy(s account.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var policyRequest account.ApprovalPolicyRequest
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding set approval policy account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&policyRequest); err != nil {
+			logger.Log.Error("Error while decoding set approval policy body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to set approval policy of account", accountId, "to", policyRequest)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.SetApprovalPolicy(r.Context(), accountId, policyRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Approval policy of account", accountId, "set")
+			respondWithJSON(w, http.StatusOK, policyRequest)
+		case err := <-errCh:
+			logger.Log.Error("Set approval policy error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set approval policy", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listRiskAssessments(s risk.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := risk.ListAssessmentsQuery{
+			Decision: r.FormValue("decision"),
+		}
+
+		if v := r.FormValue("limit"); v != "" {
+			limit, err := strconv.Atoi(v)
+
+			if err != nil || limit < 1 || limit > 1000 {
+				err := apperrors.NewArgumentError("invalid query params", "limit")
+				logger.Log.Error("List risk assessments invalid params", err)
+				respondWithError(w, http.StatusBadRequest, err)
+				return
+			}
+
+			query.Limit = limit
+		}
+
+		logger.Log.Debug("List risk assessments", query)
+
+		assessmentsCh := make(chan risk.ListAssessmentsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			assessments, err := s.List(r.Context(), query)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			assessmentsCh <- assessments
+		}()
+
+		select {
+		case assessments := <-assessmentsCh:
+			logger.Log.Debug("Successfully listed risk assessments", len(assessments.Assessments))
+			respondWithJSON(w, http.StatusOK, assessments)
+		case err := <-errCh:
+			logger.Log.Error("List risk assessments error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List risk assessments", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func setPin(s pin.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var pinRequest pin.SetPinRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&pinRequest); err != nil {
+			logger.Log.Error("Error while decoding set pin body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to set transaction pin of account", accountId)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.Set(r.Context(), customerId, accountId, pinRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Transaction pin of account", accountId, "set")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Set pin error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set pin", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func changeSecret(s secret.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var changeRequest secret.ChangeSecretRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&changeRequest); err != nil {
+			logger.Log.Error("Error while decoding change secret body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "trying to change secret")
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.Change(r.Context(), customerId, changeRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Customer", customerId, "changed secret")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Change secret error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Change secret", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func requestSecretReset(s secret.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resetRequest secret.ResetRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&resetRequest); err != nil {
+			logger.Log.Error("Error while decoding request secret reset body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.RequestReset(r.Context(), resetRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			w.WriteHeader(http.StatusAccepted)
+		case err := <-errCh:
+			logger.Log.Error("Request secret reset error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Request secret reset", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func resetSecret(s secret.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var confirmRequest secret.ConfirmResetRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&confirmRequest); err != nil {
+			logger.Log.Error("Error while decoding reset secret body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.Reset(r.Context(), confirmRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Secret reset")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Reset secret error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AuthError:
+				respondWithError(w, http.StatusForbidden, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Reset secret", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func enrollMfa(s mfa.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "trying to enroll mfa")
+
+		enrollCh := make(chan mfa.EnrollResponse)
+		errCh := make(chan error)
+
+		go func() {
+			response, err := s.Enroll(r.Context(), customerId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			enrollCh <- response
+		}()
+
+		select {
+		case response := <-enrollCh:
+			logger.Log.Debug("Customer", customerId, "started mfa enrollment")
+			respondWithJSON(w, http.StatusCreated, response)
+		case err := <-errCh:
+			logger.Log.Error("Enroll mfa error", err)
+			respondWithMfaError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Enroll mfa", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func confirmMfa(s mfa.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var codeRequest mfa.CodeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&codeRequest); err != nil {
+			logger.Log.Error("Error while decoding confirm mfa body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "trying to confirm mfa enrollment")
+
+		confirmCh := make(chan mfa.ConfirmResponse)
+		errCh := make(chan error)
+
+		go func() {
+			response, err := s.Confirm(r.Context(), customerId, codeRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			confirmCh <- response
+		}()
+
+		select {
+		case response := <-confirmCh:
+			logger.Log.Debug("Customer", customerId, "enabled mfa")
+			respondWithJSON(w, http.StatusOK, response)
+		case err := <-errCh:
+			logger.Log.Error("Confirm mfa error", err)
+			respondWithMfaError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Confirm mfa", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func disableMfa(s mfa.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var codeRequest mfa.CodeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&codeRequest); err != nil {
+			logger.Log.Error("Error while decoding disable mfa body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "trying to disable mfa")
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.Disable(r.Context(), customerId, codeRequest); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Customer", customerId, "disabled mfa")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Disable mfa error", err)
+			respondWithMfaError(w, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Disable mfa", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func respondWithMfaError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *apperrors.ArgumentError:
+		respondWithError(w, http.StatusBadRequest, err)
+	case *apperrors.AuthError:
+		respondWithError(w, http.StatusForbidden, err)
+	default:
+		respondWithError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func getLimits(s limits.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		logger.Log.Debug("Trying to get limits from", accountId)
+
+		limitsCh := make(chan limits.LimitsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			l, err := s.Get(r.Context(), accountId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			limitsCh <- l
+		}()
+
+		select {
+		case l := <-limitsCh:
+			logger.Log.Debug("Got limits from", accountId, l)
+			respondWithJSON(w, http.StatusOK, l)
+		case err := <-errCh:
+			logger.Log.Error("Get limits error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Get limits", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func updateLimits(s limits.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var updateRequest limits.UpdateLimitsRequest
+		accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+
+		if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+			logger.Log.Error("Error while decoding update limits body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to update limits from", accountId)
+
+		limitsCh := make(chan limits.LimitsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			l, err := s.Update(r.Context(), accountId, updateRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			limitsCh <- l
+		}()
+
+		select {
+		case l := <-limitsCh:
+			logger.Log.Debug("Updated limits from", accountId, l)
+			respondWithJSON(w, http.StatusOK, l)
+		case err := <-errCh:
+			logger.Log.Error("Update limits error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Update limits", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// requestToken is the OAuth2 token endpoint. It takes the client credentials
+// grant as a form, with the client credentials in the form or in Basic auth.
+func requestToken(s oauth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+
+		if err := r.ParseForm(); err != nil {
+			logger.Log.Error("Error while decoding token request body", err)
+			respondWithJSON(w, http.StatusBadRequest, oauth.NewError(oauth.ErrInvalidRequest, "invalid form body"))
+			return
+		}
+
+		tokenRequest := oauth.TokenRequest{
+			GrantType:    r.PostForm.Get("grant_type"),
+			ClientId:     r.PostForm.Get("client_id"),
+			ClientSecret: r.PostForm.Get("client_secret"),
+			Scope:        r.PostForm.Get("scope"),
+		}
+
+		if id, secret, ok := r.BasicAuth(); ok {
+			tokenRequest.ClientId, tokenRequest.ClientSecret = id, secret
+		}
+
+		logger.Log.Debug("Client", tokenRequest.ClientId, "trying to get a token")
+
+		tokenCh := make(chan oauth.TokenResponse)
+		errCh := make(chan error)
+
+		go func() {
+			token, err := s.Token(r.Context(), tokenRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			tokenCh <- token
+		}()
+
+		select {
+		case token := <-tokenCh:
+			logger.Log.Debug("Client", tokenRequest.ClientId, "got a token for", token.Scope)
+			respondWithJSON(w, http.StatusOK, token)
+		case err := <-errCh:
+			logger.Log.Error("Token request error", err)
+
+			var oauthErr *oauth.Error
+			if !errors.As(err, &oauthErr) {
+				respondWithError(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			if oauthErr.Code == oauth.ErrInvalidClient {
+				w.Header().Set("WWW-Authenticate", `Basic realm="go-banking"`)
+				respondWithJSON(w, http.StatusUnauthorized, oauthErr)
+				return
+			}
+
+			respondWithJSON(w, http.StatusBadRequest, oauthErr)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Token request", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func createApiClient(s oauth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var clientRequest oauth.NewClientRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&clientRequest); err != nil {
+			logger.Log.Error("Error while decoding create api client body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Trying to create api client", clientRequest.Name)
+
+		clientCh := make(chan oauth.NewClientResponse)
+		errCh := make(chan error)
+
+		go func() {
+			client, err := s.CreateClient(r.Context(), clientRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			clientCh <- client
+		}()
+
+		select {
+		case client := <-clientCh:
+			logger.Log.Debug("Successfully created api client", client.ClientId)
+			respondWithJSON(w, http.StatusCreated, client)
+		case err := <-errCh:
+			logger.Log.Error("Create api client error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Create api client", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listApiClients(s oauth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Log.Debug("List api clients")
+
+		clientsCh := make(chan oauth.ListClientsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			clients, err := s.ListClients(r.Context())
+			if err != nil {
+				errCh <- err
+				return
+			}
+			clientsCh <- clients
+		}()
+
+		select {
+		case clients := <-clientsCh:
+			logger.Log.Debug("Successfully listed api clients", len(clients.Clients))
+			respondWithJSON(w, http.StatusOK, clients)
+		case err := <-errCh:
+			logger.Log.Error("List api clients error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List api clients", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func revokeApiClient(s oauth.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding revoke api client id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Trying to revoke api client", id)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.RevokeClient(r.Context(), id); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Api client", id, "revoked")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Revoke api client error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Revoke api client", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func freezeAccount(s freeze.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var freezeRequest freeze.FreezeRequest
+		author := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding freeze account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&freezeRequest); err != nil {
+			logger.Log.Error("Error while decoding freeze account body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Admin", author, "trying to freeze account", accountId)
+
+		freezeCh := make(chan freeze.Freeze)
+		errCh := make(chan error)
+
+		go func() {
+			f, err := s.Freeze(r.Context(), accountId, author, freezeRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			freezeCh <- f
+		}()
+
+		select {
+		case f := <-freezeCh:
+			logger.Log.Debug("Account", accountId, "frozen by freeze", f.Id)
+			respondWithJSON(w, http.StatusCreated, f)
+		case err := <-errCh:
+			logger.Log.Error("Freeze account error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Freeze account", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func liftAccountFreeze(s freeze.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		author := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding lift freeze account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		id, err := strconv.ParseUint(mux.Vars(r)["freezeId"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid freeze id format")
+			logger.Log.Error("Error while decoding lift freeze id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Admin", author, "trying to lift freeze", id, "of account", accountId)
+
+		freezeCh := make(chan freeze.Freeze)
+		errCh := make(chan error)
+
+		go func() {
+			f, err := s.Lift(r.Context(), accountId, id, author)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			freezeCh <- f
+		}()
+
+		select {
+		case f := <-freezeCh:
+			logger.Log.Debug("Freeze", f.Id, "lifted")
+			respondWithJSON(w, http.StatusOK, f)
+		case err := <-errCh:
+			logger.Log.Error("Lift freeze error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Lift freeze", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func blockAccountAmount(s freeze.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var blockRequest freeze.JudicialBlockRequest
+		author := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding judicial block account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&blockRequest); err != nil {
+			logger.Log.Error("Error while decoding judicial block body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Admin", author, "trying to block amount of account", accountId)
+
+		blockCh := make(chan freeze.JudicialBlock)
+		errCh := make(chan error)
+
+		go func() {
+			b, err := s.Block(r.Context(), accountId, author, blockRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			blockCh <- b
+		}()
+
+		select {
+		case b := <-blockCh:
+			logger.Log.Debug("Judicial block", b.Id, "of", b.Amount, "on account", accountId)
+			respondWithJSON(w, http.StatusCreated, b)
+		case err := <-errCh:
+			logger.Log.Error("Judicial block error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Judicial block", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func releaseJudicialBlock(s freeze.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		author := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding release judicial block account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		id, err := strconv.ParseUint(mux.Vars(r)["blockId"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid block id format")
+			logger.Log.Error("Error while decoding release judicial block id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Admin", author, "trying to release judicial block", id, "of account", accountId)
+
+		blockCh := make(chan freeze.JudicialBlock)
+		errCh := make(chan error)
+
+		go func() {
+			b, err := s.Release(r.Context(), accountId, id, author)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			blockCh <- b
+		}()
+
+		select {
+		case b := <-blockCh:
+			logger.Log.Debug("Judicial block", b.Id, "released")
+			respondWithJSON(w, http.StatusOK, b)
+		case err := <-errCh:
+			logger.Log.Error("Release judicial block error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Release judicial block", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listAccountFreezes(s freeze.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountId, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding list freezes account id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("List freezes of account", accountId)
+
+		listCh := make(chan freeze.ListResponse)
+		errCh := make(chan error)
+
+		go func() {
+			list, err := s.List(r.Context(), accountId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			listCh <- list
+		}()
+
+		select {
+		case list := <-listCh:
+			logger.Log.Debug("Successfully listed freezes of account", accountId)
+			respondWithJSON(w, http.StatusOK, list)
+		case err := <-errCh:
+			logger.Log.Error("List freezes error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List freezes", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func exportData(s privacy.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Customer", customerId, "trying to export data")
+
+		exportCh := make(chan privacy.Export)
+		errCh := make(chan error)
+
+		go func() {
+			export, err := s.Export(r.Context(), customerId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			exportCh <- export
+		}()
+
+		select {
+		case export := <-exportCh:
+			logger.Log.Debug("Customer", customerId, "exported data")
+			w.Header().Set("Content-Disposition", "attachment; filename=\"data-export-"+strconv.FormatUint(customerId, 10)+".json\"")
+			w.Header().Set("Cache-Control", "no-store")
+			respondWithJSON(w, http.StatusOK, export)
+		case err := <-errCh:
+			logger.Log.Error("Export data error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Export data", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listConsents(s privacy.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("List consents of customer", customerId)
+
+		consentsCh := make(chan privacy.ListConsentsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			consents, err := s.ListConsents(r.Context(), customerId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			consentsCh <- consents
+		}()
+
+		select {
+		case consents := <-consentsCh:
+			logger.Log.Debug("Successfully listed consents of customer", customerId)
+			respondWithJSON(w, http.StatusOK, consents)
+		case err := <-errCh:
+			logger.Log.Error("List consents error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List consents", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func setConsent(s privacy.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var consentRequest privacy.ConsentRequest
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		if err := json.NewDecoder(r.Body).Decode(&consentRequest); err != nil {
+			logger.Log.Error("Error while decoding set consent body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to set consent for", consentRequest.Purpose)
+
+		consentCh := make(chan privacy.Consent)
+		errCh := make(chan error)
+
+		go func() {
+			consent, err := s.SetConsent(r.Context(), customerId, consentRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			consentCh <- consent
+		}()
+
+		select {
+		case consent := <-consentCh:
+			logger.Log.Debug("Customer", customerId, "consent for", consent.Purpose, "set to", consent.Granted)
+			respondWithJSON(w, http.StatusOK, consent)
+		case err := <-errCh:
+			logger.Log.Error("Set consent error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set consent", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func getNotificationPreferences(s notification.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		logger.Log.Debug("Get notification preferences of customer", customerId)
+
+		preferencesCh := make(chan notification.Preferences)
+		errCh := make(chan error)
+
+		go func() {
+			preferences, err := s.GetPreferences(r.Context(), customerId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			preferencesCh <- preferences
+		}()
+
+		select {
+		case preferences := <-preferencesCh:
+			logger.Log.Debug("Successfully got notification preferences of customer", customerId)
+			respondWithJSON(w, http.StatusOK, preferences)
+		case err := <-errCh:
+			logger.Log.Error("Get notification preferences error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Get notification preferences", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func setNotificationPreferences(s notification.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var preferencesRequest notification.Preferences
+		customerId := r.Context().Value(middleware.CustomerIdContextKey("customerId")).(uint64)
+
+		if err := json.NewDecoder(r.Body).Decode(&preferencesRequest); err != nil {
+			logger.Log.Error("Error while decoding set notification preferences body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		logger.Log.Debug("Customer", customerId, "trying to set notification preferences")
+
+		preferencesCh := make(chan notification.Preferences)
+		errCh := make(chan error)
+
+		go func() {
+			preferences, err := s.SetPreferences(r.Context(), customerId, preferencesRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			preferencesCh <- preferences
+		}()
+
+		select {
+		case preferences := <-preferencesCh:
+			logger.Log.Debug("Customer", customerId, "notification channels set to", preferences.Channels)
+			respondWithJSON(w, http.StatusOK, preferences)
+		case err := <-errCh:
+			logger.Log.Error("Set notification preferences error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Set notification preferences", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func anonymizeCustomers(s privacy.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var anonymizeRequest privacy.AnonymizeRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&anonymizeRequest); err != nil && !errors.Is(err, io.EOF) {
+			logger.Log.Error("Error while decoding anonymize customers body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		closedBefore := time.Now()
+		if anonymizeRequest.ClosedBefore != nil {
+			closedBefore = *anonymizeRequest.ClosedBefore
+		}
+
+		logger.Log.Debug("Trying to anonymize customers with accounts closed before", closedBefore)
+
+		responseCh := make(chan privacy.AnonymizeResponse)
+		errCh := make(chan error)
+
+		go func() {
+			response, err := s.Anonymize(r.Context(), closedBefore)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			responseCh <- response
+		}()
+
+		select {
+		case response := <-responseCh:
+			logger.Log.Debug("Anonymized", response.Anonymized, "customers")
+			respondWithJSON(w, http.StatusOK, response)
+		case err := <-errCh:
+			logger.Log.Error("Anonymize customers error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Anonymize customers", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func createWebhook(s webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var subscriptionRequest webhook.NewSubscriptionRequest
+
+		if err := json.NewDecoder(r.Body).Decode(&subscriptionRequest); err != nil {
+			logger.Log.Error("Error while decoding create webhook body", err)
+			respondWithError(w, http.StatusBadRequest, apperrors.NewArgumentError(err.Error()))
+			return
+		}
+
+		owner := webhookOwner(r)
+		logger.Log.Debug("Trying to create webhook of account", owner.AccountId, "to", subscriptionRequest.Url)
+
+		subscriptionCh := make(chan webhook.NewSubscriptionResponse)
+		errCh := make(chan error)
+
+		go func() {
+			subscription, err := s.CreateSubscription(r.Context(), owner, subscriptionRequest)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			subscriptionCh <- subscription
+		}()
+
+		select {
+		case subscription := <-subscriptionCh:
+			logger.Log.Debug("Successfully created webhook", subscription.Id)
+			respondWithJSON(w, http.StatusCreated, subscription)
+		case err := <-errCh:
+			logger.Log.Error("Create webhook error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Create webhook", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listWebhooks(s webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := webhookOwner(r)
+		logger.Log.Debug("List webhooks of account", owner.AccountId)
+
+		subscriptionsCh := make(chan webhook.ListSubscriptionsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			subscriptions, err := s.ListSubscriptions(r.Context(), owner)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			subscriptionsCh <- subscriptions
+		}()
+
+		select {
+		case subscriptions := <-subscriptionsCh:
+			logger.Log.Debug("Successfully listed webhooks", len(subscriptions.Subscriptions))
+			respondWithJSON(w, http.StatusOK, subscriptions)
+		case err := <-errCh:
+			logger.Log.Error("List webhooks error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List webhooks", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func deleteWebhook(s webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding delete webhook id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Trying to delete webhook", id)
+
+		doneCh := make(chan bool)
+		errCh := make(chan error)
+
+		go func() {
+			if err := s.DeleteSubscription(r.Context(), webhookOwner(r), id); err != nil {
+				errCh <- err
+				return
+			}
+			doneCh <- true
+		}()
+
+		select {
+		case <-doneCh:
+			logger.Log.Debug("Webhook", id, "deleted")
+			w.WriteHeader(http.StatusNoContent)
+		case err := <-errCh:
+			logger.Log.Error("Delete webhook error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Delete webhook", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func listWebhookDeliveries(s webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding list webhook deliveries id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("List deliveries of webhook", id)
+
+		deliveriesCh := make(chan webhook.ListDeliveriesResponse)
+		errCh := make(chan error)
+
+		go func() {
+			deliveries, err := s.ListDeliveries(r.Context(), webhookOwner(r), id)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			deliveriesCh <- deliveries
+		}()
+
+		select {
+		case deliveries := <-deliveriesCh:
+			logger.Log.Debug("Successfully listed webhook deliveries", len(deliveries.Deliveries))
+			respondWithJSON(w, http.StatusOK, deliveries)
+		case err := <-errCh:
+			logger.Log.Error("List webhook deliveries error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List webhook deliveries", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+func replayWebhookDelivery(s webhook.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid id format")
+			logger.Log.Error("Error while decoding replay webhook id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		deliveryId, err := strconv.ParseUint(mux.Vars(r)["deliveryId"], 10, 64)
+
+		if err != nil {
+			err := apperrors.NewArgumentError("invalid delivery id format")
+			logger.Log.Error("Error while decoding replay webhook delivery id", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("Trying to replay delivery", deliveryId, "of webhook", id)
+
+		deliveryCh := make(chan webhook.Delivery)
+		errCh := make(chan error)
+
+		go func() {
+			delivery, err := s.Replay(r.Context(), webhookOwner(r), id, deliveryId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			deliveryCh <- delivery
+		}()
+
+		select {
+		case delivery := <-deliveryCh:
+			logger.Log.Debug("Webhook delivery", delivery.Id, "queued again")
+			respondWithJSON(w, http.StatusAccepted, delivery)
+		case err := <-errCh:
+			logger.Log.Error("Replay webhook delivery error", err)
+			switch err.(type) {
+			case *apperrors.AccountNotFoundError:
+				respondWithError(w, http.StatusNotFound, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Replay webhook delivery", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// webhookOwner returns who the webhooks of the request belong to: the token
+// account and, for API client tokens, the client.
+func webhookOwner(r *http.Request) webhook.Owner {
+	accountId := r.Context().Value(middleware.AccountIdContextKey("accountId")).(uint64)
+	clientId, _ := r.Context().Value(middleware.ClientIdContextKey("clientId")).(string)
+
+	return webhook.Owner{AccountId: accountId, ClientId: clientId}
+}
+
+func listAuditEvents(s audit.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, field := auditEventsQuery(r)
+
+		if field != "" {
+			err := apperrors.NewArgumentError("invalid query params", field)
+			logger.Log.Error("List audit events invalid params", err)
+			respondWithError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		logger.Log.Debug("List audit events", query.Action, query.Target, query.RequestId)
+
+		eventsCh := make(chan audit.ListEventsResponse)
+		errCh := make(chan error)
+
+		go func() {
+			events, err := s.List(r.Context(), query)
+			if err != nil {
+				errCh <- err
+				return
+			}
+			eventsCh <- events
+		}()
+
+		select {
+		case events := <-eventsCh:
+			logger.Log.Debug("Successfully listed audit events", len(events.Events))
+			respondWithJSON(w, http.StatusOK, events)
+		case err := <-errCh:
+			logger.Log.Error("List audit events error", err)
+			switch err.(type) {
+			case *apperrors.ArgumentError:
+				respondWithError(w, http.StatusBadRequest, err)
+			default:
+				respondWithError(w, http.StatusInternalServerError, err)
+			}
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("List audit events", err)
+			respondWithError(w, http.StatusRequestTimeout, err)
+		}
+	}
+}
+
+// auditEventsQuery reads the audit log filters from the query string. It
+// returns the name of the first invalid param, if any.
+func auditEventsQuery(r *http.Request) (audit.ListEventsQuery, string) {
+	query := audit.ListEventsQuery{
+		Action:    r.FormValue("action"),
+		Target:    r.FormValue("target"),
+		RequestId: r.FormValue("requestId"),
+	}
+
+	if v := r.FormValue("actorId"); v != "" {
+		actorId, err := strconv.ParseUint(v, 10, 64)
+
+		if err != nil {
+			return query, "actorId"
+		}
+
+		query.ActorId = &actorId
+	}
+
+	if v := r.FormValue("before"); v != "" {
+		beforeId, err := strconv.ParseUint(v, 10, 64)
+
+		if err != nil {
+			return query, "before"
+		}
+
+		query.BeforeId = &beforeId
+	}
+
+	if v := r.FormValue("from"); v != "" {
+		from, err := time.Parse(time.RFC3339, v)
+
+		if err != nil {
+			return query, "from"
+		}
+
+		query.From = &from
+	}
+
+	if v := r.FormValue("to"); v != "" {
+		to, err := time.Parse(time.RFC3339, v)
+
+		if err != nil {
+			return query, "to"
+		}
+
+		query.To = &to
+	}
+
+	if v := r.FormValue("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+
+		if err != nil || limit < 1 || limit > audit.MaxListLimit {
+			return query, "limit"
+		}
+
+		query.Limit = limit
+	}
+
+	return query, ""
+}
+
+func verifyAuditChain(s audit.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Log.Debug("Verify audit chain")
+
+		responseCh := make(chan audit.VerifyResponse)
+		errCh := make(chan error)
+
+		go func() {
+			response, err := s.Verify(r.Context())
+			if err != nil {
+				errCh <- err
+				return
+			}
+			responseCh <- response
+		}()
+
+		select {
+		case response := <-responseCh:
+			if !response.Valid {
+				logger.Log.Error("Audit chain broken at event", *response.BrokenAt)
+			}
+			respondWithJSON(w, http.StatusOK, response)
+		case err := <-errCh:
+			logger.Log.Error("Verify audit chain error", err)
+			respondWithError(w, http.StatusInternalServerError, err)
+		case <-r.Context().Done():
+			err := apperrors.NewInternalServerError("request timeout")
+			logger.Log.Error("Verify audit chain", err)
 			respondWithError(w, http.StatusRequestTimeout, err)
 		}
 	}
@@ -5,27 +5,51 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/freeze"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
 	"github.com/GilbertoVGL/go-banking/pkg/http/rest/middleware"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
 	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/notification"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/pin"
+	"github.com/GilbertoVGL/go-banking/pkg/privacy"
+	"github.com/GilbertoVGL/go-banking/pkg/secret"
+	"github.com/GilbertoVGL/go-banking/pkg/stream"
 	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+	"github.com/GilbertoVGL/go-banking/pkg/webhook"
 	"github.com/gorilla/mux"
 )
 
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
 type mockRepository struct{}
 
 var (
 	mockListAccount       func(context.Context, account.ListAccountQuery) (account.ListAccountsReponse, error)
 	mockAddAccount        func(context.Context, account.NewAccountRequest) error
-	mockLogin             func(context.Context, login.LoginRequest) (login.Account, error)
+	mockLogin             func(context.Context, login.LoginRequest) (login.Customer, error)
 	mockGetAccountBalance func(context.Context, uint64) (account.BalanceResponse, error)
 	mockGetTransfer       func(context.Context, uint64, transfer.ListTransferQuery) (transfer.ListTransferResponse, error)
+	mockListOwnAccounts   func(context.Context, uint64) ([]account.CustomerAccount, error)
 )
 
 func (mr *mockRepository) ListAccount(ctx context.Context, params account.ListAccountQuery) (account.ListAccountsReponse, error) {
@@ -34,9 +58,12 @@ func (mr *mockRepository) ListAccount(ctx context.Context, params account.ListAc
 func (mr *mockRepository) AddAccount(ctx context.Context, a account.NewAccountRequest) error {
 	return mockAddAccount(ctx, a)
 }
-func (mr *mockRepository) GetAccountBySecretAndCPF(ctx context.Context, l login.LoginRequest) (login.Account, error) {
+func (mr *mockRepository) GetCustomerBySecretAndCPF(ctx context.Context, l login.LoginRequest) (login.Customer, error) {
 	return mockLogin(ctx, l)
 }
+func (mr *mockRepository) ListCustomerAccounts(ctx context.Context, c uint64) ([]account.CustomerAccount, error) {
+	return mockListOwnAccounts(ctx, c)
+}
 func (mr *mockRepository) GetAccountBalance(ctx context.Context, a uint64) (account.BalanceResponse, error) {
 	return mockGetAccountBalance(ctx, a)
 }
@@ -48,6 +75,265 @@ type mockService struct {
 	r *mockRepository
 }
 
+type mockLimitsService struct {
+	limits limits.Limits
+}
+
+func (ms *mockLimitsService) Get(ctx context.Context, a uint64) (limits.LimitsResponse, error) {
+	return limits.LimitsResponse{Limits: ms.limits, Pending: []limits.PendingChange{}}, nil
+}
+func (ms *mockLimitsService) Update(ctx context.Context, a uint64, u limits.UpdateLimitsRequest) (limits.LimitsResponse, error) {
+	response := limits.LimitsResponse{Limits: ms.limits, Pending: []limits.PendingChange{}}
+	if u.Daily == nil {
+		return response, apperrors.NewArgumentError("no limit to update")
+	}
+	if *u.Daily <= ms.limits.Daily {
+		response.Daily = *u.Daily
+	} else {
+		response.Pending = append(response.Pending, limits.PendingChange{Kind: limits.Daily, Amount: *u.Daily})
+	}
+	return response, nil
+}
+func (ms *mockLimitsService) ApplyDue(ctx context.Context, now time.Time) error {
+	return nil
+}
+
+type mockPinService struct{}
+
+func (ms *mockPinService) Set(ctx context.Context, c uint64, a uint64, p pin.SetPinRequest) error {
+	if !pin.Regex.MatchString(p.Pin) {
+		return apperrors.NewArgumentError("pin must have 4 to 6 digits")
+	}
+	if p.CurrentPin != "" && p.CurrentPin != "1234" {
+		return apperrors.NewAuthError("invalid transaction pin")
+	}
+	return nil
+}
+func (ms *mockPinService) Verify(ctx context.Context, a uint64, p string) error {
+	return nil
+}
+
+type mockSecretService struct{}
+
+func (ms *mockSecretService) Change(ctx context.Context, c uint64, r secret.ChangeSecretRequest) error {
+	if r.CurrentSecret != "senha_segura" {
+		return apperrors.NewAuthError("invalid secret")
+	}
+	if len(r.NewSecret) < secret.MinLength {
+		return apperrors.NewArgumentError("newSecret must be between 8 and 16 characters")
+	}
+	return nil
+}
+func (ms *mockSecretService) RequestReset(ctx context.Context, r secret.ResetRequest) error {
+	return nil
+}
+func (ms *mockSecretService) Reset(ctx context.Context, r secret.ConfirmResetRequest) error {
+	if r.Token != "valid" {
+		return apperrors.NewAuthError("invalid or expired reset token")
+	}
+	return nil
+}
+func (ms *mockSecretService) Verify(ctx context.Context, c uint64, s string) error {
+	return nil
+}
+
+type mockOauthService struct{}
+
+func (ms *mockOauthService) CreateClient(ctx context.Context, c oauth.NewClientRequest) (oauth.NewClientResponse, error) {
+	return oauth.NewClientResponse{}, nil
+}
+func (ms *mockOauthService) ListClients(ctx context.Context) (oauth.ListClientsResponse, error) {
+	return oauth.ListClientsResponse{Clients: []oauth.Client{}}, nil
+}
+func (ms *mockOauthService) RevokeClient(ctx context.Context, id uint64) error {
+	return nil
+}
+func (ms *mockOauthService) ValidateClient(ctx context.Context, clientId string, accountId uint64) error {
+	return nil
+}
+func (ms *mockOauthService) Token(ctx context.Context, t oauth.TokenRequest) (oauth.TokenResponse, error) {
+	if t.GrantType != oauth.GrantClientCredentials {
+		return oauth.TokenResponse{}, oauth.NewError(oauth.ErrUnsupportedGrantType, "")
+	}
+	if t.ClientId != "cli_1" || t.ClientSecret != "s3cr3t" {
+		return oauth.TokenResponse{}, oauth.NewError(oauth.ErrInvalidClient, "")
+	}
+	return oauth.TokenResponse{AccessToken: "token", TokenType: "Bearer", ExpiresIn: 900, Scope: t.Scope}, nil
+}
+
+type mockFreezeService struct{}
+
+func (ms *mockFreezeService) Freeze(ctx context.Context, a uint64, author uint64, f freeze.FreezeRequest) (freeze.Freeze, error) {
+	if a == 404 {
+		return freeze.Freeze{}, apperrors.NewAccountNotFoundError("account not found")
+	}
+	if f.Reason == "" {
+		return freeze.Freeze{}, apperrors.NewArgumentError("reason")
+	}
+	return freeze.Freeze{Id: 1, AccountId: a, BlockCredits: f.BlockCredits, Reason: f.Reason, CreatedBy: author}, nil
+}
+func (ms *mockFreezeService) Lift(ctx context.Context, a uint64, id uint64, author uint64) (freeze.Freeze, error) {
+	if id != 1 {
+		return freeze.Freeze{}, apperrors.NewAccountNotFoundError("freeze not found")
+	}
+	return freeze.Freeze{Id: id, AccountId: a, LiftedBy: &author}, nil
+}
+func (ms *mockFreezeService) Block(ctx context.Context, a uint64, author uint64, b freeze.JudicialBlockRequest) (freeze.JudicialBlock, error) {
+	if b.Amount == nil || *b.Amount < 1 {
+		return freeze.JudicialBlock{}, apperrors.NewArgumentError("amount")
+	}
+	return freeze.JudicialBlock{Id: 1, AccountId: a, Amount: *b.Amount, CreatedBy: author}, nil
+}
+func (ms *mockFreezeService) Release(ctx context.Context, a uint64, id uint64, author uint64) (freeze.JudicialBlock, error) {
+	if id != 1 {
+		return freeze.JudicialBlock{}, apperrors.NewAccountNotFoundError("judicial block not found")
+	}
+	return freeze.JudicialBlock{Id: id, AccountId: a, ReleasedBy: &author}, nil
+}
+func (ms *mockFreezeService) List(ctx context.Context, a uint64) (freeze.ListResponse, error) {
+	return freeze.ListResponse{Freezes: []freeze.Freeze{}, JudicialBlocks: []freeze.JudicialBlock{}}, nil
+}
+
+type mockPrivacyService struct{}
+
+func (ms *mockPrivacyService) Export(ctx context.Context, c uint64) (privacy.Export, error) {
+	return privacy.Export{Profile: account.Profile{Id: c, Name: "Roberval Neto"}}, nil
+}
+func (ms *mockPrivacyService) ListConsents(ctx context.Context, c uint64) (privacy.ListConsentsResponse, error) {
+	return privacy.ListConsentsResponse{Consents: []privacy.Consent{}}, nil
+}
+func (ms *mockPrivacyService) SetConsent(ctx context.Context, c uint64, r privacy.ConsentRequest) (privacy.Consent, error) {
+	if r.Purpose != privacy.PurposeMarketing || r.Granted == nil {
+		return privacy.Consent{}, apperrors.NewArgumentError("purpose")
+	}
+	return privacy.Consent{Purpose: r.Purpose, Granted: *r.Granted}, nil
+}
+func (ms *mockPrivacyService) Anonymize(ctx context.Context, closedBefore time.Time) (privacy.AnonymizeResponse, error) {
+	return privacy.AnonymizeResponse{Anonymized: 1}, nil
+}
+
+type mockAuditService struct{}
+
+func (ms *mockAuditService) List(ctx context.Context, q audit.ListEventsQuery) (audit.ListEventsResponse, error) {
+	if q.From != nil && q.To != nil && q.To.Before(*q.From) {
+		return audit.ListEventsResponse{}, apperrors.NewArgumentError("to")
+	}
+	return audit.ListEventsResponse{Events: []audit.Event{}}, nil
+}
+func (ms *mockAuditService) Verify(ctx context.Context) (audit.VerifyResponse, error) {
+	return audit.VerifyResponse{Valid: true}, nil
+}
+
+type mockWebhookService struct{}
+
+func (ms *mockWebhookService) CreateSubscription(ctx context.Context, o webhook.Owner, r webhook.NewSubscriptionRequest) (webhook.NewSubscriptionResponse, error) {
+	if r.Url == "" {
+		return webhook.NewSubscriptionResponse{}, apperrors.NewArgumentError("url")
+	}
+	return webhook.NewSubscriptionResponse{Subscription: webhook.Subscription{Id: 1, AccountId: o.AccountId, Url: r.Url}, Secret: "whsec_test"}, nil
+}
+func (ms *mockWebhookService) ListSubscriptions(ctx context.Context, o webhook.Owner) (webhook.ListSubscriptionsResponse, error) {
+	return webhook.ListSubscriptionsResponse{Subscriptions: []webhook.Subscription{}}, nil
+}
+func (ms *mockWebhookService) DeleteSubscription(ctx context.Context, o webhook.Owner, id uint64) error {
+	if id != 1 {
+		return apperrors.NewAccountNotFoundError("webhook subscription not found")
+	}
+	return nil
+}
+func (ms *mockWebhookService) ListDeliveries(ctx context.Context, o webhook.Owner, id uint64) (webhook.ListDeliveriesResponse, error) {
+	if id != 1 {
+		return webhook.ListDeliveriesResponse{}, apperrors.NewAccountNotFoundError("webhook subscription not found")
+	}
+	return webhook.ListDeliveriesResponse{Deliveries: []webhook.Delivery{}}, nil
+}
+func (ms *mockWebhookService) Replay(ctx context.Context, o webhook.Owner, id uint64, deliveryId uint64) (webhook.Delivery, error) {
+	if id != 1 {
+		return webhook.Delivery{}, apperrors.NewAccountNotFoundError("webhook subscription not found")
+	}
+	return webhook.Delivery{Id: deliveryId, SubscriptionId: id, Status: webhook.StatusPending}, nil
+}
+
+type mockStreamService struct {
+	head      uint64
+	live      []outbox.Event
+	missed    []outbox.Event
+	truncated bool
+}
+
+// Subscribe hands out the live events and then closes the subscription, as
+// if the client fell behind, so the stream ends.
+func (ms *mockStreamService) Subscribe(a uint64) *stream.Subscription {
+	c := make(chan outbox.Event, len(ms.live))
+	for _, e := range ms.live {
+		c <- e
+	}
+	close(c)
+	return &stream.Subscription{AccountId: a, Head: ms.head, C: c}
+}
+func (ms *mockStreamService) Unsubscribe(s *stream.Subscription) {}
+func (ms *mockStreamService) Since(ctx context.Context, a uint64, afterId uint64) ([]outbox.Event, bool, error) {
+	events := []outbox.Event{}
+	for _, e := range ms.missed {
+		if e.Id > afterId {
+			events = append(events, e)
+		}
+	}
+	return events, ms.truncated, nil
+}
+
+func transferEvent(id uint64, origin uint64, destination uint64) outbox.Event {
+	payload, _ := json.Marshal(outbox.TransferPayload{TransferId: id, Origin: origin, Destination: destination, Amount: 100})
+	return outbox.Event{Id: id, Type: outbox.TypeTransferCompleted, Payload: payload}
+}
+
+func accountEvent(id uint64, eventType string, payload interface{}) outbox.Event {
+	raw, _ := json.Marshal(payload)
+	return outbox.Event{Id: id, Type: eventType, Payload: raw}
+}
+
+type mockNotificationService struct{}
+
+func (ms *mockNotificationService) GetPreferences(ctx context.Context, c uint64) (notification.Preferences, error) {
+	return notification.DefaultPreferences(), nil
+}
+func (ms *mockNotificationService) SetPreferences(ctx context.Context, c uint64, p notification.Preferences) (notification.Preferences, error) {
+	return notification.Validate(p)
+}
+
+type mockHoldService struct{}
+
+func (ms *mockHoldService) Authorize(ctx context.Context, o uint64, a hold.AuthorizeRequest) (hold.Hold, error) {
+	if a.Pin != "1234" {
+		return hold.Hold{}, apperrors.NewAuthError("invalid transaction pin")
+	}
+	if *a.Amount > 100000 && a.Secret != "senha_segura" {
+		return hold.Hold{}, apperrors.NewChallengeError("first transfer above 1000.00 to this destination")
+	}
+	return hold.Hold{Id: 1, AccountId: o, Destination: *a.Destination, Amount: *a.Amount, Status: hold.StatusActive}, nil
+}
+func (ms *mockHoldService) Capture(ctx context.Context, a uint64, id uint64, c hold.CaptureRequest) (hold.Hold, error) {
+	h := hold.Hold{Id: id, AccountId: 1, Destination: a, Amount: 100, Status: hold.StatusActive}
+	if id != 1 {
+		return hold.Hold{}, apperrors.NewAccountNotFoundError("hold not found")
+	}
+	if c.Amount != nil && *c.Amount > h.Amount {
+		return hold.Hold{}, apperrors.NewArgumentError("amount must be positive and at most the hold amount")
+	}
+	h.Status = hold.StatusCaptured
+	h.CapturedAmount = h.Amount
+	return h, nil
+}
+func (ms *mockHoldService) Void(ctx context.Context, a uint64, id uint64) (hold.Hold, error) {
+	return hold.Hold{Id: id, Destination: a, Status: hold.StatusVoided}, nil
+}
+func (ms *mockHoldService) List(ctx context.Context, a uint64) (hold.ListHoldsResponse, error) {
+	return hold.ListHoldsResponse{Holds: []hold.Hold{}}, nil
+}
+func (ms *mockHoldService) ExpireDue(ctx context.Context, now time.Time) error {
+	return nil
+}
+
 func (ms *mockService) List(ctx context.Context, a account.ListAccountQuery) (account.ListAccountsReponse, error) {
 	return ms.r.ListAccount(ctx, a)
 }
@@ -63,15 +349,134 @@ func (ms *mockService) NewAccount(ctx context.Context, a account.NewAccountReque
 func (ms *mockService) GetBalance(ctx context.Context, a uint64) (account.BalanceResponse, error) {
 	return ms.r.GetAccountBalance(ctx, a)
 }
+func (ms *mockService) ListOwn(ctx context.Context, c uint64) (account.ListCustomerAccountsResponse, error) {
+	accounts, err := ms.r.ListCustomerAccounts(ctx, c)
+	return account.ListCustomerAccountsResponse{Data: accounts}, err
+}
+func (ms *mockService) OpenAccount(ctx context.Context, c uint64, o account.OpenAccountRequest) (account.CustomerAccount, error) {
+	return account.CustomerAccount{Id: 2, Product: o.Product, Active: true}, nil
+}
+func (ms *mockService) SetOverdraftLimit(ctx context.Context, a uint64, o account.OverdraftLimitRequest) error {
+	if o.Limit == nil || *o.Limit < 0 {
+		return apperrors.NewArgumentError("limit")
+	}
+	return nil
+}
+func (ms *mockService) SetApprovalPolicy(ctx context.Context, a uint64, p account.ApprovalPolicyRequest) error {
+	return nil
+}
+func (ms *mockService) UpdateProfile(ctx context.Context, c uint64, u account.UpdateProfileRequest) (account.Profile, error) {
+	if u.Name == nil || strings.TrimSpace(*u.Name) == "" {
+		return account.Profile{}, apperrors.NewArgumentError("name must have 1 to 120 characters")
+	}
+	return account.Profile{Id: c, Name: strings.TrimSpace(*u.Name), Cpf: "050.930.920-88"}, nil
+}
+func (ms *mockService) Deactivate(ctx context.Context, a uint64) error {
+	if a == 404 {
+		return apperrors.NewAccountNotFoundError("account not found")
+	}
+	return nil
+}
+func (ms *mockService) Reactivate(ctx context.Context, a uint64) error {
+	if a == 3 {
+		return apperrors.NewArgumentError("account is closed")
+	}
+	return nil
+}
+func (ms *mockService) Close(ctx context.Context, c uint64, a uint64, r account.CloseAccountRequest) (account.CloseAccountResponse, error) {
+	if a != 1 {
+		return account.CloseAccountResponse{}, apperrors.NewAccountNotFoundError("account not found")
+	}
+	if r.PayoutDestination == nil {
+		return account.CloseAccountResponse{}, apperrors.NewArgumentError("account balance must be zero or paid out", "payoutDestination")
+	}
+	if r.Pin != "1234" {
+		return account.CloseAccountResponse{}, apperrors.NewAuthError("invalid transaction pin")
+	}
+	transferId := uint64(9)
+	return account.CloseAccountResponse{Id: a, ClosedAt: time.Now(), PayoutTransferId: &transferId, PayoutAmount: 500}, nil
+}
 func (ms *mockService) LoginUser(ctx context.Context, l login.LoginRequest) (login.LoginReponse, error) {
-	account, err := ms.r.GetAccountBySecretAndCPF(ctx, l)
-	return login.LoginReponse{Token: account.Cpf}, err
+	customer, err := ms.r.GetCustomerBySecretAndCPF(ctx, l)
+	return login.LoginReponse{Token: customer.Cpf}, err
+}
+func (ms *mockService) CompleteMfaLogin(ctx context.Context, m login.MfaLoginRequest) (login.LoginReponse, error) {
+	if m.Token != "pending" {
+		return login.LoginReponse{}, apperrors.NewAuthError("invalid mfa token")
+	}
+	if m.Code != "123456" {
+		return login.LoginReponse{}, apperrors.NewAuthError("invalid code")
+	}
+	return login.LoginReponse{Token: "full"}, nil
+}
+func (ms *mockService) SelectAccount(ctx context.Context, c uint64, a uint64, s uint64, m bool) (login.LoginReponse, error) {
+	return login.LoginReponse{}, nil
+}
+func (ms *mockService) ValidateSession(ctx context.Context, c uint64, a uint64, s uint64, i time.Time) error {
+	return nil
+}
+func (ms *mockService) ListSessions(ctx context.Context, c uint64, current uint64) (login.ListSessionsResponse, error) {
+	sessions := []login.Session{{Id: 5, CustomerId: c, UserAgent: "curl/8.0"}, {Id: 6, CustomerId: c, UserAgent: "Firefox"}}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].Id == current
+	}
+	return login.ListSessionsResponse{Sessions: sessions}, nil
+}
+func (ms *mockService) TerminateSession(ctx context.Context, c uint64, id uint64) error {
+	if id != 5 && id != 6 {
+		return apperrors.NewAccountNotFoundError("session not found")
+	}
+	return nil
 }
 func (ms *mockService) GetTransfers(ctx context.Context, a uint64, l transfer.ListTransferQuery) (transfer.ListTransferResponse, error) {
 	return ms.r.GetTransfers(ctx, a, l)
 }
-func (ms *mockService) DoTransfer(ctx context.Context, t transfer.TransferRequest) error {
-	return nil
+func (ms *mockService) DoTransfer(ctx context.Context, t transfer.TransferRequest) (transfer.TransferResponse, error) {
+	if *t.Amount == 666 {
+		return transfer.TransferResponse{}, apperrors.NewChallengeError("more than 5 transfers in 10 minutes")
+	}
+	if *t.Amount > 1000 {
+		return transfer.TransferResponse{Id: 7, Origin: t.Origin, Destination: *t.Destination, Amount: *t.Amount, Status: transfer.TransferPendingApproval}, nil
+	}
+	return transfer.TransferResponse{Id: 1, Origin: t.Origin, Destination: *t.Destination, Amount: *t.Amount, Fee: 150, Status: transfer.TransferCompleted}, nil
+}
+func (ms *mockService) ApproveTransfer(ctx context.Context, c uint64, id uint64) (transfer.PendingTransfer, error) {
+	return ms.decideTransfer(c, id, transfer.ApprovalExecuted)
+}
+func (ms *mockService) RejectTransfer(ctx context.Context, c uint64, id uint64) (transfer.PendingTransfer, error) {
+	return ms.decideTransfer(c, id, transfer.ApprovalRejected)
+}
+func (ms *mockService) decideTransfer(c uint64, id uint64, status string) (transfer.PendingTransfer, error) {
+	switch {
+	case id != 7:
+		return transfer.PendingTransfer{}, apperrors.NewAccountNotFoundError("pending transfer not found")
+	case c == 1:
+		return transfer.PendingTransfer{}, apperrors.NewTransferRequestError("the requester cannot decide on its own transfer")
+	case c != 2:
+		return transfer.PendingTransfer{}, apperrors.NewAuthError("not an approver of the origin account")
+	}
+	return transfer.PendingTransfer{Id: id, RequestedBy: 1, Status: status}, nil
+}
+func (ms *mockService) ListPendingTransfers(ctx context.Context, c uint64) (transfer.ListPendingTransfersResponse, error) {
+	return transfer.ListPendingTransfersResponse{Transfers: []transfer.PendingTransfer{}}, nil
+}
+func (ms *mockService) DoOwnAccountsTransfer(ctx context.Context, c uint64, t transfer.TransferRequest) (transfer.TransferResponse, error) {
+	if t.Origin == *t.Destination {
+		return transfer.TransferResponse{}, apperrors.NewTransferRequestError("origin and destination must be different accounts")
+	}
+	return transfer.TransferResponse{Id: 1, Origin: t.Origin, Destination: *t.Destination, Amount: *t.Amount}, nil
+}
+func (ms *mockService) DoBatchTransfer(ctx context.Context, o uint64, b transfer.BatchRequest) (transfer.Batch, error) {
+	if len(b.Items) == 0 {
+		return transfer.Batch{}, apperrors.NewArgumentError("items", "a batch must have between 1 and 500 items")
+	}
+	return transfer.Batch{Id: 1, Origin: o, Mode: b.Mode, Status: transfer.BatchProcessing}, nil
+}
+func (ms *mockService) GetBatch(ctx context.Context, o uint64, id uint64) (transfer.Batch, error) {
+	if id != 1 {
+		return transfer.Batch{}, apperrors.NewAccountNotFoundError("batch not found")
+	}
+	return transfer.Batch{Id: id, Origin: o, Status: transfer.BatchCompleted}, nil
 }
 
 func TestDoLogin(t *testing.T) {
@@ -93,8 +498,8 @@ func TestDoLogin(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		mockLogin = func(ctx context.Context, l login.LoginRequest) (login.Account, error) {
-			return login.Account{}, nil
+		mockLogin = func(ctx context.Context, l login.LoginRequest) (login.Customer, error) {
+			return login.Customer{}, nil
 		}
 
 		rr := httptest.NewRecorder()
@@ -123,8 +528,8 @@ func TestDoLogin(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		mockLogin = func(ctx context.Context, l login.LoginRequest) (login.Account, error) {
-			return login.Account{}, errors.New("bad_test")
+		mockLogin = func(ctx context.Context, l login.LoginRequest) (login.Customer, error) {
+			return login.Customer{}, errors.New("bad_test")
 		}
 
 		rr := httptest.NewRecorder()
@@ -139,6 +544,73 @@ func TestDoLogin(t *testing.T) {
 	})
 }
 
+func TestGetJWKS(t *testing.T) {
+	k, err := keys.NewSet("", "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	getJWKS(k).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	if body := strings.TrimSpace(rr.Body.String()); body != `{"keys":[]}` {
+		t.Errorf("handler returned unexpected body: %s", body)
+	}
+}
+
+func TestDoMfaLogin(t *testing.T) {
+	s := mockService{&mockRepository{}}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"doMfaLogin is OK", `{"token":"pending","code":"123456"}`, http.StatusOK},
+		{"doMfaLogin wrong code", `{"token":"pending","code":"654321"}`, http.StatusUnauthorized},
+		{"doMfaLogin full token", `{"token":"full","code":"123456"}`, http.StatusUnauthorized},
+		{"doMfaLogin invalid body", `{"token":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/login/mfa", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			doMfaLogin(&s).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var result login.LoginReponse
+			json.NewDecoder(rr.Body).Decode(&result)
+
+			if result.Token != "full" {
+				t.Errorf("handler returned unexpected body: %+v", result)
+			}
+		})
+	}
+}
+
 func TestDoTransfer(t *testing.T) {
 	path := url.URL{
 		Path: "/transfers",
@@ -163,7 +635,8 @@ func TestDoTransfer(t *testing.T) {
 		rr := httptest.NewRecorder()
 		handler := http.HandlerFunc(doTransfer(&s))
 		ctx := req.Context()
-		ctx = context.WithValue(ctx, middleware.UserIdContextKey("userId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
 		ro := req.Clone(ctx)
 
 		handler.ServeHTTP(rr, ro)
@@ -172,9 +645,238 @@ func TestDoTransfer(t *testing.T) {
 			t.Errorf("handler returned wrong status code: got %v want %v",
 				status, http.StatusOK)
 		}
+
+		expected := transfer.TransferResponse{Id: 1, Origin: 1, Destination: 1, Amount: 2, Fee: 150, Status: transfer.TransferCompleted}
+		var result transfer.TransferResponse
+		json.NewDecoder(rr.Body).Decode(&result)
+
+		if result != expected {
+			t.Errorf("handler returned unexpected body: \ngot \n\t%v\n want \n\t%v",
+				result, expected)
+		}
+	})
+
+	t.Run("doTransfer above the approval threshold", func(t *testing.T) {
+		var large int64 = 5000
+		jsonPayload, _ := json.Marshal(transfer.TransferRequest{Destination: &d, Amount: &large})
+		req, err := http.NewRequest(http.MethodPost, path.String(), bytes.NewBuffer(jsonPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+		doTransfer(&s).ServeHTTP(rr, req.Clone(ctx))
+
+		if status := rr.Code; status != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusAccepted)
+		}
+
+		var result transfer.TransferResponse
+		json.NewDecoder(rr.Body).Decode(&result)
+
+		if result.Status != transfer.TransferPendingApproval {
+			t.Errorf("handler returned status %q want %q", result.Status, transfer.TransferPendingApproval)
+		}
+	})
+
+	t.Run("doTransfer challenged by risk screening", func(t *testing.T) {
+		var suspicious int64 = 666
+		jsonPayload, _ := json.Marshal(transfer.TransferRequest{Destination: &d, Amount: &suspicious})
+		req, err := http.NewRequest(http.MethodPost, path.String(), bytes.NewBuffer(jsonPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := httptest.NewRecorder()
+		ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+		doTransfer(&s).ServeHTTP(rr, req.Clone(ctx))
+
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusForbidden)
+		}
 	})
 }
 
+func TestDecideTransfer(t *testing.T) {
+	r := &mockRepository{}
+	s := mockService{r}
+
+	tests := []struct {
+		name     string
+		path     string
+		customer uint64
+		client   bool
+		status   int
+	}{
+		{"approve is OK", "/transfers/7/approve", 2, false, http.StatusOK},
+		{"reject is OK", "/transfers/7/reject", 2, false, http.StatusOK},
+		{"approve by the requester", "/transfers/7/approve", 1, false, http.StatusBadRequest},
+		{"approve by someone else", "/transfers/7/approve", 3, false, http.StatusForbidden},
+		{"approve through an API client", "/transfers/7/approve", 2, true, http.StatusForbidden},
+		{"reject through an API client", "/transfers/7/reject", 2, true, http.StatusForbidden},
+		{"approve not found", "/transfers/8/approve", 2, false, http.StatusNotFound},
+		{"approve invalid id", "/transfers/abc/approve", 2, false, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), tt.customer)
+			if tt.client {
+				ctx = context.WithValue(ctx, middleware.ScopesContextKey("scopes"), []string{oauth.ScopeTransfersWrite})
+			}
+			router := mux.NewRouter()
+			router.Handle("/transfers/{id}/approve", middleware.CustomersOnly(decideTransfer(&s, true)))
+			router.Handle("/transfers/{id}/reject", middleware.CustomersOnly(decideTransfer(&s, false)))
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestDoOwnAccountsTransfer(t *testing.T) {
+	path := url.URL{
+		Path: "/me/transfers",
+	}
+	r := &mockRepository{}
+	s := mockService{r}
+
+	tests := []struct {
+		name        string
+		origin      uint64
+		destination uint64
+		status      int
+	}{
+		{"doOwnAccountsTransfer is OK", 2, 1, http.StatusCreated},
+		{"doOwnAccountsTransfer defaults origin to the selected account", 0, 3, http.StatusCreated},
+		{"doOwnAccountsTransfer same account", 0, 1, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a int64 = 2
+			q := transfer.TransferRequest{
+				Origin:      tt.origin,
+				Destination: &tt.destination,
+				Amount:      &a,
+			}
+			jsonPayload, _ := json.Marshal(q)
+			req, err := http.NewRequest(http.MethodPost, path.String(), bytes.NewBuffer(jsonPayload))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(doOwnAccountsTransfer(&s))
+			ctx := req.Context()
+			ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
+			ro := req.Clone(ctx)
+
+			handler.ServeHTTP(rr, ro)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestDoBatchTransfer(t *testing.T) {
+	path := url.URL{
+		Path: "/transfers/batch",
+	}
+	r := &mockRepository{}
+	s := mockService{r}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"doBatchTransfer JSON is OK", "application/json", `{"mode":"atomic","items":[{"destination":2,"amount":100}]}`, http.StatusAccepted},
+		{"doBatchTransfer CSV is OK", "text/csv", "destination,amount\n2,100\n3,250\n", http.StatusAccepted},
+		{"doBatchTransfer invalid CSV", "text/csv", "2,100\n3,abc\n", http.StatusBadRequest},
+		{"doBatchTransfer empty batch", "application/json", `{"mode":"atomic","items":[]}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, path.String()+"?mode=bestEffort", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(doBatchTransfer(&s))
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+			ro := req.Clone(ctx)
+
+			handler.ServeHTTP(rr, ro)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestGetBatchTransfer(t *testing.T) {
+	r := &mockRepository{}
+	s := mockService{r}
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{"getBatchTransfer is OK", "1", http.StatusOK},
+		{"getBatchTransfer not found", "2", http.StatusNotFound},
+		{"getBatchTransfer invalid id", "abc", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/transfers/batch/"+tt.id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+			ro := mux.SetURLVars(req.Clone(ctx), map[string]string{"id": tt.id})
+
+			getBatchTransfer(&s).ServeHTTP(rr, ro)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
 func TestGetTransfer(t *testing.T) {
 	path := url.URL{
 		Path:     "/transfers",
@@ -201,7 +903,7 @@ func TestGetTransfer(t *testing.T) {
 		rr := httptest.NewRecorder()
 		handler := http.HandlerFunc(listTransfer(&s))
 		ctx := req.Context()
-		ctx = context.WithValue(ctx, middleware.UserIdContextKey("userId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
 		ro := req.Clone(ctx)
 
 		handler.ServeHTTP(rr, ro)
@@ -425,6 +1127,25 @@ func TestGetBalance(t *testing.T) {
 				result, expected)
 		}
 	})
+
+	t.Run("getBalance through an API client", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, path.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx := context.WithValue(req.Context(), middleware.ScopesContextKey("scopes"), []string{oauth.ScopeAccountsRead})
+
+		rr := httptest.NewRecorder()
+		router := mux.NewRouter()
+		router.Handle("/accounts/{id}/balance", middleware.CustomersOnly(getBalance(&s)))
+		router.ServeHTTP(rr, req.Clone(ctx))
+
+		if status := rr.Code; status != http.StatusForbidden {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusForbidden)
+		}
+	})
 }
 
 func TestGetSelfBalance(t *testing.T) {
@@ -447,7 +1168,7 @@ func TestGetSelfBalance(t *testing.T) {
 		rr := httptest.NewRecorder()
 		handler := http.HandlerFunc(getSelfBalance(&s))
 		ctx := req.Context()
-		ctx = context.WithValue(ctx, middleware.UserIdContextKey("userId"), uint64(1))
+		ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
 		ro := req.Clone(ctx)
 
 		handler.ServeHTTP(rr, ro)
@@ -467,3 +1188,831 @@ func TestGetSelfBalance(t *testing.T) {
 		}
 	})
 }
+
+func TestListOwnAccounts(t *testing.T) {
+	path := url.URL{
+		Path: "/me/accounts",
+	}
+	r := &mockRepository{}
+	s := mockService{r}
+
+	t.Run("listOwnAccounts is OK", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, path.String(), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mockListOwnAccounts = func(ctx context.Context, c uint64) ([]account.CustomerAccount, error) {
+			if c != 7 {
+				t.Errorf("service called with wrong customer: got %v want %v", c, 7)
+			}
+			return []account.CustomerAccount{{Id: 1, Balance: 10, Active: true}}, nil
+		}
+
+		rr := httptest.NewRecorder()
+		handler := http.HandlerFunc(listOwnAccounts(&s))
+		ctx := req.Context()
+		ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(7))
+		ro := req.Clone(ctx)
+
+		handler.ServeHTTP(rr, ro)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+
+		var result account.ListCustomerAccountsResponse
+		json.NewDecoder(rr.Body).Decode(&result)
+
+		if len(result.Data) != 1 || result.Data[0].Id != 1 {
+			t.Errorf("handler returned unexpected body: %v", result)
+		}
+	})
+}
+
+func TestSetOverdraftLimit(t *testing.T) {
+	r := &mockRepository{}
+	s := mockService{r}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"setOverdraftLimit is OK", "/admin/accounts/2/overdraft", `{"limit":50000}`, http.StatusOK},
+		{"setOverdraftLimit negative limit", "/admin/accounts/2/overdraft", `{"limit":-1}`, http.StatusBadRequest},
+		{"setOverdraftLimit missing limit", "/admin/accounts/2/overdraft", `{}`, http.StatusBadRequest},
+		{"setOverdraftLimit invalid id", "/admin/accounts/abc/overdraft", `{"limit":1}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/accounts/{id}/overdraft", setOverdraftLimit(&s))
+			router.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuthorizeHold(t *testing.T) {
+	s := mockHoldService{}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"authorizeHold is OK", `{"destination":2,"amount":100,"pin":"1234"}`, http.StatusCreated},
+		{"authorizeHold wrong pin", `{"destination":2,"amount":100,"pin":"0000"}`, http.StatusForbidden},
+		{"authorizeHold challenged", `{"destination":2,"amount":150000,"pin":"1234"}`, http.StatusForbidden},
+		{"authorizeHold challenge answered", `{"destination":2,"amount":150000,"pin":"1234","secret":"senha_segura"}`, http.StatusCreated},
+		{"authorizeHold invalid body", `{"destination":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/holds", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+			handler := http.HandlerFunc(authorizeHold(&s))
+			handler.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestCaptureHold(t *testing.T) {
+	s := mockHoldService{}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+	}{
+		{"captureHold full is OK", "/holds/1/capture", ``, http.StatusOK},
+		{"captureHold partial is OK", "/holds/1/capture", `{"amount":40}`, http.StatusOK},
+		{"captureHold above the hold amount", "/holds/1/capture", `{"amount":101}`, http.StatusBadRequest},
+		{"captureHold not found", "/holds/2/capture", ``, http.StatusNotFound},
+		{"captureHold invalid id", "/holds/abc/capture", ``, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(2))
+			router := mux.NewRouter()
+			router.HandleFunc("/holds/{id}/capture", captureHold(&s))
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestSetPin(t *testing.T) {
+	s := mockPinService{}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"setPin is OK", `{"pin":"4321","currentPin":"1234"}`, http.StatusNoContent},
+		{"setPin with secret is OK", `{"pin":"4321","secret":"secret_pass"}`, http.StatusNoContent},
+		{"setPin wrong current pin", `{"pin":"4321","currentPin":"0000"}`, http.StatusForbidden},
+		{"setPin too short", `{"pin":"12","currentPin":"1234"}`, http.StatusBadRequest},
+		{"setPin invalid body", `{"pin":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/me/pin", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
+
+			setPin(&s).ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestChangeSecret(t *testing.T) {
+	s := mockSecretService{}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"changeSecret is OK", `{"currentSecret":"senha_segura","newSecret":"outra_senha"}`, http.StatusNoContent},
+		{"changeSecret wrong current secret", `{"currentSecret":"errada_123","newSecret":"outra_senha"}`, http.StatusForbidden},
+		{"changeSecret too short", `{"currentSecret":"senha_segura","newSecret":"curta"}`, http.StatusBadRequest},
+		{"changeSecret invalid body", `{"currentSecret":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/me/secret", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+			changeSecret(&s).ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestResetSecret(t *testing.T) {
+	s := mockSecretService{}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"requestSecretReset is OK", requestSecretReset(&s), `{"cpf":"050.930.920-88"}`, http.StatusAccepted},
+		{"resetSecret is OK", resetSecret(&s), `{"token":"valid","newSecret":"outra_senha"}`, http.StatusNoContent},
+		{"resetSecret used token", resetSecret(&s), `{"token":"used","newSecret":"outra_senha"}`, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/secret/reset", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+		})
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	s := mockOauthService{}
+
+	tests := []struct {
+		name   string
+		body   string
+		basic  bool
+		status int
+		error  string
+	}{
+		{"requestToken is OK", "grant_type=client_credentials&client_id=cli_1&client_secret=s3cr3t&scope=accounts:read", false, http.StatusOK, ""},
+		{"requestToken with basic auth", "grant_type=client_credentials", true, http.StatusOK, ""},
+		{"requestToken wrong secret", "grant_type=client_credentials&client_id=cli_1&client_secret=guess", false, http.StatusUnauthorized, oauth.ErrInvalidClient},
+		{"requestToken other grant", "grant_type=password&client_id=cli_1&client_secret=s3cr3t", false, http.StatusBadRequest, oauth.ErrUnsupportedGrantType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.basic {
+				req.SetBasicAuth("cli_1", "s3cr3t")
+			}
+
+			rr := httptest.NewRecorder()
+			requestToken(&s).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+
+			if rr.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("token response without Cache-Control: no-store")
+			}
+
+			if tt.error == "" {
+				return
+			}
+
+			var result oauth.Error
+			json.NewDecoder(rr.Body).Decode(&result)
+
+			if result.Code != tt.error {
+				t.Errorf("handler returned unexpected error: got %v want %v", result.Code, tt.error)
+			}
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := mockService{}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{"listSessions is OK", http.MethodGet, "/me/sessions", listSessions(&s), http.StatusOK},
+		{"terminateSession is OK", http.MethodDelete, "/me/sessions/6", terminateSession(&s), http.StatusNoContent},
+		{"terminateSession not found", http.MethodDelete, "/me/sessions/7", terminateSession(&s), http.StatusNotFound},
+		{"terminateSession invalid id", http.MethodDelete, "/me/sessions/abc", terminateSession(&s), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := req.Context()
+			ctx = context.WithValue(ctx, middleware.CustomerIdContextKey("customerId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.SessionIdContextKey("sessionId"), uint64(5))
+
+			router := mux.NewRouter()
+			router.HandleFunc("/me/sessions", tt.handler)
+			router.HandleFunc("/me/sessions/{id}", tt.handler)
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+
+			if tt.method != http.MethodGet {
+				return
+			}
+
+			var result login.ListSessionsResponse
+			json.NewDecoder(rr.Body).Decode(&result)
+
+			if len(result.Sessions) != 2 || !result.Sessions[0].Current || result.Sessions[1].Current {
+				t.Errorf("handler returned unexpected body: %+v", result)
+			}
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	s := mockService{}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"updateProfile is OK", `{"name":" Maria Silva "}`, http.StatusOK},
+		{"updateProfile empty name", `{"name":""}`, http.StatusBadRequest},
+		{"updateProfile nothing to update", `{}`, http.StatusBadRequest},
+		{"updateProfile invalid body", `{"name":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPatch, "/me", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+			updateProfile(&s).ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var result account.Profile
+			json.NewDecoder(rr.Body).Decode(&result)
+
+			if result.Name != "Maria Silva" {
+				t.Errorf("handler returned unexpected body: %+v", result)
+			}
+		})
+	}
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	s := mockService{}
+
+	tests := []struct {
+		name    string
+		path    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"deactivate is OK", "/admin/accounts/1/deactivate", setAccountActive(&s, false), "", http.StatusNoContent},
+		{"deactivate not found", "/admin/accounts/404/deactivate", setAccountActive(&s, false), "", http.StatusNotFound},
+		{"reactivate is OK", "/admin/accounts/1/reactivate", setAccountActive(&s, true), "", http.StatusNoContent},
+		{"reactivate closed account", "/admin/accounts/3/reactivate", setAccountActive(&s, true), "", http.StatusBadRequest},
+		{"close with payout", "/me/accounts/1/close", closeAccount(&s), `{"payoutDestination":2,"pin":"1234"}`, http.StatusOK},
+		{"close with balance and no payout", "/me/accounts/1/close", closeAccount(&s), "", http.StatusBadRequest},
+		{"close with wrong pin", "/me/accounts/1/close", closeAccount(&s), `{"payoutDestination":2,"pin":"0000"}`, http.StatusForbidden},
+		{"close other customer account", "/me/accounts/2/close", closeAccount(&s), `{}`, http.StatusNotFound},
+		{"close invalid id", "/me/accounts/abc/close", closeAccount(&s), `{}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/accounts/{id}/deactivate", tt.handler)
+			router.HandleFunc("/admin/accounts/{id}/reactivate", tt.handler)
+			router.HandleFunc("/me/accounts/{id}/close", tt.handler)
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestFreezes(t *testing.T) {
+	s := mockFreezeService{}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"freeze is OK", http.MethodPost, "/admin/accounts/1/freezes", freezeAccount(&s), `{"blockCredits":true,"reason":"fraud","reference":"T-1"}`, http.StatusCreated},
+		{"freeze without reason", http.MethodPost, "/admin/accounts/1/freezes", freezeAccount(&s), `{"reference":"T-1"}`, http.StatusBadRequest},
+		{"freeze not found", http.MethodPost, "/admin/accounts/404/freezes", freezeAccount(&s), `{"reason":"fraud","reference":"T-1"}`, http.StatusNotFound},
+		{"freeze invalid body", http.MethodPost, "/admin/accounts/1/freezes", freezeAccount(&s), `{`, http.StatusBadRequest},
+		{"list is OK", http.MethodGet, "/admin/accounts/1/freezes", listAccountFreezes(&s), "", http.StatusOK},
+		{"lift is OK", http.MethodPost, "/admin/accounts/1/freezes/1/lift", liftAccountFreeze(&s), "", http.StatusOK},
+		{"lift not found", http.MethodPost, "/admin/accounts/1/freezes/2/lift", liftAccountFreeze(&s), "", http.StatusNotFound},
+		{"lift invalid id", http.MethodPost, "/admin/accounts/1/freezes/abc/lift", liftAccountFreeze(&s), "", http.StatusBadRequest},
+		{"block is OK", http.MethodPost, "/admin/accounts/1/judicial-blocks", blockAccountAmount(&s), `{"amount":500,"reason":"court order","reference":"0001234-56.2026.8.26.0100"}`, http.StatusCreated},
+		{"block without amount", http.MethodPost, "/admin/accounts/1/judicial-blocks", blockAccountAmount(&s), `{"reason":"court order","reference":"1"}`, http.StatusBadRequest},
+		{"release is OK", http.MethodPost, "/admin/accounts/1/judicial-blocks/1/release", releaseJudicialBlock(&s), "", http.StatusOK},
+		{"release not found", http.MethodPost, "/admin/accounts/1/judicial-blocks/2/release", releaseJudicialBlock(&s), "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+
+			router := mux.NewRouter()
+			router.HandleFunc("/admin/accounts/{id}/freezes", tt.handler)
+			router.HandleFunc("/admin/accounts/{id}/freezes/{freezeId}/lift", tt.handler)
+			router.HandleFunc("/admin/accounts/{id}/judicial-blocks", tt.handler)
+			router.HandleFunc("/admin/accounts/{id}/judicial-blocks/{blockId}/release", tt.handler)
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestPrivacy(t *testing.T) {
+	s := mockPrivacyService{}
+
+	tests := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"export is OK", http.MethodGet, exportData(&s), "", http.StatusOK},
+		{"list consents is OK", http.MethodGet, listConsents(&s), "", http.StatusOK},
+		{"set consent is OK", http.MethodPut, setConsent(&s), `{"purpose":"marketing","granted":false}`, http.StatusOK},
+		{"set unknown consent", http.MethodPut, setConsent(&s), `{"purpose":"profiling","granted":true}`, http.StatusBadRequest},
+		{"set consent invalid body", http.MethodPut, setConsent(&s), `{`, http.StatusBadRequest},
+		{"anonymize is OK", http.MethodPost, anonymizeCustomers(&s), `{"closedBefore":"2021-01-01T00:00:00Z"}`, http.StatusOK},
+		{"anonymize without body", http.MethodPost, anonymizeCustomers(&s), "", http.StatusOK},
+		{"anonymize invalid date", http.MethodPost, anonymizeCustomers(&s), `{"closedBefore":"yesterday"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+			tt.handler.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("export is an attachment", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me/data-export", nil)
+		ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+		rr := httptest.NewRecorder()
+
+		exportData(&s).ServeHTTP(rr, req.Clone(ctx))
+
+		if got := rr.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+			t.Errorf("got Content-Disposition %q", got)
+		}
+	})
+}
+
+func TestAuditEvents(t *testing.T) {
+	s := mockAuditService{}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		query   string
+		status  int
+	}{
+		{"list is OK", listAuditEvents(&s), "", http.StatusOK},
+		{"list with filters", listAuditEvents(&s), "?actorId=1&action=login&from=2021-01-01T00:00:00Z&limit=10", http.StatusOK},
+		{"list invalid actor", listAuditEvents(&s), "?actorId=me", http.StatusBadRequest},
+		{"list invalid date", listAuditEvents(&s), "?from=yesterday", http.StatusBadRequest},
+		{"list invalid limit", listAuditEvents(&s), "?limit=5000", http.StatusBadRequest},
+		{"list reversed dates", listAuditEvents(&s), "?from=2021-02-01T00:00:00Z&to=2021-01-01T00:00:00Z", http.StatusBadRequest},
+		{"verify is OK", verifyAuditChain(&s), "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/admin/audit-events"+tt.query, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	s := mockWebhookService{}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		route   string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"create is OK", http.MethodPost, "/webhooks", "/webhooks", createWebhook(&s), `{"url":"https://partner.example.com","eventTypes":["transfer.received"]}`, http.StatusCreated},
+		{"create invalid", http.MethodPost, "/webhooks", "/webhooks", createWebhook(&s), `{"eventTypes":["transfer.received"]}`, http.StatusBadRequest},
+		{"create invalid body", http.MethodPost, "/webhooks", "/webhooks", createWebhook(&s), `{`, http.StatusBadRequest},
+		{"list is OK", http.MethodGet, "/webhooks", "/webhooks", listWebhooks(&s), "", http.StatusOK},
+		{"delete is OK", http.MethodDelete, "/webhooks/1", "/webhooks/{id}", deleteWebhook(&s), "", http.StatusNoContent},
+		{"delete not found", http.MethodDelete, "/webhooks/2", "/webhooks/{id}", deleteWebhook(&s), "", http.StatusNotFound},
+		{"deliveries are OK", http.MethodGet, "/webhooks/1/deliveries", "/webhooks/{id}/deliveries", listWebhookDeliveries(&s), "", http.StatusOK},
+		{"deliveries invalid id", http.MethodGet, "/webhooks/x/deliveries", "/webhooks/{id}/deliveries", listWebhookDeliveries(&s), "", http.StatusBadRequest},
+		{"replay is OK", http.MethodPost, "/webhooks/1/deliveries/3/replay", "/webhooks/{id}/deliveries/{deliveryId}/replay", replayWebhookDelivery(&s), "", http.StatusAccepted},
+		{"replay not found", http.MethodPost, "/webhooks/2/deliveries/3/replay", "/webhooks/{id}/deliveries/{deliveryId}/replay", replayWebhookDelivery(&s), "", http.StatusNotFound},
+		{"replay invalid delivery id", http.MethodPost, "/webhooks/1/deliveries/x/replay", "/webhooks/{id}/deliveries/{deliveryId}/replay", replayWebhookDelivery(&s), "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc(tt.route, tt.handler)
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+			router.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	r := &mockRepository{}
+	a := mockService{r}
+	mockGetAccountBalance = func(ctx context.Context, i uint64) (account.BalanceResponse, error) {
+		return account.BalanceResponse{Balance: 500}, nil
+	}
+
+	s := mockStreamService{
+		head:   4,
+		missed: []outbox.Event{transferEvent(2, 7, 1), transferEvent(3, 1, 7)},
+		live: []outbox.Event{transferEvent(4, 7, 1), transferEvent(5, 7, 1), transferEvent(6, 1, 7),
+			accountEvent(7, outbox.TypeHoldChanged, outbox.HoldPayload{HoldId: 1, AccountId: 1, Amount: 50, Status: "active"}),
+			accountEvent(8, outbox.TypePostingCompleted, outbox.PostingPayload{Origin: 99, Destination: 1, Amount: 3, Kind: "interest"}),
+			accountEvent(9, outbox.TypeJudicialBlockChanged, outbox.JudicialBlockPayload{BlockId: 1, AccountId: 1, Amount: 20, Released: true}),
+		},
+	}
+
+	tests := []struct {
+		name        string
+		lastEventId string
+		status      int
+		expected    []string
+	}{
+		{"live is OK", "", http.StatusOK, []string{
+			"id: 4\nevent: balance\n",
+			"id: 5\nevent: transfer.received\n",
+			"id: 5\nevent: balance\n",
+			"id: 6\nevent: balance\n",
+			"id: 7\nevent: hold.active\n",
+			"id: 7\nevent: balance\n",
+			"id: 8\nevent: interest.paid\n",
+			"id: 9\nevent: judicial_block.released\n",
+		}},
+		{"resume is OK", "1", http.StatusOK, []string{
+			"id: 2\nevent: transfer.received\n",
+			"id: 3\nevent: balance\n",
+			"id: 4\nevent: transfer.received\n",
+			"id: 4\nevent: balance\n",
+			"id: 5\nevent: transfer.received\n",
+		}},
+		{"invalid last event id", "x", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/me/events", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.lastEventId != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventId)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+			streamEvents(&s, &a).ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+
+			body := rr.Body.String()
+			last := 0
+
+			for _, e := range tt.expected {
+				i := strings.Index(body[last:], e)
+				if i < 0 {
+					t.Fatalf("handler stream is missing %q in order:\n%s", e, body)
+				}
+				last += i + len(e)
+			}
+
+			if tt.status == http.StatusOK && rr.Header().Get("Content-Type") != "text/event-stream" {
+				t.Errorf("handler returned wrong content type: %s", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	t.Run("resume past the replay resets", func(t *testing.T) {
+		s := mockStreamService{head: 4, missed: []outbox.Event{transferEvent(2, 7, 1)}, live: []outbox.Event{transferEvent(5, 7, 1)}, truncated: true}
+
+		req, err := http.NewRequest(http.MethodGet, "/me/events", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Last-Event-ID", "1")
+		rr := httptest.NewRecorder()
+		ctx := context.WithValue(req.Context(), middleware.AccountIdContextKey("accountId"), uint64(1))
+		streamEvents(&s, &a).ServeHTTP(rr, req.Clone(ctx))
+
+		body := rr.Body.String()
+
+		if !strings.Contains(body, "id: 4\nevent: reset\n") {
+			t.Errorf("handler stream is missing the reset event:\n%s", body)
+		}
+
+		if strings.Contains(body, "transfer.received") || strings.Contains(body, "event: balance") {
+			t.Errorf("handler stream went on after the reset:\n%s", body)
+		}
+	})
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	s := mockNotificationService{}
+
+	tests := []struct {
+		name    string
+		method  string
+		handler http.HandlerFunc
+		body    string
+		status  int
+	}{
+		{"get is OK", http.MethodGet, getNotificationPreferences(&s), "", http.StatusOK},
+		{"set is OK", http.MethodPut, setNotificationPreferences(&s), `{"language":"en","email":"maria@example.com","channels":["email"],"muted":["transfer_received"]}`, http.StatusOK},
+		{"set without address", http.MethodPut, setNotificationPreferences(&s), `{"channels":["sms"]}`, http.StatusBadRequest},
+		{"set muting security", http.MethodPut, setNotificationPreferences(&s), `{"muted":["secret_changed"]}`, http.StatusBadRequest},
+		{"set invalid body", http.MethodPut, setNotificationPreferences(&s), `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "/me/notification-preferences", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			ctx := context.WithValue(req.Context(), middleware.CustomerIdContextKey("customerId"), uint64(1))
+			tt.handler.ServeHTTP(rr, req.Clone(ctx))
+
+			if status := rr.Code; status != tt.status {
+				t.Errorf("handler returned wrong status code: got %v want %v: %s",
+					status, tt.status, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateLimits(t *testing.T) {
+	path := url.URL{
+		Path: "/me/limits",
+	}
+	s := mockLimitsService{limits.Limits{PerTransaction: 100, Daily: 1000, Monthly: 10000, Nightly: 50}}
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		daily   int64
+		pending int
+		mfa     bool
+	}{
+		{"updateLimits lowers right away", `{"daily":500}`, http.StatusOK, 500, 0, true},
+		{"updateLimits raise is pending", `{"daily":5000}`, http.StatusOK, 1000, 1, true},
+		{"updateLimits nothing to update", `{}`, http.StatusBadRequest, 0, 0, true},
+		{"updateLimits without mfa", `{"daily":500}`, http.StatusForbidden, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, path.String(), strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rr := httptest.NewRecorder()
+			handler := middleware.RequireMFA(updateLimits(&s))
+			ctx := req.Context()
+			ctx = context.WithValue(ctx, middleware.AccountIdContextKey("accountId"), uint64(1))
+			ctx = context.WithValue(ctx, middleware.MfaContextKey("mfa"), tt.mfa)
+			ro := req.Clone(ctx)
+
+			handler.ServeHTTP(rr, ro)
+
+			if status := rr.Code; status != tt.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v",
+					status, tt.status)
+			}
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var result limits.LimitsResponse
+			json.NewDecoder(rr.Body).Decode(&result)
+
+			if result.Daily != tt.daily || len(result.Pending) != tt.pending {
+				t.Errorf("handler returned unexpected body: %+v", result)
+			}
+		})
+	}
+}
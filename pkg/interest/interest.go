@@ -0,0 +1,41 @@
+package interest
+
+import "time"
+
+// DaysInYear is the day count convention used to turn annual rates into daily
+// ones.
+const DaysInYear = 365
+
+// SavingsAccount is an account whose product pays interest.
+type SavingsAccount struct {
+	Id      uint64
+	Balance int64
+	RateBps int64
+}
+
+// Accrual is the interest earned by an account on a single day. It is credited
+// to the account balance only when the month is over.
+type Accrual struct {
+	AccountId uint64
+	Date      time.Time
+	Balance   int64
+	RateBps   int64
+	Amount    int64
+}
+
+// OverdrawnAccount is an account with a negative balance.
+type OverdrawnAccount struct {
+	Id      uint64
+	Balance int64
+	RateBps int64
+}
+
+// OverdraftCharge is the interest charged on a single day for a negative
+// balance, debited from the account on the same day.
+type OverdraftCharge struct {
+	AccountId uint64
+	Date      time.Time
+	Balance   int64
+	RateBps   int64
+	Amount    int64
+}
@@ -0,0 +1,227 @@
+package interest
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/money"
+)
+
+type Repository interface {
+	GetLastAccrualDate(context.Context) (*time.Time, error)
+	ListAccountsToAccrue(context.Context, time.Time, time.Time) ([]SavingsAccount, error)
+	AddInterestAccrual(context.Context, Accrual) error
+	PayInterestAccruals(context.Context, time.Time) (int, error)
+	GetLastOverdraftChargeDate(context.Context) (*time.Time, error)
+	ListOverdrawnAccounts(context.Context, time.Time, time.Time) ([]OverdrawnAccount, error)
+	AddOverdraftCharge(context.Context, OverdraftCharge) error
+}
+
+// MaxBackfillDays bounds how many days Accrue and ChargeOverdraft catch up on
+// after the jobs did not run for a while.
+const MaxBackfillDays = 31
+
+type Service interface {
+	Accrue(context.Context, time.Time) error
+	Pay(context.Context, time.Time) error
+	ChargeOverdraft(context.Context, time.Time) error
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+// Accrue records the interest earned on the given day, over its closing
+// balance, by every savings account that has not been accrued for it yet. The
+// days missed since the last accrual are accrued first, as Backfill lists.
+func (s *service) Accrue(ctx context.Context, day time.Time) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		last, err := s.r.GetLastAccrualDate(ctx)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		for _, date := range Backfill(last, day) {
+			if err := s.accrue(ctx, date); err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *service) accrue(ctx context.Context, date time.Time) error {
+	accounts, err := s.r.ListAccountsToAccrue(ctx, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range accounts {
+		accrual := Accrual{
+			AccountId: a.Id,
+			Date:      date,
+			Balance:   a.Balance,
+			RateBps:   a.RateBps,
+			Amount:    DailyInterest(a.Balance, a.RateBps),
+		}
+
+		if err := s.r.AddInterestAccrual(ctx, accrual); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Debug("Accrued interest of", date.Format("2006-01-02"), "for", len(accounts), "accounts")
+	return nil
+}
+
+// Pay credits every unpaid accrual from the months before the one of day.
+func (s *service) Pay(ctx context.Context, day time.Time) error {
+	doneCh := make(chan int)
+	errCh := make(chan error)
+	date := Day(day)
+	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, config.Location)
+
+	go func() {
+		paid, err := s.r.PayInterestAccruals(ctx, firstOfMonth)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		doneCh <- paid
+	}()
+
+	select {
+	case paid := <-doneCh:
+		logger.Log.Debug("Paid interest before", firstOfMonth.Format("2006-01-02"), "to", paid, "accounts")
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ChargeOverdraft debits the interest of the given day from every account that
+// closed it with a negative balance and was not charged for it yet. The days
+// missed since the last charge are charged first, as Backfill lists.
+func (s *service) ChargeOverdraft(ctx context.Context, day time.Time) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		last, err := s.r.GetLastOverdraftChargeDate(ctx)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		for _, date := range Backfill(last, day) {
+			if err := s.chargeOverdraft(ctx, date); err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *service) chargeOverdraft(ctx context.Context, date time.Time) error {
+	accounts, err := s.r.ListOverdrawnAccounts(ctx, date, date.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+
+	for _, a := range accounts {
+		charge := OverdraftCharge{
+			AccountId: a.Id,
+			Date:      date,
+			Balance:   a.Balance,
+			RateBps:   a.RateBps,
+			Amount:    DailyInterest(-a.Balance, a.RateBps),
+		}
+
+		if charge.Amount == 0 {
+			continue
+		}
+
+		if err := s.r.AddOverdraftCharge(ctx, charge); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Debug("Charged overdraft interest of", date.Format("2006-01-02"), "for", len(accounts), "accounts")
+	return nil
+}
+
+// Day is the start of the day of t in config.Location, the time zone the
+// interest days follow.
+func Day(t time.Time) time.Time {
+	t = t.In(config.Location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, config.Location)
+}
+
+// Backfill returns, in order, the days to process for the day of t when last
+// was the latest one processed: every day from last, which a failed run may
+// have left half done, up to the day of t, and no more than MaxBackfillDays.
+// Last is a date, read back at midnight UTC. Without one, only the day of t.
+func Backfill(last *time.Time, t time.Time) []time.Time {
+	day := Day(t)
+	first := day
+
+	if last != nil {
+		first = time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, config.Location)
+
+		if oldest := day.AddDate(0, 0, 1-MaxBackfillDays); first.Before(oldest) {
+			first = oldest
+		}
+	}
+
+	var days []time.Time
+
+	for d := first; !d.After(day); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+
+	return days
+}
+
+// DailyInterest is the interest, in cents, earned in one day by balance at
+// the annual rate rateBps (in basis points), with banker's rounding.
+func DailyInterest(balance int64, rateBps int64) int64 {
+	if balance <= 0 || rateBps <= 0 {
+		return 0
+	}
+
+	return money.MulDivHalfEven(balance, rateBps, 10000*DaysInYear)
+}
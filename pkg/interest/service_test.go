@@ -0,0 +1,100 @@
+package interest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/config"
+)
+
+func TestDailyInterest(t *testing.T) {
+	tests := []struct {
+		name     string
+		balance  int64
+		rateBps  int64
+		expected int64
+	}{
+		{"zero balance", 0, 600, 0},
+		{"negative balance", -10000, 600, 0},
+		{"no rate", 100000, 0, 0},
+		{"rounds up", 10000, 600, 2},
+		{"rounds half to even", 365000, 5, 0},
+		{"rounds half to even up", 1095000, 5, 2},
+		{"large balance", 100000000, 1000, 27397},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DailyInterest(tt.balance, tt.rateBps); got != tt.expected {
+				t.Errorf("DailyInterest(%d, %d) = %d, want %d", tt.balance, tt.rateBps, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDay(t *testing.T) {
+	location := config.Location
+	defer func() { config.Location = location }()
+
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	config.Location = saoPaulo
+
+	// 01:30 UTC is still the previous day in São Paulo.
+	got := Day(time.Date(2021, 3, 5, 1, 30, 0, 0, time.UTC))
+	want := time.Date(2021, 3, 4, 0, 0, 0, 0, saoPaulo)
+
+	if !got.Equal(want) || got.Location() != saoPaulo {
+		t.Errorf("Day() = %v, want %v", got, want)
+	}
+
+	if end := got.AddDate(0, 0, 1); !end.Equal(time.Date(2021, 3, 5, 3, 0, 0, 0, time.UTC)) {
+		t.Errorf("end of day = %v", end.UTC())
+	}
+}
+
+func TestBackfill(t *testing.T) {
+	location := config.Location
+	defer func() { config.Location = location }()
+
+	config.Location = time.UTC
+
+	day := time.Date(2021, 3, 10, 15, 0, 0, 0, time.UTC)
+	date := func(year int, month time.Month, d int) *time.Time {
+		t := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		name  string
+		last  *time.Time
+		first *time.Time
+		count int
+	}{
+		{"never processed", nil, date(2021, 3, 10), 1},
+		{"up to date", date(2021, 3, 10), date(2021, 3, 10), 1},
+		{"missed days", date(2021, 3, 7), date(2021, 3, 7), 4},
+		{"bounded", date(2020, 1, 1), date(2021, 2, 8), MaxBackfillDays},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days := Backfill(tt.last, day)
+
+			if len(days) != tt.count {
+				t.Fatalf("got %d days want %d", len(days), tt.count)
+			}
+
+			if !days[0].Equal(*tt.first) {
+				t.Errorf("first day = %v, want %v", days[0], *tt.first)
+			}
+
+			if last := days[len(days)-1]; !last.Equal(Day(day)) {
+				t.Errorf("last day = %v, want %v", last, Day(day))
+			}
+		})
+	}
+}
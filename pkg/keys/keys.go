@@ -0,0 +1,81 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Key is a token signing key, or a validation only one when Private is nil.
+type Key struct {
+	Id      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+	// RemovedAt is when the key file went away. The key keeps validating
+	// tokens until the ones it signed have expired.
+	RemovedAt *time.Time
+}
+
+// JWK is the public part of a key, as published in the JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is the /.well-known/jwks.json document.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParsePEM reads a RSA (RS256) or Ed25519 (EdDSA) key, private or public,
+// from PEM data.
+func ParsePEM(id string, data []byte) (*Key, error) {
+	if private, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &Key{Id: id, Method: jwt.SigningMethodRS256, Private: private, Public: &private.PublicKey}, nil
+	}
+
+	if private, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		return &Key{Id: id, Method: jwt.SigningMethodEdDSA, Private: private, Public: private.(ed25519.PrivateKey).Public()}, nil
+	}
+
+	if public, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &Key{Id: id, Method: jwt.SigningMethodRS256, Public: public}, nil
+	}
+
+	if public, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return &Key{Id: id, Method: jwt.SigningMethodEdDSA, Public: public}, nil
+	}
+
+	return nil, errors.New("not a RSA or Ed25519 PEM key")
+}
+
+// JWK returns the public part of k.
+func (k *Key) JWK() JWK {
+	jwk := JWK{Kid: k.Id, Use: "sig", Alg: k.Method.Alg()}
+
+	switch public := k.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+
+	return jwk
+}
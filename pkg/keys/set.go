@@ -0,0 +1,207 @@
+package keys
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// Set holds the keys tokens are signed and validated with.
+//
+// Keys are the *.pem files of a directory, the file name without extension
+// being the key id (kid). The private key whose id sorts last signs new
+// tokens, so a key is rotated by adding a file with a later name, e.g. a
+// date. Every key in the directory validates tokens, and a removed key keeps
+// validating for retention, long enough for the tokens it signed to expire.
+//
+// Without a directory tokens are signed with the HMAC secret, as before keys
+// were supported.
+type Set struct {
+	dir       string
+	secret    []byte
+	retention time.Duration
+
+	mu      sync.RWMutex
+	keys    map[string]*Key
+	current *Key
+}
+
+// NewSet loads the keys in dir, or uses secret when dir is empty.
+func NewSet(dir string, secret string, retention time.Duration) (*Set, error) {
+	s := &Set{dir: dir, secret: []byte(secret), retention: retention, keys: map[string]*Key{}}
+
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := s.Reload(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Reload reads the key directory again. Keys that fail to load keep their
+// previous version, if any.
+func (s *Set) Reload(now time.Time) error {
+	if s.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	loaded := map[string]*Key{}
+
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := readKey(id, path)
+
+		if err != nil {
+			logger.Log.Error("JWT key", id, "load error:", err)
+
+			if previous, ok := s.keys[id]; ok && previous.RemovedAt == nil {
+				loaded[id] = previous
+			}
+			continue
+		}
+
+		loaded[id] = key
+	}
+
+	for id, key := range s.keys {
+		if _, ok := loaded[id]; ok {
+			continue
+		}
+
+		if key.RemovedAt == nil {
+			removedAt := now
+			key.RemovedAt = &removedAt
+			logger.Log.Info("JWT key", id, "removed, validating its tokens until", now.Add(s.retention).Format(time.RFC3339))
+		}
+
+		if now.Before(key.RemovedAt.Add(s.retention)) {
+			loaded[id] = key
+		}
+	}
+
+	current := latestPrivate(loaded)
+
+	if current == nil {
+		return fmt.Errorf("no private key in %s to sign tokens with", s.dir)
+	}
+
+	if s.current == nil || s.current.Id != current.Id {
+		logger.Log.Info("Signing tokens with JWT key", current.Id)
+	}
+
+	s.keys = loaded
+	s.current = current
+
+	return nil
+}
+
+// Sign returns the token with claims signed by the current key, whose id goes
+// in the kid header.
+func (s *Set) Sign(claims jwt.MapClaims) (string, error) {
+	if s.dir == "" {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	}
+
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(current.Method, claims)
+	token.Header["kid"] = current.Id
+
+	return token.SignedString(current.Private)
+}
+
+// Keyfunc returns the key a token is validated with, as jwt.Parse expects.
+// The token algorithm must be the one of the key named by its kid, so a
+// public key can never be taken as an HMAC secret.
+func (s *Set) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if s.dir == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return s.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.Public, nil
+}
+
+// JWKS returns the public keys that validate tokens.
+func (s *Set) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, key.JWK())
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].Kid < jwks.Keys[j].Kid
+	})
+
+	return jwks
+}
+
+func readKey(id string, path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePEM(id, data)
+}
+
+func latestPrivate(keys map[string]*Key) *Key {
+	var latest *Key
+
+	for _, key := range keys {
+		if key.Private == nil || key.RemovedAt != nil {
+			continue
+		}
+
+		if latest == nil || key.Id > latest.Id {
+			latest = key
+		}
+	}
+
+	return latest
+}
@@ -0,0 +1,222 @@
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/golang-jwt/jwt"
+)
+
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func writeKey(t *testing.T, dir string, id string, private interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := ioutil.WriteFile(filepath.Join(dir, id+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func parse(s *Set, token string) error {
+	_, err := jwt.Parse(token, s.Keyfunc)
+	return err
+}
+
+func TestSetSignAndValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		private interface{}
+		alg     string
+		kty     string
+	}{
+		{"RS256", rsaKey, "RS256", "RSA"},
+		{"EdDSA", edKey, "EdDSA", "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := tempDir(t)
+			writeKey(t, dir, "2026-01", tt.private)
+
+			s, err := NewSet(dir, "", time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := s.Sign(jwt.MapClaims{"customerId": 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := jwt.Parse(signed, s.Keyfunc)
+			if err != nil {
+				t.Fatalf("token signed by the set does not validate: %v", err)
+			}
+
+			if token.Header["kid"] != "2026-01" || token.Method.Alg() != tt.alg {
+				t.Errorf("unexpected header %v", token.Header)
+			}
+
+			jwks := s.JWKS()
+
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != tt.kty || jwks.Keys[0].Alg != tt.alg || jwks.Keys[0].Kid != "2026-01" {
+				t.Errorf("unexpected jwks %+v", jwks)
+			}
+		})
+	}
+}
+
+func TestSetRefusesForgedAlgorithm(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := tempDir(t)
+	writeKey(t, dir, "2026-01", edKey)
+
+	s, err := NewSet(dir, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"customerId": 1})
+	forged.Header["kid"] = "2026-01"
+	signed, _ := forged.SignedString([]byte(edKey.Public().(ed25519.PublicKey)))
+
+	if err := parse(s, signed); err == nil {
+		t.Errorf("HMAC token with the kid of a public key validated")
+	}
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodEdDSA, jwt.MapClaims{"customerId": 1})
+	unknown.Header["kid"] = "other"
+	signed, _ = unknown.SignedString(edKey)
+
+	if err := parse(s, signed); err == nil {
+		t.Errorf("token with an unknown kid validated")
+	}
+}
+
+func TestSetRotation(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	dir := tempDir(t)
+	writeKey(t, dir, "2026-01", oldKey)
+
+	s, err := NewSet(dir, "", 15*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldToken, _ := s.Sign(jwt.MapClaims{"customerId": 1})
+
+	writeKey(t, dir, "2026-02", newKey)
+	now := time.Now()
+
+	if err := s.Reload(now); err != nil {
+		t.Fatal(err)
+	}
+
+	newToken, _ := s.Sign(jwt.MapClaims{"customerId": 1})
+
+	if token, _ := jwt.Parse(newToken, s.Keyfunc); token == nil || token.Header["kid"] != "2026-02" {
+		t.Fatalf("the newest key should sign after a reload")
+	}
+
+	if err := parse(s, oldToken); err != nil {
+		t.Errorf("token of the previous key should still validate: %v", err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "2026-01.pem")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Reload(now); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := parse(s, oldToken); err != nil {
+		t.Errorf("token of a removed key should validate within the retention: %v", err)
+	}
+
+	if err := s.Reload(now.Add(16 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := parse(s, oldToken); err == nil {
+		t.Errorf("token of a removed key validated after the retention")
+	}
+
+	if err := parse(s, newToken); err != nil {
+		t.Errorf("token of the current key should validate: %v", err)
+	}
+
+	if jwks := s.JWKS(); len(jwks.Keys) != 1 {
+		t.Errorf("removed key still published: %+v", jwks)
+	}
+}
+
+func TestSetHMAC(t *testing.T) {
+	s, err := NewSet("", "secret", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := s.Sign(jwt.MapClaims{"customerId": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := parse(s, signed); err != nil {
+		t.Errorf("HMAC token should validate: %v", err)
+	}
+
+	if jwks := s.JWKS(); len(jwks.Keys) != 0 {
+		t.Errorf("HMAC secret must not be published: %+v", jwks)
+	}
+}
+
+func TestNewSetWithoutPrivateKey(t *testing.T) {
+	if _, err := NewSet(tempDir(t), "", time.Minute); err == nil {
+		t.Errorf("a key directory without a private key should fail to load")
+	}
+}
@@ -0,0 +1,122 @@
+package keys
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Issuer is the iss claim of every token.
+const Issuer = "go-banking"
+
+// Leeway is the clock skew tolerated when checking exp, iat and nbf, for
+// tokens validated by hosts whose clocks are slightly off from the issuer's.
+const Leeway = 30 * time.Second
+
+// Parse validates tokenString for audience and returns its claims. The token
+// must be signed by one of the keys, with an allowed algorithm, and carry the
+// exp, iat, iss, aud and sub claims.
+func (s *Set) Parse(tokenString string, audience string) (jwt.MapClaims, error) {
+	parser := jwt.Parser{ValidMethods: s.algorithms(), SkipClaimsValidation: true}
+
+	token, err := parser.Parse(tokenString, s.Keyfunc)
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+
+	if !ok {
+		return nil, errors.New("unexpected claims")
+	}
+
+	if err := validateClaims(claims, audience, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// algorithms is the allow-list of signing algorithms: HMAC only without keys
+// and only the asymmetric ones with them.
+func (s *Set) algorithms() []string {
+	if s.dir == "" {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+func validateClaims(claims jwt.MapClaims, audience string, now time.Time) error {
+	exp, ok := numericClaim(claims, "exp")
+
+	if !ok {
+		return errors.New("missing exp")
+	}
+
+	if now.After(time.Unix(exp, 0).Add(Leeway)) {
+		return errors.New("token expired")
+	}
+
+	iat, ok := numericClaim(claims, "iat")
+
+	if !ok {
+		return errors.New("missing iat")
+	}
+
+	if time.Unix(iat, 0).After(now.Add(Leeway)) {
+		return errors.New("token issued in the future")
+	}
+
+	if _, present := claims["nbf"]; present {
+		nbf, ok := numericClaim(claims, "nbf")
+
+		if !ok || time.Unix(nbf, 0).After(now.Add(Leeway)) {
+			return errors.New("token not valid yet")
+		}
+	}
+
+	if iss, _ := claims["iss"].(string); iss != Issuer {
+		return errors.New("unexpected issuer")
+	}
+
+	if !hasAudience(claims, audience) {
+		return errors.New("unexpected audience")
+	}
+
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return errors.New("missing sub")
+	}
+
+	return nil
+}
+
+// numericClaim reads a NumericDate claim, whole seconds since the epoch.
+func numericClaim(claims jwt.MapClaims, name string) (int64, bool) {
+	value, ok := claims[name].(float64)
+
+	if !ok {
+		return 0, false
+	}
+
+	return int64(value), true
+}
+
+// hasAudience tells whether the aud claim, a string or a list of them,
+// includes audience.
+func hasAudience(claims jwt.MapClaims, audience string) bool {
+	switch aud := claims["aud"].(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
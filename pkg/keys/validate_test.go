@@ -0,0 +1,57 @@
+package keys
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestValidateClaims(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss": Issuer,
+			"aud": "api",
+			"sub": "1",
+			"iat": float64(now.Unix()),
+			"exp": float64(now.Add(15 * time.Minute).Unix()),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(jwt.MapClaims)
+		valid  bool
+	}{
+		{"valid", func(c jwt.MapClaims) {}, true},
+		{"audience list", func(c jwt.MapClaims) { c["aud"] = []interface{}{"other", "api"} }, true},
+		{"expired within leeway", func(c jwt.MapClaims) { c["exp"] = float64(now.Add(-Leeway + time.Second).Unix()) }, true},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = float64(now.Add(-Leeway - time.Second).Unix()) }, false},
+		{"issued in the future within leeway", func(c jwt.MapClaims) { c["iat"] = float64(now.Add(Leeway).Unix()) }, true},
+		{"issued in the future", func(c jwt.MapClaims) { c["iat"] = float64(now.Add(time.Minute).Unix()) }, false},
+		{"not valid yet", func(c jwt.MapClaims) { c["nbf"] = float64(now.Add(time.Minute).Unix()) }, false},
+		{"nbf not a number", func(c jwt.MapClaims) { c["nbf"] = "soon" }, false},
+		{"missing exp", func(c jwt.MapClaims) { delete(c, "exp") }, false},
+		{"exp not a number", func(c jwt.MapClaims) { c["exp"] = "tomorrow" }, false},
+		{"missing iat", func(c jwt.MapClaims) { delete(c, "iat") }, false},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "someone-else" }, false},
+		{"missing issuer", func(c jwt.MapClaims) { delete(c, "iss") }, false},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "mfa" }, false},
+		{"missing audience", func(c jwt.MapClaims) { delete(c, "aud") }, false},
+		{"missing sub", func(c jwt.MapClaims) { delete(c, "sub") }, false},
+		{"sub not a string", func(c jwt.MapClaims) { c["sub"] = float64(1) }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := valid()
+			tt.change(claims)
+
+			if err := validateClaims(claims, "api", now); (err == nil) != tt.valid {
+				t.Errorf("validateClaims = %v want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
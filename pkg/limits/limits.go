@@ -0,0 +1,42 @@
+package limits
+
+import "time"
+
+// Kinds of limit, matching the limit_* columns of accounts.
+const (
+	PerTransaction = "perTransaction"
+	Daily          = "daily"
+	Monthly        = "monthly"
+	Nightly        = "nightly"
+)
+
+// RaiseDelay is how long a request to raise a limit waits before taking
+// effect. Lowering a limit is immediate.
+const RaiseDelay = 24 * time.Hour
+
+// Limits caps how much can leave an account. Nightly caps the total sent
+// between 20h and 6h, and also each transfer sent in that period.
+type Limits struct {
+	PerTransaction int64 `json:"perTransaction"`
+	Daily          int64 `json:"daily"`
+	Monthly        int64 `json:"monthly"`
+	Nightly        int64 `json:"nightly"`
+}
+
+type PendingChange struct {
+	Kind        string    `json:"kind"`
+	Amount      int64     `json:"amount"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
+
+type LimitsResponse struct {
+	Limits
+	Pending []PendingChange `json:"pending"`
+}
+
+type UpdateLimitsRequest struct {
+	PerTransaction *int64 `json:"perTransaction"`
+	Daily          *int64 `json:"daily"`
+	Monthly        *int64 `json:"monthly"`
+	Nightly        *int64 `json:"nightly"`
+}
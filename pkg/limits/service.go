@@ -0,0 +1,205 @@
+package limits
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+type Repository interface {
+	GetAccountLimits(context.Context, uint64) (Limits, error)
+	ListPendingLimitChanges(context.Context, uint64) ([]PendingChange, error)
+	LowerAccountLimit(context.Context, uint64, string, int64) error
+	AddLimitChangeRequest(context.Context, uint64, PendingChange) error
+	ApplyDueLimitChanges(context.Context, time.Time) (int, error)
+}
+
+type Service interface {
+	Get(context.Context, uint64) (LimitsResponse, error)
+	Update(context.Context, uint64, UpdateLimitsRequest) (LimitsResponse, error)
+	ApplyDue(context.Context, time.Time) error
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+func (s *service) Get(ctx context.Context, accountId uint64) (LimitsResponse, error) {
+	limitsCh := make(chan LimitsResponse)
+	errCh := make(chan error)
+
+	go func() {
+		limits, err := s.get(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		limitsCh <- limits
+	}()
+
+	select {
+	case limits := <-limitsCh:
+		return limits, nil
+	case err := <-errCh:
+		return LimitsResponse{}, err
+	case <-ctx.Done():
+		return LimitsResponse{}, ctx.Err()
+	}
+}
+
+// Update lowers the requested limits right away and schedules the raises to
+// take effect after RaiseDelay.
+func (s *service) Update(ctx context.Context, accountId uint64, u UpdateLimitsRequest) (LimitsResponse, error) {
+	limitsCh := make(chan LimitsResponse)
+	errCh := make(chan error)
+
+	go func() {
+		requested, err := validateLimitsValues(u)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		current, err := s.r.GetAccountLimits(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		now := time.Now()
+
+		for _, kind := range []string{PerTransaction, Daily, Monthly, Nightly} {
+			amount, ok := requested[kind]
+			if !ok {
+				continue
+			}
+
+			if amount <= current.get(kind) {
+				err = s.r.LowerAccountLimit(ctx, accountId, kind, amount)
+			} else {
+				err = s.r.AddLimitChangeRequest(ctx, accountId, PendingChange{
+					Kind:        kind,
+					Amount:      amount,
+					EffectiveAt: now.Add(RaiseDelay),
+				})
+			}
+
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		limits, err := s.get(ctx, accountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		limitsCh <- limits
+	}()
+
+	select {
+	case limits := <-limitsCh:
+		return limits, nil
+	case err := <-errCh:
+		return LimitsResponse{}, err
+	case <-ctx.Done():
+		return LimitsResponse{}, ctx.Err()
+	}
+}
+
+// ApplyDue applies every requested raise whose delay is over.
+func (s *service) ApplyDue(ctx context.Context, now time.Time) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.r.ApplyDueLimitChanges(ctx, now); err != nil {
+			errCh <- err
+			return
+		}
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *service) get(ctx context.Context, accountId uint64) (LimitsResponse, error) {
+	var response LimitsResponse
+	var err error
+
+	response.Limits, err = s.r.GetAccountLimits(ctx, accountId)
+	if err != nil {
+		return response, err
+	}
+
+	response.Pending, err = s.r.ListPendingLimitChanges(ctx, accountId)
+	if err != nil {
+		return response, err
+	}
+
+	return response, nil
+}
+
+func (l Limits) get(kind string) int64 {
+	switch kind {
+	case PerTransaction:
+		return l.PerTransaction
+	case Daily:
+		return l.Daily
+	case Monthly:
+		return l.Monthly
+	default:
+		return l.Nightly
+	}
+}
+
+func validateLimitsValues(u UpdateLimitsRequest) (map[string]int64, error) {
+	var invalid []string
+	requested := map[string]int64{}
+
+	values := map[string]*int64{
+		PerTransaction: u.PerTransaction,
+		Daily:          u.Daily,
+		Monthly:        u.Monthly,
+		Nightly:        u.Nightly,
+	}
+
+	for _, kind := range []string{PerTransaction, Daily, Monthly, Nightly} {
+		value := values[kind]
+
+		if value == nil {
+			continue
+		}
+
+		if *value < 0 {
+			invalid = append(invalid, kind)
+			continue
+		}
+
+		requested[kind] = *value
+	}
+
+	if len(invalid) > 0 {
+		return requested, apperrors.NewArgumentError("limits must not be negative", strings.Join(invalid, ", "))
+	}
+
+	if len(requested) == 0 {
+		return requested, apperrors.NewArgumentError("no limit to update")
+	}
+
+	return requested, nil
+}
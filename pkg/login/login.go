@@ -2,22 +2,125 @@ package login
 
 import "time"
 
-type Account struct {
+// Token claims about MFA. ClaimMfa tells whether the customer passed MFA
+// when logging in; ClaimMfaPending marks the short lived token that only
+// completes a login with MFA.
+const (
+	ClaimMfa        = "mfa"
+	ClaimMfaPending = "mfaPending"
+)
+
+// ClaimSession carries the id of the session an access token belongs to.
+const ClaimSession = "sid"
+
+// SessionTouchInterval is how often, at most, the last seen time of a
+// session is updated.
+const SessionTouchInterval = time.Minute
+
+// Token audiences: access tokens are for the API, the Auth middleware only
+// accepts those, and the first step of a login with MFA only completes it.
+const (
+	Audience    = "go-banking-api"
+	MfaAudience = "go-banking-mfa"
+)
+
+// TokenExpiry is how long an access token is good for.
+const TokenExpiry = 15 * time.Minute
+
+// MfaPendingExpiry is how long a customer has to give the MFA code after the
+// secret.
+const MfaPendingExpiry = 5 * time.Minute
+
+// Customer roles, admins can also use the /admin routes.
+const (
+	RoleCustomer = "customer"
+	RoleAdmin    = "admin"
+)
+
+type Customer struct {
 	Id         uint64
 	Name       string
 	Cpf        string
-	Balance    int64
 	Secret     string
+	Role       string
+	MfaEnabled bool
+	// SecretChangedAt is when the secret last changed, tokens issued before
+	// that are no longer accepted.
+	SecretChangedAt *time.Time
+	Active          bool
+	Created_at      time.Duration
+	Updated_at      time.Duration
+}
+
+type Account struct {
+	Id         uint64
+	CustomerId uint64
 	Active     bool
-	Created_at time.Duration
-	Updated_at time.Duration
 }
 
+// LoginRequest authenticates a customer. Account optionally selects which of
+// the customer accounts the token operates on, the oldest active one is used
+// otherwise.
 type LoginRequest struct {
-	Cpf    string `json:"cpf"`
-	Secret string `json:"secret"`
+	Cpf     string  `json:"cpf"`
+	Secret  string  `json:"secret"`
+	Account *uint64 `json:"account,omitempty"`
+	Device  Device  `json:"-"`
 }
 
+// Device is where a login comes from, taken from the request rather than the
+// body.
+type Device struct {
+	UserAgent string
+	Ip        string
+}
+
+// Reasons a login is refused.
+const (
+	FailureInvalidCredentials = "invalid_credentials"
+	FailureInactive           = "inactive"
+	FailureMfa                = "mfa"
+)
+
+// Failure is a refused login attempt. When CustomerId is not known the
+// customer is looked up by Cpf, if there is one.
+type Failure struct {
+	CustomerId *uint64
+	Cpf        string
+	Reason     string
+	Device     Device
+}
+
+// LoginReponse carries the token. When MfaRequired is set the token only
+// completes the login, at POST /login/mfa.
 type LoginReponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token"`
+	MfaRequired bool   `json:"mfaRequired,omitempty"`
+}
+
+// MfaLoginRequest completes a login of a customer with MFA enabled, Token
+// being the one the first step returned.
+type MfaLoginRequest struct {
+	Token  string `json:"token"`
+	Code   string `json:"code"`
+	Device Device `json:"-"`
+}
+
+// Session is a login of a customer, from one device. Every token issued from
+// it, also when selecting another account, carries its id, and they all stop
+// working once it is terminated. ExpiresAt is when the last of them expires.
+type Session struct {
+	Id           uint64     `json:"id"`
+	CustomerId   uint64     `json:"-"`
+	UserAgent    string     `json:"userAgent"`
+	Ip           string     `json:"ip"`
+	Current      bool       `json:"current"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastSeenAt   time.Time  `json:"lastSeenAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	TerminatedAt *time.Time `json:"-"`
+}
+
+type ListSessionsResponse struct {
+	Sessions []Session `json:"sessions"`
 }
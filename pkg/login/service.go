@@ -5,33 +5,64 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
 	"github.com/GilbertoVGL/go-banking/pkg/validators"
 	"github.com/golang-jwt/jwt"
 )
 
 type Repository interface {
-	GetAccountBySecretAndCPF(context.Context, LoginRequest) (Account, error)
+	GetCustomerBySecretAndCPF(context.Context, LoginRequest) (Customer, error)
+	GetCustomerById(context.Context, uint64) (Customer, error)
+	GetCustomerAccounts(context.Context, uint64) ([]Account, error)
+	AddSession(context.Context, Session) (Session, error)
+	GetSession(context.Context, uint64) (Session, error)
+	TouchSession(context.Context, uint64) error
+	ExtendSession(context.Context, uint64, time.Time) error
+	ListCustomerSessions(context.Context, uint64) ([]Session, error)
+	TerminateSession(context.Context, uint64, uint64) error
+	AddLoginFailure(context.Context, Failure) error
 }
 
 type Service interface {
 	LoginUser(context.Context, LoginRequest) (LoginReponse, error)
+	CompleteMfaLogin(context.Context, MfaLoginRequest) (LoginReponse, error)
+	SelectAccount(context.Context, uint64, uint64, uint64, bool) (LoginReponse, error)
+	ValidateSession(context.Context, uint64, uint64, uint64, time.Time) error
+	ListSessions(context.Context, uint64, uint64) (ListSessionsResponse, error)
+	TerminateSession(context.Context, uint64, uint64) error
+}
+
+// MfaVerifier checks the MFA code of a customer.
+type MfaVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+// Signer signs tokens and validates them, for an audience, returning their
+// claims.
+type Signer interface {
+	Sign(jwt.MapClaims) (string, error)
+	Parse(string, string) (jwt.MapClaims, error)
 }
 
 type service struct {
-	r Repository
+	r      Repository
+	mfa    MfaVerifier
+	signer Signer
 }
 
-func New(r Repository) *service {
-	return &service{r}
+func New(r Repository, mfa MfaVerifier, signer Signer) *service {
+	return &service{r, mfa, signer}
 }
 
 func (s *service) LoginUser(ctx context.Context, loginReq LoginRequest) (LoginReponse, error) {
 	var login LoginReponse
-	accountCh := make(chan Account)
+	customerCh := make(chan Customer)
 	errCh := make(chan error)
 
 	go func() {
@@ -40,24 +71,154 @@ func (s *service) LoginUser(ctx context.Context, loginReq LoginRequest) (LoginRe
 			return
 		}
 
+		loginReq.Cpf, _ = validators.NormalizeCPF(loginReq.Cpf)
 		loginReq.Secret = fmt.Sprintf("%x", sha256.Sum256([]byte(loginReq.Secret+os.Getenv("SALT"))))
 
-		account, err := s.r.GetAccountBySecretAndCPF(ctx, loginReq)
+		customer, err := s.r.GetCustomerBySecretAndCPF(ctx, loginReq)
 		if err != nil {
+			if _, ok := err.(*apperrors.AuthError); ok {
+				s.addFailure(ctx, Failure{Cpf: loginReq.Cpf, Reason: FailureInvalidCredentials, Device: loginReq.Device})
+			}
+
 			errCh <- err
 			return
 		}
 
-		accountCh <- account
+		customerCh <- customer
 	}()
 
 	select {
-	case account := <-accountCh:
-		if !account.Active {
+	case customer := <-customerCh:
+		if !customer.Active {
+			s.addFailure(ctx, Failure{CustomerId: &customer.Id, Reason: FailureInactive, Device: loginReq.Device})
 			return login, apperrors.NewAuthError("this account is inactive")
 		}
+
+		account, err := s.selectAccount(ctx, customer.Id, loginReq.Account)
+
+		if err != nil {
+			return login, err
+		}
+
+		if customer.MfaEnabled {
+			login.MfaRequired = true
+			login.Token, err = s.generatePendingToken(customer, account.Id)
+		} else {
+			login.Token, err = s.startSession(ctx, customer, account.Id, loginReq.Device, false)
+		}
+
+		if err != nil {
+			return login, apperrors.NewAuthError("failed to create user token")
+		}
+
+		return login, nil
+	case err := <-errCh:
+		return login, err
+	case <-ctx.Done():
+		return login, ctx.Err()
+	}
+}
+
+// CompleteMfaLogin exchanges the token of the first login step and a valid
+// MFA code for a full token.
+func (s *service) CompleteMfaLogin(ctx context.Context, m MfaLoginRequest) (LoginReponse, error) {
+	var login LoginReponse
+	loginCh := make(chan LoginReponse)
+	errCh := make(chan error)
+
+	go func() {
+		customerId, accountId, issuedAt, err := s.parsePendingToken(m.Token)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if m.Code == "" {
+			errCh <- apperrors.NewArgumentError("missing values", "Code")
+			return
+		}
+
+		customer, err := s.r.GetCustomerById(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := checkCustomer(customer, issuedAt); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.mfa.Verify(ctx, customerId, m.Code); err != nil {
+			if _, ok := err.(*apperrors.AuthError); ok {
+				s.addFailure(ctx, Failure{CustomerId: &customerId, Reason: FailureMfa, Device: m.Device})
+			}
+
+			errCh <- err
+			return
+		}
+
+		token, err := s.startSession(ctx, customer, accountId, m.Device, true)
+		if err != nil {
+			errCh <- apperrors.NewAuthError("failed to create user token")
+			return
+		}
+
+		loginCh <- LoginReponse{Token: token}
+	}()
+
+	select {
+	case login = <-loginCh:
+		return login, nil
+	case err := <-errCh:
+		return login, err
+	case <-ctx.Done():
+		return login, ctx.Err()
+	}
+}
+
+// SelectAccount issues a token for another account of the customer, in the
+// same session and keeping whether the customer passed MFA.
+func (s *service) SelectAccount(ctx context.Context, customerId uint64, accountId uint64, sessionId uint64, mfa bool) (LoginReponse, error) {
+	var login LoginReponse
+	customerCh := make(chan Customer)
+	errCh := make(chan error)
+
+	go func() {
+		customer, err := s.r.GetCustomerById(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if !customer.Active {
+			errCh <- apperrors.NewAuthError("this account is inactive")
+			return
+		}
+
+		if _, err := s.selectAccount(ctx, customerId, &accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.checkSession(ctx, customerId, sessionId); err != nil {
+			errCh <- err
+			return
+		}
+
+		customerCh <- customer
+	}()
+
+	select {
+	case customer := <-customerCh:
+		now := time.Now()
+
+		if err := s.r.ExtendSession(ctx, sessionId, now.Add(TokenExpiry)); err != nil {
+			return login, err
+		}
+
 		var err error
-		login.Token, err = generateToken(account)
+		login.Token, err = s.generateToken(customer, accountId, sessionId, mfa, now)
 
 		if err != nil {
 			return login, apperrors.NewAuthError("failed to create user token")
@@ -71,13 +232,265 @@ func (s *service) LoginUser(ctx context.Context, loginReq LoginRequest) (LoginRe
 	}
 }
 
-func generateToken(account Account) (string, error) {
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// ValidateSession tells whether a token issued at issuedAt to the customer,
+// for the account, is still good: the customer and the account must be
+// active, the secret not changed since and the session, when the token has
+// one, not terminated.
+func (s *service) ValidateSession(ctx context.Context, customerId uint64, accountId uint64, sessionId uint64, issuedAt time.Time) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		customer, err := s.r.GetCustomerById(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := checkCustomer(customer, issuedAt); err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.selectAccount(ctx, customerId, &accountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		if sessionId != 0 {
+			session, err := s.checkSession(ctx, customerId, sessionId)
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			if time.Since(session.LastSeenAt) > SessionTouchInterval {
+				if err := s.r.TouchSession(ctx, sessionId); err != nil {
+					errCh <- err
+					return
+				}
+			}
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+			return apperrors.NewAuthError("customer not found")
+		}
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ListSessions returns the sessions of the customer not yet terminated nor
+// expired, flagging currentId as the current one.
+func (s *service) ListSessions(ctx context.Context, customerId uint64, currentId uint64) (ListSessionsResponse, error) {
+	var response ListSessionsResponse
+	sessionsCh := make(chan []Session)
+	errCh := make(chan error)
+
+	go func() {
+		sessions, err := s.r.ListCustomerSessions(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		for i := range sessions {
+			sessions[i].Current = sessions[i].Id == currentId
+		}
+
+		sessionsCh <- sessions
+	}()
+
+	select {
+	case response.Sessions = <-sessionsCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// TerminateSession ends a session of the customer, which can be the current
+// one, logging it out.
+func (s *service) TerminateSession(ctx context.Context, customerId uint64, id uint64) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.r.TerminateSession(ctx, customerId, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startSession records a new session of the customer, from device, and
+// returns its first token.
+func (s *service) startSession(ctx context.Context, customer Customer, accountId uint64, device Device, mfa bool) (string, error) {
+	now := time.Now()
+
+	session, err := s.r.AddSession(ctx, Session{
+		CustomerId: customer.Id,
+		UserAgent:  device.UserAgent,
+		Ip:         device.Ip,
+		ExpiresAt:  now.Add(TokenExpiry),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.generateToken(customer, accountId, session.Id, mfa, now)
+}
+
+// addFailure records a refused login. Failing to record it does not change
+// the answer to the customer.
+func (s *service) addFailure(ctx context.Context, f Failure) {
+	if err := s.r.AddLoginFailure(ctx, f); err != nil {
+		logger.Log.Error("Add login failure error:", err)
+	}
+}
+
+// checkSession returns the session with id when it belongs to the customer
+// and was not terminated.
+func (s *service) checkSession(ctx context.Context, customerId uint64, id uint64) (Session, error) {
+	session, err := s.r.GetSession(ctx, id)
+
+	if err != nil {
+		if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+			return session, apperrors.NewAuthError("session not found")
+		}
+		return session, err
+	}
+
+	if session.CustomerId != customerId {
+		return session, apperrors.NewAuthError("session not found")
+	}
+
+	if session.TerminatedAt != nil {
+		return session, apperrors.NewAuthError("session terminated, log in again")
+	}
+
+	return session, nil
+}
+
+// checkCustomer refuses tokens of inactive customers and those issued before
+// the last secret change. Tokens only carry whole seconds, so one issued in
+// the same second as the change is still accepted.
+func checkCustomer(customer Customer, issuedAt time.Time) error {
+	if !customer.Active {
+		return apperrors.NewAuthError("this account is inactive")
+	}
+
+	if customer.SecretChangedAt != nil && issuedAt.Unix() < customer.SecretChangedAt.Unix() {
+		return apperrors.NewAuthError("session expired, log in again")
+	}
+
+	return nil
+}
+
+// selectAccount returns the customer account with the given id, or the oldest
+// active one when id is nil.
+func (s *service) selectAccount(ctx context.Context, customerId uint64, id *uint64) (Account, error) {
+	accounts, err := s.r.GetCustomerAccounts(ctx, customerId)
+
+	if err != nil {
+		return Account{}, err
+	}
+
+	for _, account := range accounts {
+		if id != nil && account.Id != *id {
+			continue
+		}
+
+		if !account.Active {
+			if id != nil {
+				return Account{}, apperrors.NewAuthError("this account is inactive")
+			}
+			continue
+		}
+
+		return account, nil
+	}
+
+	if id != nil {
+		return Account{}, apperrors.NewAuthError("account not found for this customer")
+	}
+
+	return Account{}, apperrors.NewAuthError("customer has no active account")
+}
+
+func (s *service) generateToken(customer Customer, accountId uint64, sessionId uint64, mfa bool, now time.Time) (string, error) {
+	return s.signer.Sign(jwt.MapClaims{
+		"iss":        keys.Issuer,
+		"aud":        Audience,
+		"sub":        strconv.FormatUint(customer.Id, 10),
 		"authorized": true,
-		"userId":     account.Id,
-		"exp":        time.Now().Add(time.Minute * 15).Unix(),
+		"customerId": customer.Id,
+		"accountId":  accountId,
+		"role":       customer.Role,
+		ClaimMfa:     mfa,
+		ClaimSession: sessionId,
+		"iat":        now.Unix(),
+		"exp":        now.Add(TokenExpiry).Unix(),
 	})
-	return at.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// generatePendingToken returns the token of the first step of a login with
+// MFA. Its audience is MfaAudience, so the Auth middleware refuses it.
+func (s *service) generatePendingToken(customer Customer, accountId uint64) (string, error) {
+	now := time.Now()
+
+	return s.signer.Sign(jwt.MapClaims{
+		"iss":           keys.Issuer,
+		"aud":           MfaAudience,
+		"sub":           strconv.FormatUint(customer.Id, 10),
+		ClaimMfaPending: true,
+		"accountId":     accountId,
+		"iat":           now.Unix(),
+		"exp":           now.Add(MfaPendingExpiry).Unix(),
+	})
+}
+
+func (s *service) parsePendingToken(tokenString string) (uint64, uint64, time.Time, error) {
+	invalid := apperrors.NewAuthError("invalid mfa token")
+
+	claims, err := s.signer.Parse(tokenString, MfaAudience)
+
+	if err != nil {
+		return 0, 0, time.Time{}, invalid
+	}
+
+	pending, _ := claims[ClaimMfaPending].(bool)
+	sub, _ := claims["sub"].(string)
+	customerId, errCustomer := strconv.ParseUint(sub, 10, 64)
+	accountId, okAccount := claims["accountId"].(float64)
+	issuedAt, _ := claims["iat"].(float64)
+
+	if !pending || errCustomer != nil || !okAccount {
+		return 0, 0, time.Time{}, invalid
+	}
+
+	return customerId, uint64(accountId), time.Unix(int64(issuedAt), 0), nil
 }
 
 func validateValues(l LoginRequest) error {
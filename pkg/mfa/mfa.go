@@ -0,0 +1,80 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Issuer names the app in authenticator apps.
+const Issuer = "go-banking"
+
+// MaxAttempts wrong codes in a row lock MFA, and so the logins of the
+// customer, for LockoutDuration.
+const (
+	MaxAttempts     = 5
+	LockoutDuration = 15 * time.Minute
+)
+
+// RecoveryCodes is how many single use recovery codes a confirmed enrollment
+// gets.
+const RecoveryCodes = 10
+
+// CustomerMfa is the stored MFA state of a customer. Secret is set when the
+// enrollment starts and Enabled only once it is confirmed with a code.
+type CustomerMfa struct {
+	Secret         string
+	Enabled        bool
+	LastStep       *int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// EnrollResponse carries the new secret, for manual entry, and the otpauth
+// URI to show as a QR code.
+type EnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// CodeRequest carries a TOTP code or, outside of enrollment, a recovery code.
+type CodeRequest struct {
+	Code string `json:"code"`
+}
+
+// ConfirmResponse carries the recovery codes, shown only this once.
+type ConfirmResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// GenerateRecoveryCodes returns RecoveryCodes new random codes, in the
+// xxxxx-xxxxx form.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodes)
+
+	for i := range codes {
+		b := make([]byte, 5)
+
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+
+		code := hex.EncodeToString(b)
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored form of a recovery code. Codes are
+// random enough for the app SALT alone to do, so they can be looked up by
+// hash.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.Replace(strings.TrimSpace(code), "-", "", -1))
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(code+os.Getenv("SALT"))))
+}
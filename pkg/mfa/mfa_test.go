@@ -0,0 +1,35 @@
+package mfa
+
+import "testing"
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != RecoveryCodes {
+		t.Fatalf("got %d codes want %d", len(codes), RecoveryCodes)
+	}
+
+	seen := map[string]bool{}
+
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected code format %s", code)
+		}
+
+		if seen[code] {
+			t.Errorf("repeated code %s", code)
+		}
+		seen[code] = true
+	}
+
+	hash := HashRecoveryCode("abcde-12345")
+
+	for _, typed := range []string{"abcde12345", " ABCDE-12345 "} {
+		if got := HashRecoveryCode(typed); got != hash {
+			t.Errorf("code typed as %q should hash as abcde-12345", typed)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/validators"
+)
+
+type Repository interface {
+	GetCustomerMfa(context.Context, uint64) (CustomerMfa, error)
+	StartCustomerMfa(context.Context, uint64, string) error
+	EnableCustomerMfa(context.Context, uint64, int64, []string) error
+	DisableCustomerMfa(context.Context, uint64) error
+	UseMfaStep(context.Context, uint64, int64) (bool, error)
+	UseMfaRecoveryCode(context.Context, uint64, string) (bool, error)
+	CountMfaAttempt(context.Context, uint64, int, time.Duration) (CustomerMfa, bool, error)
+	ResetMfaFailures(context.Context, uint64) error
+	GetCustomerById(context.Context, uint64) (login.Customer, error)
+}
+
+type Service interface {
+	Enroll(context.Context, uint64) (EnrollResponse, error)
+	Confirm(context.Context, uint64, CodeRequest) (ConfirmResponse, error)
+	Disable(context.Context, uint64, CodeRequest) error
+	Verify(context.Context, uint64, string) error
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+// Enroll starts, or starts over, the MFA enrollment of a customer with a new
+// secret. MFA is only enforced once the enrollment is confirmed.
+func (s *service) Enroll(ctx context.Context, customerId uint64) (EnrollResponse, error) {
+	var response EnrollResponse
+	responseCh := make(chan EnrollResponse)
+	errCh := make(chan error)
+
+	go func() {
+		current, err := s.r.GetCustomerMfa(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if current.Enabled {
+			errCh <- apperrors.NewArgumentError("mfa already enabled")
+			return
+		}
+
+		customer, err := s.r.GetCustomerById(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		secret, err := GenerateSecret()
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to generate mfa secret")
+			return
+		}
+
+		if err := s.r.StartCustomerMfa(ctx, customerId, secret); err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- EnrollResponse{
+			Secret: secret,
+			URI:    URI(Issuer, validators.FormatCPF(customer.Cpf), secret),
+		}
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// Confirm enables MFA once the customer proves, with a code, that the secret
+// was enrolled, and returns the recovery codes.
+func (s *service) Confirm(ctx context.Context, customerId uint64, c CodeRequest) (ConfirmResponse, error) {
+	var response ConfirmResponse
+	responseCh := make(chan ConfirmResponse)
+	errCh := make(chan error)
+
+	go func() {
+		current, err := s.r.GetCustomerMfa(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if current.Enabled {
+			errCh <- apperrors.NewArgumentError("mfa already enabled")
+			return
+		}
+
+		if current.Secret == "" {
+			errCh <- apperrors.NewArgumentError("mfa enrollment not started")
+			return
+		}
+
+		step, ok := Match(current.Secret, c.Code, time.Now())
+		if !ok {
+			errCh <- apperrors.NewArgumentError("invalid code")
+			return
+		}
+
+		codes, err := GenerateRecoveryCodes()
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to generate recovery codes")
+			return
+		}
+
+		hashes := make([]string, len(codes))
+		for i, code := range codes {
+			hashes[i] = HashRecoveryCode(code)
+		}
+
+		if err := s.r.EnableCustomerMfa(ctx, customerId, step, hashes); err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- ConfirmResponse{codes}
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// Disable turns MFA off, after checking a code, and drops the recovery codes.
+func (s *service) Disable(ctx context.Context, customerId uint64, c CodeRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.verify(ctx, customerId, c.Code); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.r.DisableCustomerMfa(ctx, customerId); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Verify checks a TOTP code, or an unused recovery code, of a customer with
+// MFA enabled. Each code is accepted only once and wrong ones count towards
+// the lockout.
+func (s *service) Verify(ctx context.Context, customerId uint64, code string) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.verify(ctx, customerId, code); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *service) verify(ctx context.Context, customerId uint64, code string) error {
+	current, counted, err := s.r.CountMfaAttempt(ctx, customerId, MaxAttempts, LockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if !counted {
+		return s.refusal(ctx, customerId)
+	}
+
+	ok, err := s.use(ctx, customerId, current.Secret, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return apperrors.NewAuthError("invalid code")
+	}
+
+	return s.r.ResetMfaFailures(ctx, customerId)
+}
+
+// refusal tells why an attempt against MFA was not counted.
+func (s *service) refusal(ctx context.Context, customerId uint64) error {
+	current, err := s.r.GetCustomerMfa(ctx, customerId)
+	if err != nil {
+		return err
+	}
+
+	if !current.Enabled {
+		return apperrors.NewAuthError("mfa not enabled")
+	}
+
+	if current.LockedUntil == nil {
+		return apperrors.NewAuthError("mfa locked")
+	}
+
+	return apperrors.NewAuthError("mfa locked until", current.LockedUntil.Format(time.RFC3339))
+}
+
+// use consumes code, a TOTP code when it has Digits characters and a recovery
+// code otherwise. A TOTP code of a time step already used does not pass.
+func (s *service) use(ctx context.Context, customerId uint64, secret string, code string) (bool, error) {
+	if len(code) == Digits {
+		step, ok := Match(secret, code, time.Now())
+
+		if !ok {
+			return false, nil
+		}
+
+		return s.r.UseMfaStep(ctx, customerId, step)
+	}
+
+	if code == "" {
+		return false, nil
+	}
+
+	return s.r.UseMfaRecoveryCode(ctx, customerId, HashRecoveryCode(code))
+}
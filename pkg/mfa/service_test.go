@@ -0,0 +1,106 @@
+package mfa
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+// mockRepository keeps the MFA state of a single customer, counting attempts
+// as the database does.
+type mockRepository struct {
+	stored CustomerMfa
+}
+
+func (m *mockRepository) GetCustomerMfa(ctx context.Context, id uint64) (CustomerMfa, error) {
+	return m.stored, nil
+}
+func (m *mockRepository) StartCustomerMfa(ctx context.Context, id uint64, secret string) error {
+	return nil
+}
+func (m *mockRepository) EnableCustomerMfa(ctx context.Context, id uint64, step int64, hashes []string) error {
+	return nil
+}
+func (m *mockRepository) DisableCustomerMfa(ctx context.Context, id uint64) error {
+	return nil
+}
+func (m *mockRepository) UseMfaStep(ctx context.Context, id uint64, step int64) (bool, error) {
+	if m.stored.LastStep != nil && *m.stored.LastStep >= step {
+		return false, nil
+	}
+
+	m.stored.LastStep = &step
+	return true, nil
+}
+func (m *mockRepository) UseMfaRecoveryCode(ctx context.Context, id uint64, hash string) (bool, error) {
+	return false, nil
+}
+func (m *mockRepository) CountMfaAttempt(ctx context.Context, id uint64, maxAttempts int, lockout time.Duration) (CustomerMfa, bool, error) {
+	if !m.stored.Enabled || (m.stored.LockedUntil != nil && m.stored.LockedUntil.After(time.Now())) {
+		return CustomerMfa{}, false, nil
+	}
+
+	m.stored.FailedAttempts++
+
+	if m.stored.FailedAttempts >= maxAttempts {
+		lockedUntil := time.Now().Add(lockout)
+		m.stored.FailedAttempts, m.stored.LockedUntil = 0, &lockedUntil
+	}
+
+	return m.stored, true, nil
+}
+func (m *mockRepository) ResetMfaFailures(ctx context.Context, id uint64) error {
+	m.stored.FailedAttempts, m.stored.LockedUntil = 0, nil
+	return nil
+}
+func (m *mockRepository) GetCustomerById(ctx context.Context, id uint64) (login.Customer, error) {
+	return login.Customer{}, nil
+}
+
+func TestVerify(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := func() string {
+		c, err := Code(secret, Step(time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	r := &mockRepository{stored: CustomerMfa{Secret: secret, Enabled: true}}
+	s := New(r)
+	ctx := context.Background()
+
+	if err := s.Verify(ctx, 1, "000000x"); err == nil {
+		t.Fatal("wrong code verified")
+	}
+
+	if err := s.Verify(ctx, 1, code()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if r.stored.FailedAttempts != 0 {
+		t.Errorf("got %d failed attempts after the right code want 0", r.stored.FailedAttempts)
+	}
+
+	for i := 0; i < MaxAttempts; i++ {
+		if err := s.Verify(ctx, 1, "000000x"); err == nil {
+			t.Fatal("wrong code verified")
+		}
+	}
+
+	r.stored.LastStep = nil
+
+	err = s.Verify(ctx, 1, code())
+	if _, ok := err.(*apperrors.AuthError); !ok || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("got %v for the right code while locked want a lockout error", err)
+	}
+}
@@ -0,0 +1,113 @@
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters, the defaults of RFC 6238 and of authenticator apps.
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// Skew is how many periods before and after now a code is still accepted,
+	// to make up for clock drift.
+	Skew = 1
+)
+
+// secretSize is the length, in bytes, of a generated secret: 160 bits, as
+// recommended for HMAC-SHA1.
+const secretSize = 20
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random secret, base32 encoded.
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretSize)
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	return encoding.EncodeToString(secret), nil
+}
+
+// Step returns the time step t falls in.
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code returns the code of secret for the time step.
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil {
+		return "", err
+	}
+
+	return hotp(key, uint64(step), Digits), nil
+}
+
+// Match returns the time step, within Skew of now, whose code is code.
+func Match(secret string, code string, now time.Time) (int64, bool) {
+	current := Step(now)
+
+	for _, step := range []int64{current, current - 1, current + 1} {
+		if step < current-Skew || step > current+Skew {
+			continue
+		}
+
+		expected, err := Code(secret, step)
+
+		if err != nil {
+			return 0, false
+		}
+
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// URI returns the otpauth URI authenticator apps enroll secret from, usually
+// shown as a QR code.
+func URI(issuer string, account string, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{
+		"secret":    []string{secret},
+		"issuer":    []string{issuer},
+		"algorithm": []string{"SHA1"},
+		"digits":    []string{fmt.Sprint(Digits)},
+		"period":    []string{fmt.Sprint(int(Period / time.Second))},
+	}
+
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// hotp is the RFC 4226 HMAC-SHA1 one time password of counter.
+func hotp(key []byte, counter uint64, digits int) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, counter)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
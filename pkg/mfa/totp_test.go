@@ -0,0 +1,95 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors, base32 encoded.
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHotp(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	key := []byte("12345678901234567890")
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			step := Step(time.Unix(tt.unix, 0))
+
+			if got := hotp(key, uint64(step), 8); got != tt.code {
+				t.Errorf("hotp at %d = %s want %s", tt.unix, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	code, err := Code(rfcSecret, Step(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code != "050471" {
+		t.Errorf("got code %s want the 6 digits RFC one 050471", code)
+	}
+
+	tests := []struct {
+		name  string
+		at    time.Time
+		match bool
+	}{
+		{"same period", now, true},
+		{"one period later", now.Add(Period), true},
+		{"one period earlier", now.Add(-Period), true},
+		{"two periods later", now.Add(2 * Period), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Match(rfcSecret, code, tt.at)
+
+			if ok != tt.match {
+				t.Fatalf("Match = %v want %v", ok, tt.match)
+			}
+
+			if ok && step != Step(now) {
+				t.Errorf("matched step %d want %d", step, Step(now))
+			}
+		})
+	}
+
+	if _, ok := Match(rfcSecret, "000000", now); ok {
+		t.Errorf("wrong code matched")
+	}
+}
+
+func TestGenerateSecretAndURI(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("generated secret %s does not decode: %v", secret, err)
+	}
+
+	uri := URI("go-banking", "05093092088", secret)
+
+	if !strings.HasPrefix(uri, "otpauth://totp/go-banking:05093092088?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("unexpected uri %s", uri)
+	}
+}
@@ -0,0 +1,33 @@
+package money
+
+import "math/big"
+
+// MulDivHalfEven returns amount * num / den rounded half to even (banker's
+// rounding). The intermediate product is computed without overflow.
+func MulDivHalfEven(amount, num, den int64) int64 {
+	if den == 0 {
+		return 0
+	}
+
+	n := new(big.Int).Mul(big.NewInt(amount), big.NewInt(num))
+	d := big.NewInt(den)
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+
+	if r.Sign() == 0 {
+		return q.Int64()
+	}
+
+	twiceR := new(big.Int).Abs(r)
+	twiceR.Lsh(twiceR, 1)
+
+	switch twiceR.Cmp(new(big.Int).Abs(d)) {
+	case 1:
+		q.Add(q, big.NewInt(int64(n.Sign()*d.Sign())))
+	case 0:
+		if q.Bit(0) == 1 {
+			q.Add(q, big.NewInt(int64(n.Sign()*d.Sign())))
+		}
+	}
+
+	return q.Int64()
+}
@@ -0,0 +1,31 @@
+package money
+
+import "testing"
+
+func TestMulDivHalfEven(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   int64
+		num      int64
+		den      int64
+		expected int64
+	}{
+		{"exact", 100, 3, 3, 100},
+		{"rounds down", 14, 1, 10, 1},
+		{"rounds up", 16, 1, 10, 2},
+		{"half to even down", 25, 1, 10, 2},
+		{"half to even up", 35, 1, 10, 4},
+		{"negative half to even", -25, 1, 10, -2},
+		{"negative rounds away", -16, 1, 10, -2},
+		{"no overflow", 9000000000000000, 600, 3650000, 1479452054795},
+		{"zero denominator", 10, 1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MulDivHalfEven(tt.amount, tt.num, tt.den); got != tt.expected {
+				t.Errorf("MulDivHalfEven(%d, %d, %d) = %d, want %d", tt.amount, tt.num, tt.den, got, tt.expected)
+			}
+		})
+	}
+}
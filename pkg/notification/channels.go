@@ -0,0 +1,190 @@
+package notification
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// Channel sends messages to recipients through one medium. Send is only
+// called for recipients with an address on the channel.
+type Channel interface {
+	Name() string
+	Send(context.Context, Recipient, Message) error
+}
+
+// smtpTimeout bounds a whole exchange with the SMTP server, so a server that
+// does not answer holds the outbox back for no longer than that.
+const smtpTimeout = 30 * time.Second
+
+// SMTPChannel sends email through an SMTP server, a local stand-in as MailHog
+// in development. From may carry a display name, as "go-banking
+// <no-reply@example.com>". It authenticates only when given a username.
+type SMTPChannel struct {
+	addr     string
+	from     string
+	username string
+	password string
+}
+
+func NewSMTPChannel(addr string, from string, username string, password string) *SMTPChannel {
+	return &SMTPChannel{addr, from, username, password}
+}
+
+func (c *SMTPChannel) Name() string {
+	return ChannelEmail
+}
+
+func (c *SMTPChannel) Send(ctx context.Context, to Recipient, m Message) error {
+	from, err := mail.ParseAddress(c.from)
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(c.addr)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: smtpTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if c.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", c.username, c.password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	if err := client.Rcpt(to.Email); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(c.message(to, m)); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (c *SMTPChannel) message(to Recipient, m Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", c.from)
+	fmt.Fprintf(&b, "To: %s\r\n", to.Email)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
+
+// StubChannel stands in for a provider not integrated yet, as SMS and push:
+// it appends each message to a file, as a JSON line, or writes it to the log
+// when there is no file.
+type StubChannel struct {
+	name string
+	path string
+	mu   sync.Mutex
+}
+
+func NewStubChannel(name string, path string) *StubChannel {
+	return &StubChannel{name: name, path: path}
+}
+
+func (c *StubChannel) Name() string {
+	return c.name
+}
+
+func (c *StubChannel) Send(ctx context.Context, to Recipient, m Message) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"sentAt":     time.Now().UTC(),
+		"channel":    c.name,
+		"customerId": to.CustomerId,
+		"to":         address(c.name, to),
+		"kind":       m.Kind,
+		"subject":    m.Subject,
+		"body":       m.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	if c.path == "" {
+		logger.Log.Info(string(line))
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func address(channel string, to Recipient) string {
+	return Preferences{Email: to.Email, Phone: to.Phone, PushToken: to.PushToken}.Address(channel)
+}
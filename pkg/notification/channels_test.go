@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP accepts a single message and hands its data, and envelope, to got.
+func fakeSMTP(t *testing.T, got chan<- string) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		defer l.Close()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		var envelope, data strings.Builder
+
+		reply("220 localhost ESMTP")
+
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				envelope.WriteString(strings.TrimSpace(line) + "\n")
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 go ahead")
+
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				got <- envelope.String() + data.String()
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func TestSMTPChannel(t *testing.T) {
+	got := make(chan string, 1)
+	c := NewSMTPChannel(fakeSMTP(t, got), "go-banking <no-reply@go-banking.local>", "", "")
+
+	m := Message{Kind: KindTransferReceived, Subject: "Você recebeu R$ 1,00", Body: "Olá, Maria."}
+
+	if err := c.Send(context.Background(), Recipient{CustomerId: 1, Email: "maria@example.com"}, m); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-got
+
+	for _, want := range []string{
+		"MAIL FROM:<no-reply@go-banking.local>",
+		"RCPT TO:<maria@example.com>",
+		"From: go-banking <no-reply@go-banking.local>",
+		"To: maria@example.com",
+		"Subject: =?utf-8?q?Voc=C3=AA_recebeu_R$_1,00?=",
+		"Olá, Maria.",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message is missing %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestSMTPChannelTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	// The server accepts the connection but never greets.
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	c := NewSMTPChannel(l.Addr().String(), "no-reply@go-banking.local", "", "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	if err := c.Send(ctx, Recipient{CustomerId: 1, Email: "maria@example.com"}, Message{}); err == nil {
+		t.Fatal("sent to a server that never answered")
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("took %v to give up want about the context deadline", elapsed)
+	}
+}
+
+func TestStubChannel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "notifications")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "notifications.log")
+	c := NewStubChannel(ChannelSMS, path)
+
+	for i := 0; i < 2; i++ {
+		if err := c.Send(context.Background(), Recipient{CustomerId: 1, Phone: "+5511999998888"}, Message{Kind: KindNewDevice, Body: "Olá"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[0], `"to":"+5511999998888"`) || !strings.Contains(lines[0], `"channel":"sms"`) {
+		t.Errorf("wrong stub output:\n%s", content)
+	}
+}
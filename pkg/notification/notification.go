@@ -0,0 +1,170 @@
+package notification
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// Kinds of notification. The security ones, about the access to the
+// account, cannot be muted.
+const (
+	KindTransferReceived = "transfer_received"
+	KindNewDevice        = "new_device"
+	KindSecretChanged    = "secret_changed"
+	KindLimitChanged     = "limit_changed"
+)
+
+var Kinds = []string{KindTransferReceived, KindNewDevice, KindSecretChanged, KindLimitChanged}
+
+var securityKinds = []string{KindNewDevice, KindSecretChanged}
+
+// Channels notifications are sent through, each needing its address in the
+// customer preferences.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+	ChannelPush  = "push"
+)
+
+var Channels = []string{ChannelEmail, ChannelSMS, ChannelPush}
+
+const (
+	LanguagePtBR = "pt-BR"
+	LanguageEn   = "en"
+)
+
+var Languages = []string{LanguagePtBR, LanguageEn}
+
+// PhoneRegex is a phone number in E.164 format.
+var PhoneRegex = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// Preferences are where, in which language and about what a customer wants
+// to be notified. Customers who never set them get DefaultPreferences, which
+// send nothing until there is an address to send to.
+type Preferences struct {
+	Language  string     `json:"language"`
+	Email     string     `json:"email"`
+	Phone     string     `json:"phone"`
+	PushToken string     `json:"pushToken"`
+	Channels  []string   `json:"channels"`
+	Muted     []string   `json:"muted"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+func DefaultPreferences() Preferences {
+	return Preferences{Language: LanguagePtBR, Channels: []string{}, Muted: []string{}}
+}
+
+// Address returns the address of the customer on channel, empty when there
+// is none.
+func (p Preferences) Address(channel string) string {
+	switch channel {
+	case ChannelEmail:
+		return p.Email
+	case ChannelSMS:
+		return p.Phone
+	case ChannelPush:
+		return p.PushToken
+	default:
+		return ""
+	}
+}
+
+func (p Preferences) IsMuted(kind string) bool {
+	return contains(p.Muted, kind)
+}
+
+// Validate checks p, defaulting its language, and returns it cleaned up.
+func Validate(p Preferences) (Preferences, error) {
+	p.Email = strings.TrimSpace(p.Email)
+	p.Phone = strings.TrimSpace(p.Phone)
+	p.PushToken = strings.TrimSpace(p.PushToken)
+	p.UpdatedAt = nil
+
+	if p.Language == "" {
+		p.Language = LanguagePtBR
+	}
+
+	if !contains(Languages, p.Language) {
+		return p, apperrors.NewArgumentError("language must be one of", strings.Join(Languages, ", "))
+	}
+
+	if p.Email != "" {
+		if a, err := mail.ParseAddress(p.Email); err != nil || a.Address != p.Email {
+			return p, apperrors.NewArgumentError("invalid email")
+		}
+	}
+
+	if p.Phone != "" && !PhoneRegex.MatchString(p.Phone) {
+		return p, apperrors.NewArgumentError("phone must be in E.164 format, as +5511999998888")
+	}
+
+	channels := []string{}
+
+	for _, c := range p.Channels {
+		if !contains(Channels, c) {
+			return p, apperrors.NewArgumentError("unknown channel", c)
+		}
+
+		if p.Address(c) == "" {
+			return p, apperrors.NewArgumentError("channel needs an address", c)
+		}
+
+		if !contains(channels, c) {
+			channels = append(channels, c)
+		}
+	}
+
+	muted := []string{}
+
+	for _, k := range p.Muted {
+		if !contains(Kinds, k) {
+			return p, apperrors.NewArgumentError("unknown notification kind", k)
+		}
+
+		if contains(securityKinds, k) {
+			return p, apperrors.NewArgumentError("security notifications cannot be muted", k)
+		}
+
+		if !contains(muted, k) {
+			muted = append(muted, k)
+		}
+	}
+
+	p.Channels = channels
+	p.Muted = muted
+
+	return p, nil
+}
+
+// Recipient is who a message goes to, with the addresses from their
+// preferences.
+type Recipient struct {
+	CustomerId uint64
+	Name       string
+	Email      string
+	Phone      string
+	PushToken  string
+}
+
+// Message is a rendered notification. Subject is short enough to be a push
+// title and Body to be an SMS.
+type Message struct {
+	Kind    string
+	Subject string
+	Body    string
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
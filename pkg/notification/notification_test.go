@@ -0,0 +1,126 @@
+package notification
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       Preferences
+		wantErr bool
+	}{
+		{"defaults", Preferences{}, false},
+		{"email", Preferences{Email: "maria@example.com", Channels: []string{ChannelEmail}}, false},
+		{"all channels", Preferences{Language: LanguageEn, Email: "maria@example.com", Phone: "+5511999998888", PushToken: "abc", Channels: Channels}, false},
+		{"mute transfers", Preferences{Muted: []string{KindTransferReceived}}, false},
+		{"unknown language", Preferences{Language: "es"}, true},
+		{"invalid email", Preferences{Email: "maria"}, true},
+		{"email with name", Preferences{Email: "Maria <maria@example.com>"}, true},
+		{"invalid phone", Preferences{Phone: "11999998888"}, true},
+		{"channel without address", Preferences{Channels: []string{ChannelSMS}}, true},
+		{"unknown channel", Preferences{Channels: []string{"pigeon"}}, true},
+		{"unknown kind", Preferences{Muted: []string{"spam"}}, true},
+		{"mute security", Preferences{Muted: []string{KindNewDevice}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Validate(tt.p)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && p.Language == "" {
+				t.Error("Validate() left the language empty")
+			}
+		})
+	}
+
+	p, _ := Validate(Preferences{Email: "maria@example.com", Channels: []string{ChannelEmail, ChannelEmail}})
+
+	if len(p.Channels) != 1 {
+		t.Errorf("Validate() kept repeated channels: %v", p.Channels)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		cents    int64
+		language string
+		want     string
+	}{
+		{0, LanguagePtBR, "R$ 0,00"},
+		{5, LanguagePtBR, "R$ 0,05"},
+		{123456, LanguagePtBR, "R$ 1.234,56"},
+		{100000000, LanguagePtBR, "R$ 1.000.000,00"},
+		{-123456, LanguagePtBR, "-R$ 1.234,56"},
+		{123456, LanguageEn, "R$1,234.56"},
+		{99900, LanguageEn, "R$999.00"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatAmount(tt.cents, tt.language); got != tt.want {
+			t.Errorf("FormatAmount(%d, %s) = %s, want %s", tt.cents, tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	d := Data{
+		Name:        "Maria",
+		AccountId:   2,
+		From:        "Roberval",
+		FromAccount: 1,
+		Amount:      12345,
+		Limit:       limits.Daily,
+		Previous:    100000,
+		Device:      "Firefox",
+		Ip:          "10.0.0.1",
+		At:          time.Date(2021, 3, 4, 15, 30, 0, 0, time.UTC),
+	}
+
+	for _, kind := range Kinds {
+		for _, language := range Languages {
+			m, err := Render(kind, language, d)
+			if err != nil {
+				t.Fatalf("Render(%s, %s) error = %v", kind, language, err)
+			}
+
+			if m.Subject == "" || m.Body == "" || strings.Contains(m.Body, "<no value>") {
+				t.Errorf("Render(%s, %s) = %+v", kind, language, m)
+			}
+		}
+	}
+
+	m, _ := Render(KindTransferReceived, LanguagePtBR, d)
+	if want := "Olá, Maria. Você recebeu R$ 123,45 de Roberval (conta 1) na conta 2 em 04/03/2021 às 15:30."; m.Body != want {
+		t.Errorf("Render() body = %s, want %s", m.Body, want)
+	}
+
+	m, _ = Render(KindLimitChanged, LanguageEn, d)
+	if !strings.Contains(m.Body, "the daily limit of account 2 changed from R$1,000.00 to R$123.45") {
+		t.Errorf("Render() body = %s", m.Body)
+	}
+
+	if m, _ := Render(KindSecretChanged, "es", d); !strings.HasPrefix(m.Body, "Olá") {
+		t.Errorf("Render() did not fall back to pt-BR: %s", m.Body)
+	}
+
+	if _, err := Render("spam", LanguagePtBR, d); err == nil {
+		t.Error("Render() of an unknown kind did not fail")
+	}
+}
@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+type Repository interface {
+	GetNotificationPreferences(context.Context, uint64) (Preferences, error)
+	SetNotificationPreferences(context.Context, uint64, Preferences) (Preferences, error)
+	GetAccountById(context.Context, uint64) (account.Account, error)
+	GetCustomerById(context.Context, uint64) (login.Customer, error)
+	MarkNotificationSent(context.Context, uint64, uint64, string) (bool, error)
+}
+
+type Service interface {
+	GetPreferences(context.Context, uint64) (Preferences, error)
+	SetPreferences(context.Context, uint64, Preferences) (Preferences, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+// GetPreferences returns the notification preferences of a customer, the
+// defaults when they never set any.
+func (s *service) GetPreferences(ctx context.Context, customerId uint64) (Preferences, error) {
+	var preferences Preferences
+	preferencesCh := make(chan Preferences)
+	errCh := make(chan error)
+
+	go func() {
+		p, err := s.r.GetNotificationPreferences(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		preferencesCh <- p
+	}()
+
+	select {
+	case preferences = <-preferencesCh:
+		return preferences, nil
+	case err := <-errCh:
+		return preferences, err
+	case <-ctx.Done():
+		return preferences, ctx.Err()
+	}
+}
+
+// SetPreferences replaces the notification preferences of a customer.
+func (s *service) SetPreferences(ctx context.Context, customerId uint64, p Preferences) (Preferences, error) {
+	var preferences Preferences
+	preferencesCh := make(chan Preferences)
+	errCh := make(chan error)
+
+	go func() {
+		p, err := Validate(p)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		p, err = s.r.SetNotificationPreferences(ctx, customerId, p)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		preferencesCh <- p
+	}()
+
+	select {
+	case preferences = <-preferencesCh:
+		return preferences, nil
+	case err := <-errCh:
+		return preferences, err
+	case <-ctx.Done():
+		return preferences, ctx.Err()
+	}
+}
@@ -0,0 +1,228 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+// Sink notifies customers of the outbox events about them. Sending is best
+// effort: a channel failing is logged and the event is not retried, so one
+// provider down neither blocks the outbox nor repeats what the others sent.
+// Each notification is recorded before it is sent, so the events of a batch
+// delivered again after a later one held it back are not notified twice.
+// Only failing to read the preferences or record a notification holds the
+// events back.
+type Sink struct {
+	r        Repository
+	channels map[string]Channel
+}
+
+// NewSink builds the sink with a channel per name, later ones replacing
+// earlier ones of the same name.
+func NewSink(r Repository, channels ...Channel) *Sink {
+	s := &Sink{r: r, channels: map[string]Channel{}}
+
+	for _, c := range channels {
+		s.channels[c.Name()] = c
+	}
+
+	return s
+}
+
+func (s *Sink) Name() string {
+	return "notifications"
+}
+
+func (s *Sink) Deliver(ctx context.Context, events []outbox.Event) error {
+	for _, e := range events {
+		customerId, kind, d, err := s.notification(ctx, e)
+
+		switch err.(type) {
+		case *apperrors.AccountNotFoundError:
+			logger.Log.Warn("Skipping notification of event", e.Id, err)
+			continue
+		case *decodeError:
+			logger.Log.Error("Skipping notification of event", e.Id, err)
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if kind == "" {
+			continue
+		}
+
+		if err := s.notify(ctx, e.Id, customerId, kind, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// notification returns who to notify of e, and with what. The kind is empty
+// when e is not notified.
+func (s *Sink) notification(ctx context.Context, e outbox.Event) (uint64, string, Data, error) {
+	d := Data{At: e.CreatedAt}
+
+	switch e.Type {
+	case outbox.TypeTransferCompleted:
+		var t outbox.TransferPayload
+
+		if err := decode(e, &t); err != nil {
+			return 0, "", d, err
+		}
+
+		destination, err := s.r.GetAccountById(ctx, t.Destination)
+		if err != nil {
+			return 0, "", d, err
+		}
+
+		origin, err := s.r.GetAccountById(ctx, t.Origin)
+		if err != nil {
+			return 0, "", d, err
+		}
+
+		// Moving money between own accounts is no news.
+		if origin.CustomerId == destination.CustomerId {
+			return 0, "", d, nil
+		}
+
+		d.Name = destination.Name
+		d.AccountId = destination.Id
+		d.From = origin.Name
+		d.FromAccount = origin.Id
+		d.Amount = t.Amount
+
+		return destination.CustomerId, KindTransferReceived, d, nil
+	case outbox.TypeLoginSucceeded:
+		var l outbox.LoginSucceededPayload
+
+		if err := decode(e, &l); err != nil {
+			return 0, "", d, err
+		}
+
+		if !l.NewDevice {
+			return 0, "", d, nil
+		}
+
+		customer, err := s.r.GetCustomerById(ctx, l.CustomerId)
+		if err != nil {
+			return 0, "", d, err
+		}
+
+		d.Name = customer.Name
+		d.Device = l.UserAgent
+		d.Ip = l.Ip
+
+		return customer.Id, KindNewDevice, d, nil
+	case outbox.TypeSecretChanged:
+		var c outbox.SecretChangedPayload
+
+		if err := decode(e, &c); err != nil {
+			return 0, "", d, err
+		}
+
+		customer, err := s.r.GetCustomerById(ctx, c.CustomerId)
+		if err != nil {
+			return 0, "", d, err
+		}
+
+		d.Name = customer.Name
+		d.Reset = c.Reset
+
+		return customer.Id, KindSecretChanged, d, nil
+	case outbox.TypeLimitChanged:
+		var c outbox.LimitChangedPayload
+
+		if err := decode(e, &c); err != nil {
+			return 0, "", d, err
+		}
+
+		a, err := s.r.GetAccountById(ctx, c.AccountId)
+		if err != nil {
+			return 0, "", d, err
+		}
+
+		d.Name = a.Name
+		d.AccountId = a.Id
+		d.Limit = c.Kind
+		d.Previous = c.Previous
+		d.Amount = c.Amount
+		d.Pending = c.Pending
+		d.At = c.EffectiveAt
+
+		return a.CustomerId, KindLimitChanged, d, nil
+	default:
+		return 0, "", d, nil
+	}
+}
+
+// notify sends kind to the customer through the channels they chose, unless
+// it was sent already for the event with eventId.
+func (s *Sink) notify(ctx context.Context, eventId uint64, customerId uint64, kind string, d Data) error {
+	p, err := s.r.GetNotificationPreferences(ctx, customerId)
+	if err != nil {
+		return err
+	}
+
+	if p.IsMuted(kind) || len(p.Channels) == 0 {
+		return nil
+	}
+
+	m, err := Render(kind, p.Language, d)
+	if err != nil {
+		logger.Log.Error("Notification render error:", err)
+		return nil
+	}
+
+	first, err := s.r.MarkNotificationSent(ctx, eventId, customerId, kind)
+	if err != nil {
+		return err
+	}
+
+	if !first {
+		logger.Log.Debug("Notification", kind, "of event", eventId, "to customer", customerId, "already sent")
+		return nil
+	}
+
+	to := Recipient{CustomerId: customerId, Name: d.Name, Email: p.Email, Phone: p.Phone, PushToken: p.PushToken}
+
+	for _, name := range p.Channels {
+		c, ok := s.channels[name]
+
+		if !ok || p.Address(name) == "" {
+			continue
+		}
+
+		if err := c.Send(ctx, to, m); err != nil {
+			logger.Log.Error("Notification", kind, "to customer", customerId, "through", name, "error:", err)
+		}
+	}
+
+	return nil
+}
+
+// decodeError is a payload that does not decode. It never will, so the sink
+// skips the event instead of holding the outbox back on it.
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return "payload decode error: " + e.err.Error()
+}
+
+func decode(e outbox.Event, v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return &decodeError{err}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+type mockRepository struct {
+	preferences map[uint64]Preferences
+	accounts    map[uint64]account.Account
+	sent        map[string]bool
+}
+
+func newMockRepository() *mockRepository {
+	return &mockRepository{
+		preferences: map[uint64]Preferences{
+			10: {Language: LanguagePtBR, Email: "maria@example.com", Phone: "+5511999998888", Channels: []string{ChannelEmail, ChannelSMS}},
+			20: {Language: LanguageEn, PushToken: "abc", Channels: []string{ChannelPush}, Muted: []string{KindTransferReceived}},
+		},
+		accounts: map[uint64]account.Account{
+			1: {Id: 1, CustomerId: 10, Name: "Maria"},
+			2: {Id: 2, CustomerId: 20, Name: "Roberval"},
+			3: {Id: 3, CustomerId: 10, Name: "Maria"},
+		},
+		sent: map[string]bool{},
+	}
+}
+
+func (r *mockRepository) GetNotificationPreferences(ctx context.Context, customerId uint64) (Preferences, error) {
+	if p, ok := r.preferences[customerId]; ok {
+		return p, nil
+	}
+	return DefaultPreferences(), nil
+}
+
+func (r *mockRepository) SetNotificationPreferences(ctx context.Context, customerId uint64, p Preferences) (Preferences, error) {
+	r.preferences[customerId] = p
+	return p, nil
+}
+
+func (r *mockRepository) GetAccountById(ctx context.Context, id uint64) (account.Account, error) {
+	if a, ok := r.accounts[id]; ok {
+		return a, nil
+	}
+	return account.Account{}, apperrors.NewAccountNotFoundError("account not found")
+}
+
+func (r *mockRepository) GetCustomerById(ctx context.Context, id uint64) (login.Customer, error) {
+	for _, a := range r.accounts {
+		if a.CustomerId == id {
+			return login.Customer{Id: id, Name: a.Name}, nil
+		}
+	}
+	return login.Customer{}, apperrors.NewAccountNotFoundError("customer not found")
+}
+
+func (r *mockRepository) MarkNotificationSent(ctx context.Context, eventId uint64, customerId uint64, kind string) (bool, error) {
+	key := fmt.Sprint(eventId, customerId, kind)
+	if r.sent[key] {
+		return false, nil
+	}
+	r.sent[key] = true
+	return true, nil
+}
+
+type sent struct {
+	channel    string
+	customerId uint64
+	kind       string
+}
+
+type mockChannel struct {
+	name string
+	sent *[]sent
+	err  error
+}
+
+func (c *mockChannel) Name() string {
+	return c.name
+}
+
+func (c *mockChannel) Send(ctx context.Context, to Recipient, m Message) error {
+	*c.sent = append(*c.sent, sent{c.name, to.CustomerId, m.Kind})
+	return c.err
+}
+
+func event(id uint64, eventType string, payload interface{}) outbox.Event {
+	raw, _ := json.Marshal(payload)
+	return outbox.Event{Id: id, Type: eventType, Payload: raw, CreatedAt: time.Now()}
+}
+
+func TestSink(t *testing.T) {
+	var got []sent
+	s := NewSink(newMockRepository(),
+		&mockChannel{ChannelEmail, &got, nil},
+		&mockChannel{ChannelSMS, &got, errors.New("provider down")},
+		&mockChannel{ChannelPush, &got, nil},
+	)
+
+	events := []outbox.Event{
+		event(1, outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 2, Destination: 1, Amount: 100}),
+		event(2, outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 1, Destination: 2, Amount: 100}),
+		event(3, outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 3, Destination: 1, Amount: 100}),
+		event(4, outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 2, Destination: 404, Amount: 100}),
+		event(5, outbox.TypeLoginSucceeded, outbox.LoginSucceededPayload{CustomerId: 20, UserAgent: "curl", NewDevice: true}),
+		event(6, outbox.TypeLoginSucceeded, outbox.LoginSucceededPayload{CustomerId: 20, UserAgent: "curl"}),
+		event(7, outbox.TypeSecretChanged, outbox.SecretChangedPayload{CustomerId: 10}),
+		event(8, outbox.TypeLimitChanged, outbox.LimitChangedPayload{AccountId: 2, Kind: "daily", Amount: 100, Pending: true}),
+		event(9, outbox.TypeAccountOpened, outbox.AccountPayload{AccountId: 1, CustomerId: 10}),
+		event(10, outbox.TypeSecretChanged, "not an object"),
+	}
+
+	if err := s.Deliver(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []sent{
+		{ChannelEmail, 10, KindTransferReceived},
+		{ChannelSMS, 10, KindTransferReceived},
+		{ChannelPush, 20, KindNewDevice},
+		{ChannelEmail, 10, KindSecretChanged},
+		{ChannelSMS, 10, KindSecretChanged},
+		{ChannelPush, 20, KindLimitChanged},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("wrong notifications: got %v want %v", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("wrong notification %d: got %v want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSinkRedelivery(t *testing.T) {
+	var got []sent
+	r := newMockRepository()
+	s := NewSink(r, &mockChannel{ChannelEmail, &got, nil}, &mockChannel{ChannelSMS, &got, nil})
+
+	events := []outbox.Event{
+		event(1, outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 2, Destination: 1, Amount: 100}),
+		event(2, outbox.TypeSecretChanged, outbox.SecretChangedPayload{CustomerId: 10}),
+	}
+
+	// The relay delivers the batch again when a later event failed.
+	for i := 0; i < 2; i++ {
+		if err := s.Deliver(context.Background(), events); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(got) != 4 {
+		t.Errorf("got %d notifications sent want 4: %v", len(got), got)
+	}
+}
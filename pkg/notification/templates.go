@@ -0,0 +1,195 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
+)
+
+// Data fills the templates. Not every kind uses every field.
+type Data struct {
+	Name string
+	// AccountId is the account the notification is about.
+	AccountId uint64
+	// From is who sent a transfer received, by name and account.
+	From        string
+	FromAccount uint64
+	Amount      int64
+	// Limit is the kind of the limit changed, Previous its former amount.
+	Limit    string
+	Previous int64
+	Pending  bool
+	Reset    bool
+	Device   string
+	Ip       string
+	At       time.Time
+}
+
+type text struct {
+	subject string
+	body    string
+}
+
+var texts = map[string]map[string]text{
+	KindTransferReceived: {
+		LanguagePtBR: {
+			"Você recebeu {{money .Amount}}",
+			"Olá, {{.Name}}. Você recebeu {{money .Amount}} de {{.From}} (conta {{.FromAccount}}) na conta {{.AccountId}} em {{when .At}}.",
+		},
+		LanguageEn: {
+			"You received {{money .Amount}}",
+			"Hi {{.Name}}, you received {{money .Amount}} from {{.From}} (account {{.FromAccount}}) in account {{.AccountId}} on {{when .At}}.",
+		},
+	},
+	KindNewDevice: {
+		LanguagePtBR: {
+			"Novo acesso à sua conta",
+			"Olá, {{.Name}}. Houve um login em {{when .At}} de um dispositivo novo ({{.Device}}, IP {{.Ip}}). Se não foi você, troque sua senha e encerre a sessão.",
+		},
+		LanguageEn: {
+			"New sign-in to your account",
+			"Hi {{.Name}}, there was a login on {{when .At}} from a new device ({{.Device}}, IP {{.Ip}}). If it was not you, change your password and terminate the session.",
+		},
+	},
+	KindSecretChanged: {
+		LanguagePtBR: {
+			"Sua senha foi alterada",
+			"Olá, {{.Name}}. Sua senha foi {{if .Reset}}redefinida{{else}}alterada{{end}} em {{when .At}} e suas sessões foram encerradas. Se não foi você, fale com a gente.",
+		},
+		LanguageEn: {
+			"Your password was changed",
+			"Hi {{.Name}}, your password was {{if .Reset}}reset{{else}}changed{{end}} on {{when .At}} and your sessions were terminated. If it was not you, contact us.",
+		},
+	},
+	KindLimitChanged: {
+		LanguagePtBR: {
+			"Limite de transferência alterado",
+			"Olá, {{.Name}}. O limite {{limit .Limit}} da conta {{.AccountId}} {{if .Pending}}passará{{else}}passou{{end}} de {{money .Previous}} para {{money .Amount}} em {{when .At}}.",
+		},
+		LanguageEn: {
+			"Transfer limit changed",
+			"Hi {{.Name}}, the {{limit .Limit}} limit of account {{.AccountId}} {{if .Pending}}will change{{else}}changed{{end}} from {{money .Previous}} to {{money .Amount}} on {{when .At}}.",
+		},
+	},
+}
+
+var limitNames = map[string]map[string]string{
+	LanguagePtBR: {
+		limits.PerTransaction: "por transferência",
+		limits.Daily:          "diário",
+		limits.Monthly:        "mensal",
+		limits.Nightly:        "noturno",
+	},
+	LanguageEn: {
+		limits.PerTransaction: "per transfer",
+		limits.Daily:          "daily",
+		limits.Monthly:        "monthly",
+		limits.Nightly:        "nightly",
+	},
+}
+
+type templates struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var compiled = compile()
+
+func compile() map[string]map[string]templates {
+	c := map[string]map[string]templates{}
+
+	for kind, byLanguage := range texts {
+		c[kind] = map[string]templates{}
+
+		for language, t := range byLanguage {
+			funcs := funcsFor(language)
+			c[kind][language] = templates{
+				subject: template.Must(template.New(kind + ".subject").Funcs(funcs).Parse(t.subject)),
+				body:    template.Must(template.New(kind + ".body").Funcs(funcs).Parse(t.body)),
+			}
+		}
+	}
+
+	return c
+}
+
+func funcsFor(language string) template.FuncMap {
+	return template.FuncMap{
+		"money": func(cents int64) string { return FormatAmount(cents, language) },
+		"when":  func(t time.Time) string { return formatTime(t, language) },
+		"limit": func(kind string) string {
+			if name, ok := limitNames[language][kind]; ok {
+				return name
+			}
+			return kind
+		},
+	}
+}
+
+// Render fills the template of kind in language, or in pt-BR when there is
+// none in it.
+func Render(kind string, language string, d Data) (Message, error) {
+	byLanguage, ok := compiled[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for notification kind %s", kind)
+	}
+
+	t, ok := byLanguage[language]
+	if !ok {
+		t = byLanguage[LanguagePtBR]
+	}
+
+	var subject, body bytes.Buffer
+
+	if err := t.subject.Execute(&subject, d); err != nil {
+		return Message{}, err
+	}
+
+	if err := t.body.Execute(&body, d); err != nil {
+		return Message{}, err
+	}
+
+	return Message{Kind: kind, Subject: subject.String(), Body: body.String()}, nil
+}
+
+// FormatAmount formats cents as reais, as R$ 1.234,56 in pt-BR and
+// R$1,234.56 in English.
+func FormatAmount(cents int64, language string) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	thousands, decimal, prefix := ".", ",", "R$ "
+	if language == LanguageEn {
+		thousands, decimal, prefix = ",", ".", "R$"
+	}
+
+	units := fmt.Sprint(cents / 100)
+	var grouped strings.Builder
+
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s%s%s%s%02d", sign, prefix, grouped.String(), decimal, cents%100)
+}
+
+func formatTime(t time.Time, language string) string {
+	t = t.In(config.Location)
+
+	if language == LanguageEn {
+		return t.Format("Jan 2, 2006 at 3:04 PM")
+	}
+
+	return t.Format("02/01/2006 às 15:04")
+}
@@ -0,0 +1,163 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Scopes API clients can be granted. Customer tokens carry no scope and can
+// do everything the customer can.
+const (
+	ScopeAccountsRead   = "accounts:read"
+	ScopeTransfersRead  = "transfers:read"
+	ScopeTransfersWrite = "transfers:write"
+	ScopeHoldsRead      = "holds:read"
+	ScopeHoldsWrite     = "holds:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+)
+
+var Scopes = []string{ScopeAccountsRead, ScopeTransfersRead, ScopeTransfersWrite, ScopeHoldsRead, ScopeHoldsWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite}
+
+// Token claims of client tokens: the space separated scopes, as in RFC 6749,
+// and the client id.
+const (
+	ClaimScope    = "scope"
+	ClaimClientId = "clientId"
+)
+
+const GrantClientCredentials = "client_credentials"
+
+// RFC 6749 error codes of the token endpoint.
+const (
+	ErrInvalidRequest       = "invalid_request"
+	ErrInvalidClient        = "invalid_client"
+	ErrUnsupportedGrantType = "unsupported_grant_type"
+	ErrInvalidScope         = "invalid_scope"
+)
+
+// Client is an API client, a partner system acting on one account without
+// the customer CPF and secret.
+type Client struct {
+	Id         uint64    `json:"id"`
+	ClientId   string    `json:"clientId"`
+	Name       string    `json:"name"`
+	AccountId  uint64    `json:"accountId"`
+	Scopes     []string  `json:"scopes"`
+	Active     bool      `json:"active"`
+	SecretHash string    `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type NewClientRequest struct {
+	Name      string   `json:"name"`
+	AccountId *uint64  `json:"accountId"`
+	Scopes    []string `json:"scopes"`
+}
+
+// NewClientResponse carries the client secret, shown only this once.
+type NewClientResponse struct {
+	Client
+	ClientSecret string `json:"clientSecret"`
+}
+
+type ListClientsResponse struct {
+	Clients []Client `json:"clients"`
+}
+
+// TokenRequest is a client credentials grant. Scope optionally narrows the
+// client scopes.
+type TokenRequest struct {
+	GrantType    string
+	ClientId     string
+	ClientSecret string
+	Scope        string
+}
+
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// Error is a token endpoint error, in the RFC 6749 format.
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+
+	return e.Code + ": " + e.Description
+}
+
+func NewError(code string, description string) *Error {
+	return &Error{code, description}
+}
+
+// GenerateCredentials returns a new client id and secret.
+func GenerateCredentials() (string, string, error) {
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+
+	return "cli_" + hex.EncodeToString(id), hex.EncodeToString(secret), nil
+}
+
+// HashSecret returns the stored form of a client secret. Secrets are random
+// enough to not need a salt.
+func HashSecret(secret string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(secret)))
+}
+
+// grantedScopes returns the scopes of requested, space separated, the client
+// has, or all of the client scopes when requested is empty. It fails when
+// any requested scope is not the client's.
+func grantedScopes(client []string, requested string) ([]string, bool) {
+	if strings.TrimSpace(requested) == "" {
+		return client, true
+	}
+
+	has := map[string]bool{}
+	for _, scope := range client {
+		has[scope] = true
+	}
+
+	var granted []string
+
+	for _, scope := range strings.Fields(requested) {
+		if !has[scope] {
+			return nil, false
+		}
+
+		granted = append(granted, scope)
+	}
+
+	return granted, true
+}
+
+func validScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
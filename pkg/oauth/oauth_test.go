@@ -0,0 +1,74 @@
+package oauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGrantedScopes(t *testing.T) {
+	client := []string{ScopeAccountsRead, ScopeTransfersWrite}
+
+	tests := []struct {
+		name      string
+		requested string
+		granted   []string
+		ok        bool
+	}{
+		{"all client scopes by default", "", client, true},
+		{"narrower", ScopeAccountsRead, []string{ScopeAccountsRead}, true},
+		{"extra spaces", "  transfers:write   accounts:read ", []string{ScopeTransfersWrite, ScopeAccountsRead}, true},
+		{"not granted", ScopeHoldsWrite, nil, false},
+		{"partly granted", ScopeAccountsRead + " " + ScopeHoldsRead, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			granted, ok := grantedScopes(client, tt.requested)
+
+			if ok != tt.ok || !reflect.DeepEqual(granted, tt.granted) {
+				t.Errorf("grantedScopes(%q) = %v, %v want %v, %v", tt.requested, granted, ok, tt.granted, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateClientValues(t *testing.T) {
+	account := uint64(1)
+
+	tests := []struct {
+		name  string
+		c     NewClientRequest
+		valid bool
+	}{
+		{"valid", NewClientRequest{"ERP", &account, []string{ScopeTransfersWrite}}, true},
+		{"missing name", NewClientRequest{" ", &account, []string{ScopeTransfersWrite}}, false},
+		{"missing account", NewClientRequest{"ERP", nil, []string{ScopeTransfersWrite}}, false},
+		{"no scopes", NewClientRequest{"ERP", &account, nil}, false},
+		{"unknown scope", NewClientRequest{"ERP", &account, []string{"admin"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateClientValues(tt.c); (err == nil) != tt.valid {
+				t.Errorf("validateClientValues = %v want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestGenerateCredentials(t *testing.T) {
+	id, secret, err := GenerateCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	otherId, otherSecret, _ := GenerateCredentials()
+
+	if id == otherId || secret == otherSecret || len(id) != 20 || len(secret) != 64 {
+		t.Errorf("unexpected credentials %s %s and %s %s", id, secret, otherId, otherSecret)
+	}
+
+	if HashSecret(secret) == secret {
+		t.Errorf("client secrets should be stored hashed")
+	}
+}
@@ -0,0 +1,301 @@
+package oauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+type Repository interface {
+	AddApiClient(context.Context, Client) (Client, error)
+	GetApiClient(context.Context, string) (Client, error)
+	ListApiClients(context.Context) ([]Client, error)
+	RevokeApiClient(context.Context, uint64) error
+	GetAccountById(context.Context, uint64) (account.Account, error)
+}
+
+type Service interface {
+	CreateClient(context.Context, NewClientRequest) (NewClientResponse, error)
+	ListClients(context.Context) (ListClientsResponse, error)
+	RevokeClient(context.Context, uint64) error
+	Token(context.Context, TokenRequest) (TokenResponse, error)
+	ValidateClient(context.Context, string, uint64) error
+}
+
+// Signer signs tokens.
+type Signer interface {
+	Sign(jwt.MapClaims) (string, error)
+}
+
+type service struct {
+	r      Repository
+	signer Signer
+}
+
+func New(r Repository, signer Signer) *service {
+	return &service{r, signer}
+}
+
+// CreateClient registers an API client for an account and returns its
+// credentials.
+func (s *service) CreateClient(ctx context.Context, c NewClientRequest) (NewClientResponse, error) {
+	var response NewClientResponse
+	responseCh := make(chan NewClientResponse)
+	errCh := make(chan error)
+
+	go func() {
+		if err := validateClientValues(c); err != nil {
+			errCh <- err
+			return
+		}
+
+		if _, err := s.r.GetAccountById(ctx, *c.AccountId); err != nil {
+			errCh <- err
+			return
+		}
+
+		clientId, secret, err := GenerateCredentials()
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to generate client credentials")
+			return
+		}
+
+		client, err := s.r.AddApiClient(ctx, Client{
+			ClientId:   clientId,
+			Name:       strings.TrimSpace(c.Name),
+			AccountId:  *c.AccountId,
+			Scopes:     c.Scopes,
+			Active:     true,
+			SecretHash: HashSecret(secret),
+		})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- NewClientResponse{client, secret}
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+func (s *service) ListClients(ctx context.Context) (ListClientsResponse, error) {
+	var response ListClientsResponse
+	clientsCh := make(chan []Client)
+	errCh := make(chan error)
+
+	go func() {
+		clients, err := s.r.ListApiClients(ctx)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		clientsCh <- clients
+	}()
+
+	select {
+	case response.Clients = <-clientsCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// RevokeClient stops a client from getting new tokens. Tokens already issued
+// stop working too, as ValidateClient refuses them.
+func (s *service) RevokeClient(ctx context.Context, id uint64) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.r.RevokeApiClient(ctx, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ValidateClient tells whether a token of clientId, for accountId, is still
+// good: the client must exist, be active and still be for the account.
+// Failures are *apperrors.AuthError.
+func (s *service) ValidateClient(ctx context.Context, clientId string, accountId uint64) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		c, err := s.r.GetApiClient(ctx, clientId)
+		if err != nil {
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+				errCh <- apperrors.NewAuthError("api client not found")
+				return
+			}
+
+			errCh <- err
+			return
+		}
+
+		if !c.Active {
+			errCh <- apperrors.NewAuthError("api client revoked")
+			return
+		}
+
+		if c.AccountId != accountId {
+			errCh <- apperrors.NewAuthError("invalid authentication token")
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Token runs the client credentials grant, issuing a token for the client
+// account limited to the granted scopes. Failures are *Error.
+func (s *service) Token(ctx context.Context, t TokenRequest) (TokenResponse, error) {
+	var response TokenResponse
+	responseCh := make(chan TokenResponse)
+	errCh := make(chan error)
+
+	go func() {
+		if t.GrantType != GrantClientCredentials {
+			errCh <- NewError(ErrUnsupportedGrantType, "only client_credentials is supported")
+			return
+		}
+
+		if t.ClientId == "" || t.ClientSecret == "" {
+			errCh <- NewError(ErrInvalidClient, "missing client credentials")
+			return
+		}
+
+		client, err := s.r.GetApiClient(ctx, t.ClientId)
+		if err != nil {
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+				errCh <- NewError(ErrInvalidClient, "")
+				return
+			}
+
+			errCh <- err
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(HashSecret(t.ClientSecret)), []byte(client.SecretHash)) != 1 || !client.Active {
+			errCh <- NewError(ErrInvalidClient, "")
+			return
+		}
+
+		scopes, ok := grantedScopes(client.Scopes, t.Scope)
+		if !ok {
+			errCh <- NewError(ErrInvalidScope, "scope not granted to this client")
+			return
+		}
+
+		a, err := s.r.GetAccountById(ctx, client.AccountId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if !a.Active {
+			errCh <- NewError(ErrInvalidClient, "client account is inactive")
+			return
+		}
+
+		scope := strings.Join(scopes, " ")
+		now := time.Now()
+
+		token, err := s.signer.Sign(jwt.MapClaims{
+			"iss":         keys.Issuer,
+			"aud":         login.Audience,
+			"sub":         strconv.FormatUint(a.CustomerId, 10),
+			"accountId":   a.Id,
+			ClaimClientId: client.ClientId,
+			ClaimScope:    scope,
+			"iat":         now.Unix(),
+			"exp":         now.Add(login.TokenExpiry).Unix(),
+		})
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to create client token")
+			return
+		}
+
+		responseCh <- TokenResponse{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   int64(login.TokenExpiry / time.Second),
+			Scope:       scope,
+		}
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+func validateClientValues(c NewClientRequest) error {
+	var invalid []string
+
+	if strings.TrimSpace(c.Name) == "" {
+		invalid = append(invalid, "name")
+	}
+	if c.AccountId == nil {
+		invalid = append(invalid, "accountId")
+	}
+	if len(c.Scopes) == 0 {
+		invalid = append(invalid, "scopes")
+	}
+
+	if len(invalid) > 0 {
+		return apperrors.NewArgumentError("missing values", strings.Join(invalid, ", "))
+	}
+
+	for _, scope := range c.Scopes {
+		if !validScope(scope) {
+			return apperrors.NewArgumentError("invalid scope", scope)
+		}
+	}
+
+	return nil
+}
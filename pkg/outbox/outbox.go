@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Types of the domain events published through the outbox.
+const (
+	TypeAccountCreated       = "AccountCreated"
+	TypeAccountOpened        = "AccountOpened"
+	TypeAccountClosed        = "AccountClosed"
+	TypeTransferCompleted    = "TransferCompleted"
+	TypeLoginSucceeded       = "LoginSucceeded"
+	TypeLoginFailed          = "LoginFailed"
+	TypeSecretChanged        = "SecretChanged"
+	TypeLimitChanged         = "LimitChanged"
+	TypePostingCompleted     = "PostingCompleted"
+	TypeHoldChanged          = "HoldChanged"
+	TypeJudicialBlockChanged = "JudicialBlockChanged"
+)
+
+// Event is a domain event, written to the outbox in the same database
+// transaction as the change it describes. Subject names what the event is
+// about, as in account:42, and Payload is one of the payloads below.
+type Event struct {
+	Id        uint64          `json:"id"`
+	Type      string          `json:"type"`
+	Subject   string          `json:"subject"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// AccountPayload is the payload of AccountCreated, AccountOpened and
+// AccountClosed.
+type AccountPayload struct {
+	AccountId  uint64 `json:"accountId"`
+	CustomerId uint64 `json:"customerId"`
+	Product    string `json:"product,omitempty"`
+}
+
+type TransferPayload struct {
+	TransferId  uint64 `json:"transferId"`
+	Origin      uint64 `json:"origin"`
+	Destination uint64 `json:"destination"`
+	Amount      int64  `json:"amount"`
+	Fee         int64  `json:"fee"`
+}
+
+// PostingPayload is a posting the bank makes on its own between an account
+// and one of its system accounts, Kind telling which: interest paid to the
+// account or overdraft interest charged to it.
+type PostingPayload struct {
+	TransferId  uint64 `json:"transferId"`
+	Origin      uint64 `json:"origin"`
+	Destination uint64 `json:"destination"`
+	Amount      int64  `json:"amount"`
+	Kind        string `json:"kind"`
+}
+
+// HoldPayload is a hold authorized, or finished as Status tells.
+type HoldPayload struct {
+	HoldId         uint64 `json:"holdId"`
+	AccountId      uint64 `json:"accountId"`
+	Amount         int64  `json:"amount"`
+	CapturedAmount int64  `json:"capturedAmount"`
+	Status         string `json:"status"`
+}
+
+// JudicialBlockPayload is a judicial block placed on an account or, when
+// Released, lifted from it. The case behind it is left out, as the account
+// holder gets the event.
+type JudicialBlockPayload struct {
+	BlockId   uint64 `json:"blockId"`
+	AccountId uint64 `json:"accountId"`
+	Amount    int64  `json:"amount"`
+	Released  bool   `json:"released"`
+}
+
+// LoginSucceededPayload tells, in NewDevice, whether the customer logged in
+// before but never from this user agent.
+type LoginSucceededPayload struct {
+	CustomerId uint64 `json:"customerId"`
+	SessionId  uint64 `json:"sessionId"`
+	Ip         string `json:"ip"`
+	UserAgent  string `json:"userAgent"`
+	NewDevice  bool   `json:"newDevice"`
+}
+
+// LoginFailedPayload has no customer when the CPF is unknown.
+type LoginFailedPayload struct {
+	CustomerId *uint64 `json:"customerId"`
+	Reason     string  `json:"reason"`
+	Ip         string  `json:"ip"`
+}
+
+// SecretChangedPayload tells, in Reset, whether the secret was reset with a
+// token rather than changed by the customer.
+type SecretChangedPayload struct {
+	CustomerId uint64 `json:"customerId"`
+	Reset      bool   `json:"reset"`
+}
+
+// LimitChangedPayload is a transfer limit change of an account. Pending
+// raises only take effect at EffectiveAt.
+type LimitChangedPayload struct {
+	AccountId   uint64    `json:"accountId"`
+	Kind        string    `json:"kind"`
+	Previous    int64     `json:"previous"`
+	Amount      int64     `json:"amount"`
+	Pending     bool      `json:"pending"`
+	EffectiveAt time.Time `json:"effectiveAt"`
+}
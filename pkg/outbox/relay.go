@@ -0,0 +1,77 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// relayBatchSize is how many events the relay reads and delivers at a time.
+const relayBatchSize = 100
+
+type Repository interface {
+	ListOutboxEvents(context.Context, uint64, int) ([]Event, error)
+	GetOutboxOffset(context.Context, string) (uint64, error)
+	SetOutboxOffset(context.Context, string, uint64) error
+}
+
+// Relay delivers the outbox events to its sinks, at least once and in order.
+// Each sink has its own offset, the id of the last event it got, so a sink
+// that is down only holds back itself.
+type Relay struct {
+	r     Repository
+	sinks []Sink
+}
+
+func NewRelay(r Repository, sinks ...Sink) *Relay {
+	return &Relay{r, sinks}
+}
+
+// Run delivers to every sink the events after its offset until it is caught
+// up. A failed batch is delivered again on the next run.
+func (rl *Relay) Run(ctx context.Context) error {
+	var lastErr error
+
+	for _, s := range rl.sinks {
+		if err := rl.deliver(ctx, s); err != nil {
+			logger.Log.Error("Outbox sink", s.Name(), "error:", err)
+			lastErr = err
+		}
+	}
+
+	return lastErr
+}
+
+func (rl *Relay) deliver(ctx context.Context, s Sink) error {
+	offset, err := rl.r.GetOutboxOffset(ctx, s.Name())
+	if err != nil {
+		return err
+	}
+
+	for {
+		events, err := rl.r.ListOutboxEvents(ctx, offset, relayBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		if err := s.Deliver(ctx, events); err != nil {
+			return err
+		}
+
+		offset = events[len(events)-1].Id
+
+		if err := rl.r.SetOutboxOffset(ctx, s.Name(), offset); err != nil {
+			return err
+		}
+
+		logger.Log.Debug("Delivered", len(events), "outbox events to", s.Name())
+
+		if len(events) < relayBatchSize {
+			return nil
+		}
+	}
+}
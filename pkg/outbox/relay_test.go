@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+type mockRepository struct {
+	events  []Event
+	offsets map[string]uint64
+}
+
+func newMockRepository(n int) *mockRepository {
+	r := &mockRepository{offsets: map[string]uint64{}}
+
+	for i := 1; i <= n; i++ {
+		r.events = append(r.events, Event{Id: uint64(i), Type: TypeTransferCompleted})
+	}
+
+	return r
+}
+
+func (r *mockRepository) ListOutboxEvents(ctx context.Context, afterId uint64, limit int) ([]Event, error) {
+	events := []Event{}
+
+	for _, e := range r.events {
+		if e.Id > afterId && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+func (r *mockRepository) GetOutboxOffset(ctx context.Context, sink string) (uint64, error) {
+	return r.offsets[sink], nil
+}
+
+func (r *mockRepository) SetOutboxOffset(ctx context.Context, sink string, offset uint64) error {
+	r.offsets[sink] = offset
+	return nil
+}
+
+type mockSink struct {
+	name      string
+	delivered []uint64
+	// failAt fails the batch holding this event id.
+	failAt uint64
+}
+
+func (s *mockSink) Name() string {
+	return s.name
+}
+
+func (s *mockSink) Deliver(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		if e.Id == s.failAt {
+			return errors.New("sink down")
+		}
+	}
+
+	for _, e := range events {
+		s.delivered = append(s.delivered, e.Id)
+	}
+
+	return nil
+}
+
+func TestRelay(t *testing.T) {
+	t.Run("delivers every event in order", func(t *testing.T) {
+		r := newMockRepository(250)
+		s := &mockSink{name: "a"}
+
+		if err := NewRelay(r, s).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(s.delivered) != 250 || s.delivered[0] != 1 || s.delivered[249] != 250 {
+			t.Errorf("got %d events delivered", len(s.delivered))
+		}
+
+		if r.offsets["a"] != 250 {
+			t.Errorf("got offset %d", r.offsets["a"])
+		}
+	})
+
+	t.Run("resumes from the offset", func(t *testing.T) {
+		r := newMockRepository(10)
+		r.offsets["a"] = 7
+		s := &mockSink{name: "a"}
+
+		if err := NewRelay(r, s).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(s.delivered) != 3 || s.delivered[0] != 8 {
+			t.Errorf("got %v delivered", s.delivered)
+		}
+	})
+
+	t.Run("failing sink holds back only itself", func(t *testing.T) {
+		r := newMockRepository(150)
+		down := &mockSink{name: "down", failAt: 120}
+		up := &mockSink{name: "up"}
+
+		if err := NewRelay(r, down, up).Run(context.Background()); err == nil {
+			t.Error("got no error")
+		}
+
+		if r.offsets["down"] != 100 {
+			t.Errorf("got failing sink offset %d", r.offsets["down"])
+		}
+
+		if r.offsets["up"] != 150 {
+			t.Errorf("got sink offset %d", r.offsets["up"])
+		}
+
+		down.failAt = 0
+
+		if err := NewRelay(r, down, up).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(down.delivered) != 150 || down.delivered[100] != 101 {
+			t.Errorf("got %d events delivered after recovery", len(down.delivered))
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// Sink is where the relay delivers events. A sink may get an event more than
+// once, so consumers should skip the ids they have already seen.
+type Sink interface {
+	// Name identifies the sink delivery offset, it must not change across
+	// restarts.
+	Name() string
+	Deliver(context.Context, []Event) error
+}
+
+// NewSinks builds the sinks of a comma separated spec: stdout, file:<path>
+// or an http(s) URL.
+func NewSinks(spec string) ([]Sink, error) {
+	var sinks []Sink
+
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+
+		switch {
+		case s == "":
+			continue
+		case s == "stdout":
+			sinks = append(sinks, NewWriterSink(s, os.Stdout))
+		case strings.HasPrefix(s, "file:"):
+			sinks = append(sinks, NewFileSink(strings.TrimPrefix(s, "file:")))
+		case strings.HasPrefix(s, "http://"), strings.HasPrefix(s, "https://"):
+			sinks = append(sinks, NewHTTPSink(s, nil))
+		default:
+			return nil, apperrors.NewEnvVarError("invalid outbox sink", s)
+		}
+	}
+
+	return sinks, nil
+}
+
+// WriterSink writes each event as a JSON line.
+type WriterSink struct {
+	name string
+	w    io.Writer
+	mu   sync.Mutex
+}
+
+func NewWriterSink(name string, w io.Writer) *WriterSink {
+	return &WriterSink{name: name, w: w}
+}
+
+func (s *WriterSink) Name() string {
+	return s.name
+}
+
+func (s *WriterSink) Deliver(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return writeLines(s.w, events)
+}
+
+// FileSink appends each event to a file as a JSON line.
+type FileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (s *FileSink) Name() string {
+	return "file:" + s.path
+}
+
+func (s *FileSink) Deliver(ctx context.Context, events []Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := writeLines(f, events); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeLines(w io.Writer, events []Event) error {
+	e := json.NewEncoder(w)
+
+	for _, event := range events {
+		if err := e.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HTTPSink posts each batch of events to a URL as {"events": [...]}. Any
+// status other than 2xx fails the batch.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink returns a sink posting to url with client, or with a client
+// with a 10 seconds timeout when it is nil.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &HTTPSink{url: url, client: client}
+}
+
+func (s *HTTPSink) Name() string {
+	return s.url
+}
+
+func (s *HTTPSink) Deliver(ctx context.Context, events []Event) error {
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{events})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("outbox sink %s answered %s", s.url, res.Status)
+	}
+
+	return nil
+}
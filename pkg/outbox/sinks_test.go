@@ -0,0 +1,117 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewSinks(t *testing.T) {
+	sinks, err := NewSinks("stdout, file:events.log,https://example.com/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{"stdout", "file:events.log", "https://example.com/events"}
+
+	if len(sinks) != len(names) {
+		t.Fatalf("got %d sinks", len(sinks))
+	}
+
+	for i, s := range sinks {
+		if s.Name() != names[i] {
+			t.Errorf("got sink %q want %q", s.Name(), names[i])
+		}
+	}
+
+	if sinks, err := NewSinks(""); err != nil || len(sinks) != 0 {
+		t.Errorf("got %d sinks and %v for an empty spec", len(sinks), err)
+	}
+
+	if _, err := NewSinks("kafka://broker"); err == nil {
+		t.Error("got no error for an unknown sink")
+	}
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	events := []Event{{Id: 1, Type: TypeAccountCreated}, {Id: 2, Type: TypeLoginFailed}}
+
+	if err := NewWriterSink("buffer", &buf).Deliver(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[1], `"type":"LoginFailed"`) {
+		t.Errorf("got %q", buf.String())
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	s := NewFileSink(filepath.Join(dir, "events.log"))
+
+	for i := uint64(1); i <= 2; i++ {
+		if err := s.Deliver(context.Background(), []Event{{Id: i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "events.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := strings.Count(string(content), "\n"); lines != 2 {
+		t.Errorf("got %d lines appended", lines)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got struct {
+		Events []Event `json:"events"`
+	}
+
+	status := http.StatusNoContent
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got content type %q", r.Header.Get("Content-Type"))
+		}
+
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s := NewHTTPSink(srv.URL, srv.Client())
+	events := []Event{{Id: 7, Type: TypeTransferCompleted, Payload: json.RawMessage(`{"amount":100}`)}}
+
+	if err := s.Deliver(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Events) != 1 || got.Events[0].Id != 7 || string(got.Events[0].Payload) != `{"amount":100}` {
+		t.Errorf("got %+v", got.Events)
+	}
+
+	status = http.StatusServiceUnavailable
+
+	if err := s.Deliver(context.Background(), events); err == nil {
+		t.Error("got no error for a 503")
+	}
+}
@@ -0,0 +1,76 @@
+package pin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxAttempts wrong PINs in a row lock the PIN for LockoutDuration.
+const (
+	MaxAttempts     = 3
+	LockoutDuration = 30 * time.Minute
+)
+
+// Regex matches a valid transaction PIN: 4 to 6 digits.
+var Regex = regexp.MustCompile(`^\d{4,6}$`)
+
+// saltSize is the length, in bytes, of the random salt of each PIN hash.
+const saltSize = 16
+
+// AccountPin is the stored transaction PIN state of an account. Hash is empty
+// when no PIN was set.
+type AccountPin struct {
+	Hash           string
+	FailedAttempts int
+	LockedUntil    *time.Time
+}
+
+// SetPinRequest sets a new PIN. The first PIN, or one replacing a forgotten
+// or locked PIN, is confirmed with the customer secret; otherwise with the
+// current PIN.
+type SetPinRequest struct {
+	Pin        string `json:"pin"`
+	CurrentPin string `json:"currentPin,omitempty"`
+	Secret     string `json:"secret,omitempty"`
+}
+
+// Hash returns the salted hash of pin, as stored, in the salt$hash form. The
+// app SALT is mixed in too, so a leaked table alone is not enough to brute
+// force the small PIN space.
+func Hash(pin string) (string, error) {
+	salt := make([]byte, saltSize)
+
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(salt) + "$" + digest(salt, pin), nil
+}
+
+// Matches tells whether pin is the one hashed into hash.
+func Matches(hash string, pin string) bool {
+	parts := strings.SplitN(hash, "$", 2)
+
+	if len(parts) != 2 {
+		return false
+	}
+
+	salt, err := hex.DecodeString(parts[0])
+
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(parts[1]), []byte(digest(salt, pin))) == 1
+}
+
+func digest(salt []byte, pin string) string {
+	return fmt.Sprintf("%x", sha256.Sum256(append(append([]byte{}, salt...), []byte(pin+os.Getenv("SALT"))...)))
+}
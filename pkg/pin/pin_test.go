@@ -0,0 +1,56 @@
+package pin
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	first, err := Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second {
+		t.Errorf("hashes of the same pin should be salted differently, got %s twice", first)
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		pin     string
+		matches bool
+	}{
+		{"right pin", first, "1234", true},
+		{"right pin other salt", second, "1234", true},
+		{"wrong pin", first, "1235", false},
+		{"empty pin", first, "", false},
+		{"no hash", "", "1234", false},
+		{"malformed hash", "zz$abc", "1234", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.hash, tt.pin); got != tt.matches {
+				t.Errorf("Matches(%q, %q) = %v want %v", tt.hash, tt.pin, got, tt.matches)
+			}
+		})
+	}
+}
+
+func TestRegex(t *testing.T) {
+	for pin, valid := range map[string]bool{
+		"1234":    true,
+		"123456":  true,
+		"123":     false,
+		"1234567": false,
+		"12a4":    false,
+		" 1234":   false,
+	} {
+		if got := Regex.MatchString(pin); got != valid {
+			t.Errorf("Regex.MatchString(%q) = %v want %v", pin, got, valid)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package pin
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+type Repository interface {
+	GetAccountPin(context.Context, uint64) (AccountPin, error)
+	SetAccountPin(context.Context, uint64, string) error
+	CountPinAttempt(context.Context, uint64, int, time.Duration) (AccountPin, bool, error)
+	ResetPinFailures(context.Context, uint64) error
+	GetAccountById(context.Context, uint64) (account.Account, error)
+}
+
+type Service interface {
+	Set(context.Context, uint64, uint64, SetPinRequest) error
+	Verify(context.Context, uint64, string) error
+}
+
+// SecretVerifier checks the secret of a logged in customer, with its own
+// wrong attempts lockout.
+type SecretVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+type service struct {
+	r       Repository
+	secrets SecretVerifier
+}
+
+func New(r Repository, secrets SecretVerifier) *service {
+	return &service{r, secrets}
+}
+
+// Set stores a new PIN for an account of the customer, after confirming it
+// with the current PIN or the customer secret. Setting it with the secret also
+// lifts a lockout.
+func (s *service) Set(ctx context.Context, customerId uint64, accountId uint64, p SetPinRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if !Regex.MatchString(p.Pin) {
+			errCh <- apperrors.NewArgumentError("pin must have 4 to 6 digits")
+			return
+		}
+
+		if err := s.confirm(ctx, customerId, accountId, p); err != nil {
+			errCh <- err
+			return
+		}
+
+		hash, err := Hash(p.Pin)
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to hash pin")
+			return
+		}
+
+		if err := s.r.SetAccountPin(ctx, accountId, hash); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Verify checks pin against the account PIN, counting wrong attempts towards
+// the lockout.
+func (s *service) Verify(ctx context.Context, accountId uint64, pin string) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.verify(ctx, accountId, pin); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// verify counts the attempt before comparing pin, and takes it back when pin
+// is right, so concurrent wrong guesses are capped by MaxAttempts too.
+func (s *service) verify(ctx context.Context, accountId uint64, pin string) error {
+	stored, counted, err := s.r.CountPinAttempt(ctx, accountId, MaxAttempts, LockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if !counted {
+		return s.refusal(ctx, accountId)
+	}
+
+	if !Matches(stored.Hash, pin) {
+		return apperrors.NewAuthError("invalid transaction pin")
+	}
+
+	return s.r.ResetPinFailures(ctx, accountId)
+}
+
+// refusal tells why an attempt against the account PIN was not counted.
+func (s *service) refusal(ctx context.Context, accountId uint64) error {
+	stored, err := s.r.GetAccountPin(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	if stored.Hash == "" {
+		return apperrors.NewAuthError("transaction pin not set")
+	}
+
+	if stored.LockedUntil == nil {
+		return apperrors.NewAuthError("transaction pin locked")
+	}
+
+	return apperrors.NewAuthError("transaction pin locked until", stored.LockedUntil.Format(time.RFC3339))
+}
+
+func (s *service) confirm(ctx context.Context, customerId uint64, accountId uint64, p SetPinRequest) error {
+	a, err := s.r.GetAccountById(ctx, accountId)
+	if err != nil {
+		return err
+	}
+
+	if a.CustomerId != customerId {
+		return apperrors.NewAuthError("account does not belong to this customer")
+	}
+
+	if p.CurrentPin != "" {
+		return s.verify(ctx, accountId, p.CurrentPin)
+	}
+
+	if p.Secret == "" {
+		return apperrors.NewArgumentError("currentPin or secret")
+	}
+
+	return s.secrets.Verify(ctx, customerId, p.Secret)
+}
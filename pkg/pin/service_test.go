@@ -0,0 +1,80 @@
+package pin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// mockRepository keeps the PIN state of a single account, counting attempts
+// as the database does.
+type mockRepository struct {
+	stored AccountPin
+}
+
+func (m *mockRepository) GetAccountPin(ctx context.Context, id uint64) (AccountPin, error) {
+	return m.stored, nil
+}
+func (m *mockRepository) SetAccountPin(ctx context.Context, id uint64, hash string) error {
+	m.stored = AccountPin{Hash: hash}
+	return nil
+}
+func (m *mockRepository) CountPinAttempt(ctx context.Context, id uint64, maxAttempts int, lockout time.Duration) (AccountPin, bool, error) {
+	if m.stored.Hash == "" || (m.stored.LockedUntil != nil && m.stored.LockedUntil.After(time.Now())) {
+		return AccountPin{}, false, nil
+	}
+
+	m.stored.FailedAttempts++
+
+	if m.stored.FailedAttempts >= maxAttempts {
+		lockedUntil := time.Now().Add(lockout)
+		m.stored.FailedAttempts, m.stored.LockedUntil = 0, &lockedUntil
+	}
+
+	return m.stored, true, nil
+}
+func (m *mockRepository) ResetPinFailures(ctx context.Context, id uint64) error {
+	m.stored.FailedAttempts, m.stored.LockedUntil = 0, nil
+	return nil
+}
+func (m *mockRepository) GetAccountById(ctx context.Context, id uint64) (account.Account, error) {
+	return account.Account{Id: id, CustomerId: 1}, nil
+}
+
+func TestVerify(t *testing.T) {
+	hash, err := Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &mockRepository{stored: AccountPin{Hash: hash}}
+	s := New(r, nil)
+	ctx := context.Background()
+
+	if err := s.Verify(ctx, 1, "1111"); err == nil {
+		t.Fatal("wrong pin verified")
+	}
+
+	if err := s.Verify(ctx, 1, "1234"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if r.stored.FailedAttempts != 0 {
+		t.Errorf("got %d failed attempts after the right pin want 0", r.stored.FailedAttempts)
+	}
+
+	for i := 0; i < MaxAttempts; i++ {
+		if err := s.Verify(ctx, 1, "1111"); err == nil {
+			t.Fatal("wrong pin verified")
+		}
+	}
+
+	err = s.Verify(ctx, 1, "1234")
+	if _, ok := err.(*apperrors.AuthError); !ok || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("got %v for the right pin while locked want a lockout error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+)
+
+// Purposes customers can give or withdraw consent for. Processing required by
+// the account contract or by law does not depend on consent.
+const (
+	PurposeMarketing   = "marketing"
+	PurposeDataSharing = "data_sharing"
+)
+
+var Purposes = []string{PurposeMarketing, PurposeDataSharing}
+
+// Directions of an exported transfer, from the customer account side.
+const (
+	DirectionDebit  = "debit"
+	DirectionCredit = "credit"
+)
+
+// PseudonymPrefix starts the names given to anonymized customers.
+const PseudonymPrefix = "Cliente anonimizado "
+
+type Consent struct {
+	Purpose   string    `json:"purpose"`
+	Granted   bool      `json:"granted"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type ConsentRequest struct {
+	Purpose string `json:"purpose"`
+	Granted *bool  `json:"granted"`
+}
+
+type ListConsentsResponse struct {
+	Consents []Consent `json:"consents"`
+}
+
+// Export is everything the bank keeps about a customer, as the LGPD
+// portability right requires.
+type Export struct {
+	GeneratedAt time.Time                 `json:"generatedAt"`
+	Profile     account.Profile           `json:"profile"`
+	Accounts    []account.CustomerAccount `json:"accounts"`
+	Transfers   []Transfer                `json:"transfers"`
+	Sessions    []Session                 `json:"sessions"`
+	Consents    []Consent                 `json:"consents"`
+}
+
+// Transfer is a movement of one of the customer accounts. The counterparty is
+// identified by account and name only.
+type Transfer struct {
+	Id                    uint64    `json:"id"`
+	RelatedId             *uint64   `json:"relatedId,omitempty"`
+	AccountId             uint64    `json:"accountId"`
+	Direction             string    `json:"direction"`
+	Amount                int64     `json:"amount"`
+	Kind                  string    `json:"kind"`
+	CounterpartyAccountId uint64    `json:"counterpartyAccountId"`
+	CounterpartyName      string    `json:"counterpartyName"`
+	CreatedAt             time.Time `json:"createdAt"`
+}
+
+// Session is a login session, terminated and expired ones included.
+type Session struct {
+	Id           uint64     `json:"id"`
+	UserAgent    string     `json:"userAgent"`
+	Ip           string     `json:"ip"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	LastSeenAt   time.Time  `json:"lastSeenAt"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	TerminatedAt *time.Time `json:"terminatedAt,omitempty"`
+}
+
+// AnonymizeRequest anonymizes the customers whose accounts were all closed
+// before ClosedBefore, now when it is not set.
+type AnonymizeRequest struct {
+	ClosedBefore *time.Time `json:"closedBefore"`
+}
+
+type AnonymizeResponse struct {
+	Anonymized int `json:"anonymized"`
+}
+
+// Pseudonym returns a random name for an anonymized customer, so that its
+// counterparties still tell it apart from others without learning who it was.
+func Pseudonym() (string, error) {
+	b := make([]byte, 4)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return PseudonymPrefix + strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+func validPurpose(purpose string) bool {
+	for _, p := range Purposes {
+		if p == purpose {
+			return true
+		}
+	}
+
+	return false
+}
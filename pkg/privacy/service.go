@@ -0,0 +1,213 @@
+package privacy
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/validators"
+)
+
+type Repository interface {
+	GetCustomerById(context.Context, uint64) (login.Customer, error)
+	ListCustomerAccounts(context.Context, uint64) ([]account.CustomerAccount, error)
+	ListCustomerTransfers(context.Context, uint64) ([]Transfer, error)
+	ListCustomerSessionHistory(context.Context, uint64) ([]login.Session, error)
+	ListConsents(context.Context, uint64) ([]Consent, error)
+	SetConsent(context.Context, uint64, Consent) (Consent, error)
+	ListAnonymizableCustomers(context.Context, time.Time) ([]uint64, error)
+	AnonymizeCustomer(context.Context, uint64, time.Time, string) error
+}
+
+type Service interface {
+	Export(context.Context, uint64) (Export, error)
+	ListConsents(context.Context, uint64) (ListConsentsResponse, error)
+	SetConsent(context.Context, uint64, ConsentRequest) (Consent, error)
+	Anonymize(context.Context, time.Time) (AnonymizeResponse, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+// Export gathers the customer data into a single document.
+func (s *service) Export(ctx context.Context, customerId uint64) (Export, error) {
+	var export Export
+	exportCh := make(chan Export)
+	errCh := make(chan error)
+
+	go func() {
+		e := Export{GeneratedAt: time.Now().UTC()}
+
+		customer, err := s.r.GetCustomerById(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		e.Profile = account.Profile{Id: customer.Id, Name: customer.Name, Cpf: validators.FormatCPF(customer.Cpf)}
+
+		if e.Accounts, err = s.r.ListCustomerAccounts(ctx, customerId); err != nil {
+			errCh <- err
+			return
+		}
+
+		if e.Transfers, err = s.r.ListCustomerTransfers(ctx, customerId); err != nil {
+			errCh <- err
+			return
+		}
+
+		sessions, err := s.r.ListCustomerSessionHistory(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		e.Sessions = make([]Session, len(sessions))
+		for i, session := range sessions {
+			e.Sessions[i] = Session{
+				Id:           session.Id,
+				UserAgent:    session.UserAgent,
+				Ip:           session.Ip,
+				CreatedAt:    session.CreatedAt,
+				LastSeenAt:   session.LastSeenAt,
+				ExpiresAt:    session.ExpiresAt,
+				TerminatedAt: session.TerminatedAt,
+			}
+		}
+
+		if e.Consents, err = s.r.ListConsents(ctx, customerId); err != nil {
+			errCh <- err
+			return
+		}
+
+		exportCh <- e
+	}()
+
+	select {
+	case export = <-exportCh:
+		return export, nil
+	case err := <-errCh:
+		return export, err
+	case <-ctx.Done():
+		return export, ctx.Err()
+	}
+}
+
+func (s *service) ListConsents(ctx context.Context, customerId uint64) (ListConsentsResponse, error) {
+	var response ListConsentsResponse
+	consentsCh := make(chan []Consent)
+	errCh := make(chan error)
+
+	go func() {
+		consents, err := s.r.ListConsents(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		consentsCh <- consents
+	}()
+
+	select {
+	case response.Consents = <-consentsCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// SetConsent gives or withdraws the customer consent for a purpose.
+func (s *service) SetConsent(ctx context.Context, customerId uint64, c ConsentRequest) (Consent, error) {
+	var consent Consent
+	consentCh := make(chan Consent)
+	errCh := make(chan error)
+
+	go func() {
+		if !validPurpose(c.Purpose) {
+			errCh <- apperrors.NewArgumentError("purpose")
+			return
+		}
+
+		if c.Granted == nil {
+			errCh <- apperrors.NewArgumentError("granted")
+			return
+		}
+
+		consent, err := s.r.SetConsent(ctx, customerId, Consent{Purpose: c.Purpose, Granted: *c.Granted})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		consentCh <- consent
+	}()
+
+	select {
+	case consent = <-consentCh:
+		return consent, nil
+	case err := <-errCh:
+		return consent, err
+	case <-ctx.Done():
+		return consent, ctx.Err()
+	}
+}
+
+// Anonymize scrubs the name and CPF of every customer whose accounts were all
+// closed before closedBefore, giving each a pseudonym. Their transfers are
+// kept for accounting. Customers that opened an account meanwhile are skipped.
+func (s *service) Anonymize(ctx context.Context, closedBefore time.Time) (AnonymizeResponse, error) {
+	var response AnonymizeResponse
+	doneCh := make(chan int)
+	errCh := make(chan error)
+
+	go func() {
+		ids, err := s.r.ListAnonymizableCustomers(ctx, closedBefore)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		anonymized := 0
+
+		for _, id := range ids {
+			pseudonym, err := Pseudonym()
+			if err != nil {
+				errCh <- apperrors.NewInternalServerError(err.Error())
+				return
+			}
+
+			if err := s.r.AnonymizeCustomer(ctx, id, closedBefore, pseudonym); err != nil {
+				if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+					continue
+				}
+
+				errCh <- err
+				return
+			}
+
+			anonymized++
+		}
+
+		doneCh <- anonymized
+	}()
+
+	select {
+	case response.Anonymized = <-doneCh:
+		logger.Log.Debug("Anonymized", response.Anonymized, "customers with accounts closed before", closedBefore.Format(time.RFC3339))
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
@@ -0,0 +1,113 @@
+package privacy
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+type mockRepository struct {
+	pseudonyms map[uint64]string
+}
+
+func (m *mockRepository) GetCustomerById(ctx context.Context, id uint64) (login.Customer, error) {
+	return login.Customer{Id: id, Name: "Roberval Neto", Cpf: "05093092088"}, nil
+}
+func (m *mockRepository) ListCustomerAccounts(ctx context.Context, id uint64) ([]account.CustomerAccount, error) {
+	return []account.CustomerAccount{{Id: 1}}, nil
+}
+func (m *mockRepository) ListCustomerTransfers(ctx context.Context, id uint64) ([]Transfer, error) {
+	return []Transfer{}, nil
+}
+func (m *mockRepository) ListCustomerSessionHistory(ctx context.Context, id uint64) ([]login.Session, error) {
+	return []login.Session{{Id: 7, Ip: "127.0.0.1"}}, nil
+}
+func (m *mockRepository) ListConsents(ctx context.Context, id uint64) ([]Consent, error) {
+	return []Consent{}, nil
+}
+func (m *mockRepository) SetConsent(ctx context.Context, id uint64, c Consent) (Consent, error) {
+	return c, nil
+}
+func (m *mockRepository) ListAnonymizableCustomers(ctx context.Context, closedBefore time.Time) ([]uint64, error) {
+	return []uint64{1, 2, 3}, nil
+}
+func (m *mockRepository) AnonymizeCustomer(ctx context.Context, id uint64, closedBefore time.Time, pseudonym string) error {
+	// Customer 2 opened an account after being listed.
+	if id == 2 {
+		return apperrors.NewAccountNotFoundError("customer not found")
+	}
+	m.pseudonyms[id] = pseudonym
+	return nil
+}
+
+func TestExport(t *testing.T) {
+	s := New(&mockRepository{})
+
+	export, err := s.Export(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if export.Profile.Cpf != "050.930.920-88" {
+		t.Errorf("got cpf %s want it formatted", export.Profile.Cpf)
+	}
+
+	if len(export.Sessions) != 1 || export.Sessions[0].Ip != "127.0.0.1" {
+		t.Errorf("got sessions %v", export.Sessions)
+	}
+}
+
+func TestSetConsent(t *testing.T) {
+	s := New(&mockRepository{})
+	granted := true
+
+	tests := []struct {
+		name    string
+		request ConsentRequest
+		invalid string
+	}{
+		{"valid", ConsentRequest{Purpose: PurposeMarketing, Granted: &granted}, ""},
+		{"unknown purpose", ConsentRequest{Purpose: "profiling", Granted: &granted}, "purpose"},
+		{"missing granted", ConsentRequest{Purpose: PurposeDataSharing}, "granted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.SetConsent(context.Background(), 1, tt.request)
+
+			if tt.invalid == "" {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.invalid) {
+				t.Errorf("got error %v want one about %s", err, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestAnonymize(t *testing.T) {
+	r := &mockRepository{pseudonyms: map[uint64]string{}}
+	s := New(r)
+
+	response, err := s.Anonymize(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if response.Anonymized != 2 {
+		t.Errorf("got %d anonymized customers want 2", response.Anonymized)
+	}
+
+	if !strings.HasPrefix(r.pseudonyms[1], PseudonymPrefix) || r.pseudonyms[1] == r.pseudonyms[3] {
+		t.Errorf("got pseudonyms %v", r.pseudonyms)
+	}
+}
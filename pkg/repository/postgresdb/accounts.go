@@ -0,0 +1,278 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// UpdateCustomerName renames the customer and audits it in a single database
+// transaction.
+func (r *postgresDB) UpdateCustomerName(ctx context.Context, id uint64, name string) (account.Profile, error) {
+	var p account.Profile
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := "update customers set name = $2, updated_at = now() where id = $1 returning id, name, coalesce(cpf, '')"
+		logger.Log.Debug("Update customer name query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id, name).Scan(&p.Id, &p.Name, &p.Cpf); err != nil {
+			logger.Log.Error("Update customer name query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return p, apperrors.NewAccountNotFoundError("customer not found")
+			}
+
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		// The audit log outlives anonymization, so it names the fields changed
+		// and not their values.
+		if err := appendAuditEvent(ctx, tx, audit.ActionCustomerUpdate, audit.Target("customer", id),
+			nil, map[string]interface{}{"fields": []string{"name"}}); err != nil {
+			return p, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Update customer name database transaction commit error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return p, nil
+	case <-ctx.Done():
+		return p, ctx.Err()
+	}
+}
+
+// SetAccountActive deactivates or reactivates an account. Closed accounts are
+// not found.
+func (r *postgresDB) SetAccountActive(ctx context.Context, id uint64, active bool) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var previous bool
+		query := "select active from accounts where id = $1 and closed_at is null for update"
+		logger.Log.Debug("Lock account active query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&previous); err != nil {
+			logger.Log.Error("Lock account active query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		query = "update accounts set active = $2, updated_at = now() where id = $1"
+		logger.Log.Debug("Set account active query:", query, id, active)
+
+		if _, err := tx.Exec(ctx, query, id, active); err != nil {
+			logger.Log.Error("Set account active query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		action := audit.ActionAccountDeactivate
+		if active {
+			action = audit.ActionAccountReactivate
+		}
+
+		if err := appendAuditEvent(ctx, tx, action, audit.Target("account", id),
+			map[string]interface{}{"active": previous}, map[string]interface{}{"active": active}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Set account active database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CloseAccount closes an account in a single database transaction, paying
+// its balance out to payout when it has one. The account must have no
+// active holds, freezes, judicial blocks nor debt. Its pending transfers are
+// rejected and the holds in its favor voided, as it takes no more credits.
+func (r *postgresDB) CloseAccount(ctx context.Context, id uint64, payout *uint64) (account.CloseAccountResponse, error) {
+	response := account.CloseAccountResponse{Id: id}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return response, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var customerId uint64
+		var balance, held, blocked int64
+		var frozen bool
+		var closedAt *time.Time
+
+		query := "select a.customer_id, a.balance, " + heldAmountExpression + ", " + blockedAmountExpression + ", " + frozenExpression +
+			", a.closed_at from accounts a where a.id = $1 for update of a"
+		logger.Log.Debug("Lock closing account query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&customerId, &balance, &held, &blocked, &frozen, &closedAt); err != nil {
+			logger.Log.Error("Lock closing account query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return response, apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		switch {
+		case closedAt != nil:
+			return response, apperrors.NewArgumentError("account already closed")
+		case frozen:
+			return response, apperrors.NewArgumentError("account is frozen")
+		case blocked > 0:
+			return response, apperrors.NewArgumentError("account has judicial blocks")
+		case held > 0:
+			return response, apperrors.NewArgumentError("account has active holds")
+		case balance < 0:
+			return response, apperrors.NewArgumentError("account has a negative balance")
+		case balance > 0 && payout == nil:
+			return response, apperrors.NewArgumentError("account balance must be zero or paid out", "payoutDestination")
+		}
+
+		if balance > 0 {
+			var payoutCustomerId uint64
+
+			payoutQuery := "select customer_id from accounts where id = $1"
+			logger.Log.Debug("Payout account customer query:", payoutQuery, *payout)
+
+			if err := tx.QueryRow(ctx, payoutQuery, *payout).Scan(&payoutCustomerId); err != nil {
+				logger.Log.Error("Payout account customer query error:", err)
+
+				if errors.Is(err, pgx.ErrNoRows) {
+					return response, apperrors.NewArgumentError("payoutDestination account not found")
+				}
+
+				return response, apperrors.NewDatabaseError(err.Error())
+			}
+
+			if payoutCustomerId != customerId {
+				return response, apperrors.NewArgumentError("payoutDestination must be an account of the customer")
+			}
+
+			transferId, err := postTransfer(ctx, tx, id, *payout, balance, transfer.KindPayout, nil)
+
+			if err != nil {
+				return response, err
+			}
+
+			response.PayoutTransferId = &transferId
+			response.PayoutAmount = balance
+		}
+
+		closeQuery := "update accounts set active = false, closed_at = now(), updated_at = now() where id = $1 returning closed_at"
+		logger.Log.Debug("Close account query:", closeQuery, id)
+
+		if err := tx.QueryRow(ctx, closeQuery, id).Scan(&response.ClosedAt); err != nil {
+			logger.Log.Error("Close account query error:", err)
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		pendingQuery := "update pending_transfers set status = $2, decided_at = now() where account_origin_id = $1 and status = $3"
+		logger.Log.Debug("Reject closing account pending transfers query:", pendingQuery, id)
+
+		if _, err := tx.Exec(ctx, pendingQuery, id, transfer.ApprovalRejected, transfer.ApprovalPending); err != nil {
+			logger.Log.Error("Reject closing account pending transfers query error:", err)
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		holdsQuery := "update holds set status = $2, finished_at = now() where destination = $1 and status = $3"
+		logger.Log.Debug("Void closing account holds query:", holdsQuery, id)
+
+		if _, err := tx.Exec(ctx, holdsQuery, id, hold.StatusVoided, hold.StatusActive); err != nil {
+			logger.Log.Error("Void closing account holds query error:", err)
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionAccountClose, audit.Target("account", id),
+			map[string]interface{}{"balance": balance}, response); err != nil {
+			return response, err
+		}
+
+		// The audit event goes first: every transaction takes the audit lock
+		// before the outbox one, so the two never wait on each other.
+		if response.PayoutTransferId != nil {
+			if err := addOutboxEvent(ctx, tx, outbox.TypeTransferCompleted, audit.Target("transfer", *response.PayoutTransferId),
+				outbox.TransferPayload{TransferId: *response.PayoutTransferId, Origin: id, Destination: *payout, Amount: balance}); err != nil {
+				return response, err
+			}
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeAccountClosed, audit.Target("account", id),
+			outbox.AccountPayload{AccountId: id, CustomerId: customerId}); err != nil {
+			return response, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Close account database transaction commit error:", err)
+			return response, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return response, nil
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
@@ -0,0 +1,303 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+const pendingTransferColumns = `p.id, p.account_origin_id, p.account_destination_id, p.amount, p.requested_by,
+					p.required_approvals,
+					(select count(*) from transfer_approvals ta where ta.pending_transfer_id = p.id and ta.approved),
+					p.status, p.error, p.transfer_id, p.created_at, p.decided_at`
+
+// AddPendingTransfer stores a transfer waiting for the approvers of its
+// origin.
+func (r *postgresDB) AddPendingTransfer(ctx context.Context, p transfer.PendingTransfer) (uint64, error) {
+	var id uint64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return id, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into pending_transfers (account_origin_id, account_destination_id, amount, requested_by, required_approvals, status)
+				values ($1, $2, $3, $4, $5, $6) returning id`
+		logger.Log.Debug("Add pending transfer query:", query, p.Origin, p.Destination, p.Amount, p.RequestedBy, p.RequiredApprovals, p.Status)
+
+		if err := tx.QueryRow(ctx, query, p.Origin, p.Destination, p.Amount, p.RequestedBy, p.RequiredApprovals, p.Status).Scan(&id); err != nil {
+			logger.Log.Error("Add pending transfer query error:", err)
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		after := map[string]interface{}{"origin": p.Origin, "destination": p.Destination, "amount": p.Amount,
+			"requiredApprovals": p.RequiredApprovals, "status": p.Status}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionTransferRequest, audit.Target("pending_transfer", id), nil, after); err != nil {
+			return id, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add pending transfer database transaction commit error:", err)
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return id, nil
+	case <-ctx.Done():
+		return id, ctx.Err()
+	}
+}
+
+// DecidePendingTransfer records an approval or rejection by customerId, who
+// must be an approver of the transfer origin and not its requester. A
+// rejection, or the approval that completes the required ones, moves the
+// transfer out of pending so it is decided exactly once.
+func (r *postgresDB) DecidePendingTransfer(ctx context.Context, id uint64, customerId uint64, approve bool) (transfer.PendingTransfer, error) {
+	var p transfer.PendingTransfer
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		lockQuery := "select " + pendingTransferColumns + " from pending_transfers p where p.id = $1 for update"
+		logger.Log.Debug("Lock pending transfer query:", lockQuery, id)
+
+		if p, err = scanPendingTransfer(tx.QueryRow(ctx, lockQuery, id)); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return p, apperrors.NewAccountNotFoundError("pending transfer not found")
+			}
+
+			logger.Log.Error("Lock pending transfer query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		var approver bool
+
+		approverQuery := "select exists (select 1 from account_approvers where account_id = $1 and customer_id = $2)"
+		logger.Log.Debug("Account approver query:", approverQuery, p.Origin, customerId)
+
+		if err := tx.QueryRow(ctx, approverQuery, p.Origin, customerId).Scan(&approver); err != nil {
+			logger.Log.Error("Account approver query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		switch {
+		case !approver:
+			return p, apperrors.NewAuthError("not an approver of the origin account")
+		case p.RequestedBy == customerId:
+			return p, apperrors.NewTransferRequestError("the requester cannot decide on its own transfer")
+		case p.Status != transfer.ApprovalPending:
+			return p, apperrors.NewTransferRequestError("transfer is no longer pending")
+		}
+
+		decisionQuery := `insert into transfer_approvals (pending_transfer_id, customer_id, approved) values ($1, $2, $3)
+						on conflict do nothing`
+		logger.Log.Debug("Add transfer approval query:", decisionQuery, id, customerId, approve)
+		tag, err := tx.Exec(ctx, decisionQuery, id, customerId, approve)
+
+		if err != nil {
+			logger.Log.Error("Add transfer approval query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return p, apperrors.NewTransferRequestError("customer already decided on this transfer")
+		}
+
+		status := transfer.ApprovalPending
+
+		switch {
+		case !approve:
+			status = transfer.ApprovalRejected
+		case p.Approvals+1 >= p.RequiredApprovals:
+			status = transfer.ApprovalApproved
+		}
+
+		updateQuery := `update pending_transfers p set status = $2, decided_at = case when $2 = $3 then null else now() end
+					where p.id = $1 returning ` + pendingTransferColumns
+		logger.Log.Debug("Decide pending transfer query:", updateQuery, id, status)
+
+		before := map[string]interface{}{"status": p.Status, "approvals": p.Approvals}
+
+		if p, err = scanPendingTransfer(tx.QueryRow(ctx, updateQuery, id, status, transfer.ApprovalPending)); err != nil {
+			logger.Log.Error("Decide pending transfer query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		action := audit.ActionTransferReject
+		if approve {
+			action = audit.ActionTransferApprove
+		}
+
+		if err := appendAuditEvent(ctx, tx, action, audit.Target("pending_transfer", id),
+			before, map[string]interface{}{"status": p.Status, "approvals": p.Approvals}); err != nil {
+			return p, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Decide pending transfer database transaction commit error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return p, nil
+	case <-ctx.Done():
+		return p, ctx.Err()
+	}
+}
+
+// FinishPendingTransfer records how the execution of an approved transfer
+// ended.
+func (r *postgresDB) FinishPendingTransfer(ctx context.Context, p transfer.PendingTransfer) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var before string
+
+		lockQuery := "select status from pending_transfers where id = $1 for update"
+		logger.Log.Debug("Lock pending transfer query:", lockQuery, p.Id)
+
+		if err := tx.QueryRow(ctx, lockQuery, p.Id).Scan(&before); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("pending transfer not found")
+			}
+
+			logger.Log.Error("Lock pending transfer query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		query := "update pending_transfers set status = $2, error = $3, transfer_id = $4 where id = $1"
+		logger.Log.Debug("Finish pending transfer query:", query, p.Id, p.Status, p.Error, p.TransferId)
+
+		if _, err := tx.Exec(ctx, query, p.Id, p.Status, p.Error, p.TransferId); err != nil {
+			logger.Log.Error("Finish pending transfer query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		after := map[string]interface{}{"status": p.Status, "error": p.Error, "transferId": p.TransferId}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionTransferFinish, audit.Target("pending_transfer", p.Id),
+			map[string]interface{}{"status": before}, after); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Finish pending transfer database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ListPendingTransfers returns the transfers still pending that customerId
+// requested or approves.
+func (r *postgresDB) ListPendingTransfers(ctx context.Context, customerId uint64) ([]transfer.PendingTransfer, error) {
+	transfers := []transfer.PendingTransfer{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return transfers, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + pendingTransferColumns + ` from pending_transfers p
+				where p.status = $2 and (
+					p.requested_by = $1
+					or exists (select 1 from account_approvers aa where aa.account_id = p.account_origin_id and aa.customer_id = $1)
+				)
+				order by p.id`
+		logger.Log.Debug("List pending transfers query:", query, customerId)
+
+		rows, err := conn.Query(ctx, query, customerId, transfer.ApprovalPending)
+
+		if err != nil {
+			logger.Log.Error("List pending transfers query error:", err)
+			return transfers, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			p, err := scanPendingTransfer(rows)
+
+			if err != nil {
+				logger.Log.Error("List pending transfers scan error:", err)
+				return transfers, apperrors.NewDatabaseError(err.Error())
+			}
+
+			transfers = append(transfers, p)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List pending transfers rows error:", err)
+			return transfers, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return transfers, nil
+	case <-ctx.Done():
+		return transfers, ctx.Err()
+	}
+}
+
+func scanPendingTransfer(row pgx.Row) (transfer.PendingTransfer, error) {
+	var p transfer.PendingTransfer
+
+	err := row.Scan(&p.Id, &p.Origin, &p.Destination, &p.Amount, &p.RequestedBy, &p.RequiredApprovals, &p.Approvals,
+		&p.Status, &p.Error, &p.TransferId, &p.CreatedAt, &p.DecidedAt)
+
+	return p, err
+}
@@ -0,0 +1,179 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+const auditEventColumns = `id, actor_id, client_id, action, target, request_id, ip, before, after,
+					created_at, prev_hash, hash`
+
+// auditChainLockKey is the advisory lock that serializes appends to the audit
+// chain, so that each event is chained to the one committed before it.
+const auditChainLockKey = 4601
+
+// appendAuditEvent records, as part of tx, the actor in ctx doing action on
+// target. The event only exists if tx commits.
+func appendAuditEvent(ctx context.Context, tx pgx.Tx, action string, target string, before interface{}, after interface{}) error {
+	e, err := audit.NewEvent(audit.ActorFrom(ctx), action, target, before, after, time.Now())
+
+	if err != nil {
+		logger.Log.Error("Audit event encode error:", err)
+		return apperrors.NewInternalServerError(err.Error())
+	}
+
+	if _, err := tx.Exec(ctx, "select pg_advisory_xact_lock($1)", auditChainLockKey); err != nil {
+		logger.Log.Error("Audit chain lock error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	e.PrevHash = audit.GenesisHash
+	query := "select hash from audit_events order by id desc limit 1"
+
+	if err := tx.QueryRow(ctx, query).Scan(&e.PrevHash); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		logger.Log.Error("Audit chain head query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	if e.Hash, err = e.ComputeHash(e.PrevHash); err != nil {
+		logger.Log.Error("Audit event hash error:", err)
+		return apperrors.NewInternalServerError(err.Error())
+	}
+
+	query = `insert into audit_events (actor_id, client_id, action, target, request_id, ip, before, after,
+				created_at, prev_hash, hash) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	logger.Log.Debug("Add audit event query:", query, e.ActorId, e.Action, e.Target, e.RequestId)
+
+	if _, err := tx.Exec(ctx, query, e.ActorId, e.ClientId, e.Action, e.Target, e.RequestId, e.Ip,
+		string(e.Before), string(e.After), e.CreatedAt, e.PrevHash, e.Hash); err != nil {
+		logger.Log.Error("Add audit event query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	return nil
+}
+
+func (r *postgresDB) ListAuditEvents(ctx context.Context, q audit.ListEventsQuery) ([]audit.Event, error) {
+	events := []audit.Event{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return events, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + auditEventColumns + ` from audit_events
+				where ($1::bigint is null or actor_id = $1)
+				and ($2 = '' or action = $2)
+				and ($3 = '' or target = $3)
+				and ($4 = '' or request_id = $4)
+				and ($5::timestamptz is null or created_at >= $5)
+				and ($6::timestamptz is null or created_at < $6)
+				and ($7::bigint is null or id < $7)
+				order by id desc
+				limit $8`
+		args := []interface{}{q.ActorId, q.Action, q.Target, q.RequestId, q.From, q.To, q.BeforeId, q.Limit}
+		logger.Log.Debug(append([]interface{}{"List audit events query:", query}, args...)...)
+
+		rows, err := conn.Query(ctx, query, args...)
+
+		if err != nil {
+			logger.Log.Error("List audit events query error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			e, err := scanAuditEvent(rows)
+
+			if err != nil {
+				logger.Log.Error("List audit events scan error:", err)
+				return events, apperrors.NewDatabaseError(err.Error())
+			}
+
+			events = append(events, e)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List audit events rows error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return events, nil
+	case <-ctx.Done():
+		return events, ctx.Err()
+	}
+}
+
+// ListAuditChain returns up to limit events after afterId, in chain order.
+func (r *postgresDB) ListAuditChain(ctx context.Context, afterId uint64, limit int) ([]audit.Event, error) {
+	events := []audit.Event{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return events, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + auditEventColumns + " from audit_events where id > $1 order by id limit $2"
+		logger.Log.Debug("List audit chain query:", query, afterId, limit)
+
+		rows, err := conn.Query(ctx, query, afterId, limit)
+
+		if err != nil {
+			logger.Log.Error("List audit chain query error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			e, err := scanAuditEvent(rows)
+
+			if err != nil {
+				logger.Log.Error("List audit chain scan error:", err)
+				return events, apperrors.NewDatabaseError(err.Error())
+			}
+
+			events = append(events, e)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List audit chain rows error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return events, nil
+	case <-ctx.Done():
+		return events, ctx.Err()
+	}
+}
+
+func scanAuditEvent(row pgx.Row) (audit.Event, error) {
+	var e audit.Event
+	var before, after string
+
+	err := row.Scan(&e.Id, &e.ActorId, &e.ClientId, &e.Action, &e.Target, &e.RequestId, &e.Ip, &before, &after,
+		&e.CreatedAt, &e.PrevHash, &e.Hash)
+
+	e.Before = []byte(before)
+	e.After = []byte(after)
+
+	return e, err
+}
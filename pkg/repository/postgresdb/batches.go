@@ -0,0 +1,179 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// AddTransferBatch stores b and its items in a single database transaction
+// and returns the batch id.
+func (r *postgresDB) AddTransferBatch(ctx context.Context, b transfer.Batch) (uint64, error) {
+	var id uint64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return id, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := "insert into transfer_batches (account_id, mode, status) values ($1, $2, $3) returning id"
+		logger.Log.Debug("Add transfer batch query:", query, b.Origin, b.Mode, b.Status)
+
+		if err := tx.QueryRow(ctx, query, b.Origin, b.Mode, b.Status).Scan(&id); err != nil {
+			logger.Log.Error("Add transfer batch query error:", err)
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		itemQuery := `insert into transfer_batch_items (batch_id, position, destination, amount, status)
+					values ($1, $2, $3, $4, $5)`
+
+		for _, item := range b.Items {
+			logger.Log.Debug("Add transfer batch item query:", itemQuery, id, item.Position, item.Destination, item.Amount, item.Status)
+
+			if _, err := tx.Exec(ctx, itemQuery, id, item.Position, item.Destination, item.Amount, item.Status); err != nil {
+				logger.Log.Error("Add transfer batch item query error:", err)
+				return id, apperrors.NewDatabaseError(err.Error())
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add transfer batch database transaction commit error:", err)
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return id, nil
+	case <-ctx.Done():
+		return id, ctx.Err()
+	}
+}
+
+func (r *postgresDB) UpdateTransferBatchItem(ctx context.Context, batchId uint64, item transfer.BatchItem) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := `update transfer_batch_items set status = $3, fee = $4, error = $5, transfer_id = $6
+				where batch_id = $1 and position = $2`
+		logger.Log.Debug("Update transfer batch item query:", query, batchId, item.Position, item.Status, item.Fee, item.Error, item.TransferId)
+
+		if _, err := conn.Exec(ctx, query, batchId, item.Position, item.Status, item.Fee, item.Error, item.TransferId); err != nil {
+			logger.Log.Error("Update transfer batch item query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) FinishTransferBatch(ctx context.Context, id uint64, status string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := "update transfer_batches set status = $2, finished_at = now() where id = $1"
+		logger.Log.Debug("Finish transfer batch query:", query, id, status)
+
+		if _, err := conn.Exec(ctx, query, id, status); err != nil {
+			logger.Log.Error("Finish transfer batch query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetTransferBatch(ctx context.Context, id uint64) (transfer.Batch, error) {
+	var b transfer.Batch
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return b, err
+		}
+
+		defer conn.Release()
+
+		query := "select id, account_id, mode, status, created_at from transfer_batches where id = $1"
+		logger.Log.Debug("Get transfer batch query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&b.Id, &b.Origin, &b.Mode, &b.Status, &b.CreatedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return b, apperrors.NewAccountNotFoundError("batch not found")
+			}
+
+			logger.Log.Error("Get transfer batch query error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		itemsQuery := `select position, destination, amount, fee, status, error, transfer_id
+					from transfer_batch_items
+					where batch_id = $1
+					order by position`
+		logger.Log.Debug("Get transfer batch items query:", itemsQuery, id)
+
+		rows, err := conn.Query(ctx, itemsQuery, id)
+
+		if err != nil {
+			logger.Log.Error("Get transfer batch items query error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var item transfer.BatchItem
+
+			if err := rows.Scan(&item.Position, &item.Destination, &item.Amount, &item.Fee, &item.Status, &item.Error, &item.TransferId); err != nil {
+				logger.Log.Error("Get transfer batch items scan error:", err)
+				return b, apperrors.NewDatabaseError(err.Error())
+			}
+
+			b.Items = append(b.Items, item)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("Get transfer batch items rows error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return b, nil
+	case <-ctx.Done():
+		return b, ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+)
+
+const apiClientColumns = "id, client_id, secret_hash, name, account_id, scopes, active, created_at"
+
+func (r *postgresDB) AddApiClient(ctx context.Context, c oauth.Client) (oauth.Client, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return c, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return c, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into api_clients (client_id, secret_hash, name, account_id, scopes, active)
+				values ($1, $2, $3, $4, $5, $6) returning ` + apiClientColumns
+		logger.Log.Debug("Add api client query:", query, c.ClientId, c.Name, c.AccountId, c.Scopes)
+
+		c, err = scanApiClient(tx.QueryRow(ctx, query, c.ClientId, c.SecretHash, c.Name, c.AccountId, c.Scopes, c.Active))
+
+		if err != nil {
+			logger.Log.Error("Add api client query error:", err)
+			return c, apperrors.NewDatabaseError(err.Error())
+		}
+
+		// The secret hash is left out of the event by the client JSON encoding.
+		if err := appendAuditEvent(ctx, tx, audit.ActionApiClientCreate, audit.Target("client", c.Id), nil, c); err != nil {
+			return c, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add api client database transaction commit error:", err)
+			return c, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return c, nil
+	case <-ctx.Done():
+		return c, ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetApiClient(ctx context.Context, clientId string) (oauth.Client, error) {
+	var c oauth.Client
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return c, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + apiClientColumns + " from api_clients where client_id = $1"
+		logger.Log.Debug("Get api client query:", query, clientId)
+
+		c, err = scanApiClient(conn.QueryRow(ctx, query, clientId))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return c, apperrors.NewAccountNotFoundError("api client not found")
+			}
+
+			logger.Log.Error("Get api client query error:", err)
+			return c, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return c, nil
+	case <-ctx.Done():
+		return c, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListApiClients(ctx context.Context) ([]oauth.Client, error) {
+	clients := []oauth.Client{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return clients, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + apiClientColumns + " from api_clients order by id"
+		logger.Log.Debug("List api clients query:", query)
+
+		rows, err := conn.Query(ctx, query)
+
+		if err != nil {
+			logger.Log.Error("List api clients query error:", err)
+			return clients, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			c, err := scanApiClient(rows)
+
+			if err != nil {
+				logger.Log.Error("List api clients scan error:", err)
+				return clients, apperrors.NewDatabaseError(err.Error())
+			}
+
+			clients = append(clients, c)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List api clients rows error:", err)
+			return clients, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return clients, nil
+	case <-ctx.Done():
+		return clients, ctx.Err()
+	}
+}
+
+func (r *postgresDB) RevokeApiClient(ctx context.Context, id uint64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var previous bool
+		query := "select active from api_clients where id = $1 for update"
+		logger.Log.Debug("Lock api client query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&previous); err != nil {
+			logger.Log.Error("Lock api client query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("api client not found")
+			}
+
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		query = "update api_clients set active = false where id = $1"
+		logger.Log.Debug("Revoke api client query:", query, id)
+
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			logger.Log.Error("Revoke api client query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionApiClientRevoke, audit.Target("client", id),
+			map[string]interface{}{"active": previous}, map[string]interface{}{"active": false}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Revoke api client database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func scanApiClient(row pgx.Row) (oauth.Client, error) {
+	var c oauth.Client
+
+	err := row.Scan(&c.Id, &c.ClientId, &c.SecretHash, &c.Name, &c.AccountId, &c.Scopes, &c.Active, &c.CreatedAt)
+
+	return c, err
+}
@@ -0,0 +1,378 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/freeze"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+const freezeColumns = "id, account_id, block_credits, reason, reference, created_by, created_at, lifted_by, lifted_at"
+
+const judicialBlockColumns = "id, account_id, amount, reason, reference, created_by, created_at, released_by, released_at"
+
+func (r *postgresDB) AddAccountFreeze(ctx context.Context, f freeze.Freeze) (freeze.Freeze, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return f, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into account_freezes (account_id, block_credits, reason, reference, created_by)
+				values ($1, $2, $3, $4, $5) returning ` + freezeColumns
+		logger.Log.Debug("Add account freeze query:", query, f.AccountId, f.BlockCredits, f.Reason, f.Reference, f.CreatedBy)
+
+		f, err = scanFreeze(tx.QueryRow(ctx, query, f.AccountId, f.BlockCredits, f.Reason, f.Reference, f.CreatedBy))
+
+		if err != nil {
+			logger.Log.Error("Add account freeze query error:", err)
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionAccountFreeze, audit.Target("account", f.AccountId), nil, f); err != nil {
+			return f, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add account freeze database transaction commit error:", err)
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return f, nil
+	case <-ctx.Done():
+		return f, ctx.Err()
+	}
+}
+
+// LiftAccountFreeze lifts a freeze of the account still in force.
+func (r *postgresDB) LiftAccountFreeze(ctx context.Context, accountId uint64, id uint64, author uint64) (freeze.Freeze, error) {
+	var f freeze.Freeze
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return f, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update account_freezes set lifted_by = $1, lifted_at = now()
+				where id = $2 and account_id = $3 and lifted_at is null returning ` + freezeColumns
+		logger.Log.Debug("Lift account freeze query:", query, author, id, accountId)
+
+		f, err = scanFreeze(tx.QueryRow(ctx, query, author, id, accountId))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return f, apperrors.NewAccountNotFoundError("freeze not found")
+			}
+
+			logger.Log.Error("Lift account freeze query error:", err)
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionAccountFreezeLift, audit.Target("account", f.AccountId),
+			nil, f); err != nil {
+			return f, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Lift account freeze database transaction commit error:", err)
+			return f, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return f, nil
+	case <-ctx.Done():
+		return f, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListAccountFreezes(ctx context.Context, accountId uint64) ([]freeze.Freeze, error) {
+	freezes := []freeze.Freeze{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return freezes, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + freezeColumns + " from account_freezes where account_id = $1 order by id desc"
+		logger.Log.Debug("List account freezes query:", query, accountId)
+
+		rows, err := conn.Query(ctx, query, accountId)
+
+		if err != nil {
+			logger.Log.Error("List account freezes query error:", err)
+			return freezes, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			f, err := scanFreeze(rows)
+
+			if err != nil {
+				logger.Log.Error("List account freezes scan error:", err)
+				return freezes, apperrors.NewDatabaseError(err.Error())
+			}
+
+			freezes = append(freezes, f)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List account freezes rows error:", err)
+			return freezes, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return freezes, nil
+	case <-ctx.Done():
+		return freezes, ctx.Err()
+	}
+}
+
+func (r *postgresDB) AddJudicialBlock(ctx context.Context, b freeze.JudicialBlock) (freeze.JudicialBlock, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return b, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into judicial_blocks (account_id, amount, reason, reference, created_by)
+				values ($1, $2, $3, $4, $5) returning ` + judicialBlockColumns
+		logger.Log.Debug("Add judicial block query:", query, b.AccountId, b.Amount, b.Reason, b.Reference, b.CreatedBy)
+
+		b, err = scanJudicialBlock(tx.QueryRow(ctx, query, b.AccountId, b.Amount, b.Reason, b.Reference, b.CreatedBy))
+
+		if err != nil {
+			logger.Log.Error("Add judicial block query error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionJudicialBlock, audit.Target("account", b.AccountId), nil, b); err != nil {
+			return b, err
+		}
+
+		if err := addJudicialBlockEvent(ctx, tx, b); err != nil {
+			return b, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add judicial block database transaction commit error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return b, nil
+	case <-ctx.Done():
+		return b, ctx.Err()
+	}
+}
+
+// ReleaseJudicialBlock releases a judicial block of the account still in
+// force.
+func (r *postgresDB) ReleaseJudicialBlock(ctx context.Context, accountId uint64, id uint64, author uint64) (freeze.JudicialBlock, error) {
+	var b freeze.JudicialBlock
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return b, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update judicial_blocks set released_by = $1, released_at = now()
+				where id = $2 and account_id = $3 and released_at is null returning ` + judicialBlockColumns
+		logger.Log.Debug("Release judicial block query:", query, author, id, accountId)
+
+		b, err = scanJudicialBlock(tx.QueryRow(ctx, query, author, id, accountId))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return b, apperrors.NewAccountNotFoundError("judicial block not found")
+			}
+
+			logger.Log.Error("Release judicial block query error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionJudicialBlockRelease, audit.Target("account", b.AccountId),
+			nil, b); err != nil {
+			return b, err
+		}
+
+		if err := addJudicialBlockEvent(ctx, tx, b); err != nil {
+			return b, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Release judicial block database transaction commit error:", err)
+			return b, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return b, nil
+	case <-ctx.Done():
+		return b, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListJudicialBlocks(ctx context.Context, accountId uint64) ([]freeze.JudicialBlock, error) {
+	blocks := []freeze.JudicialBlock{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return blocks, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + judicialBlockColumns + " from judicial_blocks where account_id = $1 order by id desc"
+		logger.Log.Debug("List judicial blocks query:", query, accountId)
+
+		rows, err := conn.Query(ctx, query, accountId)
+
+		if err != nil {
+			logger.Log.Error("List judicial blocks query error:", err)
+			return blocks, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			b, err := scanJudicialBlock(rows)
+
+			if err != nil {
+				logger.Log.Error("List judicial blocks scan error:", err)
+				return blocks, apperrors.NewDatabaseError(err.Error())
+			}
+
+			blocks = append(blocks, b)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List judicial blocks rows error:", err)
+			return blocks, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return blocks, nil
+	case <-ctx.Done():
+		return blocks, ctx.Err()
+	}
+}
+
+// GetAccountRestrictions returns the freezes and judicial blocks in force on
+// the account, as they limit what it can spend.
+func (r *postgresDB) GetAccountRestrictions(ctx context.Context, id uint64) (account.Restrictions, error) {
+	var res account.Restrictions
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return res, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + frozenExpression + `, exists (
+					select 1 from account_freezes f
+					where f.account_id = a.id and f.block_credits and f.lifted_at is null
+				), ` + blockedAmountExpression + " from accounts a where a.id = $1"
+		logger.Log.Debug("Get account restrictions query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&res.Frozen, &res.CreditsFrozen, &res.Blocked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return res, apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			logger.Log.Error("Get account restrictions query error:", err)
+			return res, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return res, nil
+	case <-ctx.Done():
+		return res, ctx.Err()
+	}
+}
+
+func scanFreeze(row pgx.Row) (freeze.Freeze, error) {
+	var f freeze.Freeze
+
+	err := row.Scan(&f.Id, &f.AccountId, &f.BlockCredits, &f.Reason, &f.Reference, &f.CreatedBy, &f.CreatedAt,
+		&f.LiftedBy, &f.LiftedAt)
+
+	return f, err
+}
+
+func scanJudicialBlock(row pgx.Row) (freeze.JudicialBlock, error) {
+	var b freeze.JudicialBlock
+
+	err := row.Scan(&b.Id, &b.AccountId, &b.Amount, &b.Reason, &b.Reference, &b.CreatedBy, &b.CreatedAt,
+		&b.ReleasedBy, &b.ReleasedAt)
+
+	return b, err
+}
+
+// addJudicialBlockEvent publishes, as part of tx, that b was placed or released.
+func addJudicialBlockEvent(ctx context.Context, tx pgx.Tx, b freeze.JudicialBlock) error {
+	return addOutboxEvent(ctx, tx, outbox.TypeJudicialBlockChanged, audit.Target("account", b.AccountId),
+		outbox.JudicialBlockPayload{BlockId: b.Id, AccountId: b.AccountId, Amount: b.Amount, Released: b.ReleasedAt != nil})
+}
@@ -0,0 +1,449 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+const holdColumns = `id, account_id, destination, amount, captured_amount, reference, status,
+					expires_at, created_at, finished_at, transfer_id`
+
+// AddHold checks the account funds and limits as a transfer would and, when
+// they allow it, reserves the hold amount.
+func (r *postgresDB) AddHold(ctx context.Context, h hold.Hold, w transfer.LimitWindows) (hold.Hold, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return h, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		available, err := lockAvailableBalance(ctx, tx, h.AccountId)
+
+		if err != nil {
+			return h, err
+		}
+
+		if available < h.Amount {
+			return h, apperrors.NewTransferRequestError("not enough funds")
+		}
+
+		t := transfer.Transfer{Origin: h.AccountId, Destination: h.Destination, Amount: h.Amount, Windows: w}
+
+		if err := checkTransferLimits(ctx, tx, t, 0); err != nil {
+			return h, err
+		}
+
+		query := `insert into holds (account_id, destination, amount, reference, status, expires_at)
+				values ($1, $2, $3, $4, $5, $6) returning ` + holdColumns
+		logger.Log.Debug("Add hold query:", query, h.AccountId, h.Destination, h.Amount, h.Reference, h.Status, h.ExpiresAt)
+
+		h, err = scanHold(tx.QueryRow(ctx, query, h.AccountId, h.Destination, h.Amount, h.Reference, h.Status, h.ExpiresAt))
+
+		if err != nil {
+			logger.Log.Error("Add hold query error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionHoldAuthorize, audit.Target("hold", h.Id), nil, h); err != nil {
+			return h, err
+		}
+
+		if err := addHoldEvent(ctx, tx, h); err != nil {
+			return h, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add hold database transaction commit error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return h, nil
+	case <-ctx.Done():
+		return h, ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetHold(ctx context.Context, id uint64) (hold.Hold, error) {
+	var h hold.Hold
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return h, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + holdColumns + " from holds where id = $1"
+		logger.Log.Debug("Get hold query:", query, id)
+
+		h, err = scanHold(conn.QueryRow(ctx, query, id))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return h, apperrors.NewAccountNotFoundError("hold not found")
+			}
+
+			logger.Log.Error("Get hold query error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return h, nil
+	case <-ctx.Done():
+		return h, ctx.Err()
+	}
+}
+
+// CaptureHold settles amount of an active hold as a transfer, in a single
+// database transaction. What is left of the hold is released. The capture is
+// checked against the origin limits in w again, as they may have been lowered
+// or used up by other holds since the authorization.
+func (r *postgresDB) CaptureHold(ctx context.Context, id uint64, amount int64, w transfer.LimitWindows) (hold.Hold, error) {
+	var h hold.Hold
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return h, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		h, err = lockActiveHold(ctx, tx, id)
+
+		if err != nil {
+			return h, err
+		}
+
+		if amount > h.Amount {
+			return h, apperrors.NewArgumentError("amount must be at most the hold amount")
+		}
+
+		// The funds were reserved on authorization, so they are not checked
+		// again: the ledger balance is debited even if it goes negative. A
+		// freeze since then still stops the capture.
+		if _, err := lockAvailableBalance(ctx, tx, h.AccountId); err != nil {
+			return h, err
+		}
+
+		t := transfer.Transfer{Origin: h.AccountId, Destination: h.Destination, Amount: amount, Windows: w}
+
+		if err := checkTransferLimits(ctx, tx, t, h.Id); err != nil {
+			return h, err
+		}
+
+		transferId, err := postTransfer(ctx, tx, h.AccountId, h.Destination, amount, transfer.KindTransfer, nil)
+
+		if err != nil {
+			return h, err
+		}
+
+		query := `update holds set status = $2, captured_amount = $3, transfer_id = $4, finished_at = now()
+				where id = $1 returning ` + holdColumns
+		logger.Log.Debug("Capture hold query:", query, id, amount, transferId)
+
+		before := h
+		h, err = scanHold(tx.QueryRow(ctx, query, id, hold.StatusCaptured, amount, transferId))
+
+		if err != nil {
+			logger.Log.Error("Capture hold query error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionHoldCapture, audit.Target("hold", id), before, h); err != nil {
+			return h, err
+		}
+
+		if err := addHoldEvent(ctx, tx, h); err != nil {
+			return h, err
+		}
+
+		payload := outbox.TransferPayload{TransferId: transferId, Origin: h.AccountId, Destination: h.Destination, Amount: amount}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeTransferCompleted, audit.Target("transfer", transferId), payload); err != nil {
+			return h, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Capture hold database transaction commit error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return h, nil
+	case <-ctx.Done():
+		return h, ctx.Err()
+	}
+}
+
+func (r *postgresDB) VoidHold(ctx context.Context, id uint64) (hold.Hold, error) {
+	var h hold.Hold
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return h, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		before, err := lockActiveHold(ctx, tx, id)
+
+		if err != nil {
+			return h, err
+		}
+
+		query := "update holds set status = $2, finished_at = now() where id = $1 returning " + holdColumns
+		logger.Log.Debug("Void hold query:", query, id)
+
+		h, err = scanHold(tx.QueryRow(ctx, query, id, hold.StatusVoided))
+
+		if err != nil {
+			logger.Log.Error("Void hold query error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionHoldVoid, audit.Target("hold", id), before, h); err != nil {
+			return h, err
+		}
+
+		if err := addHoldEvent(ctx, tx, h); err != nil {
+			return h, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Void hold database transaction commit error:", err)
+			return h, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return h, nil
+	case <-ctx.Done():
+		return h, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListAccountHolds(ctx context.Context, id uint64) ([]hold.Hold, error) {
+	holds := []hold.Hold{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return holds, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + holdColumns + " from holds where account_id = $1 or destination = $1 order by id desc"
+		logger.Log.Debug("List account holds query:", query, id)
+
+		rows, err := conn.Query(ctx, query, id)
+
+		if err != nil {
+			logger.Log.Error("List account holds query error:", err)
+			return holds, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			h, err := scanHold(rows)
+
+			if err != nil {
+				logger.Log.Error("List account holds scan error:", err)
+				return holds, apperrors.NewDatabaseError(err.Error())
+			}
+
+			holds = append(holds, h)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List account holds rows error:", err)
+			return holds, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return holds, nil
+	case <-ctx.Done():
+		return holds, ctx.Err()
+	}
+}
+
+// ExpireHolds marks the active holds past their expiry as expired, publishing
+// each, and returns how many were. They stop reducing the available balance
+// at expiry anyway, this only records it.
+func (r *postgresDB) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return 0, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return 0, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := "update holds set status = $1, finished_at = $3 where status = $2 and expires_at <= $3 returning " + holdColumns
+		logger.Log.Debug("Expire holds query:", query, now)
+
+		rows, err := tx.Query(ctx, query, hold.StatusExpired, hold.StatusActive, now)
+
+		if err != nil {
+			logger.Log.Error("Expire holds query error:", err)
+			return 0, apperrors.NewDatabaseError(err.Error())
+		}
+
+		var expired []hold.Hold
+
+		for rows.Next() {
+			h, err := scanHold(rows)
+
+			if err != nil {
+				rows.Close()
+				logger.Log.Error("Expire holds scan error:", err)
+				return 0, apperrors.NewDatabaseError(err.Error())
+			}
+
+			expired = append(expired, h)
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("Expire holds rows error:", err)
+			return 0, apperrors.NewDatabaseError(err.Error())
+		}
+
+		for _, h := range expired {
+			if err := addHoldEvent(ctx, tx, h); err != nil {
+				return 0, err
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Expire holds database transaction commit error:", err)
+			return 0, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return len(expired), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetHeldAmount(ctx context.Context, id uint64) (int64, error) {
+	var held int64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return held, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + heldAmountExpression + " from accounts a where a.id = $1"
+		logger.Log.Debug("Get held amount query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&held); err != nil {
+			logger.Log.Error("Get held amount query error:", err)
+			return held, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return held, nil
+	case <-ctx.Done():
+		return held, ctx.Err()
+	}
+}
+
+// lockActiveHold locks the hold row until the end of tx, failing when it can
+// no longer be captured or voided.
+func lockActiveHold(ctx context.Context, tx pgx.Tx, id uint64) (hold.Hold, error) {
+	query := "select " + holdColumns + " from holds where id = $1 for update"
+	logger.Log.Debug("Lock active hold query:", query, id)
+
+	h, err := scanHold(tx.QueryRow(ctx, query, id))
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return h, apperrors.NewAccountNotFoundError("hold not found")
+		}
+
+		logger.Log.Error("Lock active hold query error:", err)
+		return h, apperrors.NewDatabaseError(err.Error())
+	}
+
+	if h.Status != hold.StatusActive || !h.ExpiresAt.After(time.Now()) {
+		return h, apperrors.NewTransferRequestError("hold is no longer active")
+	}
+
+	return h, nil
+}
+
+func scanHold(row pgx.Row) (hold.Hold, error) {
+	var h hold.Hold
+
+	err := row.Scan(&h.Id, &h.AccountId, &h.Destination, &h.Amount, &h.CapturedAmount, &h.Reference, &h.Status,
+		&h.ExpiresAt, &h.CreatedAt, &h.FinishedAt, &h.TransferId)
+
+	return h, err
+}
+
+// addHoldEvent publishes, as part of tx, the hold as it is now.
+func addHoldEvent(ctx context.Context, tx pgx.Tx, h hold.Hold) error {
+	return addOutboxEvent(ctx, tx, outbox.TypeHoldChanged, audit.Target("hold", h.Id),
+		outbox.HoldPayload{HoldId: h.Id, AccountId: h.AccountId, Amount: h.Amount, CapturedAmount: h.CapturedAmount, Status: h.Status})
+}
@@ -0,0 +1,440 @@
+package postgresdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/interest"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// closingBalanceExpression is the balance of the account a at $2, the current
+// one with the transfers posted since undone.
+const closingBalanceExpression = `a.balance
+	- coalesce((select sum(t.amount) from transfers t where t.account_destination_id = a.id and t.created_at >= $2), 0)
+	+ coalesce((select sum(t.amount) from transfers t where t.account_origin_id = a.id and t.created_at >= $2), 0)`
+
+// GetLastAccrualDate returns the latest date interest was accrued for, nil
+// when it never was.
+func (r *postgresDB) GetLastAccrualDate(ctx context.Context) (*time.Time, error) {
+	return r.getLastDate(ctx, "Last accrual date", "select max(accrual_date) from interest_accruals")
+}
+
+// ListAccountsToAccrue returns the savings accounts not accrued for date yet,
+// with their balance at end, the close of date.
+func (r *postgresDB) ListAccountsToAccrue(ctx context.Context, date time.Time, end time.Time) ([]interest.SavingsAccount, error) {
+	accounts := []interest.SavingsAccount{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return accounts, err
+		}
+
+		defer conn.Release()
+
+		query := `select 
+					a.id, 
+					b.balance, 
+					p.interest_rate_bps 
+				from accounts as a
+				inner join account_products as p
+					on a.product = p.code
+				cross join lateral (select ` + closingBalanceExpression + ` as balance) as b
+				where 
+					a.active
+					and a.created_at < $2
+					and b.balance > 0
+					and p.interest_rate_bps > 0
+					and a.closed_at is null
+					and a.id not in (select account_id from system_accounts)
+					and not exists (
+						select 1 from interest_accruals as ia 
+						where ia.account_id = a.id and ia.accrual_date = $1
+					)
+				order by a.id;`
+		logger.Log.Debug("List accounts to accrue query:", query, date, end)
+		rows, err := conn.Query(ctx, query, date, end)
+
+		if err != nil {
+			logger.Log.Error("List accounts to accrue query error:", err)
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var account interest.SavingsAccount
+
+			if err := rows.Scan(&account.Id, &account.Balance, &account.RateBps); err != nil {
+				return accounts, apperrors.NewDatabaseError(err.Error())
+			}
+
+			accounts = append(accounts, account)
+		}
+
+		if err := rows.Err(); err != nil {
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return accounts, nil
+	case <-ctx.Done():
+		return accounts, ctx.Err()
+	}
+}
+
+func (r *postgresDB) AddInterestAccrual(ctx context.Context, a interest.Accrual) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := `insert into interest_accruals (account_id, accrual_date, balance, rate_bps, amount) 
+				values ($1, $2, $3, $4, $5) 
+				on conflict (account_id, accrual_date) do nothing`
+		logger.Log.Debug("Add interest accrual query:", query, a.AccountId, a.Date, a.Amount)
+
+		if _, err := conn.Exec(ctx, query, a.AccountId, a.Date, a.Balance, a.RateBps, a.Amount); err != nil {
+			logger.Log.Error("Add interest accrual query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PayInterestAccruals credits, one account per database transaction, the
+// accruals dated before the given date that were not paid yet. It returns how
+// many accounts were credited.
+func (r *postgresDB) PayInterestAccruals(ctx context.Context, before time.Time) (int, error) {
+	var paid int
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return paid, err
+		}
+
+		defer conn.Release()
+
+		// Closed accounts take no credits, their unpaid interest is forfeited.
+		// Accounts frozen for credits are paid once the freeze is lifted.
+		query := `select distinct i.account_id from interest_accruals i
+				inner join accounts a on a.id = i.account_id
+				where i.paid_at is null and i.accrual_date < $1 and a.closed_at is null
+				and not exists (
+					select 1 from account_freezes f
+					where f.account_id = a.id and f.block_credits and f.lifted_at is null
+				)
+				order by i.account_id`
+		logger.Log.Debug("Accounts with unpaid interest query:", query, before)
+		rows, err := conn.Query(ctx, query, before)
+
+		if err != nil {
+			logger.Log.Error("Accounts with unpaid interest query error:", err)
+			return paid, apperrors.NewDatabaseError(err.Error())
+		}
+
+		var ids []uint64
+
+		for rows.Next() {
+			var id uint64
+
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return paid, apperrors.NewDatabaseError(err.Error())
+			}
+
+			ids = append(ids, id)
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return paid, apperrors.NewDatabaseError(err.Error())
+		}
+
+		for _, id := range ids {
+			credited, err := payAccountInterest(ctx, conn, id, before)
+
+			if err != nil {
+				return paid, err
+			}
+
+			if credited {
+				paid++
+			}
+		}
+
+		return paid, nil
+	case <-ctx.Done():
+		return paid, ctx.Err()
+	}
+}
+
+func payAccountInterest(ctx context.Context, conn beginner, accountId uint64, before time.Time) (bool, error) {
+	tx, err := conn.Begin(ctx)
+
+	if err != nil {
+		return false, apperrors.NewDatabaseError(err.Error())
+	}
+
+	defer tx.Rollback(ctx)
+
+	var amount int64
+	markQuery := `with paid as (
+					update interest_accruals set paid_at = now() 
+					where account_id = $1 and paid_at is null and accrual_date < $2 
+					returning amount
+				)
+				select coalesce(sum(amount), 0) from paid`
+	logger.Log.Debug("Mark interest paid query:", markQuery, accountId, before)
+
+	if err := tx.QueryRow(ctx, markQuery, accountId, before).Scan(&amount); err != nil {
+		logger.Log.Error("Mark interest paid query error:", err)
+		return false, apperrors.NewDatabaseError(err.Error())
+	}
+
+	if amount > 0 {
+		origin, err := systemAccountId(ctx, tx, interestExpenseAccount)
+
+		if err != nil {
+			return false, err
+		}
+
+		transferId, err := postTransfer(ctx, tx, origin, accountId, amount, transfer.KindInterest, nil)
+
+		if err != nil {
+			return false, err
+		}
+
+		linkQuery := "update interest_accruals set transfer_id = $1 where account_id = $2 and paid_at = now() and transfer_id is null"
+		if _, err := tx.Exec(ctx, linkQuery, transferId, accountId); err != nil {
+			logger.Log.Error("Link interest transfer query error:", err)
+			return false, apperrors.NewDatabaseError(err.Error())
+		}
+
+		payload := outbox.PostingPayload{TransferId: transferId, Origin: origin, Destination: accountId, Amount: amount, Kind: transfer.KindInterest}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypePostingCompleted, audit.Target("transfer", transferId), payload); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		logger.Log.Error("Pay interest database transaction commit error:", err)
+		return false, apperrors.NewDatabaseError(err.Error())
+	}
+
+	return amount > 0, nil
+}
+
+func (r *postgresDB) GetAccruedInterest(ctx context.Context, id uint64) (int64, error) {
+	var accrued int64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return accrued, err
+		}
+
+		defer conn.Release()
+
+		query := "select coalesce(sum(amount), 0) from interest_accruals where account_id = $1 and paid_at is null"
+		logger.Log.Debug("Get accrued interest query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&accrued); err != nil {
+			logger.Log.Error("Get accrued interest query error:", err)
+			return accrued, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return accrued, nil
+	case <-ctx.Done():
+		return accrued, ctx.Err()
+	}
+}
+
+// GetLastOverdraftChargeDate returns the latest date overdraft interest was
+// charged for, nil when it never was.
+func (r *postgresDB) GetLastOverdraftChargeDate(ctx context.Context) (*time.Time, error) {
+	return r.getLastDate(ctx, "Last overdraft charge date", "select max(charge_date) from overdraft_charges")
+}
+
+// ListOverdrawnAccounts returns the accounts not charged for date yet that
+// closed it, at end, with a negative balance.
+func (r *postgresDB) ListOverdrawnAccounts(ctx context.Context, date time.Time, end time.Time) ([]interest.OverdrawnAccount, error) {
+	accounts := []interest.OverdrawnAccount{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return accounts, err
+		}
+
+		defer conn.Release()
+
+		query := `select 
+					a.id, 
+					b.balance, 
+					p.overdraft_rate_bps 
+				from accounts as a
+				inner join account_products as p
+					on a.product = p.code
+				cross join lateral (select ` + closingBalanceExpression + ` as balance) as b
+				where 
+					a.created_at < $2
+					and b.balance < 0
+					and p.overdraft_rate_bps > 0
+					and a.closed_at is null
+					and a.id not in (select account_id from system_accounts)
+					and not exists (
+						select 1 from overdraft_charges as oc 
+						where oc.account_id = a.id and oc.charge_date = $1
+					)
+				order by a.id;`
+		logger.Log.Debug("List overdrawn accounts query:", query, date, end)
+		rows, err := conn.Query(ctx, query, date, end)
+
+		if err != nil {
+			logger.Log.Error("List overdrawn accounts query error:", err)
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var account interest.OverdrawnAccount
+
+			if err := rows.Scan(&account.Id, &account.Balance, &account.RateBps); err != nil {
+				return accounts, apperrors.NewDatabaseError(err.Error())
+			}
+
+			accounts = append(accounts, account)
+		}
+
+		if err := rows.Err(); err != nil {
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return accounts, nil
+	case <-ctx.Done():
+		return accounts, ctx.Err()
+	}
+}
+
+// AddOverdraftCharge records the charge and debits it from the account in the
+// same database transaction. A day already charged is left untouched.
+func (r *postgresDB) AddOverdraftCharge(ctx context.Context, c interest.OverdraftCharge) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into overdraft_charges (account_id, charge_date, balance, rate_bps, amount) 
+				values ($1, $2, $3, $4, $5) 
+				on conflict (account_id, charge_date) do nothing`
+		logger.Log.Debug("Add overdraft charge query:", query, c.AccountId, c.Date, c.Amount)
+		tag, err := tx.Exec(ctx, query, c.AccountId, c.Date, c.Balance, c.RateBps, c.Amount)
+
+		if err != nil {
+			logger.Log.Error("Add overdraft charge query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		destination, err := systemAccountId(ctx, tx, overdraftRevenueAccount)
+
+		if err != nil {
+			return err
+		}
+
+		transferId, err := postTransfer(ctx, tx, c.AccountId, destination, c.Amount, transfer.KindOverdraftInterest, nil)
+
+		if err != nil {
+			return err
+		}
+
+		linkQuery := "update overdraft_charges set transfer_id = $1 where account_id = $2 and charge_date = $3"
+		if _, err := tx.Exec(ctx, linkQuery, transferId, c.AccountId, c.Date); err != nil {
+			logger.Log.Error("Link overdraft charge transfer query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		payload := outbox.PostingPayload{TransferId: transferId, Origin: c.AccountId, Destination: destination, Amount: c.Amount,
+			Kind: transfer.KindOverdraftInterest}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypePostingCompleted, audit.Target("transfer", transferId), payload); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add overdraft charge database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) getLastDate(ctx context.Context, name string, query string) (*time.Time, error) {
+	var date *time.Time
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return date, err
+		}
+
+		defer conn.Release()
+
+		logger.Log.Debug(name+" query:", query)
+
+		if err := conn.QueryRow(ctx, query).Scan(&date); err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return date, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return date, nil
+	case <-ctx.Done():
+		return date, ctx.Err()
+	}
+}
@@ -0,0 +1,133 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// System accounts purposes, matching the system_accounts table.
+const (
+	interestExpenseAccount  = "interest_expense"
+	overdraftRevenueAccount = "overdraft_revenue"
+	feeRevenueAccount       = "fee_revenue"
+)
+
+type queryRower interface {
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+}
+
+// systemAccountId returns the bank owned account used for purpose.
+func systemAccountId(ctx context.Context, q queryRower, purpose string) (uint64, error) {
+	var id uint64
+
+	query := "select account_id from system_accounts where purpose = $1"
+	logger.Log.Debug("System account query:", query, purpose)
+
+	if err := q.QueryRow(ctx, query, purpose).Scan(&id); err != nil {
+		logger.Log.Error("System account query error:", err)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return id, apperrors.NewAccountNotFoundError("system account not found", purpose)
+		}
+
+		return id, apperrors.NewDatabaseError(err.Error())
+	}
+
+	return id, nil
+}
+
+// heldAmountExpression sums the active holds on the account aliased a.
+const heldAmountExpression = `coalesce((
+		select sum(h.amount) from holds h
+		where h.account_id = a.id and h.status = 'active' and h.expires_at > now()
+	), 0)`
+
+// blockedAmountExpression sums the judicial blocks in force on the account
+// aliased a.
+const blockedAmountExpression = `coalesce((
+		select sum(b.amount) from judicial_blocks b
+		where b.account_id = a.id and b.released_at is null
+	), 0)`
+
+// frozenExpression tells whether the account aliased a has a freeze in force.
+const frozenExpression = `exists (
+		select 1 from account_freezes f
+		where f.account_id = a.id and f.lifted_at is null
+	)`
+
+// lockAvailableBalance locks the account row until the end of tx and returns
+// how much can be debited from it, counting its overdraft limit, holds and
+// judicial blocks. Frozen accounts cannot be debited at all.
+func lockAvailableBalance(ctx context.Context, tx pgx.Tx, id uint64) (int64, error) {
+	var available int64
+	var frozen bool
+
+	query := "select a.balance + a.overdraft_limit - " + heldAmountExpression + " - " + blockedAmountExpression + ", " +
+		frozenExpression + " from accounts a where a.id = $1 for update of a"
+	logger.Log.Debug("Lock available balance query:", query, id)
+
+	if err := tx.QueryRow(ctx, query, id).Scan(&available, &frozen); err != nil {
+		logger.Log.Error("Lock available balance query error:", err)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return available, apperrors.NewAccountNotFoundError("account not found")
+		}
+
+		return available, apperrors.NewDatabaseError(err.Error())
+	}
+
+	if frozen {
+		return available, apperrors.NewTransferRequestError("account is frozen")
+	}
+
+	return available, nil
+}
+
+// postTransfer records a ledger movement, optionally related to another one,
+// and applies it to both balances. It does not check funds, callers are
+// responsible for that, but refuses destination accounts closed or frozen for
+// credits.
+func postTransfer(ctx context.Context, tx pgx.Tx, origin uint64, destination uint64, amount int64, kind string, related *uint64) (uint64, error) {
+	var id uint64
+
+	insertTransferQuery := "insert into transfers (account_origin_id, account_destination_id, amount, kind, related_id) values ($1, $2, $3, $4, $5) returning id"
+	originBalanceQuery := "update accounts set balance = balance - $1, updated_at = now() where id = $2"
+	destinationBalanceQuery := `update accounts a set balance = balance + $1, updated_at = now()
+		where a.id = $2 and a.closed_at is null and not exists (
+			select 1 from account_freezes f
+			where f.account_id = a.id and f.block_credits and f.lifted_at is null
+		)`
+	logger.Log.Debug("Post transfer query:", insertTransferQuery, origin, destination, amount, kind)
+
+	if err := tx.QueryRow(ctx, insertTransferQuery, origin, destination, amount, kind, related).Scan(&id); err != nil {
+		logger.Log.Error("Post transfer insert transfer query error:", err)
+		return id, apperrors.NewDatabaseError(err.Error())
+	}
+
+	if _, err := tx.Exec(ctx, originBalanceQuery, amount, origin); err != nil {
+		logger.Log.Error("Post transfer origin balance query error:", err)
+		return id, apperrors.NewDatabaseError(err.Error())
+	}
+
+	tag, err := tx.Exec(ctx, destinationBalanceQuery, amount, destination)
+
+	if err != nil {
+		logger.Log.Error("Post transfer destination balance query error:", err)
+		return id, apperrors.NewDatabaseError(err.Error())
+	}
+
+	if tag.RowsAffected() == 0 {
+		return id, apperrors.NewTransferRequestError("destination account is closed or frozen")
+	}
+
+	return id, nil
+}
+
+type beginner interface {
+	Begin(context.Context) (pgx.Tx, error)
+}
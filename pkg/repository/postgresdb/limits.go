@@ -0,0 +1,448 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// limitColumns maps the limit kinds to their accounts columns. Queries only
+// ever interpolate values from here.
+var limitColumns = map[string]string{
+	limits.PerTransaction: "limit_per_transaction",
+	limits.Daily:          "limit_daily",
+	limits.Monthly:        "limit_monthly",
+	limits.Nightly:        "limit_nightly",
+}
+
+// checkTransferLimits checks t against the origin limits, counting the active
+// holds of the origin along with its transfers. The hold being captured, if
+// any, is left out of the sums since t is its capture. The origin row must
+// already be locked by tx so concurrent transfers are summed one at a time.
+func checkTransferLimits(ctx context.Context, tx pgx.Tx, t transfer.Transfer, capturing uint64) error {
+	var l limits.Limits
+	var daySum, monthSum, nightSum int64
+
+	limitsQuery := "select limit_per_transaction, limit_daily, limit_monthly, limit_nightly from accounts where id = $1"
+	logger.Log.Debug("Transfer limits query:", limitsQuery, t.Origin)
+
+	if err := tx.QueryRow(ctx, limitsQuery, t.Origin).Scan(&l.PerTransaction, &l.Daily, &l.Monthly, &l.Nightly); err != nil {
+		logger.Log.Error("Transfer limits query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	if t.Amount > l.PerTransaction {
+		return apperrors.NewTransferRequestError("amount exceeds the per transaction limit")
+	}
+
+	night := !t.Windows.NightStart.IsZero()
+
+	if night && t.Amount > l.Nightly {
+		return apperrors.NewTransferRequestError("amount exceeds the nightly limit")
+	}
+
+	sumsQuery := `select 
+					coalesce(sum(case when created_at >= $2 then amount else 0 end), 0),
+					coalesce(sum(case when created_at >= $3 then amount else 0 end), 0),
+					coalesce(sum(case when $5 and created_at >= $4 then amount else 0 end), 0)
+				from (
+					select amount, created_at from transfers 
+					where 
+						account_origin_id = $1 
+						and kind = $6
+						and created_at >= least($2, $3, $4)
+					union all
+					select amount, created_at from holds
+					where
+						account_id = $1
+						and status = $7
+						and expires_at > now()
+						and id <> $8
+						and created_at >= least($2, $3, $4)
+				) as spent`
+	logger.Log.Debug("Transfer limits sums query:", sumsQuery, t.Origin, capturing)
+
+	if err := tx.QueryRow(ctx, sumsQuery, t.Origin, t.Windows.DayStart, t.Windows.MonthStart, nightStart(t.Windows), night, transfer.KindTransfer, hold.StatusActive, capturing).Scan(&daySum, &monthSum, &nightSum); err != nil {
+		logger.Log.Error("Transfer limits sums query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	switch {
+	case daySum+t.Amount > l.Daily:
+		return apperrors.NewTransferRequestError("amount exceeds the daily limit")
+	case monthSum+t.Amount > l.Monthly:
+		return apperrors.NewTransferRequestError("amount exceeds the monthly limit")
+	case night && nightSum+t.Amount > l.Nightly:
+		return apperrors.NewTransferRequestError("amount exceeds the nightly limit")
+	}
+
+	return nil
+}
+
+// nightStart is the night window start to query with, the day start outside
+// nighttime so it never widens the period read.
+func nightStart(w transfer.LimitWindows) time.Time {
+	if w.NightStart.IsZero() {
+		return w.DayStart
+	}
+
+	return w.NightStart
+}
+
+func (r *postgresDB) GetAccountLimits(ctx context.Context, id uint64) (limits.Limits, error) {
+	var l limits.Limits
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return l, err
+		}
+
+		defer conn.Release()
+
+		query := "select limit_per_transaction, limit_daily, limit_monthly, limit_nightly from accounts where id = $1"
+		logger.Log.Debug("Get account limits query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&l.PerTransaction, &l.Daily, &l.Monthly, &l.Nightly); err != nil {
+			logger.Log.Error("Get account limits query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return l, apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return l, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return l, nil
+	case <-ctx.Done():
+		return l, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListPendingLimitChanges(ctx context.Context, id uint64) ([]limits.PendingChange, error) {
+	pending := []limits.PendingChange{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return pending, err
+		}
+
+		defer conn.Release()
+
+		query := `select kind, amount, effective_at 
+				from limit_change_requests 
+				where account_id = $1 and applied_at is null and cancelled_at is null 
+				order by effective_at`
+		logger.Log.Debug("List pending limit changes query:", query, id)
+		rows, err := conn.Query(ctx, query, id)
+
+		if err != nil {
+			logger.Log.Error("List pending limit changes query error:", err)
+			return pending, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var change limits.PendingChange
+
+			if err := rows.Scan(&change.Kind, &change.Amount, &change.EffectiveAt); err != nil {
+				return pending, apperrors.NewDatabaseError(err.Error())
+			}
+
+			pending = append(pending, change)
+		}
+
+		if err := rows.Err(); err != nil {
+			return pending, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return pending, nil
+	case <-ctx.Done():
+		return pending, ctx.Err()
+	}
+}
+
+// LowerAccountLimit applies the new limit right away and cancels any pending
+// raise of the same kind.
+func (r *postgresDB) LowerAccountLimit(ctx context.Context, id uint64, kind string, amount int64) error {
+	column, ok := limitColumns[kind]
+
+	if !ok {
+		return apperrors.NewArgumentError("unknown limit", kind)
+	}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		previous, err := lockAccountLimit(ctx, tx, id, column)
+
+		if err != nil {
+			return err
+		}
+
+		if err := cancelPendingLimitChanges(ctx, tx, id, kind); err != nil {
+			return err
+		}
+
+		query := "update accounts set " + column + " = $1, updated_at = now() where id = $2"
+		logger.Log.Debug("Lower account limit query:", query, amount, id)
+
+		if _, err := tx.Exec(ctx, query, amount, id); err != nil {
+			logger.Log.Error("Lower account limit query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionLimitLower, audit.Target("account", id),
+			map[string]interface{}{kind: previous}, map[string]interface{}{kind: amount}); err != nil {
+			return err
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeLimitChanged, audit.Target("account", id),
+			outbox.LimitChangedPayload{AccountId: id, Kind: kind, Previous: previous, Amount: amount, EffectiveAt: time.Now()}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Lower account limit database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AddLimitChangeRequest schedules a raise, replacing any pending one of the
+// same kind.
+func (r *postgresDB) AddLimitChangeRequest(ctx context.Context, id uint64, c limits.PendingChange) error {
+	if _, ok := limitColumns[c.Kind]; !ok {
+		return apperrors.NewArgumentError("unknown limit", c.Kind)
+	}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		column := limitColumns[c.Kind]
+		previous, err := lockAccountLimit(ctx, tx, id, column)
+
+		if err != nil {
+			return err
+		}
+
+		if err := cancelPendingLimitChanges(ctx, tx, id, c.Kind); err != nil {
+			return err
+		}
+
+		query := "insert into limit_change_requests (account_id, kind, amount, effective_at) values ($1, $2, $3, $4)"
+		logger.Log.Debug("Add limit change request query:", query, id, c.Kind, c.Amount, c.EffectiveAt)
+
+		if _, err := tx.Exec(ctx, query, id, c.Kind, c.Amount, c.EffectiveAt); err != nil {
+			logger.Log.Error("Add limit change request query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		after := map[string]interface{}{"kind": c.Kind, "amount": c.Amount, "effectiveAt": c.EffectiveAt}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionLimitRaiseRequest, audit.Target("account", id), nil, after); err != nil {
+			return err
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeLimitChanged, audit.Target("account", id),
+			outbox.LimitChangedPayload{AccountId: id, Kind: c.Kind, Previous: previous, Amount: c.Amount, Pending: true, EffectiveAt: c.EffectiveAt}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add limit change request database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lockAccountLimit locks the account row until the end of tx and returns the
+// current value of the limit column.
+func lockAccountLimit(ctx context.Context, tx pgx.Tx, id uint64, column string) (int64, error) {
+	var amount int64
+
+	query := "select " + column + " from accounts where id = $1 for update"
+	logger.Log.Debug("Lock account limit query:", query, id)
+
+	if err := tx.QueryRow(ctx, query, id).Scan(&amount); err != nil {
+		logger.Log.Error("Lock account limit query error:", err)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return amount, apperrors.NewAccountNotFoundError("account not found")
+		}
+
+		return amount, apperrors.NewDatabaseError(err.Error())
+	}
+
+	return amount, nil
+}
+
+func cancelPendingLimitChanges(ctx context.Context, tx pgx.Tx, id uint64, kind string) error {
+	query := `update limit_change_requests set cancelled_at = now() 
+			where account_id = $1 and kind = $2 and applied_at is null and cancelled_at is null`
+	logger.Log.Debug("Cancel pending limit changes query:", query, id, kind)
+
+	if _, err := tx.Exec(ctx, query, id, kind); err != nil {
+		logger.Log.Error("Cancel pending limit changes query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	return nil
+}
+
+// ApplyDueLimitChanges applies, in a single database transaction, the pending
+// raises effective at now. It returns how many were applied.
+func (r *postgresDB) ApplyDueLimitChanges(ctx context.Context, now time.Time) (int, error) {
+	var applied int
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return applied, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return applied, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update limit_change_requests set applied_at = now() 
+				where applied_at is null and cancelled_at is null and effective_at <= $1 
+				returning account_id, kind, amount`
+		logger.Log.Debug("Apply due limit changes query:", query, now)
+		rows, err := tx.Query(ctx, query, now)
+
+		if err != nil {
+			logger.Log.Error("Apply due limit changes query error:", err)
+			return applied, apperrors.NewDatabaseError(err.Error())
+		}
+
+		type change struct {
+			accountId uint64
+			kind      string
+			amount    int64
+		}
+		var changes []change
+
+		for rows.Next() {
+			var c change
+
+			if err := rows.Scan(&c.accountId, &c.kind, &c.amount); err != nil {
+				rows.Close()
+				return applied, apperrors.NewDatabaseError(err.Error())
+			}
+
+			changes = append(changes, c)
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return applied, apperrors.NewDatabaseError(err.Error())
+		}
+
+		for _, c := range changes {
+			column, ok := limitColumns[c.kind]
+
+			if !ok {
+				logger.Log.Warn("Skipping limit change of unknown kind", c.kind)
+				continue
+			}
+
+			previous, err := lockAccountLimit(ctx, tx, c.accountId, column)
+
+			if err != nil {
+				return applied, err
+			}
+
+			updateQuery := "update accounts set " + column + " = $1, updated_at = now() where id = $2"
+
+			if _, err := tx.Exec(ctx, updateQuery, c.amount, c.accountId); err != nil {
+				logger.Log.Error("Apply limit change query error:", err)
+				return applied, apperrors.NewDatabaseError(err.Error())
+			}
+
+			if err := appendAuditEvent(ctx, tx, audit.ActionLimitRaiseApply, audit.Target("account", c.accountId),
+				map[string]interface{}{c.kind: previous}, map[string]interface{}{c.kind: c.amount}); err != nil {
+				return applied, err
+			}
+
+			if err := addOutboxEvent(ctx, tx, outbox.TypeLimitChanged, audit.Target("account", c.accountId),
+				outbox.LimitChangedPayload{AccountId: c.accountId, Kind: c.kind, Previous: previous, Amount: c.amount, EffectiveAt: now}); err != nil {
+				return applied, err
+			}
+
+			applied++
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Apply due limit changes database transaction commit error:", err)
+			return 0, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return applied, nil
+	case <-ctx.Done():
+		return applied, ctx.Err()
+	}
+}
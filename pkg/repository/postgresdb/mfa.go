@@ -0,0 +1,315 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/mfa"
+)
+
+func (r *postgresDB) GetCustomerMfa(ctx context.Context, id uint64) (mfa.CustomerMfa, error) {
+	var m mfa.CustomerMfa
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return m, err
+		}
+
+		defer conn.Release()
+
+		query := `select coalesce(mfa_secret, ''), mfa_enabled, mfa_last_step, mfa_failed_attempts, mfa_locked_until
+				from customers where id = $1`
+		logger.Log.Debug("Get customer mfa query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&m.Secret, &m.Enabled, &m.LastStep, &m.FailedAttempts, &m.LockedUntil); err != nil {
+			logger.Log.Error("Get customer mfa query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return m, apperrors.NewAccountNotFoundError("customer not found")
+			}
+
+			return m, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return m, nil
+	case <-ctx.Done():
+		return m, ctx.Err()
+	}
+}
+
+// StartCustomerMfa stores the secret of an enrollment not yet confirmed,
+// replacing the one of any previous unconfirmed enrollment.
+func (r *postgresDB) StartCustomerMfa(ctx context.Context, id uint64, secret string) error {
+	query := `update customers set mfa_secret = $2, mfa_last_step = null, updated_at = now()
+			where id = $1 and not mfa_enabled`
+
+	return r.execCustomerMfa(ctx, "Start customer mfa", query, id, secret)
+}
+
+// EnableCustomerMfa confirms the enrollment, marking step as used, replaces
+// the recovery codes and audits it in a single database transaction.
+func (r *postgresDB) EnableCustomerMfa(ctx context.Context, id uint64, step int64, hashes []string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update customers set mfa_enabled = true, mfa_last_step = $2, mfa_failed_attempts = 0, mfa_locked_until = null, updated_at = now()
+				where id = $1 and not mfa_enabled and mfa_secret is not null`
+		logger.Log.Debug("Enable customer mfa query:", query, id, step)
+
+		tag, err := tx.Exec(ctx, query, id, step)
+
+		if err != nil {
+			logger.Log.Error("Enable customer mfa query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewArgumentError("mfa enrollment not started")
+		}
+
+		deleteQuery := "delete from mfa_recovery_codes where customer_id = $1"
+		logger.Log.Debug("Delete mfa recovery codes query:", deleteQuery, id)
+
+		if _, err := tx.Exec(ctx, deleteQuery, id); err != nil {
+			logger.Log.Error("Delete mfa recovery codes query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		insertQuery := "insert into mfa_recovery_codes (customer_id, code_hash) select $1, unnest($2::text[])"
+		logger.Log.Debug("Add mfa recovery codes query:", insertQuery, id)
+
+		if _, err := tx.Exec(ctx, insertQuery, id, hashes); err != nil {
+			logger.Log.Error("Add mfa recovery codes query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionMfaEnable, audit.Target("customer", id),
+			map[string]interface{}{"enabled": false}, map[string]interface{}{"enabled": true}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Enable customer mfa database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// DisableCustomerMfa clears the secret and the recovery codes and audits it
+// in a single database transaction.
+func (r *postgresDB) DisableCustomerMfa(ctx context.Context, id uint64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var previous bool
+
+		query := "select mfa_enabled from customers where id = $1 for update"
+		logger.Log.Debug("Lock customer mfa query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&previous); err != nil {
+			logger.Log.Error("Lock customer mfa query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("customer not found")
+			}
+
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		query = `update customers set mfa_secret = null, mfa_enabled = false, mfa_last_step = null,
+					mfa_failed_attempts = 0, mfa_locked_until = null, updated_at = now()
+				where id = $1`
+		logger.Log.Debug("Disable customer mfa query:", query, id)
+
+		if _, err := tx.Exec(ctx, query, id); err != nil {
+			logger.Log.Error("Disable customer mfa query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		deleteQuery := "delete from mfa_recovery_codes where customer_id = $1"
+		logger.Log.Debug("Delete mfa recovery codes query:", deleteQuery, id)
+
+		if _, err := tx.Exec(ctx, deleteQuery, id); err != nil {
+			logger.Log.Error("Delete mfa recovery codes query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionMfaDisable, audit.Target("customer", id),
+			map[string]interface{}{"enabled": previous}, map[string]interface{}{"enabled": false}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Disable customer mfa database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// UseMfaStep marks step as used and tells whether it was not used before: a
+// code is only accepted for a time step later than the last one accepted.
+func (r *postgresDB) UseMfaStep(ctx context.Context, id uint64, step int64) (bool, error) {
+	query := `update customers set mfa_last_step = $2
+			where id = $1 and (mfa_last_step is null or mfa_last_step < $2)`
+
+	return r.useMfaCode(ctx, "Use mfa step", query, id, step)
+}
+
+// UseMfaRecoveryCode marks the unused recovery code with hash as used and
+// tells whether there was one.
+func (r *postgresDB) UseMfaRecoveryCode(ctx context.Context, id uint64, hash string) (bool, error) {
+	query := `update mfa_recovery_codes set used_at = now()
+			where customer_id = $1 and code_hash = $2 and used_at is null`
+
+	return r.useMfaCode(ctx, "Use mfa recovery code", query, id, hash)
+}
+
+// CountMfaAttempt counts an attempt against MFA as a wrong one before the
+// code is checked, so concurrent attempts cannot all get past the lockout,
+// and returns the MFA state after it. The attempt that reaches maxAttempts
+// locks MFA for lockout and starts the count over. Nothing is counted, and
+// false is returned, when MFA is not enabled or it is locked.
+func (r *postgresDB) CountMfaAttempt(ctx context.Context, id uint64, maxAttempts int, lockout time.Duration) (mfa.CustomerMfa, bool, error) {
+	var m mfa.CustomerMfa
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return m, false, err
+		}
+
+		defer conn.Release()
+
+		query := `update customers set
+					mfa_locked_until = case when mfa_failed_attempts + 1 >= $2 then $3 else mfa_locked_until end,
+					mfa_failed_attempts = case when mfa_failed_attempts + 1 >= $2 then 0 else mfa_failed_attempts + 1 end
+				where id = $1 and mfa_enabled and (mfa_locked_until is null or mfa_locked_until <= now())
+				returning mfa_secret, mfa_enabled, mfa_last_step, mfa_failed_attempts, mfa_locked_until`
+		logger.Log.Debug("Count mfa attempt query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id, maxAttempts, time.Now().Add(lockout)).Scan(&m.Secret, &m.Enabled, &m.LastStep, &m.FailedAttempts, &m.LockedUntil); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return m, false, nil
+			}
+
+			logger.Log.Error("Count mfa attempt query error:", err)
+			return m, false, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return m, true, nil
+	case <-ctx.Done():
+		return m, false, ctx.Err()
+	}
+}
+
+// ResetMfaFailures clears the count of wrong attempts, and the lockout the
+// attempt being reset may have set, after a right code.
+func (r *postgresDB) ResetMfaFailures(ctx context.Context, id uint64) error {
+	query := "update customers set mfa_failed_attempts = 0, mfa_locked_until = null where id = $1"
+
+	return r.execCustomerMfa(ctx, "Reset mfa failures", query, id)
+}
+
+func (r *postgresDB) useMfaCode(ctx context.Context, name string, query string, id uint64, code interface{}) (bool, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return false, err
+		}
+
+		defer conn.Release()
+
+		logger.Log.Debug(name+" query:", query, id)
+		tag, err := conn.Exec(ctx, query, id, code)
+
+		if err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return false, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return tag.RowsAffected() == 1, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (r *postgresDB) execCustomerMfa(ctx context.Context, name string, query string, id uint64, args ...interface{}) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		args = append([]interface{}{id}, args...)
+		logger.Log.Debug(name+" query:", query, id)
+		tag, err := conn.Exec(ctx, query, args...)
+
+		if err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("customer not found")
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
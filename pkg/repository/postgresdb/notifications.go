@@ -0,0 +1,119 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/notification"
+)
+
+const notificationPreferencesColumns = "language, email, phone, push_token, channels, muted, updated_at"
+
+// GetNotificationPreferences returns the defaults for customers who never set
+// their preferences.
+func (r *postgresDB) GetNotificationPreferences(ctx context.Context, customerId uint64) (notification.Preferences, error) {
+	p := notification.DefaultPreferences()
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + notificationPreferencesColumns + " from notification_preferences where customer_id = $1"
+		logger.Log.Debug("Get notification preferences query:", query, customerId)
+
+		stored, err := scanNotificationPreferences(conn.QueryRow(ctx, query, customerId))
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return p, nil
+		}
+
+		if err != nil {
+			logger.Log.Error("Get notification preferences query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return stored, nil
+	case <-ctx.Done():
+		return p, ctx.Err()
+	}
+}
+
+func (r *postgresDB) SetNotificationPreferences(ctx context.Context, customerId uint64, p notification.Preferences) (notification.Preferences, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, err
+		}
+
+		defer conn.Release()
+
+		query := `insert into notification_preferences (customer_id, language, email, phone, push_token, channels, muted)
+				values ($1, $2, $3, $4, $5, $6, $7)
+				on conflict (customer_id) do update
+				set language = excluded.language, email = excluded.email, phone = excluded.phone,
+					push_token = excluded.push_token, channels = excluded.channels, muted = excluded.muted, updated_at = now()
+				returning ` + notificationPreferencesColumns
+		logger.Log.Debug("Set notification preferences query:", query, customerId, p.Language, p.Channels, p.Muted)
+
+		p, err = scanNotificationPreferences(conn.QueryRow(ctx, query, customerId, p.Language, p.Email, p.Phone, p.PushToken, p.Channels, p.Muted))
+
+		if err != nil {
+			logger.Log.Error("Set notification preferences query error:", err)
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return p, nil
+	case <-ctx.Done():
+		return p, ctx.Err()
+	}
+}
+
+// MarkNotificationSent records that the customer is notified of kind for the
+// outbox event and tells whether it was not recorded before.
+func (r *postgresDB) MarkNotificationSent(ctx context.Context, eventId uint64, customerId uint64, kind string) (bool, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return false, err
+		}
+
+		defer conn.Release()
+
+		query := `insert into notifications_sent (event_id, customer_id, kind) values ($1, $2, $3)
+				on conflict do nothing`
+		logger.Log.Debug("Mark notification sent query:", query, eventId, customerId, kind)
+
+		tag, err := conn.Exec(ctx, query, eventId, customerId, kind)
+
+		if err != nil {
+			logger.Log.Error("Mark notification sent query error:", err)
+			return false, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return tag.RowsAffected() == 1, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func scanNotificationPreferences(row pgx.Row) (notification.Preferences, error) {
+	var p notification.Preferences
+
+	err := row.Scan(&p.Language, &p.Email, &p.Phone, &p.PushToken, &p.Channels, &p.Muted, &p.UpdatedAt)
+
+	return p, err
+}
@@ -0,0 +1,241 @@
+package postgresdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+// outboxLockKey is the advisory lock held by transactions writing to the
+// outbox until they commit. Event ids are then taken in commit order and the
+// relay, reading past its offset, never skips an event committed late.
+const outboxLockKey = 4602
+
+// addOutboxEvent publishes, as part of tx, an event of eventType about
+// subject. The event only exists if tx commits.
+func addOutboxEvent(ctx context.Context, tx pgx.Tx, eventType string, subject string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+
+	if err != nil {
+		logger.Log.Error("Outbox event encode error:", err)
+		return apperrors.NewInternalServerError(err.Error())
+	}
+
+	if _, err := tx.Exec(ctx, "select pg_advisory_xact_lock($1)", outboxLockKey); err != nil {
+		logger.Log.Error("Outbox lock error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	query := "insert into outbox_events (type, subject, payload) values ($1, $2, $3)"
+	logger.Log.Debug("Add outbox event query:", query, eventType, subject)
+
+	if _, err := tx.Exec(ctx, query, eventType, subject, string(raw)); err != nil {
+		logger.Log.Error("Add outbox event query error:", err)
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	return nil
+}
+
+// ListOutboxEvents returns up to limit events after afterId, in order.
+func (r *postgresDB) ListOutboxEvents(ctx context.Context, afterId uint64, limit int) ([]outbox.Event, error) {
+	events := []outbox.Event{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return events, err
+		}
+
+		defer conn.Release()
+
+		query := `select id, type, subject, payload, created_at from outbox_events
+				where id > $1 order by id limit $2`
+		logger.Log.Debug("List outbox events query:", query, afterId, limit)
+
+		rows, err := conn.Query(ctx, query, afterId, limit)
+
+		if err != nil {
+			logger.Log.Error("List outbox events query error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var e outbox.Event
+			var payload string
+
+			if err := rows.Scan(&e.Id, &e.Type, &e.Subject, &payload, &e.CreatedAt); err != nil {
+				logger.Log.Error("List outbox events scan error:", err)
+				return events, apperrors.NewDatabaseError(err.Error())
+			}
+
+			e.Payload = []byte(payload)
+			events = append(events, e)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List outbox events rows error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return events, nil
+	case <-ctx.Done():
+		return events, ctx.Err()
+	}
+}
+
+// GetOutboxOffset returns the id of the last event delivered to the sink, 0
+// when it never got any.
+func (r *postgresDB) GetOutboxOffset(ctx context.Context, sink string) (uint64, error) {
+	var offset uint64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return offset, err
+		}
+
+		defer conn.Release()
+
+		query := "select last_event_id from outbox_offsets where sink = $1"
+		logger.Log.Debug("Get outbox offset query:", query, sink)
+
+		if err := conn.QueryRow(ctx, query, sink).Scan(&offset); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Error("Get outbox offset query error:", err)
+			return offset, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return offset, nil
+	case <-ctx.Done():
+		return offset, ctx.Err()
+	}
+}
+
+// SetOutboxOffset moves the sink offset forward to offset. It never moves
+// back, in case another instance of the relay delivered further meanwhile.
+func (r *postgresDB) SetOutboxOffset(ctx context.Context, sink string, offset uint64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := `insert into outbox_offsets (sink, last_event_id) values ($1, $2)
+				on conflict (sink) do update
+				set last_event_id = greatest(outbox_offsets.last_event_id, excluded.last_event_id), updated_at = now()`
+		logger.Log.Debug("Set outbox offset query:", query, sink, offset)
+
+		if _, err := conn.Exec(ctx, query, sink, offset); err != nil {
+			logger.Log.Error("Set outbox offset query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GetOutboxHead returns the id of the last event in the outbox, 0 when it is
+// empty.
+func (r *postgresDB) GetOutboxHead(ctx context.Context) (uint64, error) {
+	var head uint64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return head, err
+		}
+
+		defer conn.Release()
+
+		query := "select coalesce(max(id), 0) from outbox_events"
+		logger.Log.Debug("Get outbox head query:", query)
+
+		if err := conn.QueryRow(ctx, query).Scan(&head); err != nil {
+			logger.Log.Error("Get outbox head query error:", err)
+			return head, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return head, nil
+	case <-ctx.Done():
+		return head, ctx.Err()
+	}
+}
+
+// ListAccountOutboxEvents returns up to limit events, after afterId, of
+// transfers and postings with the account as origin or destination and of
+// holds and judicial blocks of the account, in order.
+func (r *postgresDB) ListAccountOutboxEvents(ctx context.Context, accountId uint64, afterId uint64, limit int) ([]outbox.Event, error) {
+	events := []outbox.Event{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return events, err
+		}
+
+		defer conn.Release()
+
+		query := `select id, type, subject, payload, created_at from outbox_events
+				where id > $2 and (
+					(type = $4 and ((payload->>'origin')::bigint = $1 or (payload->>'destination')::bigint = $1))
+					or (type = $5 and ((payload->>'origin')::bigint = $1 or (payload->>'destination')::bigint = $1))
+					or (type in ($6, $7) and (payload->>'accountId')::bigint = $1)
+				)
+				order by id limit $3`
+		logger.Log.Debug("List account outbox events query:", query, accountId, afterId, limit)
+
+		rows, err := conn.Query(ctx, query, accountId, afterId, limit, outbox.TypeTransferCompleted, outbox.TypePostingCompleted,
+			outbox.TypeHoldChanged, outbox.TypeJudicialBlockChanged)
+
+		if err != nil {
+			logger.Log.Error("List account outbox events query error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var e outbox.Event
+			var payload string
+
+			if err := rows.Scan(&e.Id, &e.Type, &e.Subject, &payload, &e.CreatedAt); err != nil {
+				logger.Log.Error("List account outbox events scan error:", err)
+				return events, apperrors.NewDatabaseError(err.Error())
+			}
+
+			e.Payload = []byte(payload)
+			events = append(events, e)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List account outbox events rows error:", err)
+			return events, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return events, nil
+	case <-ctx.Done():
+		return events, ctx.Err()
+	}
+}
@@ -0,0 +1,175 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/pin"
+)
+
+func (r *postgresDB) GetAccountPin(ctx context.Context, id uint64) (pin.AccountPin, error) {
+	var p pin.AccountPin
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, err
+		}
+
+		defer conn.Release()
+
+		query := "select coalesce(pin_hash, ''), pin_failed_attempts, pin_locked_until from accounts where id = $1"
+		logger.Log.Debug("Get account pin query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&p.Hash, &p.FailedAttempts, &p.LockedUntil); err != nil {
+			logger.Log.Error("Get account pin query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return p, apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return p, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return p, nil
+	case <-ctx.Done():
+		return p, ctx.Err()
+	}
+}
+
+// SetAccountPin replaces the account PIN hash, clearing any lockout, and
+// audits it in a single database transaction.
+func (r *postgresDB) SetAccountPin(ctx context.Context, id uint64, hash string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update accounts set pin_hash = $2, pin_failed_attempts = 0, pin_locked_until = null, updated_at = now()
+				where id = $1`
+		logger.Log.Debug("Set account pin query:", query, id)
+
+		tag, err := tx.Exec(ctx, query, id, hash)
+
+		if err != nil {
+			logger.Log.Error("Set account pin query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("account not found")
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionPinSet, audit.Target("account", id), nil, nil); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Set account pin database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// CountPinAttempt counts an attempt against the account PIN as a wrong one
+// before it is checked, so concurrent attempts cannot all get past the
+// lockout, and returns the PIN state after it. The attempt that reaches
+// maxAttempts locks the PIN for lockout and starts the count over. Nothing is
+// counted, and false is returned, when there is no PIN or it is locked.
+func (r *postgresDB) CountPinAttempt(ctx context.Context, id uint64, maxAttempts int, lockout time.Duration) (pin.AccountPin, bool, error) {
+	var p pin.AccountPin
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return p, false, err
+		}
+
+		defer conn.Release()
+
+		query := `update accounts set
+					pin_locked_until = case when pin_failed_attempts + 1 >= $2 then $3 else pin_locked_until end,
+					pin_failed_attempts = case when pin_failed_attempts + 1 >= $2 then 0 else pin_failed_attempts + 1 end
+				where id = $1 and pin_hash is not null and (pin_locked_until is null or pin_locked_until <= now())
+				returning pin_hash, pin_failed_attempts, pin_locked_until`
+		logger.Log.Debug("Count pin attempt query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id, maxAttempts, time.Now().Add(lockout)).Scan(&p.Hash, &p.FailedAttempts, &p.LockedUntil); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return p, false, nil
+			}
+
+			logger.Log.Error("Count pin attempt query error:", err)
+			return p, false, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return p, true, nil
+	case <-ctx.Done():
+		return p, false, ctx.Err()
+	}
+}
+
+// ResetPinFailures clears the count of wrong attempts, and the lockout the
+// attempt being reset may have set, after a right PIN.
+func (r *postgresDB) ResetPinFailures(ctx context.Context, id uint64) error {
+	query := "update accounts set pin_failed_attempts = 0, pin_locked_until = null where id = $1"
+
+	return r.execAccountPin(ctx, "Reset pin failures", query, id)
+}
+
+func (r *postgresDB) execAccountPin(ctx context.Context, name string, query string, id uint64, args ...interface{}) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		args = append([]interface{}{id}, args...)
+		logger.Log.Debug(name+" query:", query, id)
+		tag, err := conn.Exec(ctx, query, args...)
+
+		if err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("account not found")
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
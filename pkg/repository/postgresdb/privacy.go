@@ -0,0 +1,344 @@
+package postgresdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/privacy"
+)
+
+// ListCustomerTransfers returns every transfer of the accounts of the
+// customer, each seen from the customer account side.
+func (r *postgresDB) ListCustomerTransfers(ctx context.Context, customerId uint64) ([]privacy.Transfer, error) {
+	transfers := []privacy.Transfer{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return transfers, err
+		}
+
+		defer conn.Release()
+
+		query := `select tr.id, tr.related_id, a.id, tr.account_origin_id = a.id, tr.amount, tr.kind,
+					ca.id, cc.name, tr.created_at
+				from accounts a
+				inner join transfers tr
+					on tr.account_origin_id = a.id or tr.account_destination_id = a.id
+				inner join accounts ca
+					on ca.id = case when tr.account_origin_id = a.id then tr.account_destination_id else tr.account_origin_id end
+				inner join customers cc
+					on cc.id = ca.customer_id
+				where a.customer_id = $1
+				order by tr.id, a.id`
+		logger.Log.Debug("List customer transfers query:", query, customerId)
+
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("List customer transfers query error:", err)
+			return transfers, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var t privacy.Transfer
+			var debit bool
+
+			if err := rows.Scan(&t.Id, &t.RelatedId, &t.AccountId, &debit, &t.Amount, &t.Kind,
+				&t.CounterpartyAccountId, &t.CounterpartyName, &t.CreatedAt); err != nil {
+				logger.Log.Error("List customer transfers scan error:", err)
+				return transfers, apperrors.NewDatabaseError(err.Error())
+			}
+
+			t.Direction = privacy.DirectionCredit
+			if debit {
+				t.Direction = privacy.DirectionDebit
+			}
+
+			transfers = append(transfers, t)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List customer transfers rows error:", err)
+			return transfers, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return transfers, nil
+	case <-ctx.Done():
+		return transfers, ctx.Err()
+	}
+}
+
+// ListCustomerSessionHistory returns every session of the customer, unlike
+// ListCustomerSessions which only returns the open ones.
+func (r *postgresDB) ListCustomerSessionHistory(ctx context.Context, customerId uint64) ([]login.Session, error) {
+	sessions := []login.Session{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return sessions, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + sessionColumns + " from sessions where customer_id = $1 order by id"
+		logger.Log.Debug("List customer session history query:", query, customerId)
+
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("List customer session history query error:", err)
+			return sessions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSession(rows)
+
+			if err != nil {
+				logger.Log.Error("List customer session history scan error:", err)
+				return sessions, apperrors.NewDatabaseError(err.Error())
+			}
+
+			sessions = append(sessions, s)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List customer session history rows error:", err)
+			return sessions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return sessions, nil
+	case <-ctx.Done():
+		return sessions, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListConsents(ctx context.Context, customerId uint64) ([]privacy.Consent, error) {
+	consents := []privacy.Consent{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return consents, err
+		}
+
+		defer conn.Release()
+
+		query := "select purpose, granted, updated_at from consents where customer_id = $1 order by purpose"
+		logger.Log.Debug("List consents query:", query, customerId)
+
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("List consents query error:", err)
+			return consents, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var c privacy.Consent
+
+			if err := rows.Scan(&c.Purpose, &c.Granted, &c.UpdatedAt); err != nil {
+				logger.Log.Error("List consents scan error:", err)
+				return consents, apperrors.NewDatabaseError(err.Error())
+			}
+
+			consents = append(consents, c)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List consents rows error:", err)
+			return consents, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return consents, nil
+	case <-ctx.Done():
+		return consents, ctx.Err()
+	}
+}
+
+func (r *postgresDB) SetConsent(ctx context.Context, customerId uint64, c privacy.Consent) (privacy.Consent, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return c, err
+		}
+
+		defer conn.Release()
+
+		query := `insert into consents (customer_id, purpose, granted) values ($1, $2, $3)
+				on conflict (customer_id, purpose) do update set granted = excluded.granted, updated_at = now()
+				returning purpose, granted, updated_at`
+		logger.Log.Debug("Set consent query:", query, customerId, c.Purpose, c.Granted)
+
+		if err := conn.QueryRow(ctx, query, customerId, c.Purpose, c.Granted).Scan(&c.Purpose, &c.Granted, &c.UpdatedAt); err != nil {
+			logger.Log.Error("Set consent query error:", err)
+			return c, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return c, nil
+	case <-ctx.Done():
+		return c, ctx.Err()
+	}
+}
+
+// anonymizableCustomerCondition selects the customers c not anonymized yet
+// whose accounts were all closed before $1.
+const anonymizableCustomerCondition = `c.role = 'customer' and c.anonymized_at is null
+		and exists (select 1 from accounts a where a.customer_id = c.id)
+		and not exists (
+			select 1 from accounts a
+			where a.customer_id = c.id and (a.closed_at is null or a.closed_at >= $1)
+		)`
+
+func (r *postgresDB) ListAnonymizableCustomers(ctx context.Context, closedBefore time.Time) ([]uint64, error) {
+	ids := []uint64{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return ids, err
+		}
+
+		defer conn.Release()
+
+		query := "select c.id from customers c where " + anonymizableCustomerCondition + " order by c.id"
+		logger.Log.Debug("List anonymizable customers query:", query, closedBefore)
+
+		rows, err := conn.Query(ctx, query, closedBefore)
+
+		if err != nil {
+			logger.Log.Error("List anonymizable customers query error:", err)
+			return ids, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uint64
+
+			if err := rows.Scan(&id); err != nil {
+				logger.Log.Error("List anonymizable customers scan error:", err)
+				return ids, apperrors.NewDatabaseError(err.Error())
+			}
+
+			ids = append(ids, id)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List anonymizable customers rows error:", err)
+			return ids, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return ids, nil
+	case <-ctx.Done():
+		return ids, ctx.Err()
+	}
+}
+
+// AnonymizeCustomer replaces the customer name with pseudonym and erases its
+// CPF, credentials, sessions, login devices, consents and notification
+// contacts, in a single database transaction.
+// Transfers are left untouched. The customer is not found when it is no
+// longer anonymizable.
+func (r *postgresDB) AnonymizeCustomer(ctx context.Context, id uint64, closedBefore time.Time, pseudonym string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `update customers c set name = $2, cpf = null, secret = '', mfa_secret = null, mfa_enabled = false,
+					active = false, anonymized_at = now(), updated_at = now()
+				where c.id = $3 and ` + anonymizableCustomerCondition
+		logger.Log.Debug("Anonymize customer query:", query, closedBefore, pseudonym, id)
+
+		tag, err := tx.Exec(ctx, query, closedBefore, pseudonym, id)
+
+		if err != nil {
+			logger.Log.Error("Anonymize customer query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("customer not found")
+		}
+
+		queries := []string{
+			"update sessions set user_agent = '', ip = '', terminated_at = coalesce(terminated_at, now()) where customer_id = $1",
+			"delete from mfa_recovery_codes where customer_id = $1",
+			"delete from secret_reset_tokens where customer_id = $1",
+			"delete from consents where customer_id = $1",
+			"delete from notification_preferences where customer_id = $1",
+		}
+
+		for _, query := range queries {
+			logger.Log.Debug("Anonymize customer data query:", query, id)
+
+			if _, err := tx.Exec(ctx, query, id); err != nil {
+				logger.Log.Error("Anonymize customer data query error:", err)
+				return apperrors.NewDatabaseError(err.Error())
+			}
+		}
+
+		// The login events published keep the IP and user agent of each
+		// attempt, as the sessions did.
+		query = `update outbox_events set payload = payload - 'ip' - 'userAgent'
+				where subject = $1 and type = any($2)`
+		logger.Log.Debug("Anonymize customer login events query:", query, id)
+
+		if _, err := tx.Exec(ctx, query, audit.Target("customer", id),
+			[]string{outbox.TypeLoginSucceeded, outbox.TypeLoginFailed}); err != nil {
+			logger.Log.Error("Anonymize customer login events query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		// The event must not keep what was just erased.
+		if err := appendAuditEvent(ctx, tx, audit.ActionCustomerAnonymize, audit.Target("customer", id),
+			nil, map[string]interface{}{"name": pseudonym}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Anonymize customer database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
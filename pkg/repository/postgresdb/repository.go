@@ -13,8 +13,10 @@ import (
 
 	"github.com/GilbertoVGL/go-banking/pkg/account"
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
 	"github.com/GilbertoVGL/go-banking/pkg/logger"
 	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
 	"github.com/GilbertoVGL/go-banking/pkg/transfer"
 )
 
@@ -102,13 +104,28 @@ func (r *postgresDB) GetAccountById(ctx context.Context, id uint64) (account.Acc
 
 		defer conn.Release()
 
-		query := fmt.Sprintf("select id, name, cpf, balance, active from accounts where id = '%d';", id)
-		logger.Log.Debug("Accounts query:", query)
-
-		if err := conn.QueryRow(ctx, query).Scan(&account.Id, &account.Name, &account.Cpf, &account.Balance, &account.Active); err != nil {
+		query := `select 
+					a.id, 
+					a.customer_id, 
+					a.product, 
+					c.name, 
+					coalesce(c.cpf, ''), 
+					a.balance, 
+					a.overdraft_limit, 
+					a.approval_threshold, 
+					a.required_approvals, 
+					a.active, 
+					a.closed_at 
+				from accounts as a
+				inner join customers as c
+					on a.customer_id = c.id
+				where a.id = $1;`
+		logger.Log.Debug("Accounts query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&account.Id, &account.CustomerId, &account.Product, &account.Name, &account.Cpf, &account.Balance, &account.OverdraftLimit, &account.ApprovalThreshold, &account.RequiredApprovals, &account.Active, &account.ClosedAt); err != nil {
 			logger.Log.Error("Accounts query error:", err)
 
-			if errors.Is(pgx.ErrNoRows, err) {
+			if errors.Is(err, pgx.ErrNoRows) {
 				return account, apperrors.NewAccountNotFoundError("account not found")
 			}
 			return account, apperrors.NewDatabaseError(err.Error())
@@ -120,34 +137,117 @@ func (r *postgresDB) GetAccountById(ctx context.Context, id uint64) (account.Acc
 	}
 }
 
-func (r *postgresDB) GetAccountBySecretAndCPF(ctx context.Context, l login.LoginRequest) (login.Account, error) {
-	var account login.Account
+func (r *postgresDB) GetCustomerBySecretAndCPF(ctx context.Context, l login.LoginRequest) (login.Customer, error) {
+	var customer login.Customer
 
 	select {
 	default:
 		conn, err := r.getConn()
 
 		if err != nil {
-			return account, err
+			return customer, err
 		}
 
 		defer conn.Release()
-		query := fmt.Sprintf("select id, active from accounts where cpf = '%s' AND secret = '%s';", l.Cpf, l.Secret)
-		logger.Log.Debug("Account by secret query:", query)
+		query := "select id, role, mfa_enabled, active from customers where cpf = $1 AND secret = $2;"
+		logger.Log.Debug("Customer by secret query:", query, l.Cpf)
 
-		if err := conn.QueryRow(ctx, query).Scan(&account.Id, &account.Active); err != nil {
-			logger.Log.Error("Account by secret query error:", err)
+		if err := conn.QueryRow(ctx, query, l.Cpf, l.Secret).Scan(&customer.Id, &customer.Role, &customer.MfaEnabled, &customer.Active); err != nil {
+			logger.Log.Error("Customer by secret query error:", err)
 
-			if errors.Is(pgx.ErrNoRows, err) {
-				return account, apperrors.NewDatabaseError("invalid cpf or password")
+			if errors.Is(err, pgx.ErrNoRows) {
+				return customer, apperrors.NewAuthError("invalid cpf or password")
 			}
 
-			return account, err
+			return customer, err
 		}
 
-		return account, nil
+		return customer, nil
 	case <-ctx.Done():
-		return account, ctx.Err()
+		return customer, ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetCustomerById(ctx context.Context, id uint64) (login.Customer, error) {
+	return r.getCustomer(ctx, "Customer by id", "id = $1", id)
+}
+
+func (r *postgresDB) GetCustomerByCpf(ctx context.Context, cpf string) (login.Customer, error) {
+	return r.getCustomer(ctx, "Customer by cpf", "cpf = $1", cpf)
+}
+
+func (r *postgresDB) getCustomer(ctx context.Context, name string, where string, arg interface{}) (login.Customer, error) {
+	var customer login.Customer
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return customer, err
+		}
+
+		defer conn.Release()
+		query := "select id, name, coalesce(cpf, ''), secret, role, mfa_enabled, secret_changed_at, active from customers where " + where
+		logger.Log.Debug(name+" query:", query, arg)
+
+		if err := conn.QueryRow(ctx, query, arg).Scan(&customer.Id, &customer.Name, &customer.Cpf, &customer.Secret, &customer.Role, &customer.MfaEnabled, &customer.SecretChangedAt, &customer.Active); err != nil {
+			logger.Log.Error(name+" query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return customer, apperrors.NewAccountNotFoundError("customer not found")
+			}
+
+			return customer, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return customer, nil
+	case <-ctx.Done():
+		return customer, ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetCustomerAccounts(ctx context.Context, customerId uint64) ([]login.Account, error) {
+	accounts := []login.Account{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return accounts, err
+		}
+
+		defer conn.Release()
+
+		query := "select id, customer_id, active from accounts where customer_id = $1 order by id;"
+		logger.Log.Debug("Customer accounts query:", query, customerId)
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("Customer accounts query error:", err)
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var account login.Account
+
+			if err := rows.Scan(&account.Id, &account.CustomerId, &account.Active); err != nil {
+				return accounts, apperrors.NewDatabaseError(err.Error())
+			}
+
+			accounts = append(accounts, account)
+		}
+
+		if err := rows.Err(); err != nil {
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return accounts, nil
+	case <-ctx.Done():
+		return accounts, ctx.Err()
 	}
 }
 
@@ -165,13 +265,14 @@ func (r *postgresDB) ListAccount(ctx context.Context, params account.ListAccount
 		defer conn.Release()
 
 		query := fmt.Sprintf(`select 
-								id, 
-								name, 
-								cpf, 
-								balance 
-							from 
-								accounts 
-							order by id 
+								a.id, 
+								c.name, 
+								coalesce(c.cpf, ''), 
+								a.balance 
+							from accounts as a
+							inner join customers as c
+								on a.customer_id = c.id
+							order by a.id 
 							limit %d 
 							offset %d;`, params.PageSize, (params.PageSize * params.Page))
 		logger.Log.Debug("List account query:", query)
@@ -213,6 +314,7 @@ func (r *postgresDB) ListAccount(ctx context.Context, params account.ListAccount
 	}
 }
 
+// AddAccount registers a new customer and opens its first account.
 func (r *postgresDB) AddAccount(ctx context.Context, a account.NewAccountRequest) error {
 	select {
 	default:
@@ -224,45 +326,297 @@ func (r *postgresDB) AddAccount(ctx context.Context, a account.NewAccountRequest
 
 		defer conn.Release()
 
-		query := fmt.Sprintf("insert into accounts (name, cpf, balance, secret) values ('%s', '%s', %d, '%s')", a.Name, a.Cpf, a.Balance, a.Secret)
-		logger.Log.Debug("Add account query:", query)
-		_, err = conn.Exec(ctx, query)
+		tx, err := conn.Begin(ctx)
 
 		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var customerId, accountId uint64
+		customerQuery := "insert into customers (name, cpf, secret) values ($1, $2, $3) returning id"
+		accountQuery := "insert into accounts (customer_id, balance, product) values ($1, $2, $3) returning id"
+		logger.Log.Debug("Add account customer query:", customerQuery, a.Name, a.Cpf)
+		logger.Log.Debug("Add account query:", accountQuery, a.Balance, a.Product)
+
+		if err := tx.QueryRow(ctx, customerQuery, a.Name, a.Cpf, a.Secret).Scan(&customerId); err != nil {
+			logger.Log.Error("Add account customer query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := tx.QueryRow(ctx, accountQuery, customerId, a.Balance, a.Product).Scan(&accountId); err != nil {
 			logger.Log.Error("Add account query error:", err)
 			return apperrors.NewDatabaseError(err.Error())
 		}
 
+		// The customer signing up is the actor.
+		after := map[string]interface{}{"customerId": customerId, "product": a.Product, "balance": a.Balance}
+
+		if err := appendAuditEvent(audit.WithCustomer(ctx, customerId, ""), tx, audit.ActionAccountCreate,
+			audit.Target("account", accountId), nil, after); err != nil {
+			return err
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeAccountCreated, audit.Target("account", accountId),
+			outbox.AccountPayload{AccountId: accountId, CustomerId: customerId, Product: a.Product}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add account database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
 
-func (r *postgresDB) GetAccountBalance(ctx context.Context, id uint64) (int64, error) {
-	var balance int64
+func (r *postgresDB) AddCustomerAccount(ctx context.Context, customerId uint64, product string) (account.CustomerAccount, error) {
+	var newAccount account.CustomerAccount
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return newAccount, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return newAccount, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := "insert into accounts (customer_id, product) values ($1, $2) returning id, product, balance, active, created_at"
+		logger.Log.Debug("Add customer account query:", query, customerId, product)
+
+		if err := tx.QueryRow(ctx, query, customerId, product).Scan(&newAccount.Id, &newAccount.Product, &newAccount.Balance, &newAccount.Active, &newAccount.CreatedAt); err != nil {
+			logger.Log.Error("Add customer account query error:", err)
+			return newAccount, apperrors.NewDatabaseError(err.Error())
+		}
+
+		after := map[string]interface{}{"customerId": customerId, "product": product}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionAccountOpen, audit.Target("account", newAccount.Id), nil, after); err != nil {
+			return newAccount, err
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeAccountOpened, audit.Target("account", newAccount.Id),
+			outbox.AccountPayload{AccountId: newAccount.Id, CustomerId: customerId, Product: product}); err != nil {
+			return newAccount, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add customer account database transaction commit error:", err)
+			return newAccount, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return newAccount, nil
+	case <-ctx.Done():
+		return newAccount, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListCustomerAccounts(ctx context.Context, customerId uint64) ([]account.CustomerAccount, error) {
+	accounts := []account.CustomerAccount{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return accounts, err
+		}
+
+		defer conn.Release()
+
+		query := "select id, product, balance, active, created_at, closed_at from accounts where customer_id = $1 order by id;"
+		logger.Log.Debug("List customer accounts query:", query, customerId)
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("List customer accounts query error:", err)
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var account account.CustomerAccount
+
+			if err := rows.Scan(&account.Id, &account.Product, &account.Balance, &account.Active, &account.CreatedAt, &account.ClosedAt); err != nil {
+				return accounts, apperrors.NewDatabaseError(err.Error())
+			}
+
+			accounts = append(accounts, account)
+		}
+
+		if err := rows.Err(); err != nil {
+			return accounts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return accounts, nil
+	case <-ctx.Done():
+		return accounts, ctx.Err()
+	}
+}
+
+func (r *postgresDB) SetOverdraftLimit(ctx context.Context, id uint64, limit int64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var previous int64
+		query := "select overdraft_limit from accounts where id = $1 for update"
+		logger.Log.Debug("Lock overdraft limit query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&previous); err != nil {
+			logger.Log.Error("Lock overdraft limit query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		query = "update accounts set overdraft_limit = $1, updated_at = now() where id = $2"
+		logger.Log.Debug("Set overdraft limit query:", query, limit, id)
+
+		if _, err := tx.Exec(ctx, query, limit, id); err != nil {
+			logger.Log.Error("Set overdraft limit query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
 
+		if err := appendAuditEvent(ctx, tx, audit.ActionOverdraftLimitSet, audit.Target("account", id),
+			map[string]interface{}{"overdraftLimit": previous}, map[string]interface{}{"overdraftLimit": limit}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Set overdraft limit database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SetApprovalPolicy updates the account approval threshold and replaces its
+// approvers, which must not include the account holder, in a single database
+// transaction.
+func (r *postgresDB) SetApprovalPolicy(ctx context.Context, id uint64, p account.ApprovalPolicyRequest) error {
 	select {
 	default:
 		conn, err := r.getConn()
 
 		if err != nil {
-			return balance, err
+			return err
 		}
 
 		defer conn.Release()
 
-		query := fmt.Sprintf("select balance from accounts where id = %d", id)
-		logger.Log.Debug("Get account balance query:", query)
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var previous account.ApprovalPolicyRequest
+		var holder uint64
+		query := `select a.customer_id, a.approval_threshold, a.required_approvals,
+					coalesce((select array_agg(customer_id order by customer_id) from account_approvers where account_id = a.id), '{}')
+				from accounts a where a.id = $1 for update of a`
+		logger.Log.Debug("Lock approval policy query:", query, id)
+
+		if err := tx.QueryRow(ctx, query, id).Scan(&holder, &previous.Threshold, &previous.RequiredApprovals, &previous.Approvers); err != nil {
+			logger.Log.Error("Lock approval policy query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		// The holder requests the account transfers, and no one approves their
+		// own request.
+		for _, customerId := range p.Approvers {
+			if customerId == holder {
+				return apperrors.NewArgumentError("approvers must not include the account holder")
+			}
+		}
+
+		query = "update accounts set approval_threshold = $1, required_approvals = $2, updated_at = now() where id = $3"
+		logger.Log.Debug("Set approval policy query:", query, p.Threshold, p.RequiredApprovals, id)
+
+		if _, err := tx.Exec(ctx, query, p.Threshold, p.RequiredApprovals, id); err != nil {
+			logger.Log.Error("Set approval policy query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		deleteQuery := "delete from account_approvers where account_id = $1"
+		logger.Log.Debug("Delete account approvers query:", deleteQuery, id)
 
-		if err := conn.QueryRow(ctx, query).Scan(&balance); err != nil {
-			logger.Log.Error("Get account balance query error:", err)
-			return balance, apperrors.NewDatabaseError(err.Error())
+		if _, err := tx.Exec(ctx, deleteQuery, id); err != nil {
+			logger.Log.Error("Delete account approvers query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		insertQuery := "insert into account_approvers (account_id, customer_id) select $1, id from customers where id = $2 and active"
+
+		for _, customerId := range p.Approvers {
+			logger.Log.Debug("Add account approver query:", insertQuery, id, customerId)
+			tag, err := tx.Exec(ctx, insertQuery, id, customerId)
+
+			if err != nil {
+				logger.Log.Error("Add account approver query error:", err)
+				return apperrors.NewDatabaseError(err.Error())
+			}
+
+			if tag.RowsAffected() == 0 {
+				return apperrors.NewArgumentError("approver not found", strconv.FormatUint(customerId, 10))
+			}
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionApprovalPolicySet, audit.Target("account", id), previous, p); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Set approval policy database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
 		}
 
-		return balance, nil
+		return nil
 	case <-ctx.Done():
-		return balance, ctx.Err()
+		return ctx.Err()
 	}
 }
 
@@ -280,17 +634,24 @@ func (r *postgresDB) GetTransfers(ctx context.Context, id uint64, params transfe
 		defer conn.Release()
 
 		query := fmt.Sprintf(`select 
+							tr.id,
+							tr.related_id,
 							tr.amount,
+							tr.kind,
 							tr.created_at,
-							oa.name,
-							oa.cpf,
-							da.name,
-							da.cpf
+							oc.name,
+							coalesce(oc.cpf, ''),
+							dc.name,
+							coalesce(dc.cpf, '')
 						from transfers as tr
 						inner join accounts as oa
 							on tr.account_origin_id = oa.id
+						inner join customers as oc
+							on oa.customer_id = oc.id
 						inner join accounts as da
 							on tr.account_destination_id = da.id
+						inner join customers as dc
+							on da.customer_id = dc.id
 						where 
 							tr.account_origin_id = %d 
 							or 
@@ -311,7 +672,7 @@ func (r *postgresDB) GetTransfers(ctx context.Context, id uint64, params transfe
 		for rows.Next() {
 			var transfer transfer.ListTransfer
 
-			if err := rows.Scan(&transfer.Amount, &transfer.CreatedAt, &transfer.OriginName, &transfer.OriginCpf, &transfer.DestinationName, &transfer.DestinationCpf); err != nil {
+			if err := rows.Scan(&transfer.Id, &transfer.RelatedId, &transfer.Amount, &transfer.Kind, &transfer.CreatedAt, &transfer.OriginName, &transfer.OriginCpf, &transfer.DestinationName, &transfer.DestinationCpf); err != nil {
 
 				return transferResponse, apperrors.NewDatabaseError(err.Error())
 			}
@@ -352,13 +713,29 @@ func (r *postgresDB) GetTransfers(ctx context.Context, id uint64, params transfe
 	}
 }
 
-func (r *postgresDB) AddTransfer(ctx context.Context, t transfer.TransferRequest) error {
+// AddTransfer posts t and, when it has one, its fee to the bank revenue
+// account, in a single database transaction.
+func (r *postgresDB) AddTransfer(ctx context.Context, t transfer.Transfer) (uint64, error) {
+	ids, err := r.AddTransfers(ctx, []transfer.Transfer{t})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// AddTransfers posts every transfer, with its fee, in a single database
+// transaction: either all of them are posted or none is.
+func (r *postgresDB) AddTransfers(ctx context.Context, transfers []transfer.Transfer) ([]uint64, error) {
+	var ids []uint64
+
 	select {
 	default:
 		conn, err := r.getConn()
 
 		if err != nil {
-			return err
+			return ids, err
 		}
 
 		defer conn.Release()
@@ -366,42 +743,80 @@ func (r *postgresDB) AddTransfer(ctx context.Context, t transfer.TransferRequest
 		tx, err := conn.Begin(ctx)
 
 		if err != nil {
-			return apperrors.NewDatabaseError(err.Error())
+			return ids, apperrors.NewDatabaseError(err.Error())
 		}
 
 		defer tx.Rollback(ctx)
 
-		insertTransferQuery := fmt.Sprintf("insert into transfers (account_origin_id, account_destination_id, amount) values ('%d', '%d', %d)", t.Origin, *t.Destination, *t.Amount)
-		originBalanceQuery := fmt.Sprintf("update accounts set balance = balance - %d where id = %d", *t.Amount, t.Origin)
-		destinationBalanceQuery := fmt.Sprintf("update accounts set balance = balance + %d where id = %d", *t.Amount, *t.Destination)
-		logger.Log.Debug("Add transfer insert transfer query:", insertTransferQuery)
-		logger.Log.Debug("Add transfer origin balance query:", originBalanceQuery)
-		logger.Log.Debug("Add transfer destination balance query:", destinationBalanceQuery)
-
-		if _, err = tx.Exec(ctx, insertTransferQuery); err != nil {
-			logger.Log.Error("Add transfer insert transfer query error:", err)
-			return apperrors.NewDatabaseError(err.Error())
-		}
+		for _, t := range transfers {
+			id, err := addTransfer(ctx, tx, t)
 
-		if _, err = tx.Exec(ctx, originBalanceQuery); err != nil {
-			logger.Log.Error("Add transfer origin balance query error:", err)
-			return apperrors.NewDatabaseError(err.Error())
-		}
+			if err != nil {
+				return nil, err
+			}
 
-		if _, err = tx.Exec(ctx, destinationBalanceQuery); err != nil {
-			logger.Log.Error("Add transfer destination balance query error:", err)
-			return apperrors.NewDatabaseError(err.Error())
+			ids = append(ids, id)
 		}
 
 		err = tx.Commit(ctx)
 
 		if err != nil {
 			logger.Log.Error("Add transfer database transaction commit error:", err)
-			return apperrors.NewDatabaseError(err.Error())
+			return nil, apperrors.NewDatabaseError(err.Error())
 		}
 
-		return nil
+		return ids, nil
 	case <-ctx.Done():
-		return ctx.Err()
+		return ids, ctx.Err()
+	}
+}
+
+// addTransfer checks the origin funds and limits and posts t and its fee
+// within tx.
+func addTransfer(ctx context.Context, tx pgx.Tx, t transfer.Transfer) (uint64, error) {
+	available, err := lockAvailableBalance(ctx, tx, t.Origin)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if available < t.Amount+t.Fee {
+		return 0, apperrors.NewTransferRequestError("not enough funds")
+	}
+
+	if err := checkTransferLimits(ctx, tx, t, 0); err != nil {
+		return 0, err
+	}
+
+	id, err := postTransfer(ctx, tx, t.Origin, t.Destination, t.Amount, transfer.KindTransfer, nil)
+
+	if err != nil {
+		return id, err
+	}
+
+	if t.Fee > 0 {
+		revenue, err := systemAccountId(ctx, tx, feeRevenueAccount)
+
+		if err != nil {
+			return id, err
+		}
+
+		if _, err := postTransfer(ctx, tx, t.Origin, revenue, t.Fee, transfer.KindFee, &id); err != nil {
+			return id, err
+		}
+	}
+
+	after := map[string]interface{}{"origin": t.Origin, "destination": t.Destination, "amount": t.Amount, "fee": t.Fee}
+
+	if err := appendAuditEvent(ctx, tx, audit.ActionTransferCreate, audit.Target("transfer", id), nil, after); err != nil {
+		return id, err
 	}
+
+	payload := outbox.TransferPayload{TransferId: id, Origin: t.Origin, Destination: t.Destination, Amount: t.Amount, Fee: t.Fee}
+
+	if err := addOutboxEvent(ctx, tx, outbox.TypeTransferCompleted, audit.Target("transfer", id), payload); err != nil {
+		return id, err
+	}
+
+	return id, nil
 }
@@ -0,0 +1,143 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/risk"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+// GetRiskFacts gathers what the risk rules look at for sc, counting the
+// recent transfers since since.
+func (r *postgresDB) GetRiskFacts(ctx context.Context, sc risk.Screening, since time.Time) (risk.Facts, error) {
+	facts := risk.Facts{Screening: sc}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return facts, err
+		}
+
+		defer conn.Release()
+
+		accountQuery := `select a.balance + a.overdraft_limit - ` + heldAmountExpression + `, c.secret_changed_at
+						from accounts a
+						inner join customers c
+							on a.customer_id = c.id
+						where a.id = $1`
+		logger.Log.Debug("Risk facts account query:", accountQuery, sc.Origin)
+
+		if err := conn.QueryRow(ctx, accountQuery, sc.Origin).Scan(&facts.Available, &facts.SecretChangedAt); err != nil {
+			logger.Log.Error("Risk facts account query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return facts, apperrors.NewAccountNotFoundError("account not found")
+			}
+
+			return facts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		transfersQuery := `select 
+							count(*) filter (where account_destination_id = $2),
+							count(*) filter (where created_at >= $3)
+						from transfers
+						where account_origin_id = $1 and kind = $4`
+		logger.Log.Debug("Risk facts transfers query:", transfersQuery, sc.Origin, sc.Destination, since)
+
+		if err := conn.QueryRow(ctx, transfersQuery, sc.Origin, sc.Destination, since, transfer.KindTransfer).Scan(&facts.PreviousToDestination, &facts.RecentTransfers); err != nil {
+			logger.Log.Error("Risk facts transfers query error:", err)
+			return facts, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return facts, nil
+	case <-ctx.Done():
+		return facts, ctx.Err()
+	}
+}
+
+func (r *postgresDB) AddRiskAssessment(ctx context.Context, a risk.Assessment) (uint64, error) {
+	var id uint64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return id, err
+		}
+
+		defer conn.Release()
+
+		query := `insert into risk_assessments (account_origin_id, account_destination_id, amount, decision, reasons, passed_at)
+				values ($1, $2, $3, $4, $5, $6) returning id`
+		logger.Log.Debug("Add risk assessment query:", query, a.Origin, a.Destination, a.Amount, a.Decision, a.Reasons, a.PassedAt)
+
+		if err := conn.QueryRow(ctx, query, a.Origin, a.Destination, a.Amount, a.Decision, a.Reasons, a.PassedAt).Scan(&id); err != nil {
+			logger.Log.Error("Add risk assessment query error:", err)
+			return id, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return id, nil
+	case <-ctx.Done():
+		return id, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListRiskAssessments(ctx context.Context, q risk.ListAssessmentsQuery) ([]risk.Assessment, error) {
+	assessments := []risk.Assessment{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return assessments, err
+		}
+
+		defer conn.Release()
+
+		query := `select id, account_origin_id, account_destination_id, amount, decision, reasons, passed_at, created_at
+				from risk_assessments
+				where $1 = '' or decision = $1
+				order by id desc
+				limit $2`
+		logger.Log.Debug("List risk assessments query:", query, q.Decision, q.Limit)
+
+		rows, err := conn.Query(ctx, query, q.Decision, q.Limit)
+
+		if err != nil {
+			logger.Log.Error("List risk assessments query error:", err)
+			return assessments, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var a risk.Assessment
+
+			if err := rows.Scan(&a.Id, &a.Origin, &a.Destination, &a.Amount, &a.Decision, &a.Reasons, &a.PassedAt, &a.CreatedAt); err != nil {
+				logger.Log.Error("List risk assessments scan error:", err)
+				return assessments, apperrors.NewDatabaseError(err.Error())
+			}
+
+			assessments = append(assessments, a)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List risk assessments rows error:", err)
+			return assessments, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return assessments, nil
+	case <-ctx.Done():
+		return assessments, ctx.Err()
+	}
+}
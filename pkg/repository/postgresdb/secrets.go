@@ -0,0 +1,256 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+// setSecretQuery replaces the secret of a customer and records when, which
+// expires the tokens issued before, and drops its unused reset tokens and
+// open sessions. A lockout of the old secret does not carry over.
+const setSecretQuery = `with changed as (
+		update customers set secret = $2, secret_changed_at = now(), updated_at = now(),
+			secret_failed_attempts = 0, secret_locked_until = null
+		where id = $1
+		returning id
+	), expired as (
+		update secret_reset_tokens set used_at = now()
+		where customer_id in (select id from changed) and used_at is null
+	), terminated as (
+		update sessions set terminated_at = now()
+		where customer_id in (select id from changed) and terminated_at is null
+	)
+	select id from changed`
+
+func (r *postgresDB) SetCustomerSecret(ctx context.Context, id uint64, hash string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		if err := setCustomerSecret(ctx, tx, id, hash, false); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Set customer secret database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setCustomerSecret runs setSecretQuery as part of tx, audits it and
+// publishes the change, so the customer can be told about it.
+func setCustomerSecret(ctx context.Context, tx pgx.Tx, id uint64, hash string, reset bool) error {
+	logger.Log.Debug("Set customer secret query:", setSecretQuery, id)
+
+	if err := tx.QueryRow(ctx, setSecretQuery, id, hash).Scan(&id); err != nil {
+		logger.Log.Error("Set customer secret query error:", err)
+
+		if errors.Is(err, pgx.ErrNoRows) {
+			return apperrors.NewAccountNotFoundError("customer not found")
+		}
+
+		return apperrors.NewDatabaseError(err.Error())
+	}
+
+	// A reset is asked for without a session, the customer is the actor.
+	action := audit.ActionSecretChange
+	if reset {
+		action = audit.ActionSecretReset
+		ctx = audit.WithCustomer(ctx, id, "")
+	}
+
+	if err := appendAuditEvent(ctx, tx, action, audit.Target("customer", id), nil, nil); err != nil {
+		return err
+	}
+
+	return addOutboxEvent(ctx, tx, outbox.TypeSecretChanged, audit.Target("customer", id),
+		outbox.SecretChangedPayload{CustomerId: id, Reset: reset})
+}
+
+// CountSecretAttempt counts an attempt against the customer secret as a wrong
+// one before it is checked and returns the secret hash to check it with. The
+// attempt that reaches maxAttempts locks the secret for lockout and starts the
+// count over. Nothing is counted while it is locked, and an empty hash is
+// returned along with when the lockout ends.
+func (r *postgresDB) CountSecretAttempt(ctx context.Context, id uint64, maxAttempts int, lockout time.Duration) (string, *time.Time, error) {
+	var hash string
+	var lockedUntil *time.Time
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return hash, lockedUntil, err
+		}
+
+		defer conn.Release()
+
+		query := `update customers set
+					secret_locked_until = case when secret_failed_attempts + 1 >= $2 then $3 else secret_locked_until end,
+					secret_failed_attempts = case when secret_failed_attempts + 1 >= $2 then 0 else secret_failed_attempts + 1 end
+				where id = $1 and (secret_locked_until is null or secret_locked_until <= now())
+				returning secret`
+		logger.Log.Debug("Count secret attempt query:", query, id)
+
+		err = conn.QueryRow(ctx, query, id, maxAttempts, time.Now().Add(lockout)).Scan(&hash)
+
+		if err == nil {
+			return hash, lockedUntil, nil
+		}
+
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logger.Log.Error("Count secret attempt query error:", err)
+			return hash, lockedUntil, apperrors.NewDatabaseError(err.Error())
+		}
+
+		query = "select secret_locked_until from customers where id = $1"
+		logger.Log.Debug("Get secret lockout query:", query, id)
+
+		if err := conn.QueryRow(ctx, query, id).Scan(&lockedUntil); err != nil {
+			logger.Log.Error("Get secret lockout query error:", err)
+
+			if errors.Is(err, pgx.ErrNoRows) {
+				return hash, lockedUntil, apperrors.NewAccountNotFoundError("customer not found")
+			}
+
+			return hash, lockedUntil, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return hash, lockedUntil, nil
+	case <-ctx.Done():
+		return hash, lockedUntil, ctx.Err()
+	}
+}
+
+// ResetSecretFailures clears the count of wrong attempts, and the lockout the
+// attempt being reset may have set, after a right secret.
+func (r *postgresDB) ResetSecretFailures(ctx context.Context, id uint64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := "update customers set secret_failed_attempts = 0, secret_locked_until = null where id = $1"
+		logger.Log.Debug("Reset secret failures query:", query, id)
+
+		if _, err := conn.Exec(ctx, query, id); err != nil {
+			logger.Log.Error("Reset secret failures query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) AddSecretResetToken(ctx context.Context, customerId uint64, hash string, expiresAt time.Time) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := "insert into secret_reset_tokens (customer_id, token_hash, expires_at) values ($1, $2, $3)"
+		logger.Log.Debug("Add secret reset token query:", query, customerId, expiresAt)
+
+		if _, err := conn.Exec(ctx, query, customerId, hash, expiresAt); err != nil {
+			logger.Log.Error("Add secret reset token query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ResetCustomerSecret uses the reset token with tokenHash, if it is unused and
+// unexpired, and sets the secret of its customer in a single database
+// transaction.
+func (r *postgresDB) ResetCustomerSecret(ctx context.Context, tokenHash string, secretHash string) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var customerId uint64
+
+		query := `update secret_reset_tokens set used_at = now()
+				where token_hash = $1 and used_at is null and expires_at > now()
+				returning customer_id`
+		logger.Log.Debug("Use secret reset token query:", query)
+
+		if err := tx.QueryRow(ctx, query, tokenHash).Scan(&customerId); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return apperrors.NewAuthError("invalid or expired reset token")
+			}
+
+			logger.Log.Error("Use secret reset token query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := setCustomerSecret(ctx, tx, customerId, secretHash, true); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Reset customer secret database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
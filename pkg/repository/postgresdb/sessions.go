@@ -0,0 +1,284 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+const sessionColumns = "id, customer_id, user_agent, ip, created_at, last_seen_at, expires_at, terminated_at"
+
+// AddSession opens a session, which is when a login succeeds, and audits it.
+// The LoginSucceeded event tells whether the customer used the device before.
+func (r *postgresDB) AddSession(ctx context.Context, s login.Session) (login.Session, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return s, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		var known, sameDevice int64
+
+		devicesQuery := `select count(*), count(*) filter (where user_agent = $2) from sessions where customer_id = $1`
+		logger.Log.Debug("Session devices query:", devicesQuery, s.CustomerId, s.UserAgent)
+
+		if err := tx.QueryRow(ctx, devicesQuery, s.CustomerId, s.UserAgent).Scan(&known, &sameDevice); err != nil {
+			logger.Log.Error("Session devices query error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		query := `insert into sessions (customer_id, user_agent, ip, expires_at)
+				values ($1, $2, $3, $4) returning ` + sessionColumns
+		logger.Log.Debug("Add session query:", query, s.CustomerId, s.UserAgent, s.Ip, s.ExpiresAt)
+
+		s, err = scanSession(tx.QueryRow(ctx, query, s.CustomerId, s.UserAgent, s.Ip, s.ExpiresAt))
+
+		if err != nil {
+			logger.Log.Error("Add session query error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		after := map[string]interface{}{"sessionId": s.Id, "userAgent": s.UserAgent}
+
+		if err := appendAuditEvent(audit.WithCustomer(ctx, s.CustomerId, ""), tx, audit.ActionLogin,
+			audit.Target("customer", s.CustomerId), nil, after); err != nil {
+			return s, err
+		}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeLoginSucceeded, audit.Target("customer", s.CustomerId),
+			outbox.LoginSucceededPayload{CustomerId: s.CustomerId, SessionId: s.Id, Ip: s.Ip, UserAgent: s.UserAgent,
+				NewDevice: known > 0 && sameDevice == 0}); err != nil {
+			return s, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add session database transaction commit error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return s, nil
+	case <-ctx.Done():
+		return s, ctx.Err()
+	}
+}
+
+// AddLoginFailure publishes a LoginFailed event. It is the only trace of a
+// failed login, which changes nothing else.
+func (r *postgresDB) AddLoginFailure(ctx context.Context, f login.Failure) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		if f.CustomerId == nil && f.Cpf != "" {
+			var customerId uint64
+			query := "select id from customers where cpf = $1"
+			logger.Log.Debug("Login failure customer query:", query, f.Cpf)
+
+			err := tx.QueryRow(ctx, query, f.Cpf).Scan(&customerId)
+
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				logger.Log.Error("Login failure customer query error:", err)
+				return apperrors.NewDatabaseError(err.Error())
+			}
+
+			if err == nil {
+				f.CustomerId = &customerId
+			}
+		}
+
+		subject := "customer:unknown"
+		if f.CustomerId != nil {
+			subject = audit.Target("customer", *f.CustomerId)
+		}
+
+		payload := outbox.LoginFailedPayload{CustomerId: f.CustomerId, Reason: f.Reason, Ip: f.Device.Ip}
+
+		if err := addOutboxEvent(ctx, tx, outbox.TypeLoginFailed, subject, payload); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add login failure database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) GetSession(ctx context.Context, id uint64) (login.Session, error) {
+	var s login.Session
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return s, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + sessionColumns + " from sessions where id = $1"
+		logger.Log.Debug("Get session query:", query, id)
+
+		s, err = scanSession(conn.QueryRow(ctx, query, id))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return s, apperrors.NewAccountNotFoundError("session not found")
+			}
+
+			logger.Log.Error("Get session query error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return s, nil
+	case <-ctx.Done():
+		return s, ctx.Err()
+	}
+}
+
+func (r *postgresDB) TouchSession(ctx context.Context, id uint64) error {
+	return r.execSession(ctx, "Touch session", "update sessions set last_seen_at = now() where id = $1", id)
+}
+
+// ExtendSession pushes the expiry of a session, as a new token was issued
+// from it.
+func (r *postgresDB) ExtendSession(ctx context.Context, id uint64, expiresAt time.Time) error {
+	return r.execSession(ctx, "Extend session", "update sessions set expires_at = greatest(expires_at, $2) where id = $1", id, expiresAt)
+}
+
+// ListCustomerSessions returns the sessions of the customer with tokens still
+// good, the most recently used first.
+func (r *postgresDB) ListCustomerSessions(ctx context.Context, customerId uint64) ([]login.Session, error) {
+	sessions := []login.Session{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return sessions, err
+		}
+
+		defer conn.Release()
+
+		query := `select ` + sessionColumns + ` from sessions
+				where customer_id = $1 and terminated_at is null and expires_at > now()
+				order by last_seen_at desc`
+		logger.Log.Debug("List customer sessions query:", query, customerId)
+
+		rows, err := conn.Query(ctx, query, customerId)
+
+		if err != nil {
+			logger.Log.Error("List customer sessions query error:", err)
+			return sessions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanSession(rows)
+
+			if err != nil {
+				logger.Log.Error("List customer sessions scan error:", err)
+				return sessions, apperrors.NewDatabaseError(err.Error())
+			}
+
+			sessions = append(sessions, s)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List customer sessions rows error:", err)
+			return sessions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return sessions, nil
+	case <-ctx.Done():
+		return sessions, ctx.Err()
+	}
+}
+
+// TerminateSession ends a session of the customer. Sessions already
+// terminated, or of other customers, are not found.
+func (r *postgresDB) TerminateSession(ctx context.Context, customerId uint64, id uint64) error {
+	return r.execSession(ctx, "Terminate session",
+		"update sessions set terminated_at = now() where id = $1 and customer_id = $2 and terminated_at is null", id, customerId)
+}
+
+// execSession runs an update of a session, which is not found when the
+// update changes no row.
+func (r *postgresDB) execSession(ctx context.Context, name string, query string, args ...interface{}) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		logger.Log.Debug(append([]interface{}{name + " query:", query}, args...)...)
+
+		tag, err := conn.Exec(ctx, query, args...)
+
+		if err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("session not found")
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func scanSession(row pgx.Row) (login.Session, error) {
+	var s login.Session
+
+	err := row.Scan(&s.Id, &s.CustomerId, &s.UserAgent, &s.Ip, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.TerminatedAt)
+
+	return s, err
+}
@@ -0,0 +1,87 @@
+package postgresdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/tariff"
+	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+)
+
+func (r *postgresDB) ListTariffs(ctx context.Context, service string) ([]tariff.Tariff, error) {
+	tariffs := []tariff.Tariff{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return tariffs, err
+		}
+
+		defer conn.Release()
+
+		query := `select service, coalesce(product, ''), free_per_month, fee 
+				from tariffs 
+				where service = $1 and active 
+				order by product nulls last`
+		logger.Log.Debug("List tariffs query:", query, service)
+		rows, err := conn.Query(ctx, query, service)
+
+		if err != nil {
+			logger.Log.Error("List tariffs query error:", err)
+			return tariffs, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var t tariff.Tariff
+
+			if err := rows.Scan(&t.Service, &t.Product, &t.FreePerMonth, &t.Fee); err != nil {
+				return tariffs, apperrors.NewDatabaseError(err.Error())
+			}
+
+			tariffs = append(tariffs, t)
+		}
+
+		if err := rows.Err(); err != nil {
+			return tariffs, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return tariffs, nil
+	case <-ctx.Done():
+		return tariffs, ctx.Err()
+	}
+}
+
+// CountMonthTransfers counts the transfers sent by the account since the
+// given start of month.
+func (r *postgresDB) CountMonthTransfers(ctx context.Context, id uint64, since time.Time) (int64, error) {
+	var count int64
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return count, err
+		}
+
+		defer conn.Release()
+
+		query := "select count(*) from transfers where account_origin_id = $1 and kind = $2 and created_at >= $3"
+		logger.Log.Debug("Count month transfers query:", query, id, since)
+
+		if err := conn.QueryRow(ctx, query, id, transfer.KindTransfer, since).Scan(&count); err != nil {
+			logger.Log.Error("Count month transfers query error:", err)
+			return count, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return count, nil
+	case <-ctx.Done():
+		return count, ctx.Err()
+	}
+}
@@ -0,0 +1,468 @@
+package postgresdb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	pgx "github.com/jackc/pgx/v4"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/webhook"
+)
+
+const webhookSubscriptionColumns = "id, account_id, client_id, url, event_types, secret, created_at"
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+					last_status_code, last_error, created_at, delivered_at`
+
+func (r *postgresDB) AddWebhookSubscription(ctx context.Context, s webhook.Subscription) (webhook.Subscription, error) {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return s, err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into webhook_subscriptions (account_id, client_id, url, event_types, secret)
+				values ($1, $2, $3, $4, $5) returning ` + webhookSubscriptionColumns
+		logger.Log.Debug("Add webhook subscription query:", query, s.AccountId, s.ClientId, s.Url, s.EventTypes)
+
+		s, err = scanWebhookSubscription(tx.QueryRow(ctx, query, s.AccountId, s.ClientId, s.Url, s.EventTypes, s.Secret))
+
+		if err != nil {
+			logger.Log.Error("Add webhook subscription query error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		// The signing secret is not encoded, it stays out of the event.
+		if err := appendAuditEvent(ctx, tx, audit.ActionWebhookCreate, audit.Target("webhook", s.Id), nil, s); err != nil {
+			return s, err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add webhook subscription database transaction commit error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return s, nil
+	case <-ctx.Done():
+		return s, ctx.Err()
+	}
+}
+
+// GetWebhookSubscription returns the subscription with id, unless it was
+// deleted.
+func (r *postgresDB) GetWebhookSubscription(ctx context.Context, id uint64) (webhook.Subscription, error) {
+	var s webhook.Subscription
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return s, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + webhookSubscriptionColumns + " from webhook_subscriptions where id = $1 and deleted_at is null"
+		logger.Log.Debug("Get webhook subscription query:", query, id)
+
+		s, err = scanWebhookSubscription(conn.QueryRow(ctx, query, id))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return s, apperrors.NewAccountNotFoundError("webhook subscription not found")
+			}
+
+			logger.Log.Error("Get webhook subscription query error:", err)
+			return s, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return s, nil
+	case <-ctx.Done():
+		return s, ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListWebhookSubscriptions(ctx context.Context, o webhook.Owner) ([]webhook.Subscription, error) {
+	query := "select " + webhookSubscriptionColumns + ` from webhook_subscriptions
+			where account_id = $1 and client_id = $2 and deleted_at is null order by id`
+
+	return r.listWebhookSubscriptions(ctx, "List webhook subscriptions", query, o.AccountId, o.ClientId)
+}
+
+// ListAccountWebhookSubscriptions returns the subscriptions of the account to
+// eventType, the ones of revoked API clients left out.
+func (r *postgresDB) ListAccountWebhookSubscriptions(ctx context.Context, accountId uint64, eventType string) ([]webhook.Subscription, error) {
+	query := "select " + webhookSubscriptionColumns + ` from webhook_subscriptions s
+			where s.account_id = $1 and $2 = any(s.event_types) and s.deleted_at is null
+			and (s.client_id = '' or exists (
+				select 1 from api_clients c where c.client_id = s.client_id and c.active
+			))
+			order by s.id`
+
+	return r.listWebhookSubscriptions(ctx, "List account webhook subscriptions", query, accountId, eventType)
+}
+
+func (r *postgresDB) listWebhookSubscriptions(ctx context.Context, name string, query string, args ...interface{}) ([]webhook.Subscription, error) {
+	subscriptions := []webhook.Subscription{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return subscriptions, err
+		}
+
+		defer conn.Release()
+
+		logger.Log.Debug(append([]interface{}{name + " query:", query}, args...)...)
+
+		rows, err := conn.Query(ctx, query, args...)
+
+		if err != nil {
+			logger.Log.Error(name+" query error:", err)
+			return subscriptions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			s, err := scanWebhookSubscription(rows)
+
+			if err != nil {
+				logger.Log.Error(name+" scan error:", err)
+				return subscriptions, apperrors.NewDatabaseError(err.Error())
+			}
+
+			subscriptions = append(subscriptions, s)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error(name+" rows error:", err)
+			return subscriptions, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return subscriptions, nil
+	case <-ctx.Done():
+		return subscriptions, ctx.Err()
+	}
+}
+
+// DeleteWebhookSubscription marks the subscription deleted, keeping its
+// deliveries, and gives up on the ones still pending.
+func (r *postgresDB) DeleteWebhookSubscription(ctx context.Context, id uint64) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := "update webhook_subscriptions set deleted_at = now() where id = $1 and deleted_at is null"
+		logger.Log.Debug("Delete webhook subscription query:", query, id)
+
+		tag, err := tx.Exec(ctx, query, id)
+
+		if err != nil {
+			logger.Log.Error("Delete webhook subscription query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if tag.RowsAffected() == 0 {
+			return apperrors.NewAccountNotFoundError("webhook subscription not found")
+		}
+
+		query = `update webhook_deliveries set status = $2, next_attempt_at = null, last_error = 'subscription deleted'
+				where subscription_id = $1 and status = $3`
+		logger.Log.Debug("Drop webhook deliveries query:", query, id)
+
+		if _, err := tx.Exec(ctx, query, id, webhook.StatusDead, webhook.StatusPending); err != nil {
+			logger.Log.Error("Drop webhook deliveries query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		if err := appendAuditEvent(ctx, tx, audit.ActionWebhookDelete, audit.Target("webhook", id), nil, nil); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Delete webhook subscription database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AddWebhookDeliveries queues the deliveries, due right away. A delivery of
+// an event already queued for the subscription is skipped.
+func (r *postgresDB) AddWebhookDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		tx, err := conn.Begin(ctx)
+
+		if err != nil {
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer tx.Rollback(ctx)
+
+		query := `insert into webhook_deliveries (subscription_id, event_id, event_type, payload, created_at, next_attempt_at)
+				values ($1, $2, $3, $4, $5, now())
+				on conflict (subscription_id, event_id, event_type) do nothing`
+
+		for _, d := range deliveries {
+			logger.Log.Debug("Add webhook delivery query:", query, d.SubscriptionId, d.EventId, d.EventType)
+
+			if _, err := tx.Exec(ctx, query, d.SubscriptionId, d.EventId, d.EventType, string(d.Payload), d.CreatedAt); err != nil {
+				logger.Log.Error("Add webhook delivery query error:", err)
+				return apperrors.NewDatabaseError(err.Error())
+			}
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			logger.Log.Error("Add webhook deliveries database transaction commit error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *postgresDB) ListWebhookDeliveries(ctx context.Context, subscriptionId uint64, limit int) ([]webhook.Delivery, error) {
+	deliveries := []webhook.Delivery{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return deliveries, err
+		}
+
+		defer conn.Release()
+
+		query := "select " + webhookDeliveryColumns + " from webhook_deliveries where subscription_id = $1 order by id desc limit $2"
+		logger.Log.Debug("List webhook deliveries query:", query, subscriptionId, limit)
+
+		rows, err := conn.Query(ctx, query, subscriptionId, limit)
+
+		if err != nil {
+			logger.Log.Error("List webhook deliveries query error:", err)
+			return deliveries, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			d, err := scanWebhookDelivery(rows)
+
+			if err != nil {
+				logger.Log.Error("List webhook deliveries scan error:", err)
+				return deliveries, apperrors.NewDatabaseError(err.Error())
+			}
+
+			deliveries = append(deliveries, d)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("List webhook deliveries rows error:", err)
+			return deliveries, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return deliveries, nil
+	case <-ctx.Done():
+		return deliveries, ctx.Err()
+	}
+}
+
+// ReplayWebhookDelivery makes a delivery of the subscription pending again,
+// due right away and with no attempts.
+func (r *postgresDB) ReplayWebhookDelivery(ctx context.Context, subscriptionId uint64, id uint64) (webhook.Delivery, error) {
+	var d webhook.Delivery
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return d, err
+		}
+
+		defer conn.Release()
+
+		query := `update webhook_deliveries set status = $3, attempts = 0, next_attempt_at = now(), last_error = ''
+				where id = $1 and subscription_id = $2 returning ` + webhookDeliveryColumns
+		logger.Log.Debug("Replay webhook delivery query:", query, id, subscriptionId)
+
+		d, err = scanWebhookDelivery(conn.QueryRow(ctx, query, id, subscriptionId, webhook.StatusPending))
+
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return d, apperrors.NewAccountNotFoundError("webhook delivery not found")
+			}
+
+			logger.Log.Error("Replay webhook delivery query error:", err)
+			return d, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return d, nil
+	case <-ctx.Done():
+		return d, ctx.Err()
+	}
+}
+
+// ClaimDueWebhookDeliveries returns up to limit pending deliveries due at now
+// and pushes their next attempt to leaseUntil, so that no other instance
+// attempts them meanwhile.
+func (r *postgresDB) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]webhook.DueDelivery, error) {
+	due := []webhook.DueDelivery{}
+
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return due, err
+		}
+
+		defer conn.Release()
+
+		query := `with claimed as (
+					update webhook_deliveries set next_attempt_at = $3
+					where id in (
+						select id from webhook_deliveries
+						where status = $4 and next_attempt_at <= $1
+						order by next_attempt_at
+						limit $2
+						for update skip locked
+					)
+					returning ` + webhookDeliveryColumns + `
+				)
+				select d.*, s.url, s.secret from claimed d
+				inner join webhook_subscriptions s on s.id = d.subscription_id
+				order by d.id`
+		logger.Log.Debug("Claim due webhook deliveries query:", query, now, limit, leaseUntil)
+
+		rows, err := conn.Query(ctx, query, now, limit, leaseUntil, webhook.StatusPending)
+
+		if err != nil {
+			logger.Log.Error("Claim due webhook deliveries query error:", err)
+			return due, apperrors.NewDatabaseError(err.Error())
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var dd webhook.DueDelivery
+			var payload string
+
+			if err := rows.Scan(&dd.Id, &dd.SubscriptionId, &dd.EventId, &dd.EventType, &payload, &dd.Status, &dd.Attempts,
+				&dd.NextAttemptAt, &dd.LastStatusCode, &dd.LastError, &dd.CreatedAt, &dd.DeliveredAt, &dd.Url, &dd.Secret); err != nil {
+				logger.Log.Error("Claim due webhook deliveries scan error:", err)
+				return due, apperrors.NewDatabaseError(err.Error())
+			}
+
+			dd.Payload = []byte(payload)
+			due = append(due, dd)
+		}
+
+		if err := rows.Err(); err != nil {
+			logger.Log.Error("Claim due webhook deliveries rows error:", err)
+			return due, apperrors.NewDatabaseError(err.Error())
+		}
+
+		return due, nil
+	case <-ctx.Done():
+		return due, ctx.Err()
+	}
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt.
+func (r *postgresDB) UpdateWebhookDelivery(ctx context.Context, d webhook.Delivery) error {
+	select {
+	default:
+		conn, err := r.getConn()
+
+		if err != nil {
+			return err
+		}
+
+		defer conn.Release()
+
+		query := `update webhook_deliveries set status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+					last_error = $6, delivered_at = $7
+				where id = $1`
+		logger.Log.Debug("Update webhook delivery query:", query, d.Id, d.Status, d.Attempts, d.NextAttemptAt)
+
+		if _, err := conn.Exec(ctx, query, d.Id, d.Status, d.Attempts, d.NextAttemptAt, d.LastStatusCode,
+			d.LastError, d.DeliveredAt); err != nil {
+			logger.Log.Error("Update webhook delivery query error:", err)
+			return apperrors.NewDatabaseError(err.Error())
+		}
+
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func scanWebhookSubscription(row pgx.Row) (webhook.Subscription, error) {
+	var s webhook.Subscription
+
+	err := row.Scan(&s.Id, &s.AccountId, &s.ClientId, &s.Url, &s.EventTypes, &s.Secret, &s.CreatedAt)
+
+	return s, err
+}
+
+func scanWebhookDelivery(row pgx.Row) (webhook.Delivery, error) {
+	var d webhook.Delivery
+	var payload string
+
+	err := row.Scan(&d.Id, &d.SubscriptionId, &d.EventId, &d.EventType, &payload, &d.Status, &d.Attempts,
+		&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+
+	d.Payload = []byte(payload)
+
+	return d, err
+}
@@ -0,0 +1,83 @@
+package risk
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// FileRules reads the rules Config from a JSON file, reloading it whenever
+// the file changes so rules can be tuned without a redeploy. A file that
+// fails to load keeps the last good config in place.
+type FileRules struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	config  Config
+}
+
+// NewFileRules watches path. An empty path always uses DefaultConfig.
+func NewFileRules(path string) *FileRules {
+	return &FileRules{path: path, config: DefaultConfig}
+}
+
+// Config returns the current rules config.
+func (f *FileRules) Config() Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path == "" {
+		return f.config
+	}
+
+	info, err := os.Stat(f.path)
+
+	if err != nil {
+		logger.Log.Error("Risk rules file stat error:", err)
+		return f.config
+	}
+
+	if info.ModTime().Equal(f.modTime) {
+		return f.config
+	}
+
+	c, err := readConfig(f.path)
+
+	if err != nil {
+		logger.Log.Error("Risk rules file load error, keeping the previous rules:", err)
+		f.modTime = info.ModTime()
+		return f.config
+	}
+
+	logger.Log.Info("Risk rules loaded from", f.path)
+
+	f.modTime = info.ModTime()
+	f.config = c
+
+	return f.config
+}
+
+func readConfig(path string) (Config, error) {
+	var c Config
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return c, err
+	}
+
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&c); err != nil {
+		return c, err
+	}
+
+	return c, c.validate()
+}
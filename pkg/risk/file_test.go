@@ -0,0 +1,58 @@
+package risk
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
+func TestFileRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "risk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "rules.json")
+	write := func(content string, modTime time.Time) {
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+
+	if c := NewFileRules("").Config(); c != DefaultConfig {
+		t.Errorf("no file: got %v want the default config", c)
+	}
+
+	write(`{"velocity": {"enabled": true, "count": 3, "windowMinutes": 5, "decision": "block"}}`, start)
+	f := NewFileRules(path)
+
+	if c := f.Config(); c.Velocity.Count != 3 || c.BalanceDrain.Enabled {
+		t.Errorf("file: got %v", c)
+	}
+
+	write(`{"velocity": {"enabled": true, "count": 7, "windowMinutes": 5, "decision": "block"}}`, start.Add(time.Minute))
+
+	if c := f.Config(); c.Velocity.Count != 7 {
+		t.Errorf("changed file: got velocity count %d want 7", c.Velocity.Count)
+	}
+
+	write(`{"velocity": {"enabled": true, "count": 9, "windowMinutes": 5, "decision": "maybe"}}`, start.Add(2*time.Minute))
+
+	if c := f.Config(); c.Velocity.Count != 7 {
+		t.Errorf("invalid file: got velocity count %d want the previous 7", c.Velocity.Count)
+	}
+}
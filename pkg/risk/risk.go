@@ -0,0 +1,71 @@
+package risk
+
+import "time"
+
+// Decisions of a screening, from the least to the most severe.
+const (
+	Allow     = "allow"
+	Challenge = "challenge"
+	Block     = "block"
+)
+
+var severity = map[string]int{
+	Allow:     0,
+	Challenge: 1,
+	Block:     2,
+}
+
+// Screening is a transfer about to be executed, or a hold about to be
+// authorized.
+type Screening struct {
+	Origin      uint64
+	Destination uint64
+	Amount      int64
+	// CustomerId answers a challenge with Secret, the customer secret, as a
+	// step-up. Approved transfers had theirs answered by the approvers.
+	CustomerId uint64
+	Secret     string
+	Approved   bool
+}
+
+// Facts are what the rules know about a screening, gathered from the origin
+// history.
+type Facts struct {
+	Screening
+	Now time.Time
+	// Available is what the origin can spend, counting its overdraft limit
+	// and holds.
+	Available int64
+	// PreviousToDestination counts the transfers already sent from the
+	// origin to the destination.
+	PreviousToDestination int64
+	// RecentTransfers counts the transfers sent from the origin since the
+	// start of the velocity window.
+	RecentTransfers int64
+	// SecretChangedAt is the last time the origin customer changed their
+	// secret, if ever.
+	SecretChangedAt *time.Time
+}
+
+// Assessment is the outcome of a screening: the most severe decision of the
+// rules hit and why each of them was. PassedAt is when a challenge was
+// answered, letting the transfer through.
+type Assessment struct {
+	Id          uint64     `json:"id"`
+	Origin      uint64     `json:"origin"`
+	Destination uint64     `json:"destination"`
+	Amount      int64      `json:"amount"`
+	Decision    string     `json:"decision"`
+	Reasons     []string   `json:"reasons"`
+	PassedAt    *time.Time `json:"passedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+type ListAssessmentsQuery struct {
+	Decision string
+	Limit    int
+}
+
+type ListAssessmentsResponse struct {
+	Assessments []Assessment `json:"assessments"`
+}
@@ -0,0 +1,182 @@
+package risk
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rule looks at the facts of a screening and, when hit, tells the decision
+// it asks for and why.
+type Rule interface {
+	Evaluate(Facts) (decision string, reason string, hit bool)
+}
+
+// Config sets up the built in rules. It is read from the rules file, see
+// FileRules.
+type Config struct {
+	NewRecipient       NewRecipientRule       `json:"newRecipient"`
+	Velocity           VelocityRule           `json:"velocity"`
+	RecentSecretChange RecentSecretChangeRule `json:"recentSecretChange"`
+	BalanceDrain       BalanceDrainRule       `json:"balanceDrain"`
+}
+
+// DefaultConfig is used when no rules file is configured.
+var DefaultConfig = Config{
+	NewRecipient:       NewRecipientRule{Enabled: true, Amount: 100000, Decision: Challenge},
+	Velocity:           VelocityRule{Enabled: true, Count: 5, WindowMinutes: 10, Decision: Challenge},
+	RecentSecretChange: RecentSecretChangeRule{Enabled: true, WithinMinutes: 24 * 60, Decision: Challenge},
+	BalanceDrain:       BalanceDrainRule{Enabled: true, Percent: 90, Decision: Block},
+}
+
+// Rules returns the enabled rules of c.
+func (c Config) Rules() []Rule {
+	var rules []Rule
+
+	if c.NewRecipient.Enabled {
+		rules = append(rules, c.NewRecipient)
+	}
+
+	if c.Velocity.Enabled {
+		rules = append(rules, c.Velocity)
+	}
+
+	if c.RecentSecretChange.Enabled {
+		rules = append(rules, c.RecentSecretChange)
+	}
+
+	if c.BalanceDrain.Enabled {
+		rules = append(rules, c.BalanceDrain)
+	}
+
+	return rules
+}
+
+// VelocityWindow is how far back recent transfers are counted.
+func (c Config) VelocityWindow() time.Duration {
+	return time.Duration(c.Velocity.WindowMinutes) * time.Minute
+}
+
+func (c Config) validate() error {
+	decisions := map[string]string{
+		"newRecipient":       c.NewRecipient.Decision,
+		"velocity":           c.Velocity.Decision,
+		"recentSecretChange": c.RecentSecretChange.Decision,
+		"balanceDrain":       c.BalanceDrain.Decision,
+	}
+	enabled := map[string]bool{
+		"newRecipient":       c.NewRecipient.Enabled,
+		"velocity":           c.Velocity.Enabled,
+		"recentSecretChange": c.RecentSecretChange.Enabled,
+		"balanceDrain":       c.BalanceDrain.Enabled,
+	}
+
+	for _, name := range []string{"newRecipient", "velocity", "recentSecretChange", "balanceDrain"} {
+		if _, ok := severity[decisions[name]]; enabled[name] && !ok {
+			return fmt.Errorf("%s: unknown decision %q", name, decisions[name])
+		}
+	}
+
+	if c.Velocity.Enabled && (c.Velocity.Count < 1 || c.Velocity.WindowMinutes < 1) {
+		return fmt.Errorf("velocity count and windowMinutes must be positive")
+	}
+
+	if c.BalanceDrain.Enabled && (c.BalanceDrain.Percent < 1 || c.BalanceDrain.Percent > 100) {
+		return fmt.Errorf("balanceDrain percent must be between 1 and 100")
+	}
+
+	return nil
+}
+
+// NewRecipientRule is hit by a first transfer to a destination above Amount.
+type NewRecipientRule struct {
+	Enabled  bool   `json:"enabled"`
+	Amount   int64  `json:"amount"`
+	Decision string `json:"decision"`
+}
+
+func (r NewRecipientRule) Evaluate(f Facts) (string, string, bool) {
+	if f.PreviousToDestination == 0 && f.Amount > r.Amount {
+		return r.Decision, fmt.Sprintf("first transfer to this recipient above %d", r.Amount), true
+	}
+
+	return Allow, "", false
+}
+
+// VelocityRule is hit when the transfer would make more than Count transfers
+// from the origin in WindowMinutes.
+type VelocityRule struct {
+	Enabled       bool   `json:"enabled"`
+	Count         int64  `json:"count"`
+	WindowMinutes int64  `json:"windowMinutes"`
+	Decision      string `json:"decision"`
+}
+
+func (r VelocityRule) Evaluate(f Facts) (string, string, bool) {
+	if f.RecentTransfers+1 > r.Count {
+		return r.Decision, fmt.Sprintf("more than %d transfers in %d minutes", r.Count, r.WindowMinutes), true
+	}
+
+	return Allow, "", false
+}
+
+// RecentSecretChangeRule is hit by transfers within WithinMinutes of a secret
+// change, a common step of account takeovers.
+type RecentSecretChangeRule struct {
+	Enabled       bool   `json:"enabled"`
+	WithinMinutes int64  `json:"withinMinutes"`
+	Decision      string `json:"decision"`
+}
+
+func (r RecentSecretChangeRule) Evaluate(f Facts) (string, string, bool) {
+	within := time.Duration(r.WithinMinutes) * time.Minute
+
+	if f.SecretChangedAt != nil && f.Now.Sub(*f.SecretChangedAt) < within {
+		return r.Decision, fmt.Sprintf("secret changed less than %d minutes ago", r.WithinMinutes), true
+	}
+
+	return Allow, "", false
+}
+
+// BalanceDrainRule is hit by transfers taking more than Percent of what the
+// origin can spend.
+type BalanceDrainRule struct {
+	Enabled  bool   `json:"enabled"`
+	Percent  int64  `json:"percent"`
+	Decision string `json:"decision"`
+}
+
+func (r BalanceDrainRule) Evaluate(f Facts) (string, string, bool) {
+	if f.Available <= 0 || f.Amount*100 > f.Available*r.Percent {
+		return r.Decision, fmt.Sprintf("transfer takes more than %d%% of the available balance", r.Percent), true
+	}
+
+	return Allow, "", false
+}
+
+// Evaluate runs every rule on f. The assessment decision is the most severe
+// one asked for, allow when no rule is hit.
+func Evaluate(rules []Rule, f Facts) Assessment {
+	a := Assessment{
+		Origin:      f.Origin,
+		Destination: f.Destination,
+		Amount:      f.Amount,
+		Decision:    Allow,
+		Reasons:     []string{},
+	}
+
+	for _, rule := range rules {
+		decision, reason, hit := rule.Evaluate(f)
+
+		if !hit {
+			continue
+		}
+
+		a.Reasons = append(a.Reasons, reason)
+
+		if severity[decision] > severity[a.Decision] {
+			a.Decision = decision
+		}
+	}
+
+	return a
+}
@@ -0,0 +1,66 @@
+package risk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEvaluate(t *testing.T) {
+	now := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+	hourAgo := now.Add(-time.Hour)
+	weekAgo := now.AddDate(0, 0, -7)
+	rules := DefaultConfig.Rules()
+
+	known := Facts{
+		Screening:             Screening{Origin: 1, Destination: 2, Amount: 5000},
+		Now:                   now,
+		Available:             100000,
+		PreviousToDestination: 3,
+		RecentTransfers:       1,
+		SecretChangedAt:       &weekAgo,
+	}
+
+	tests := []struct {
+		name     string
+		change   func(f *Facts)
+		decision string
+		reasons  int
+	}{
+		{"usual transfer", func(f *Facts) {}, Allow, 0},
+		{"small first transfer", func(f *Facts) { f.PreviousToDestination = 0 }, Allow, 0},
+		{"large first transfer", func(f *Facts) { f.PreviousToDestination = 0; f.Amount = 150000; f.Available = 1000000 }, Challenge, 1},
+		{"sixth transfer in the window", func(f *Facts) { f.RecentTransfers = 5 }, Challenge, 1},
+		{"right after a secret change", func(f *Facts) { f.SecretChangedAt = &hourAgo }, Challenge, 1},
+		{"exactly 90% of the balance", func(f *Facts) { f.Amount = 90000 }, Allow, 0},
+		{"draining the balance", func(f *Facts) { f.Amount = 90001 }, Block, 1},
+		{"nothing available", func(f *Facts) { f.Available = 0 }, Block, 1},
+		{"block wins over challenge", func(f *Facts) { f.Amount = 95000; f.RecentTransfers = 9 }, Block, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := known
+			tt.change(&f)
+
+			a := Evaluate(rules, f)
+
+			if a.Decision != tt.decision || len(a.Reasons) != tt.reasons {
+				t.Errorf("got %s with reasons %v want %s with %d reasons", a.Decision, a.Reasons, tt.decision, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestConfigRules(t *testing.T) {
+	c := DefaultConfig
+	c.Velocity.Enabled = false
+	c.BalanceDrain.Enabled = false
+
+	got := c.Rules()
+	want := []Rule{c.NewRecipient, c.RecentSecretChange}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got rules %v want %v", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+package risk
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// DefaultListLimit is how many assessments are listed when no limit is asked.
+const DefaultListLimit = 100
+
+type Repository interface {
+	GetRiskFacts(context.Context, Screening, time.Time) (Facts, error)
+	AddRiskAssessment(context.Context, Assessment) (uint64, error)
+	ListRiskAssessments(context.Context, ListAssessmentsQuery) ([]Assessment, error)
+}
+
+type Service interface {
+	Screen(context.Context, Screening) (Assessment, error)
+	List(context.Context, ListAssessmentsQuery) (ListAssessmentsResponse, error)
+}
+
+// ConfigSource provides the rules config in effect.
+type ConfigSource interface {
+	Config() Config
+}
+
+// SecretVerifier checks the secret of a logged in customer, with its own
+// wrong attempts lockout.
+type SecretVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+type service struct {
+	r       Repository
+	rules   ConfigSource
+	secrets SecretVerifier
+}
+
+// New builds the risk service. A nil secrets leaves challenges unanswerable
+// but by approvals.
+func New(r Repository, rules ConfigSource, secrets SecretVerifier) *service {
+	return &service{r, rules, secrets}
+}
+
+// Screen evaluates the rules on the screening facts. A challenge is passed
+// when the screening answers it, and a wrong secret fails the screening.
+// Assessments with any rule hit are stored for review, passed or not.
+func (s *service) Screen(ctx context.Context, sc Screening) (Assessment, error) {
+	assessmentCh := make(chan Assessment)
+	errCh := make(chan error)
+
+	go func() {
+		config := s.rules.Config()
+		now := time.Now()
+
+		facts, err := s.r.GetRiskFacts(ctx, sc, now.Add(-config.VelocityWindow()))
+		if err != nil {
+			errCh <- err
+			return
+		}
+		facts.Now = now
+
+		a := Evaluate(config.Rules(), facts)
+
+		var stepUpErr error
+
+		if a.Decision == Challenge {
+			a.PassedAt, stepUpErr = s.stepUp(ctx, sc, now)
+		}
+
+		if len(a.Reasons) > 0 {
+			a.Id, err = s.r.AddRiskAssessment(ctx, a)
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		if stepUpErr != nil {
+			errCh <- stepUpErr
+			return
+		}
+
+		assessmentCh <- a
+	}()
+
+	select {
+	case a := <-assessmentCh:
+		return a, nil
+	case err := <-errCh:
+		return Assessment{}, err
+	case <-ctx.Done():
+		return Assessment{}, ctx.Err()
+	}
+}
+
+// stepUp returns when the challenge of sc was passed, nil when it was not
+// answered.
+func (s *service) stepUp(ctx context.Context, sc Screening, now time.Time) (*time.Time, error) {
+	if sc.Approved {
+		return &now, nil
+	}
+
+	if sc.Secret == "" || s.secrets == nil {
+		return nil, nil
+	}
+
+	if err := s.secrets.Verify(ctx, sc.CustomerId, sc.Secret); err != nil {
+		return nil, err
+	}
+
+	return &now, nil
+}
+
+// List returns the latest stored assessments, optionally of one decision.
+func (s *service) List(ctx context.Context, q ListAssessmentsQuery) (ListAssessmentsResponse, error) {
+	assessmentsCh := make(chan ListAssessmentsResponse)
+	errCh := make(chan error)
+
+	go func() {
+		if _, ok := severity[q.Decision]; q.Decision != "" && !ok {
+			errCh <- apperrors.NewArgumentError("decision must be one of allow, challenge, block")
+			return
+		}
+
+		if q.Limit == 0 {
+			q.Limit = DefaultListLimit
+		}
+
+		assessments, err := s.r.ListRiskAssessments(ctx, q)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		assessmentsCh <- ListAssessmentsResponse{Assessments: assessments}
+	}()
+
+	select {
+	case assessments := <-assessmentsCh:
+		return assessments, nil
+	case err := <-errCh:
+		return ListAssessmentsResponse{}, err
+	case <-ctx.Done():
+		return ListAssessmentsResponse{}, ctx.Err()
+	}
+}
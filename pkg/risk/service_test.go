@@ -0,0 +1,79 @@
+package risk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// mockRepository has every screening hit the velocity rule.
+type mockRepository struct {
+	stored []Assessment
+}
+
+func (m *mockRepository) GetRiskFacts(ctx context.Context, sc Screening, since time.Time) (Facts, error) {
+	return Facts{Screening: sc, Available: 1000000, PreviousToDestination: 1, RecentTransfers: 9}, nil
+}
+func (m *mockRepository) AddRiskAssessment(ctx context.Context, a Assessment) (uint64, error) {
+	m.stored = append(m.stored, a)
+	return uint64(len(m.stored)), nil
+}
+func (m *mockRepository) ListRiskAssessments(ctx context.Context, q ListAssessmentsQuery) ([]Assessment, error) {
+	return m.stored, nil
+}
+
+type staticRules struct{}
+
+func (staticRules) Config() Config {
+	return DefaultConfig
+}
+
+type mockSecretVerifier struct{}
+
+func (mockSecretVerifier) Verify(ctx context.Context, customerId uint64, secret string) error {
+	if secret != "senha_segura" {
+		return apperrors.NewAuthError("invalid secret")
+	}
+	return nil
+}
+
+func TestScreenStepUp(t *testing.T) {
+	tests := []struct {
+		name   string
+		sc     Screening
+		passed bool
+		err    bool
+	}{
+		{"not answered", Screening{Origin: 1, Destination: 2, Amount: 100, CustomerId: 1}, false, false},
+		{"answered with the secret", Screening{Origin: 1, Destination: 2, Amount: 100, CustomerId: 1, Secret: "senha_segura"}, true, false},
+		{"answered with a wrong secret", Screening{Origin: 1, Destination: 2, Amount: 100, CustomerId: 1, Secret: "errada"}, false, true},
+		{"answered by approvals", Screening{Origin: 1, Destination: 2, Amount: 100, Approved: true}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &mockRepository{}
+			s := New(r, staticRules{}, mockSecretVerifier{})
+
+			a, err := s.Screen(context.Background(), tt.sc)
+
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v want error %v", err, tt.err)
+			}
+
+			if len(r.stored) != 1 {
+				t.Fatalf("got %d assessments stored want 1", len(r.stored))
+			}
+
+			if passed := r.stored[0].PassedAt != nil; passed != tt.passed {
+				t.Errorf("got stored assessment passed %v want %v", passed, tt.passed)
+			}
+
+			if err == nil && a.Decision != Challenge {
+				t.Errorf("got decision %s want %s", a.Decision, Challenge)
+			}
+		})
+	}
+}
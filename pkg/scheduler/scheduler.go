@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+// Job is a task run periodically in background. Runs must be idempotent since
+// every instance of the API runs every job.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(context.Context) error
+}
+
+// Start runs every job on its own goroutine, first after one interval and then
+// on every interval, until ctx is done.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go run(ctx, job)
+	}
+}
+
+func run(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			logger.Log.Debug("Running job", job.Name)
+
+			if err := job.Run(ctx); err != nil {
+				logger.Log.Error("Job", job.Name, "error:", err)
+			}
+		case <-ctx.Done():
+			logger.Log.Info("Stopping job", job.Name)
+			return
+		}
+	}
+}
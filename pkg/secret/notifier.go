@@ -0,0 +1,52 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/validators"
+)
+
+// Notifier delivers reset tokens to customers.
+type Notifier interface {
+	NotifySecretReset(ctx context.Context, customer login.Customer, token string, expiresAt time.Time) error
+}
+
+// FileNotifier is the development notifier: it appends each reset token to a
+// file, or writes it to the log when there is no file.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) NotifySecretReset(ctx context.Context, customer login.Customer, token string, expiresAt time.Time) error {
+	line := fmt.Sprintf("%s secret reset token for customer %d (%s): %s, expires at %s\n",
+		time.Now().Format(time.RFC3339), customer.Id, validators.FormatCPF(customer.Cpf), token, expiresAt.Format(time.RFC3339))
+
+	if n.path == "" {
+		logger.Log.Info(line)
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteString(line)
+	return err
+}
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ResetTokenExpiry is how long a reset token can be used for.
+const ResetTokenExpiry = 30 * time.Minute
+
+// Secrets have MinLength to MaxLength characters, as when the customer is
+// created.
+const (
+	MinLength = 8
+	MaxLength = 16
+)
+
+// MaxAttempts wrong secrets in a row, confirming a change of a logged in
+// customer, lock those confirmations for LockoutDuration.
+const (
+	MaxAttempts     = 5
+	LockoutDuration = 15 * time.Minute
+)
+
+// resetTokenSize is the length, in bytes, of a reset token.
+const resetTokenSize = 32
+
+// ChangeSecretRequest changes the secret of the authenticated customer.
+type ChangeSecretRequest struct {
+	CurrentSecret string `json:"currentSecret"`
+	NewSecret     string `json:"newSecret"`
+}
+
+// ResetRequest asks for a reset token, sent to the customer by the notifier.
+type ResetRequest struct {
+	Cpf string `json:"cpf"`
+}
+
+// ConfirmResetRequest sets a new secret with a reset token.
+type ConfirmResetRequest struct {
+	Token     string `json:"token"`
+	NewSecret string `json:"newSecret"`
+}
+
+// Hash returns the stored form of a customer secret.
+func Hash(secret string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(secret+os.Getenv("SALT"))))
+}
+
+// GenerateResetToken returns a new random reset token, hex encoded.
+func GenerateResetToken() (string, error) {
+	token := make([]byte, resetTokenSize)
+
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(token), nil
+}
+
+// HashResetToken returns the stored form of a reset token, so a leaked table
+// can not be used to reset secrets.
+func HashResetToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
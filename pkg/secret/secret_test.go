@@ -0,0 +1,77 @@
+package secret
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+)
+
+func TestValidateSecret(t *testing.T) {
+	for secret, valid := range map[string]bool{
+		"senha_segura":      true,
+		"12345678":          true,
+		"1234567":           false,
+		"12345678901234567": false,
+		"":                  false,
+		"        ":          false,
+	} {
+		if err := validateSecret(secret); (err == nil) != valid {
+			t.Errorf("validateSecret(%q) = %v want valid %v", secret, err, valid)
+		}
+	}
+}
+
+func TestResetToken(t *testing.T) {
+	first, err := GenerateResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := GenerateResetToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first == second || len(first) != 2*resetTokenSize {
+		t.Errorf("unexpected tokens %s and %s", first, second)
+	}
+
+	if HashResetToken(first) == first || HashResetToken(first) != HashResetToken(first) {
+		t.Errorf("reset tokens should be stored hashed")
+	}
+}
+
+func TestFileNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "resets.log")
+	n := NewFileNotifier(path)
+	customer := login.Customer{Id: 3, Cpf: "05093092088"}
+
+	for _, token := range []string{"first-token", "second-token"} {
+		if err := n.NotifySecretReset(context.Background(), customer, token, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+
+	if len(lines) != 2 || !strings.Contains(lines[1], "second-token") || !strings.Contains(lines[0], "050.930.920-88") {
+		t.Errorf("unexpected notifications:\n%s", content)
+	}
+}
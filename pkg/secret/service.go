@@ -0,0 +1,244 @@
+package secret
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/validators"
+)
+
+type Repository interface {
+	GetCustomerById(context.Context, uint64) (login.Customer, error)
+	GetCustomerByCpf(context.Context, string) (login.Customer, error)
+	SetCustomerSecret(context.Context, uint64, string) error
+	CountSecretAttempt(context.Context, uint64, int, time.Duration) (string, *time.Time, error)
+	ResetSecretFailures(context.Context, uint64) error
+	AddSecretResetToken(context.Context, uint64, string, time.Time) error
+	ResetCustomerSecret(context.Context, string, string) error
+}
+
+type Service interface {
+	Change(context.Context, uint64, ChangeSecretRequest) error
+	RequestReset(context.Context, ResetRequest) error
+	Reset(context.Context, ConfirmResetRequest) error
+	Verify(context.Context, uint64, string) error
+}
+
+type service struct {
+	r Repository
+	n Notifier
+}
+
+func New(r Repository, n Notifier) *service {
+	return &service{r, n}
+}
+
+// Change replaces the secret of a customer, confirmed with the current one.
+// Tokens issued before the change stop working.
+func (s *service) Change(ctx context.Context, customerId uint64, c ChangeSecretRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if c.CurrentSecret == "" {
+			errCh <- apperrors.NewArgumentError("missing values", "currentSecret")
+			return
+		}
+
+		if err := validateSecret(c.NewSecret); err != nil {
+			errCh <- err
+			return
+		}
+
+		if c.NewSecret == c.CurrentSecret {
+			errCh <- apperrors.NewArgumentError("newSecret must be different from the current secret")
+			return
+		}
+
+		if err := s.verify(ctx, customerId, c.CurrentSecret); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.r.SetCustomerSecret(ctx, customerId, Hash(c.NewSecret)); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RequestReset sends a reset token to the customer with the CPF. Unknown and
+// inactive customers get nothing, but the caller is not told so, to not give
+// away which CPFs are customers.
+func (s *service) RequestReset(ctx context.Context, req ResetRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := validators.ValidateCPF(req.Cpf); err != nil {
+			errCh <- apperrors.NewArgumentError("invalid CPF", err.Error())
+			return
+		}
+
+		cpf, _ := validators.NormalizeCPF(req.Cpf)
+
+		customer, err := s.r.GetCustomerByCpf(ctx, cpf)
+		if err != nil {
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+				logger.Log.Info("Secret reset requested for unknown CPF")
+				doneCh <- true
+				return
+			}
+
+			errCh <- err
+			return
+		}
+
+		if !customer.Active {
+			logger.Log.Info("Secret reset requested for inactive customer", customer.Id)
+			doneCh <- true
+			return
+		}
+
+		token, err := GenerateResetToken()
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to generate reset token")
+			return
+		}
+
+		expiresAt := time.Now().Add(ResetTokenExpiry)
+
+		if err := s.r.AddSecretResetToken(ctx, customer.Id, HashResetToken(token), expiresAt); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.n.NotifySecretReset(ctx, customer, token, expiresAt); err != nil {
+			logger.Log.Error("Secret reset notification error:", err)
+			errCh <- apperrors.NewInternalServerError("failed to send reset token")
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reset sets a new secret with an unused and unexpired reset token. Tokens
+// issued before the reset stop working.
+func (s *service) Reset(ctx context.Context, req ConfirmResetRequest) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if req.Token == "" {
+			errCh <- apperrors.NewArgumentError("missing values", "token")
+			return
+		}
+
+		if err := validateSecret(req.NewSecret); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.r.ResetCustomerSecret(ctx, HashResetToken(req.Token), Hash(req.NewSecret)); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Verify checks secret against the one of a logged in customer, counting
+// wrong attempts towards the lockout.
+func (s *service) Verify(ctx context.Context, customerId uint64, secret string) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if err := s.verify(ctx, customerId, secret); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// verify counts the attempt before comparing secret, and takes it back when
+// secret is right, so concurrent wrong guesses are capped by MaxAttempts too.
+func (s *service) verify(ctx context.Context, customerId uint64, secret string) error {
+	stored, lockedUntil, err := s.r.CountSecretAttempt(ctx, customerId, MaxAttempts, LockoutDuration)
+	if err != nil {
+		return err
+	}
+
+	if stored == "" {
+		if lockedUntil == nil {
+			return apperrors.NewAuthError("secret locked")
+		}
+
+		return apperrors.NewAuthError("secret locked until", lockedUntil.Format(time.RFC3339))
+	}
+
+	if subtle.ConstantTimeCompare([]byte(Hash(secret)), []byte(stored)) != 1 {
+		return apperrors.NewAuthError("invalid secret")
+	}
+
+	return s.r.ResetSecretFailures(ctx, customerId)
+}
+
+func validateSecret(secret string) error {
+	if secret == "" {
+		return apperrors.NewArgumentError("missing values", "newSecret")
+	}
+
+	if len(secret) < MinLength || len(secret) > MaxLength || strings.TrimSpace(secret) == "" {
+		return apperrors.NewArgumentError("newSecret must be between 8 and 16 characters")
+	}
+
+	return nil
+}
@@ -1,16 +1,36 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/GilbertoVGL/go-banking/pkg/account"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
 	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/freeze"
+	"github.com/GilbertoVGL/go-banking/pkg/hold"
 	"github.com/GilbertoVGL/go-banking/pkg/http/rest"
+	"github.com/GilbertoVGL/go-banking/pkg/interest"
+	"github.com/GilbertoVGL/go-banking/pkg/keys"
+	"github.com/GilbertoVGL/go-banking/pkg/limits"
 	"github.com/GilbertoVGL/go-banking/pkg/logger"
 	"github.com/GilbertoVGL/go-banking/pkg/login"
+	"github.com/GilbertoVGL/go-banking/pkg/mfa"
+	"github.com/GilbertoVGL/go-banking/pkg/notification"
+	"github.com/GilbertoVGL/go-banking/pkg/oauth"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+	"github.com/GilbertoVGL/go-banking/pkg/pin"
+	"github.com/GilbertoVGL/go-banking/pkg/privacy"
 	"github.com/GilbertoVGL/go-banking/pkg/repository/postgresdb"
+	"github.com/GilbertoVGL/go-banking/pkg/risk"
+	"github.com/GilbertoVGL/go-banking/pkg/scheduler"
+	"github.com/GilbertoVGL/go-banking/pkg/secret"
+	"github.com/GilbertoVGL/go-banking/pkg/stream"
 	"github.com/GilbertoVGL/go-banking/pkg/transfer"
+	"github.com/GilbertoVGL/go-banking/pkg/webhook"
 )
 
 func New(port int) (*http.Server, error) {
@@ -19,11 +39,40 @@ func New(port int) (*http.Server, error) {
 		logger.Log.Warn("Unable to connect to database at startup:", err)
 	}
 
-	l := login.New(db)
-	a := account.New(db)
-	t := transfer.New(db)
+	k, err := keys.NewSet(os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_SECRET"), login.TokenExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	sinks, err := outbox.NewSinks(os.Getenv("OUTBOX_SINKS"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := mfa.New(db)
+	l := login.New(db, m, k)
+	sc := secret.New(db, secret.NewFileNotifier(os.Getenv("SECRET_RESET_FILE")))
+	p := pin.New(db, sc)
+	a := account.New(db, p)
+	rs := risk.New(db, risk.NewFileRules(os.Getenv("RISK_RULES_FILE")), sc)
+	es := stream.NewBroker(db)
+	t := transfer.New(db, rs, p, es)
+	i := interest.New(db)
+	lm := limits.New(db)
+	h := hold.New(db, rs, p)
+	o := oauth.New(db, k)
+	f := freeze.New(db)
+	pv := privacy.New(db)
+	au := audit.New(db)
+	wh := webhook.New(db)
+	wd := webhook.NewDispatcher(db, nil)
+	n := notification.New(db)
+	rl := outbox.NewRelay(db, append(sinks, webhook.NewSink(db), notification.NewSink(db, notificationChannels()...))...)
 
-	r := rest.NewRouter(l, a, t)
+	r := rest.NewRouter(l, a, t, lm, h, rs, p, m, sc, o, f, pv, au, wh, es, n, k)
+
+	es.Start(context.Background())
+	scheduler.Start(context.Background(), jobs(i, lm, h, k, rl, wd)...)
 
 	addr := fmt.Sprintf("localhost:%d", port)
 
@@ -36,3 +85,80 @@ func New(port int) (*http.Server, error) {
 		Handler:           r,
 	}, nil
 }
+
+// notificationChannels sends email through NOTIFICATION_SMTP_ADDR, when set,
+// and everything else to the stubs.
+func notificationChannels() []notification.Channel {
+	stubs := os.Getenv("NOTIFICATION_STUB_FILE")
+	channels := []notification.Channel{
+		notification.NewStubChannel(notification.ChannelEmail, stubs),
+		notification.NewStubChannel(notification.ChannelSMS, stubs),
+		notification.NewStubChannel(notification.ChannelPush, stubs),
+	}
+
+	if addr := os.Getenv("NOTIFICATION_SMTP_ADDR"); addr != "" {
+		channels = append(channels, notification.NewSMTPChannel(addr, os.Getenv("NOTIFICATION_SMTP_FROM"),
+			os.Getenv("NOTIFICATION_SMTP_USER"), os.Getenv("NOTIFICATION_SMTP_PASSWORD")))
+	}
+
+	return channels
+}
+
+func jobs(i interest.Service, lm limits.Service, h hold.Service, k *keys.Set, rl *outbox.Relay, wd *webhook.Dispatcher) []scheduler.Job {
+	return []scheduler.Job{
+		{
+			Name:     "Savings interest accrual",
+			Interval: config.JobsInterval,
+			Run: func(ctx context.Context) error {
+				yesterday := time.Now().In(config.Location).AddDate(0, 0, -1)
+				return i.Accrue(ctx, yesterday)
+			},
+		},
+		{
+			Name:     "Savings interest payment",
+			Interval: config.JobsInterval,
+			Run: func(ctx context.Context) error {
+				return i.Pay(ctx, time.Now().In(config.Location))
+			},
+		},
+		{
+			Name:     "Overdraft interest charge",
+			Interval: config.JobsInterval,
+			Run: func(ctx context.Context) error {
+				yesterday := time.Now().In(config.Location).AddDate(0, 0, -1)
+				return i.ChargeOverdraft(ctx, yesterday)
+			},
+		},
+		{
+			Name:     "Transfer limit raises",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return lm.ApplyDue(ctx, time.Now())
+			},
+		},
+		{
+			Name:     "Expired holds release",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return h.ExpireDue(ctx, time.Now())
+			},
+		},
+		{
+			Name:     "JWT keys reload",
+			Interval: time.Minute,
+			Run: func(ctx context.Context) error {
+				return k.Reload(time.Now())
+			},
+		},
+		{
+			Name:     "Outbox relay",
+			Interval: 5 * time.Second,
+			Run:      rl.Run,
+		},
+		{
+			Name:     "Webhook deliveries",
+			Interval: 5 * time.Second,
+			Run:      wd.Run,
+		},
+	}
+}
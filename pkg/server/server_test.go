@@ -1,11 +1,20 @@
 package server
 
 import (
+	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
 )
 
+func TestMain(m *testing.M) {
+	logger.New(ioutil.Discard)
+	os.Exit(m.Run())
+}
+
 func TestNewAnyPort(t *testing.T) {
 	port := 666
 	srv, err := New(port)
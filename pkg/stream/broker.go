@@ -0,0 +1,260 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+const (
+	// pollInterval is how often the broker looks for new events when it is
+	// not notified of them.
+	pollInterval = time.Second
+
+	// pollBatchSize is how many events the broker reads at a time.
+	pollBatchSize = 500
+
+	// subscriptionBuffer is how many events a subscriber may fall behind
+	// before it is dropped.
+	subscriptionBuffer = 64
+
+	// MaxReplay bounds the events replayed to a subscriber resuming from a
+	// Last-Event-ID.
+	MaxReplay = 1000
+)
+
+type Repository interface {
+	GetOutboxHead(context.Context) (uint64, error)
+	ListOutboxEvents(context.Context, uint64, int) ([]outbox.Event, error)
+	ListAccountOutboxEvents(context.Context, uint64, uint64, int) ([]outbox.Event, error)
+}
+
+type Service interface {
+	Subscribe(uint64) *Subscription
+	Unsubscribe(*Subscription)
+	Since(context.Context, uint64, uint64) ([]outbox.Event, bool, error)
+}
+
+// Subscription gets the events of an account as the broker sees them. C is
+// closed when the subscriber falls too far behind, it should then resume
+// from the last event it got. Head is the last event seen by the broker when
+// the subscription started, later events come through C.
+type Subscription struct {
+	AccountId uint64
+	Head      uint64
+	C         <-chan outbox.Event
+	c         chan outbox.Event
+}
+
+// Broker tails the outbox and hands the committed events that move or
+// reserve money to the subscriptions of the accounts involved. Every instance of the API tails
+// the outbox by itself, so it does not matter which one a client is
+// connected to.
+type Broker struct {
+	r    Repository
+	wake chan struct{}
+
+	mu            sync.Mutex
+	started       bool
+	head          uint64
+	subscriptions map[uint64]map[*Subscription]bool
+}
+
+func NewBroker(r Repository) *Broker {
+	return &Broker{
+		r:             r,
+		wake:          make(chan struct{}, 1),
+		subscriptions: map[uint64]map[*Subscription]bool{},
+	}
+}
+
+// Start tails the outbox, from its end when the broker first reaches the
+// database, until ctx is done.
+func (b *Broker) Start(ctx context.Context) {
+	go b.run(ctx)
+}
+
+// Notify tells the broker there are new events, so it does not wait for its
+// next poll. It never blocks.
+func (b *Broker) Notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Broker) Subscribe(accountId uint64) *Subscription {
+	c := make(chan outbox.Event, subscriptionBuffer)
+	s := &Subscription{AccountId: accountId, C: c, c: c}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s.Head = b.head
+
+	if b.subscriptions[accountId] == nil {
+		b.subscriptions[accountId] = map[*Subscription]bool{}
+	}
+
+	b.subscriptions[accountId][s] = true
+
+	return s
+}
+
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.drop(s)
+}
+
+// Since returns the events of the account after afterId, at most MaxReplay
+// of them, and whether there were more, which are then not replayed.
+func (b *Broker) Since(ctx context.Context, accountId uint64, afterId uint64) ([]outbox.Event, bool, error) {
+	events, err := b.r.ListAccountOutboxEvents(ctx, accountId, afterId, MaxReplay+1)
+	if err != nil {
+		return events, false, err
+	}
+
+	if len(events) > MaxReplay {
+		return events[:MaxReplay], true, nil
+	}
+
+	return events, false, nil
+}
+
+func (b *Broker) run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.poll(ctx); err != nil {
+			logger.Log.Error("Event stream poll error:", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-b.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// poll publishes the events after the head until it is caught up.
+func (b *Broker) poll(ctx context.Context) error {
+	b.mu.Lock()
+	started := b.started
+	b.mu.Unlock()
+
+	if !started {
+		head, err := b.r.GetOutboxHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		b.mu.Lock()
+		b.started = true
+		b.head = head
+		b.mu.Unlock()
+	}
+
+	for {
+		b.mu.Lock()
+		head := b.head
+		b.mu.Unlock()
+
+		events, err := b.r.ListOutboxEvents(ctx, head, pollBatchSize)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		b.publish(events)
+
+		if len(events) < pollBatchSize {
+			return nil
+		}
+	}
+}
+
+// publish hands events to the subscriptions and moves the head past them,
+// together, so a subscription gets either an event or a head past it.
+func (b *Broker) publish(events []outbox.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range events {
+		for _, accountId := range Accounts(e) {
+			for s := range b.subscriptions[accountId] {
+				select {
+				case s.c <- e:
+				default:
+					b.drop(s)
+				}
+			}
+		}
+
+		b.head = e.Id
+	}
+}
+
+// drop removes s, closing its channel. b.mu must be held.
+func (b *Broker) drop(s *Subscription) {
+	if !b.subscriptions[s.AccountId][s] {
+		return
+	}
+
+	delete(b.subscriptions[s.AccountId], s)
+	close(s.c)
+
+	if len(b.subscriptions[s.AccountId]) == 0 {
+		delete(b.subscriptions, s.AccountId)
+	}
+}
+
+// Accounts returns the accounts an event is about, none when it is not
+// streamed: money moved by transfers and postings, and funds reserved or
+// released by holds and judicial blocks.
+func Accounts(e outbox.Event) []uint64 {
+	switch e.Type {
+	case outbox.TypeTransferCompleted, outbox.TypePostingCompleted:
+		// Postings have the origin and destination of a transfer.
+		var t outbox.TransferPayload
+
+		if err := json.Unmarshal(e.Payload, &t); err != nil {
+			return nil
+		}
+
+		if t.Origin == t.Destination {
+			return []uint64{t.Origin}
+		}
+
+		return []uint64{t.Origin, t.Destination}
+	case outbox.TypeHoldChanged:
+		var h outbox.HoldPayload
+
+		if err := json.Unmarshal(e.Payload, &h); err != nil {
+			return nil
+		}
+
+		return []uint64{h.AccountId}
+	case outbox.TypeJudicialBlockChanged:
+		var b outbox.JudicialBlockPayload
+
+		if err := json.Unmarshal(e.Payload, &b); err != nil {
+			return nil
+		}
+
+		return []uint64{b.AccountId}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+type mockRepository struct {
+	events []outbox.Event
+}
+
+func (r *mockRepository) add(eventType string, origin uint64, destination uint64) {
+	payload, _ := json.Marshal(outbox.TransferPayload{Origin: origin, Destination: destination, Amount: 100})
+	r.events = append(r.events, outbox.Event{Id: uint64(len(r.events) + 1), Type: eventType, Payload: payload})
+}
+
+func (r *mockRepository) GetOutboxHead(ctx context.Context) (uint64, error) {
+	return uint64(len(r.events)), nil
+}
+
+func (r *mockRepository) ListOutboxEvents(ctx context.Context, afterId uint64, limit int) ([]outbox.Event, error) {
+	events := []outbox.Event{}
+
+	for _, e := range r.events {
+		if e.Id > afterId && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+
+	return events, nil
+}
+
+func (r *mockRepository) ListAccountOutboxEvents(ctx context.Context, accountId uint64, afterId uint64, limit int) ([]outbox.Event, error) {
+	events := []outbox.Event{}
+
+	for _, e := range r.events {
+		for _, id := range Accounts(e) {
+			if id == accountId && e.Id > afterId && len(events) < limit {
+				events = append(events, e)
+			}
+		}
+	}
+
+	return events, nil
+}
+
+func received(s *Subscription) []uint64 {
+	ids := []uint64{}
+
+	for {
+		select {
+		case e, ok := <-s.C:
+			if !ok {
+				return ids
+			}
+			ids = append(ids, e.Id)
+		default:
+			return ids
+		}
+	}
+}
+
+func TestBroker(t *testing.T) {
+	ctx := context.Background()
+	r := &mockRepository{}
+	r.add(outbox.TypeTransferCompleted, 1, 2)
+	r.add(outbox.TypeTransferCompleted, 2, 1)
+
+	b := NewBroker(r)
+
+	if err := b.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	first := b.Subscribe(1)
+	second := b.Subscribe(6)
+
+	if first.Head != 2 {
+		t.Fatalf("wrong subscription head: got %v want %v", first.Head, 2)
+	}
+
+	r.add(outbox.TypeTransferCompleted, 1, 2)
+	r.add(outbox.TypeTransferCompleted, 5, 6)
+	r.add(outbox.TypeAccountOpened, 1, 1)
+	r.add(outbox.TypeTransferCompleted, 2, 1)
+
+	if err := b.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := received(first); len(got) != 2 || got[0] != 3 || got[1] != 6 {
+		t.Errorf("wrong events of account 1: got %v want %v", got, []uint64{3, 6})
+	}
+
+	if got := received(second); len(got) != 1 || got[0] != 4 {
+		t.Errorf("wrong events of account 6: got %v want %v", got, []uint64{4})
+	}
+
+	b.Unsubscribe(first)
+	r.add(outbox.TypeTransferCompleted, 1, 2)
+
+	if err := b.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-first.C; ok {
+		t.Error("unsubscribed subscription got an event")
+	}
+
+	since, truncated, err := b.Since(ctx, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(since) != 2 || since[0].Id != 6 || since[1].Id != 7 || truncated {
+		t.Errorf("wrong events since 3: got %v truncated %v", since, truncated)
+	}
+
+	for i := 0; i < MaxReplay; i++ {
+		r.add(outbox.TypeTransferCompleted, 2, 1)
+	}
+
+	since, truncated, err = b.Since(ctx, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(since) != MaxReplay || !truncated {
+		t.Errorf("wrong replay past the limit: got %d events truncated %v", len(since), truncated)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	event := func(eventType string, payload interface{}) outbox.Event {
+		raw, _ := json.Marshal(payload)
+		return outbox.Event{Type: eventType, Payload: raw}
+	}
+
+	tests := []struct {
+		name     string
+		e        outbox.Event
+		accounts []uint64
+	}{
+		{"transfer", event(outbox.TypeTransferCompleted, outbox.TransferPayload{Origin: 1, Destination: 2}), []uint64{1, 2}},
+		{"interest", event(outbox.TypePostingCompleted, outbox.PostingPayload{Origin: 9, Destination: 1, Kind: "interest"}), []uint64{9, 1}},
+		{"hold", event(outbox.TypeHoldChanged, outbox.HoldPayload{HoldId: 4, AccountId: 1}), []uint64{1}},
+		{"judicial block", event(outbox.TypeJudicialBlockChanged, outbox.JudicialBlockPayload{BlockId: 5, AccountId: 2}), []uint64{2}},
+		{"not streamed", event(outbox.TypeAccountOpened, outbox.AccountPayload{AccountId: 1}), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Accounts(tt.e)
+
+			if len(got) != len(tt.accounts) {
+				t.Fatalf("got %v want %v", got, tt.accounts)
+			}
+
+			for i := range got {
+				if got[i] != tt.accounts[i] {
+					t.Errorf("got %v want %v", got, tt.accounts)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	ctx := context.Background()
+	r := &mockRepository{}
+	b := NewBroker(r)
+
+	if err := b.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	slow := b.Subscribe(1)
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		r.add(outbox.TypeTransferCompleted, 1, 2)
+	}
+
+	if err := b.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := received(slow); len(got) != subscriptionBuffer {
+		t.Errorf("wrong number of events before drop: got %v want %v", len(got), subscriptionBuffer)
+	}
+
+	if _, ok := <-slow.C; ok {
+		t.Error("slow subscription was not closed")
+	}
+
+	// Dropped subscriptions may still be unsubscribed.
+	b.Unsubscribe(slow)
+}
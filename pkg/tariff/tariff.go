@@ -0,0 +1,41 @@
+package tariff
+
+// Services that can be charged for, matching tariffs.service.
+const (
+	ServiceTransfer = "transfer"
+)
+
+// Tariff is the fee charged for a service. An empty Product applies to every
+// account product without a tariff of its own. The first FreePerMonth uses of
+// the service in a month are free.
+type Tariff struct {
+	Service      string
+	Product      string
+	FreePerMonth int64
+	Fee          int64
+}
+
+// Evaluate returns the fee due for one more use of a service by an account of
+// product that already used it usedThisMonth times, given the service tariffs.
+func Evaluate(tariffs []Tariff, product string, usedThisMonth int64) int64 {
+	var chosen *Tariff
+
+	for i := range tariffs {
+		t := &tariffs[i]
+
+		if t.Product == product {
+			chosen = t
+			break
+		}
+
+		if t.Product == "" && chosen == nil {
+			chosen = t
+		}
+	}
+
+	if chosen == nil || usedThisMonth < chosen.FreePerMonth {
+		return 0
+	}
+
+	return chosen.Fee
+}
@@ -0,0 +1,33 @@
+package tariff
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	tariffs := []Tariff{
+		{Service: ServiceTransfer, Product: "", FreePerMonth: 2, Fee: 300},
+		{Service: ServiceTransfer, Product: "checking", FreePerMonth: 4, Fee: 150},
+	}
+
+	tests := []struct {
+		name     string
+		tariffs  []Tariff
+		product  string
+		used     int64
+		expected int64
+	}{
+		{"within free transfers", tariffs, "checking", 3, 0},
+		{"beyond free transfers", tariffs, "checking", 4, 150},
+		{"falls back to generic tariff", tariffs, "savings", 2, 300},
+		{"generic tariff within free transfers", tariffs, "savings", 1, 0},
+		{"no tariff", nil, "checking", 100, 0},
+		{"no matching tariff", tariffs[1:], "savings", 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Evaluate(tt.tariffs, tt.product, tt.used); got != tt.expected {
+				t.Errorf("Evaluate() = %d, want %d", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package transfer
+
+import "time"
+
+// Statuses of a transfer response: completed transfers were posted, the ones
+// pending approval wait for the origin approvers and carry the pending
+// transfer id.
+const (
+	TransferCompleted       = "completed"
+	TransferPendingApproval = "pendingApproval"
+)
+
+// Pending transfer statuses. An approved transfer is being executed, and ends
+// up executed or failed.
+const (
+	ApprovalPending  = "pending"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+	ApprovalExecuted = "executed"
+	ApprovalFailed   = "failed"
+)
+
+// PendingTransfer is a transfer above the origin approval threshold, waiting
+// for RequiredApprovals approvals from the origin approvers other than its
+// requester.
+type PendingTransfer struct {
+	Id                uint64     `json:"id"`
+	Origin            uint64     `json:"origin"`
+	Destination       uint64     `json:"destination"`
+	Amount            int64      `json:"amount"`
+	RequestedBy       uint64     `json:"requestedBy"`
+	RequiredApprovals int        `json:"requiredApprovals"`
+	Approvals         int        `json:"approvals"`
+	Status            string     `json:"status"`
+	Error             string     `json:"error,omitempty"`
+	TransferId        *uint64    `json:"transferId,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	DecidedAt         *time.Time `json:"decidedAt,omitempty"`
+}
+
+type ListPendingTransfersResponse struct {
+	Transfers []PendingTransfer `json:"transfers"`
+}
+
+// requiresApproval tells whether amount is above the approval threshold, when
+// the origin has one.
+func requiresApproval(threshold *int64, amount int64) bool {
+	return threshold != nil && amount > *threshold
+}
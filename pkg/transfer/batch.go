@@ -0,0 +1,105 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// Batch execution modes: atomic posts every item or none, bestEffort posts
+// each item on its own.
+const (
+	BatchAtomic     = "atomic"
+	BatchBestEffort = "bestEffort"
+)
+
+// Batch and batch item statuses.
+const (
+	BatchProcessing         = "processing"
+	BatchCompleted          = "completed"
+	BatchPartiallyCompleted = "partiallyCompleted"
+	BatchFailed             = "failed"
+
+	ItemPending   = "pending"
+	ItemCompleted = "completed"
+	ItemFailed    = "failed"
+)
+
+const MaxBatchItems = 500
+
+type BatchItemRequest struct {
+	Destination *uint64 `json:"destination"`
+	Amount      *int64  `json:"amount"`
+}
+
+type BatchRequest struct {
+	Mode  string             `json:"mode"`
+	Pin   string             `json:"pin"`
+	Items []BatchItemRequest `json:"items"`
+	// Secret is the customer secret, sent again to answer risk challenges of
+	// the items.
+	Secret string `json:"secret,omitempty"`
+	// RequestedBy is the authenticated customer, never read from the body.
+	RequestedBy uint64 `json:"-"`
+}
+
+type BatchItem struct {
+	Position    int     `json:"position"`
+	Destination uint64  `json:"destination"`
+	Amount      int64   `json:"amount"`
+	Fee         int64   `json:"fee"`
+	Status      string  `json:"status"`
+	Error       string  `json:"error,omitempty"`
+	TransferId  *uint64 `json:"transferId,omitempty"`
+}
+
+type Batch struct {
+	Id        uint64      `json:"id"`
+	Origin    uint64      `json:"origin"`
+	Mode      string      `json:"mode"`
+	Status    string      `json:"status"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Items     []BatchItem `json:"items"`
+}
+
+// ParseBatchCSV reads destination,amount lines. A first line that is not
+// numeric is taken as a header and skipped.
+func ParseBatchCSV(r io.Reader) ([]BatchItemRequest, error) {
+	var items []BatchItemRequest
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, apperrors.NewArgumentError("invalid CSV", err.Error())
+		}
+
+		destination, destErr := strconv.ParseUint(strings.TrimSpace(record[0]), 10, 64)
+		amount, amountErr := strconv.ParseInt(strings.TrimSpace(record[1]), 10, 64)
+
+		if destErr != nil || amountErr != nil {
+			if line == 1 {
+				continue
+			}
+
+			return nil, apperrors.NewArgumentError("invalid CSV", "line "+strconv.Itoa(line))
+		}
+
+		items = append(items, BatchItemRequest{Destination: &destination, Amount: &amount})
+	}
+
+	return items, nil
+}
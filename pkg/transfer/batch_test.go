@@ -0,0 +1,44 @@
+package transfer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseBatchCSV(t *testing.T) {
+	t.Run("with header", func(t *testing.T) {
+		items, err := ParseBatchCSV(strings.NewReader("destination,amount\n2, 1500\n3,2000\n"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(items) != 2 || *items[0].Destination != 2 || *items[0].Amount != 1500 || *items[1].Destination != 3 {
+			t.Errorf("unexpected items: %+v", items)
+		}
+	})
+
+	t.Run("without header", func(t *testing.T) {
+		items, err := ParseBatchCSV(strings.NewReader("2,1500"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(items) != 1 {
+			t.Errorf("unexpected items: %+v", items)
+		}
+	})
+
+	t.Run("invalid line", func(t *testing.T) {
+		if _, err := ParseBatchCSV(strings.NewReader("destination,amount\n2,abc\n")); err == nil {
+			t.Error("expected error for invalid amount")
+		}
+	})
+
+	t.Run("wrong number of fields", func(t *testing.T) {
+		if _, err := ParseBatchCSV(strings.NewReader("2,1500,3\n")); err == nil {
+			t.Error("expected error for extra field")
+		}
+	})
+}
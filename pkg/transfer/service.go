@@ -2,30 +2,78 @@ package transfer
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/GilbertoVGL/go-banking/pkg/account"
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+	"github.com/GilbertoVGL/go-banking/pkg/audit"
+	"github.com/GilbertoVGL/go-banking/pkg/config"
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+	"github.com/GilbertoVGL/go-banking/pkg/risk"
+	"github.com/GilbertoVGL/go-banking/pkg/tariff"
+	"github.com/GilbertoVGL/go-banking/pkg/validators"
 )
 
 type Service interface {
 	GetTransfers(context.Context, uint64, ListTransferQuery) (ListTransferResponse, error)
-	DoTransfer(context.Context, TransferRequest) error
+	DoTransfer(context.Context, TransferRequest) (TransferResponse, error)
+	DoOwnAccountsTransfer(context.Context, uint64, TransferRequest) (TransferResponse, error)
+	DoBatchTransfer(context.Context, uint64, BatchRequest) (Batch, error)
+	GetBatch(context.Context, uint64, uint64) (Batch, error)
+	ApproveTransfer(context.Context, uint64, uint64) (PendingTransfer, error)
+	RejectTransfer(context.Context, uint64, uint64) (PendingTransfer, error)
+	ListPendingTransfers(context.Context, uint64) (ListPendingTransfersResponse, error)
 }
 
+// batchTimeout bounds the background execution of a transfer batch.
+const batchTimeout = 5 * time.Minute
+
 type Repository interface {
-	AddTransfer(context.Context, TransferRequest) error
+	AddTransfer(context.Context, Transfer) (uint64, error)
+	AddTransfers(context.Context, []Transfer) ([]uint64, error)
+	AddTransferBatch(context.Context, Batch) (uint64, error)
+	UpdateTransferBatchItem(context.Context, uint64, BatchItem) error
+	FinishTransferBatch(context.Context, uint64, string) error
+	GetTransferBatch(context.Context, uint64) (Batch, error)
+	AddPendingTransfer(context.Context, PendingTransfer) (uint64, error)
+	DecidePendingTransfer(context.Context, uint64, uint64, bool) (PendingTransfer, error)
+	FinishPendingTransfer(context.Context, PendingTransfer) error
+	ListPendingTransfers(context.Context, uint64) ([]PendingTransfer, error)
+	ListTariffs(context.Context, string) ([]tariff.Tariff, error)
+	CountMonthTransfers(context.Context, uint64, time.Time) (int64, error)
 	GetTransfers(context.Context, uint64, ListTransferQuery) (ListTransferResponse, error)
-	GetAccountBalance(context.Context, uint64) (int64, error)
 	GetAccountById(context.Context, uint64) (account.Account, error)
 }
 
+// Screener assesses the risk of a transfer before it is executed.
+type Screener interface {
+	Screen(context.Context, risk.Screening) (risk.Assessment, error)
+}
+
+// PinVerifier checks the transaction PIN of an account.
+type PinVerifier interface {
+	Verify(context.Context, uint64, string) error
+}
+
+// Notifier is told when transfers are committed.
+type Notifier interface {
+	Notify()
+}
+
 type service struct {
-	r Repository
+	r        Repository
+	screener Screener
+	pins     PinVerifier
+	notifier Notifier
 }
 
-func New(r Repository) *service {
-	return &service{r}
+// New builds the transfer service. A nil screener lets every transfer through,
+// a nil pins does not ask for transaction PINs and a nil notifier is not told
+// of transfers.
+func New(r Repository, screener Screener, pins PinVerifier, notifier Notifier) *service {
+	return &service{r, screener, pins, notifier}
 }
 
 func (s *service) GetTransfers(ctx context.Context, id uint64, l ListTransferQuery) (ListTransferResponse, error) {
@@ -43,6 +91,10 @@ func (s *service) GetTransfers(ctx context.Context, id uint64, l ListTransferQue
 
 	select {
 	case transferList := <-transferListCh:
+		for i := range transferList.Data {
+			transferList.Data[i].OriginCpf = validators.FormatCPF(transferList.Data[i].OriginCpf)
+			transferList.Data[i].DestinationCpf = validators.FormatCPF(transferList.Data[i].DestinationCpf)
+		}
 		return transferList, nil
 	case err := <-errCh:
 		return ListTransferResponse{}, err
@@ -51,64 +103,704 @@ func (s *service) GetTransfers(ctx context.Context, id uint64, l ListTransferQue
 	}
 }
 
-func (s *service) DoTransfer(ctx context.Context, t TransferRequest) error {
-	transferCh := make(chan bool)
+func (s *service) DoTransfer(ctx context.Context, t TransferRequest) (TransferResponse, error) {
+	transferCh := make(chan TransferResponse)
 	errCh := make(chan error)
 
 	go func() {
-		var invalid []string
+		if err := validateTransferValues(t); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.verifyPin(ctx, t.Origin, t.Pin); err != nil {
+			errCh <- err
+			return
+		}
+
+		destination, err := s.r.GetAccountById(ctx, *t.Destination)
+		if err != nil {
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+				errCh <- apperrors.NewTransferRequestError("destination account not found", err.Error())
+				return
+			}
 
-		if t.Amount == nil {
-			invalid = append(invalid, "amount")
+			errCh <- err
+			return
+		}
+
+		if destination.ClosedAt != nil {
+			errCh <- apperrors.NewTransferRequestError("destination account is closed")
+			return
 		}
 
-		if t.Destination == nil {
-			invalid = append(invalid, "destination")
+		if err := s.screen(ctx, risk.Screening{
+			Origin:      t.Origin,
+			Destination: *t.Destination,
+			Amount:      *t.Amount,
+			CustomerId:  t.RequestedBy,
+			Secret:      t.Secret,
+		}); err != nil {
+			errCh <- err
+			return
 		}
 
-		if len(invalid) > 0 {
-			errCh <- apperrors.NewArgumentError(strings.Join(invalid, ", "))
+		origin, err := s.r.GetAccountById(ctx, t.Origin)
+		if err != nil {
+			errCh <- err
+			return
 		}
 
-		if *t.Amount < 1 {
-			invalid = append(invalid, "amount")
-			errCh <- apperrors.NewArgumentError(strings.Join(invalid, ", "))
+		if requiresApproval(origin.ApprovalThreshold, *t.Amount) {
+			id, err := s.r.AddPendingTransfer(ctx, PendingTransfer{
+				Origin:            t.Origin,
+				Destination:       *t.Destination,
+				Amount:            *t.Amount,
+				RequestedBy:       t.RequestedBy,
+				RequiredApprovals: origin.RequiredApprovals,
+				Status:            ApprovalPending,
+			})
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			transferCh <- TransferResponse{
+				Id:          id,
+				Origin:      t.Origin,
+				Destination: *t.Destination,
+				Amount:      *t.Amount,
+				Status:      TransferPendingApproval,
+			}
 			return
 		}
 
-		originBalance, err := s.r.GetAccountBalance(ctx, t.Origin)
+		newTransfer := s.newTransfer(t)
 
+		fee, err := s.transferFee(ctx, newTransfer)
 		if err != nil {
 			errCh <- err
 			return
 		}
+		newTransfer.Fee = fee
 
-		if originBalance < *t.Amount {
-			errCh <- apperrors.NewTransferRequestError("not enough funds")
+		response, err := s.transfer(ctx, newTransfer)
+		if err != nil {
+			errCh <- err
 			return
 		}
 
-		if _, err := s.r.GetAccountById(ctx, *t.Destination); err != nil {
-			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
-				errCh <- apperrors.NewTransferRequestError("destination account not found", err.Error())
+		transferCh <- response
+	}()
+
+	select {
+	case response := <-transferCh:
+		return response, nil
+	case err := <-errCh:
+		return TransferResponse{}, err
+	case <-ctx.Done():
+		return TransferResponse{}, ctx.Err()
+	}
+}
+
+// DoOwnAccountsTransfer moves money between two accounts of the same customer,
+// free of charge.
+func (s *service) DoOwnAccountsTransfer(ctx context.Context, customerId uint64, t TransferRequest) (TransferResponse, error) {
+	transferCh := make(chan TransferResponse)
+	errCh := make(chan error)
+
+	go func() {
+		if err := validateTransferValues(t); err != nil {
+			errCh <- err
+			return
+		}
+
+		if t.Origin == *t.Destination {
+			errCh <- apperrors.NewTransferRequestError("origin and destination must be different accounts")
+			return
+		}
+
+		for _, id := range []uint64{t.Origin, *t.Destination} {
+			a, err := s.r.GetAccountById(ctx, id)
+
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok || (err == nil && a.CustomerId != customerId) {
+				errCh <- apperrors.NewTransferRequestError("account does not belong to this customer")
+				return
 			}
 
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		if err := s.verifyPin(ctx, t.Origin, t.Pin); err != nil {
 			errCh <- err
+			return
 		}
 
-		if err := s.r.AddTransfer(ctx, t); err != nil {
+		if err := s.screen(ctx, risk.Screening{
+			Origin:      t.Origin,
+			Destination: *t.Destination,
+			Amount:      *t.Amount,
+			CustomerId:  customerId,
+			Secret:      t.Secret,
+		}); err != nil {
 			errCh <- err
+			return
+		}
+
+		response, err := s.transfer(ctx, s.newTransfer(t))
+		if err != nil {
+			errCh <- err
+			return
 		}
 
-		transferCh <- true
+		transferCh <- response
 	}()
 
 	select {
-	case <-transferCh:
-		return nil
+	case response := <-transferCh:
+		return response, nil
 	case err := <-errCh:
-		return err
+		return TransferResponse{}, err
 	case <-ctx.Done():
-		return ctx.Err()
+		return TransferResponse{}, ctx.Err()
+	}
+}
+
+// ApproveTransfer records the customer approval of a pending transfer. The one
+// that completes the required approvals executes the transfer through the same
+// path as DoTransfer, and the pending transfer records whether it succeeded.
+func (s *service) ApproveTransfer(ctx context.Context, customerId uint64, id uint64) (PendingTransfer, error) {
+	pendingCh := make(chan PendingTransfer)
+	errCh := make(chan error)
+
+	go func() {
+		p, err := s.r.DecidePendingTransfer(ctx, id, customerId, true)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if p.Status != ApprovalApproved {
+			pendingCh <- p
+			return
+		}
+
+		p, err = s.executePendingTransfer(ctx, p)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		pendingCh <- p
+	}()
+
+	select {
+	case p := <-pendingCh:
+		return p, nil
+	case err := <-errCh:
+		return PendingTransfer{}, err
+	case <-ctx.Done():
+		return PendingTransfer{}, ctx.Err()
+	}
+}
+
+// RejectTransfer records the customer rejection of a pending transfer, which
+// is then never executed.
+func (s *service) RejectTransfer(ctx context.Context, customerId uint64, id uint64) (PendingTransfer, error) {
+	pendingCh := make(chan PendingTransfer)
+	errCh := make(chan error)
+
+	go func() {
+		p, err := s.r.DecidePendingTransfer(ctx, id, customerId, false)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		pendingCh <- p
+	}()
+
+	select {
+	case p := <-pendingCh:
+		return p, nil
+	case err := <-errCh:
+		return PendingTransfer{}, err
+	case <-ctx.Done():
+		return PendingTransfer{}, ctx.Err()
+	}
+}
+
+// ListPendingTransfers returns the transfers waiting for approval that the
+// customer requested or can approve.
+func (s *service) ListPendingTransfers(ctx context.Context, customerId uint64) (ListPendingTransfersResponse, error) {
+	pendingCh := make(chan ListPendingTransfersResponse)
+	errCh := make(chan error)
+
+	go func() {
+		transfers, err := s.r.ListPendingTransfers(ctx, customerId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		pendingCh <- ListPendingTransfersResponse{Transfers: transfers}
+	}()
+
+	select {
+	case transfers := <-pendingCh:
+		return transfers, nil
+	case err := <-errCh:
+		return ListPendingTransfersResponse{}, err
+	case <-ctx.Done():
+		return ListPendingTransfersResponse{}, ctx.Err()
+	}
+}
+
+// executePendingTransfer screens an approved transfer again, as its origin may
+// have changed since it was requested, posts it charging its fee as of now,
+// and records the outcome. The approvals answer a risk challenge. A refused
+// transfer is not an error of the approval, it is returned as a failed
+// pending transfer.
+func (s *service) executePendingTransfer(ctx context.Context, p PendingTransfer) (PendingTransfer, error) {
+	t := Transfer{
+		Origin:      p.Origin,
+		Destination: p.Destination,
+		Amount:      p.Amount,
+		Windows:     Windows(time.Now().In(config.Location)),
+	}
+
+	err := s.screen(ctx, risk.Screening{Origin: p.Origin, Destination: p.Destination, Amount: p.Amount, Approved: true})
+
+	if err == nil {
+		t.Fee, err = s.transferFee(ctx, t)
+	}
+
+	if err == nil {
+		var response TransferResponse
+		response, err = s.transfer(ctx, t)
+		if err == nil {
+			p.Status = ApprovalExecuted
+			p.TransferId = &response.Id
+		}
+	}
+
+	if err != nil {
+		switch err.(type) {
+		case *apperrors.TransferRequestError, *apperrors.AccountNotFoundError:
+			p.Status = ApprovalFailed
+			p.Error = err.Error()
+		default:
+			return p, err
+		}
+	}
+
+	if err := s.r.FinishPendingTransfer(ctx, p); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// DoBatchTransfer validates every item up front and, when all are valid,
+// stores the batch and executes it in background. The returned batch can be
+// followed through GetBatch.
+func (s *service) DoBatchTransfer(ctx context.Context, origin uint64, b BatchRequest) (Batch, error) {
+	batchCh := make(chan Batch)
+	errCh := make(chan error)
+
+	go func() {
+		if b.Mode == "" {
+			b.Mode = BatchAtomic
+		}
+
+		if err := s.verifyPin(ctx, origin, b.Pin); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.validateBatchValues(ctx, origin, b); err != nil {
+			errCh <- err
+			return
+		}
+
+		for _, item := range b.Items {
+			if err := s.screen(ctx, risk.Screening{
+				Origin:      origin,
+				Destination: *item.Destination,
+				Amount:      *item.Amount,
+				CustomerId:  b.RequestedBy,
+				Secret:      b.Secret,
+			}); err != nil {
+				errCh <- err
+				return
+			}
+		}
+
+		batch := Batch{
+			Origin: origin,
+			Mode:   b.Mode,
+			Status: BatchProcessing,
+		}
+
+		for i, item := range b.Items {
+			batch.Items = append(batch.Items, BatchItem{
+				Position:    i + 1,
+				Destination: *item.Destination,
+				Amount:      *item.Amount,
+				Status:      ItemPending,
+			})
+		}
+
+		id, err := s.r.AddTransferBatch(ctx, batch)
+		if err != nil {
+			errCh <- err
+			return
+		}
+		batch.Id = id
+
+		go s.runBatch(audit.ActorFrom(ctx), batch)
+
+		batchCh <- batch
+	}()
+
+	select {
+	case batch := <-batchCh:
+		return batch, nil
+	case err := <-errCh:
+		return Batch{}, err
+	case <-ctx.Done():
+		return Batch{}, ctx.Err()
+	}
+}
+
+func (s *service) GetBatch(ctx context.Context, origin uint64, id uint64) (Batch, error) {
+	batchCh := make(chan Batch)
+	errCh := make(chan error)
+
+	go func() {
+		batch, err := s.r.GetTransferBatch(ctx, id)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		if batch.Origin != origin {
+			errCh <- apperrors.NewAccountNotFoundError("batch not found")
+			return
+		}
+
+		batchCh <- batch
+	}()
+
+	select {
+	case batch := <-batchCh:
+		return batch, nil
+	case err := <-errCh:
+		return Batch{}, err
+	case <-ctx.Done():
+		return Batch{}, ctx.Err()
+	}
+}
+
+// runBatch executes a stored batch, recording the outcome of every item. It
+// outlives the request, so the transfers are audited under the actor that
+// submitted the batch.
+func (s *service) runBatch(actor audit.Actor, batch Batch) {
+	ctx, cancel := context.WithTimeout(audit.NewContext(context.Background(), actor), batchTimeout)
+	defer cancel()
+
+	windows := Windows(time.Now().In(config.Location))
+	status, err := s.executeBatch(ctx, batch, windows)
+
+	if err != nil {
+		logger.Log.Error("Transfer batch", batch.Id, "error:", err)
+	}
+
+	if err := s.r.FinishTransferBatch(ctx, batch.Id, status); err != nil {
+		logger.Log.Error("Transfer batch", batch.Id, "finish error:", err)
+	}
+}
+
+func (s *service) executeBatch(ctx context.Context, batch Batch, windows LimitWindows) (string, error) {
+	origin, err := s.r.GetAccountById(ctx, batch.Origin)
+	if err != nil {
+		return BatchFailed, s.failBatchItems(ctx, batch, err)
+	}
+
+	tariffs, err := s.r.ListTariffs(ctx, tariff.ServiceTransfer)
+	if err != nil {
+		return BatchFailed, s.failBatchItems(ctx, batch, err)
+	}
+
+	used, err := s.r.CountMonthTransfers(ctx, batch.Origin, windows.MonthStart)
+	if err != nil {
+		return BatchFailed, s.failBatchItems(ctx, batch, err)
+	}
+
+	transfers := make([]Transfer, len(batch.Items))
+
+	for i, item := range batch.Items {
+		transfers[i] = Transfer{
+			Origin:      batch.Origin,
+			Destination: item.Destination,
+			Amount:      item.Amount,
+			Windows:     windows,
+		}
+	}
+
+	if batch.Mode == BatchAtomic {
+		for i := range transfers {
+			transfers[i].Fee = tariff.Evaluate(tariffs, origin.Product, used+int64(i))
+		}
+
+		ids, err := s.r.AddTransfers(ctx, transfers)
+		if err != nil {
+			return BatchFailed, s.failBatchItems(ctx, batch, err)
+		}
+
+		s.notify()
+
+		for i, item := range batch.Items {
+			item.Status = ItemCompleted
+			item.Fee = transfers[i].Fee
+			item.TransferId = &ids[i]
+
+			if err := s.r.UpdateTransferBatchItem(ctx, batch.Id, item); err != nil {
+				return BatchCompleted, err
+			}
+		}
+
+		return BatchCompleted, nil
+	}
+
+	var completed int64
+
+	for i, item := range batch.Items {
+		transfers[i].Fee = tariff.Evaluate(tariffs, origin.Product, used+completed)
+
+		id, err := s.r.AddTransfer(ctx, transfers[i])
+		if err != nil {
+			item.Status = ItemFailed
+			item.Error = err.Error()
+		} else {
+			s.notify()
+			completed++
+			item.Status = ItemCompleted
+			item.Fee = transfers[i].Fee
+			item.TransferId = &id
+		}
+
+		if err := s.r.UpdateTransferBatchItem(ctx, batch.Id, item); err != nil {
+			logger.Log.Error("Transfer batch", batch.Id, "item", item.Position, "update error:", err)
+		}
+	}
+
+	switch completed {
+	case int64(len(batch.Items)):
+		return BatchCompleted, nil
+	case 0:
+		return BatchFailed, nil
+	default:
+		return BatchPartiallyCompleted, nil
+	}
+}
+
+func (s *service) failBatchItems(ctx context.Context, batch Batch, cause error) error {
+	for _, item := range batch.Items {
+		item.Status = ItemFailed
+		item.Error = cause.Error()
+
+		if err := s.r.UpdateTransferBatchItem(ctx, batch.Id, item); err != nil {
+			return err
+		}
+	}
+
+	return cause
+}
+
+func (s *service) validateBatchValues(ctx context.Context, origin uint64, b BatchRequest) error {
+	var invalid []string
+
+	if b.Mode != BatchAtomic && b.Mode != BatchBestEffort {
+		return apperrors.NewArgumentError("mode must be one of", strings.Join([]string{BatchAtomic, BatchBestEffort}, ", "))
+	}
+
+	if len(b.Items) == 0 || len(b.Items) > MaxBatchItems {
+		return apperrors.NewArgumentError("items", fmt.Sprintf("a batch must have between 1 and %d items", MaxBatchItems))
+	}
+
+	account, err := s.r.GetAccountById(ctx, origin)
+	if err != nil {
+		return err
+	}
+
+	for i, item := range b.Items {
+		position := fmt.Sprintf("item %d", i+1)
+
+		switch {
+		case item.Destination == nil || item.Amount == nil:
+			invalid = append(invalid, position+" missing destination or amount")
+		case *item.Amount < 1:
+			invalid = append(invalid, position+" invalid amount")
+		case *item.Destination == origin:
+			invalid = append(invalid, position+" destination is the origin account")
+		case requiresApproval(account.ApprovalThreshold, *item.Amount):
+			invalid = append(invalid, position+" requires approval, transfer it on its own")
+		default:
+			destination, err := s.r.GetAccountById(ctx, *item.Destination)
+			if _, ok := err.(*apperrors.AccountNotFoundError); ok {
+				invalid = append(invalid, position+" destination account not found")
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			if destination.ClosedAt != nil {
+				invalid = append(invalid, position+" destination account is closed")
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return apperrors.NewArgumentError(strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
+
+// verifyPin checks the origin transaction PIN, before anything else about the
+// transfer is looked at.
+func (s *service) verifyPin(ctx context.Context, origin uint64, pin string) error {
+	if s.pins == nil {
+		return nil
+	}
+
+	if pin == "" {
+		return apperrors.NewArgumentError("pin")
+	}
+
+	return s.pins.Verify(ctx, origin, pin)
+}
+
+// screen refuses sc when the screener blocks it or asks for a challenge that
+// sc did not answer.
+func (s *service) screen(ctx context.Context, sc risk.Screening) error {
+	if s.screener == nil {
+		return nil
+	}
+
+	a, err := s.screener.Screen(ctx, sc)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case a.Decision == risk.Block:
+		return apperrors.NewTransferRequestError("transfer blocked by risk screening", strings.Join(a.Reasons, ", "))
+	case a.Decision == risk.Challenge && a.PassedAt == nil:
+		return apperrors.NewChallengeError(strings.Join(a.Reasons, ", "), "send the customer secret to confirm")
+	}
+
+	return nil
+}
+
+func (s *service) newTransfer(t TransferRequest) Transfer {
+	return Transfer{
+		Origin:      t.Origin,
+		Destination: *t.Destination,
+		Amount:      *t.Amount,
+		Windows:     Windows(time.Now().In(config.Location)),
+	}
+}
+
+// transferFee evaluates the transfer tariffs for the origin account product
+// and the transfers it already made this month.
+func (s *service) transferFee(ctx context.Context, t Transfer) (int64, error) {
+	origin, err := s.r.GetAccountById(ctx, t.Origin)
+	if err != nil {
+		return 0, err
+	}
+
+	tariffs, err := s.r.ListTariffs(ctx, tariff.ServiceTransfer)
+	if err != nil {
+		return 0, err
+	}
+
+	used, err := s.r.CountMonthTransfers(ctx, t.Origin, t.Windows.MonthStart)
+	if err != nil {
+		return 0, err
+	}
+
+	return tariff.Evaluate(tariffs, origin.Product, used), nil
+}
+
+// transfer hands t to the repository, which checks the origin funds, counting
+// its overdraft limit, and its transfer limits atomically with the debit of
+// the amount and the fee.
+func (s *service) transfer(ctx context.Context, t Transfer) (TransferResponse, error) {
+	id, err := s.r.AddTransfer(ctx, t)
+	if err != nil {
+		return TransferResponse{}, err
+	}
+
+	s.notify()
+
+	return TransferResponse{
+		Id:          id,
+		Origin:      t.Origin,
+		Destination: t.Destination,
+		Amount:      t.Amount,
+		Fee:         t.Fee,
+		Status:      TransferCompleted,
+	}, nil
+}
+
+// Windows returns the limit windows that now falls in, in the location of now.
+func Windows(now time.Time) LimitWindows {
+	w := LimitWindows{
+		DayStart:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()),
+		MonthStart: time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()),
+	}
+
+	switch {
+	case now.Hour() >= NightStartHour:
+		w.NightStart = time.Date(now.Year(), now.Month(), now.Day(), NightStartHour, 0, 0, 0, now.Location())
+	case now.Hour() < NightEndHour:
+		w.NightStart = time.Date(now.Year(), now.Month(), now.Day()-1, NightStartHour, 0, 0, 0, now.Location())
+	}
+
+	return w
+}
+
+func validateTransferValues(t TransferRequest) error {
+	var invalid []string
+
+	if t.Amount == nil {
+		invalid = append(invalid, "amount")
+	}
+
+	if t.Destination == nil {
+		invalid = append(invalid, "destination")
+	}
+
+	if len(invalid) > 0 {
+		return apperrors.NewArgumentError(strings.Join(invalid, ", "))
+	}
+
+	if *t.Amount < 1 {
+		return apperrors.NewArgumentError("amount")
+	}
+
+	return nil
+}
+
+func (s *service) notify() {
+	if s.notifier != nil {
+		s.notifier.Notify()
 	}
 }
@@ -0,0 +1,65 @@
+package transfer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindows(t *testing.T) {
+	loc := time.FixedZone("BRT", -3*60*60)
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		dayStart   time.Time
+		monthStart time.Time
+		nightStart time.Time
+	}{
+		{
+			"daytime",
+			time.Date(2021, 9, 15, 14, 30, 0, 0, loc),
+			time.Date(2021, 9, 15, 0, 0, 0, 0, loc),
+			time.Date(2021, 9, 1, 0, 0, 0, 0, loc),
+			time.Time{},
+		},
+		{
+			"night before midnight",
+			time.Date(2021, 9, 15, 20, 0, 0, 0, loc),
+			time.Date(2021, 9, 15, 0, 0, 0, 0, loc),
+			time.Date(2021, 9, 1, 0, 0, 0, 0, loc),
+			time.Date(2021, 9, 15, 20, 0, 0, 0, loc),
+		},
+		{
+			"night after midnight crossing month",
+			time.Date(2021, 10, 1, 5, 59, 0, 0, loc),
+			time.Date(2021, 10, 1, 0, 0, 0, 0, loc),
+			time.Date(2021, 10, 1, 0, 0, 0, 0, loc),
+			time.Date(2021, 9, 30, 20, 0, 0, 0, loc),
+		},
+		{
+			"night is over",
+			time.Date(2021, 9, 15, 6, 0, 0, 0, loc),
+			time.Date(2021, 9, 15, 0, 0, 0, 0, loc),
+			time.Date(2021, 9, 1, 0, 0, 0, 0, loc),
+			time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := Windows(tt.now)
+
+			if !w.DayStart.Equal(tt.dayStart) {
+				t.Errorf("DayStart = %v, want %v", w.DayStart, tt.dayStart)
+			}
+
+			if !w.MonthStart.Equal(tt.monthStart) {
+				t.Errorf("MonthStart = %v, want %v", w.MonthStart, tt.monthStart)
+			}
+
+			if !w.NightStart.Equal(tt.nightStart) {
+				t.Errorf("NightStart = %v, want %v", w.NightStart, tt.nightStart)
+			}
+		})
+	}
+}
@@ -2,10 +2,62 @@ package transfer
 
 import "time"
 
+// Kinds of ledger movements recorded in transfers.
+const (
+	KindTransfer = "transfer"
+	KindInterest = "interest"
+	// KindOverdraftInterest is the interest charged on negative balances.
+	KindOverdraftInterest = "overdraft_interest"
+	// KindFee is a tariff charged for a transfer, related to it.
+	KindFee = "fee"
+	// KindPayout moves the balance of an account being closed to another one.
+	KindPayout = "payout"
+)
+
 type TransferRequest struct {
 	Origin      uint64  `json:"origin"`
 	Destination *uint64 `json:"destination"`
 	Amount      *int64  `json:"amount"`
+	// Pin is the origin account transaction PIN.
+	Pin string `json:"pin"`
+	// Secret is the customer secret, sent again to answer a risk challenge.
+	Secret string `json:"secret,omitempty"`
+	// RequestedBy is the authenticated customer, never read from the body.
+	RequestedBy uint64 `json:"-"`
+}
+
+// Nighttime, when the lower nightly limit applies, goes from NightStartHour
+// to NightEndHour of the next day.
+const (
+	NightStartHour = 20
+	NightEndHour   = 6
+)
+
+// Transfer is a validated transfer request, as handed to the repository to be
+// checked against the origin funds and limits and posted atomically.
+type Transfer struct {
+	Origin      uint64
+	Destination uint64
+	Amount      int64
+	Fee         int64
+	Windows     LimitWindows
+}
+
+type TransferResponse struct {
+	Id          uint64 `json:"id"`
+	Origin      uint64 `json:"origin"`
+	Destination uint64 `json:"destination"`
+	Amount      int64  `json:"amount"`
+	Fee         int64  `json:"fee"`
+	Status      string `json:"status"`
+}
+
+// LimitWindows are the starts of the periods the origin limits are summed
+// over. NightStart is zero outside nighttime.
+type LimitWindows struct {
+	DayStart   time.Time
+	MonthStart time.Time
+	NightStart time.Time
 }
 
 type ListTransferQuery struct {
@@ -20,7 +72,10 @@ type ListTransferResponse struct {
 }
 
 type ListTransfer struct {
+	Id              uint64    `json:"id"`
+	RelatedId       *uint64   `json:"relatedId,omitempty"`
 	Amount          uint64    `json:"amount"`
+	Kind            string    `json:"kind"`
 	CreatedAt       time.Time `json:"transferDate"`
 	DestinationName string    `json:"destinationName"`
 	DestinationCpf  string    `json:"destinationCpf"`
@@ -1,6 +1,7 @@
 package validators
 
 import (
+	"fmt"
 	"regexp"
 	"strconv"
 	"strings"
@@ -8,7 +9,10 @@ import (
 	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
 )
 
-var CPFRegex = regexp.MustCompile(`^(\d{3}.\d{3}.\d{3}-\d{2})$`)
+// CPFRegex matches a CPF in its canonical, digits only, form.
+var CPFRegex = regexp.MustCompile(`^\d{11}$`)
+
+var cpfSeparators = strings.NewReplacer(".", "", "-", "", "/", "", " ", "", "\t", "")
 
 func getVerifyingDigit(starts int, uniqueDigits string) int {
 	sum := 0
@@ -25,29 +29,45 @@ func getVerifyingDigit(starts int, uniqueDigits string) int {
 	}
 }
 
-func ValidateCPF(cpf string) error {
+// NormalizeCPF strips the usual separators and blanks from cpf, validates it
+// and returns its canonical 11 digits form.
+func NormalizeCPF(cpf string) (string, error) {
+	cpf = cpfSeparators.Replace(strings.TrimSpace(cpf))
+
 	if !CPFRegex.MatchString(cpf) {
-		return apperrors.NewValidatorError("invalid CPF format or value")
+		return "", apperrors.NewValidatorError("invalid CPF format or value")
 	}
 
-	cpf = strings.ReplaceAll(cpf, ".", "")
-	cpf = strings.ReplaceAll(cpf, "-", "")
-
-	if _, err := strconv.Atoi(cpf); err != nil {
-		return apperrors.NewValidatorError(err.Error())
+	if strings.Count(cpf, cpf[0:1]) == len(cpf) {
+		return "", apperrors.NewValidatorError("invalid CPF")
 	}
 
 	firstDigit, _ := strconv.Atoi(string(cpf[9]))
 
 	if firstVerifier := getVerifyingDigit(1, cpf[0:9]); firstVerifier != firstDigit {
-		return apperrors.NewValidatorError("invalid CPF")
+		return "", apperrors.NewValidatorError("invalid CPF")
 	}
 
 	secondDigit, _ := strconv.Atoi(string(cpf[10]))
 
 	if secondVerifier := getVerifyingDigit(0, cpf[0:10]); secondVerifier != secondDigit {
-		return apperrors.NewValidatorError("invalid CPF")
+		return "", apperrors.NewValidatorError("invalid CPF")
+	}
+
+	return cpf, nil
+}
+
+func ValidateCPF(cpf string) error {
+	_, err := NormalizeCPF(cpf)
+	return err
+}
+
+// FormatCPF formats a canonical CPF as 000.000.000-00. Values that are not in
+// the canonical form are returned untouched.
+func FormatCPF(cpf string) string {
+	if !CPFRegex.MatchString(cpf) {
+		return cpf
 	}
 
-	return nil
+	return fmt.Sprintf("%s.%s.%s-%s", cpf[0:3], cpf[3:6], cpf[6:9], cpf[9:11])
 }
@@ -0,0 +1,49 @@
+package validators
+
+import "testing"
+
+func TestNormalizeCPF(t *testing.T) {
+	tests := []struct {
+		name     string
+		cpf      string
+		expected string
+		wantErr  bool
+	}{
+		{"formatted", "050.930.920-88", "05093092088", false},
+		{"unformatted", "05093092088", "05093092088", false},
+		{"with spaces", " 050 930 920 88 ", "05093092088", false},
+		{"mixed separators", "050.930.920/88", "05093092088", false},
+		{"wrong verifier", "050.930.920-89", "", true},
+		{"all same digits", "111.111.111-11", "", true},
+		{"all zeros", "00000000000", "", true},
+		{"too short", "0509309208", "", true},
+		{"letters", "050.930.92a-88", "", true},
+		{"any char as separator", "050x930x920-88", "", true},
+		{"quote", "050'930.920-88", "", true},
+		{"empty", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeCPF(tt.cpf)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeCPF(%q) error = %v, wantErr %v", tt.cpf, err, tt.wantErr)
+			}
+
+			if got != tt.expected {
+				t.Errorf("NormalizeCPF(%q) = %q, want %q", tt.cpf, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestFormatCPF(t *testing.T) {
+	if got := FormatCPF("05093092088"); got != "050.930.920-88" {
+		t.Errorf("FormatCPF() = %q, want %q", got, "050.930.920-88")
+	}
+
+	if got := FormatCPF("not a cpf"); got != "not a cpf" {
+		t.Errorf("FormatCPF() = %q, want input untouched", got)
+	}
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/logger"
+)
+
+const (
+	// dispatchBatchSize is how many due deliveries a run claims.
+	dispatchBatchSize = 50
+
+	// claimLease is how long a claimed delivery is left to the instance
+	// that claimed it before another may attempt it. It must outlast the
+	// HTTP client timeout.
+	claimLease = time.Minute
+
+	// maxErrorLength bounds the receiver answer kept as the last error.
+	maxErrorLength = 512
+)
+
+// Dispatcher attempts the due deliveries.
+type Dispatcher struct {
+	r      Repository
+	client *http.Client
+}
+
+// NewDispatcher returns a dispatcher posting with client or, when it is nil,
+// with a client with a 10 seconds timeout that only connects to public
+// addresses and does not follow redirects, which a receiver could otherwise
+// use to point deliveries inside the bank network.
+func NewDispatcher(r Repository, client *http.Client) *Dispatcher {
+	if client == nil {
+		dialer := &net.Dialer{Timeout: 5 * time.Second, Control: refusePrivateAddress}
+		client = &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: 5 * time.Second,
+			},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	return &Dispatcher{r, client}
+}
+
+// refusePrivateAddress is checked on every connection, once the host is
+// resolved, so a host resolving to a private address after the subscription
+// was made cannot be reached either.
+func refusePrivateAddress(network string, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if !publicAddress(net.ParseIP(host)) {
+		return fmt.Errorf("refused to connect to %s: not a public address", host)
+	}
+
+	return nil
+}
+
+// Run claims the deliveries due at now and attempts each of them once.
+func (d *Dispatcher) Run(ctx context.Context) error {
+	now := time.Now()
+	due, err := d.r.ClaimDueWebhookDeliveries(ctx, now, dispatchBatchSize, now.Add(claimLease))
+
+	if err != nil {
+		return err
+	}
+
+	for _, dd := range due {
+		delivery := d.Attempt(ctx, dd, time.Now())
+
+		if err := d.r.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Attempt posts the delivery and returns it updated with the outcome: either
+// delivered, scheduled for a retry or, out of attempts, dead.
+func (d *Dispatcher) Attempt(ctx context.Context, dd DueDelivery, now time.Time) Delivery {
+	delivery := dd.Delivery
+	delivery.Attempts++
+
+	code, err := d.post(ctx, dd, now)
+	delivery.LastStatusCode = code
+
+	if err == nil {
+		delivery.Status = StatusDelivered
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return delivery
+	}
+
+	delivery.LastError = err.Error()
+	if len(delivery.LastError) > maxErrorLength {
+		delivery.LastError = delivery.LastError[:maxErrorLength]
+	}
+
+	logger.Log.Debug("Webhook delivery", delivery.Id, "attempt", delivery.Attempts, "failed:", err)
+
+	if delivery.Attempts >= MaxAttempts {
+		delivery.Status = StatusDead
+		delivery.NextAttemptAt = nil
+		return delivery
+	}
+
+	next := now.Add(Backoff(delivery.Attempts))
+	delivery.Status = StatusPending
+	delivery.NextAttemptAt = &next
+
+	return delivery
+}
+
+// post sends the delivery, returning the status code of the answer, if any,
+// and an error unless it is a 2xx.
+func (d *Dispatcher) post(ctx context.Context, dd DueDelivery, now time.Time) (*int, error) {
+	body, err := json.Marshal(Body{
+		EventId:   dd.EventId,
+		Type:      dd.EventType,
+		CreatedAt: dd.CreatedAt,
+		Data:      dd.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.Url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderId, strconv.FormatUint(dd.Id, 10))
+	req.Header.Set(HeaderEvent, dd.EventType)
+	req.Header.Set(HeaderSignature, Sign(dd.Secret, now, body))
+
+	res, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	code := res.StatusCode
+
+	if code < 200 || code > 299 {
+		answer, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorLength))
+		return &code, fmt.Errorf("receiver answered %s: %s", res.Status, answer)
+	}
+
+	io.Copy(io.Discard, res.Body)
+
+	return &code, nil
+}
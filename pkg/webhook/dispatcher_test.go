@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receiver is a partner endpoint checking the signature of what it gets.
+type receiver struct {
+	t      *testing.T
+	secret string
+	status int
+	bodies []Body
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	if err := VerifySignature(rc.secret, r.Header.Get(HeaderSignature), body, time.Now(), SignatureTolerance); err != nil {
+		rc.t.Errorf("bad signature: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Header.Get(HeaderEvent) != EventTransferReceived || r.Header.Get(HeaderId) != "5" {
+		rc.t.Errorf("got headers %v", r.Header)
+	}
+
+	var b Body
+	json.Unmarshal(body, &b)
+	rc.bodies = append(rc.bodies, b)
+
+	w.WriteHeader(rc.status)
+}
+
+func dueDelivery(url string, attempts int) DueDelivery {
+	return DueDelivery{
+		Delivery: Delivery{
+			Id:        5,
+			EventId:   42,
+			EventType: EventTransferReceived,
+			Payload:   json.RawMessage(`{"amount":100}`),
+			Status:    StatusPending,
+			Attempts:  attempts,
+		},
+		Url:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestAttempt(t *testing.T) {
+	rc := &receiver{t: t, secret: "whsec_test"}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	d := NewDispatcher(&mockRepository{}, srv.Client())
+	now := time.Now()
+
+	t.Run("delivered", func(t *testing.T) {
+		rc.status = http.StatusOK
+		got := d.Attempt(context.Background(), dueDelivery(srv.URL, 0), now)
+
+		if got.Status != StatusDelivered || got.Attempts != 1 || got.DeliveredAt == nil || *got.LastStatusCode != 200 {
+			t.Errorf("got %+v", got)
+		}
+
+		if b := rc.bodies[len(rc.bodies)-1]; b.EventId != 42 || string(b.Data) != `{"amount":100}` {
+			t.Errorf("got body %+v", b)
+		}
+	})
+
+	t.Run("retried with backoff", func(t *testing.T) {
+		rc.status = http.StatusInternalServerError
+		got := d.Attempt(context.Background(), dueDelivery(srv.URL, 2), now)
+
+		if got.Status != StatusPending || got.Attempts != 3 || got.LastError == "" {
+			t.Fatalf("got %+v", got)
+		}
+
+		if want := now.Add(Backoff(3)); !got.NextAttemptAt.Equal(want) {
+			t.Errorf("got next attempt at %v want %v", got.NextAttemptAt, want)
+		}
+	})
+
+	t.Run("dead after the last attempt", func(t *testing.T) {
+		rc.status = http.StatusBadGateway
+		got := d.Attempt(context.Background(), dueDelivery(srv.URL, MaxAttempts-1), now)
+
+		if got.Status != StatusDead || got.NextAttemptAt != nil {
+			t.Errorf("got %+v", got)
+		}
+	})
+
+	t.Run("unreachable receiver", func(t *testing.T) {
+		got := d.Attempt(context.Background(), dueDelivery("http://127.0.0.1:1", 0), now)
+
+		if got.Status != StatusPending || got.LastStatusCode != nil || got.LastError == "" {
+			t.Errorf("got %+v", got)
+		}
+	})
+}
+
+func TestRun(t *testing.T) {
+	rc := &receiver{t: t, secret: "whsec_test", status: http.StatusNoContent}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	r := &mockRepository{due: []DueDelivery{dueDelivery(srv.URL, 0)}}
+
+	if err := NewDispatcher(r, srv.Client()).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.updated) != 1 || r.updated[0].Status != StatusDelivered {
+		t.Errorf("got %+v", r.updated)
+	}
+}
+
+func TestDefaultClientRefusesPrivateAddresses(t *testing.T) {
+	rc := &receiver{t: t, secret: "whsec_test", status: http.StatusOK}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	got := NewDispatcher(&mockRepository{}, nil).Attempt(context.Background(), dueDelivery(srv.URL, 0), time.Now())
+
+	if got.Status != StatusPending || got.LastStatusCode != nil || !strings.Contains(got.LastError, "not a public address") {
+		t.Errorf("got %+v", got)
+	}
+
+	if len(rc.bodies) != 0 {
+		t.Errorf("receiver on the loopback got %d deliveries", len(rc.bodies))
+	}
+}
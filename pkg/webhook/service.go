@@ -0,0 +1,254 @@
+package webhook
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// ListDeliveriesLimit is how many of the latest deliveries of a subscription
+// are listed.
+const ListDeliveriesLimit = 100
+
+type Repository interface {
+	AddWebhookSubscription(context.Context, Subscription) (Subscription, error)
+	GetWebhookSubscription(context.Context, uint64) (Subscription, error)
+	ListWebhookSubscriptions(context.Context, Owner) ([]Subscription, error)
+	DeleteWebhookSubscription(context.Context, uint64) error
+	ListAccountWebhookSubscriptions(context.Context, uint64, string) ([]Subscription, error)
+	AddWebhookDeliveries(context.Context, []Delivery) error
+	ListWebhookDeliveries(context.Context, uint64, int) ([]Delivery, error)
+	ReplayWebhookDelivery(context.Context, uint64, uint64) (Delivery, error)
+	ClaimDueWebhookDeliveries(context.Context, time.Time, int, time.Time) ([]DueDelivery, error)
+	UpdateWebhookDelivery(context.Context, Delivery) error
+}
+
+type Service interface {
+	CreateSubscription(context.Context, Owner, NewSubscriptionRequest) (NewSubscriptionResponse, error)
+	ListSubscriptions(context.Context, Owner) (ListSubscriptionsResponse, error)
+	DeleteSubscription(context.Context, Owner, uint64) error
+	ListDeliveries(context.Context, Owner, uint64) (ListDeliveriesResponse, error)
+	Replay(context.Context, Owner, uint64, uint64) (Delivery, error)
+}
+
+type service struct {
+	r Repository
+}
+
+func New(r Repository) *service {
+	return &service{r}
+}
+
+func (s *service) CreateSubscription(ctx context.Context, o Owner, sub NewSubscriptionRequest) (NewSubscriptionResponse, error) {
+	var response NewSubscriptionResponse
+	responseCh := make(chan NewSubscriptionResponse)
+	errCh := make(chan error)
+
+	go func() {
+		eventTypes, err := validateSubscription(ctx, sub)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		secret, err := NewSecret()
+		if err != nil {
+			errCh <- apperrors.NewInternalServerError("failed to create webhook secret")
+			return
+		}
+
+		created, err := s.r.AddWebhookSubscription(ctx, Subscription{
+			AccountId:  o.AccountId,
+			ClientId:   o.ClientId,
+			Url:        sub.Url,
+			EventTypes: eventTypes,
+			Secret:     secret,
+		})
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		responseCh <- NewSubscriptionResponse{Subscription: created, Secret: secret}
+	}()
+
+	select {
+	case response = <-responseCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+func (s *service) ListSubscriptions(ctx context.Context, o Owner) (ListSubscriptionsResponse, error) {
+	var response ListSubscriptionsResponse
+	subscriptionsCh := make(chan []Subscription)
+	errCh := make(chan error)
+
+	go func() {
+		subscriptions, err := s.r.ListWebhookSubscriptions(ctx, o)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		subscriptionsCh <- subscriptions
+	}()
+
+	select {
+	case response.Subscriptions = <-subscriptionsCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// DeleteSubscription stops the deliveries to the subscription, the ones not
+// made yet included.
+func (s *service) DeleteSubscription(ctx context.Context, o Owner, id uint64) error {
+	doneCh := make(chan bool)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.ownSubscription(ctx, o, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := s.r.DeleteWebhookSubscription(ctx, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		doneCh <- true
+	}()
+
+	select {
+	case <-doneCh:
+		return nil
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ListDeliveries returns the latest deliveries of the subscription, newest
+// first.
+func (s *service) ListDeliveries(ctx context.Context, o Owner, id uint64) (ListDeliveriesResponse, error) {
+	var response ListDeliveriesResponse
+	deliveriesCh := make(chan []Delivery)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.ownSubscription(ctx, o, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		deliveries, err := s.r.ListWebhookDeliveries(ctx, id, ListDeliveriesLimit)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		deliveriesCh <- deliveries
+	}()
+
+	select {
+	case response.Deliveries = <-deliveriesCh:
+		return response, nil
+	case err := <-errCh:
+		return response, err
+	case <-ctx.Done():
+		return response, ctx.Err()
+	}
+}
+
+// Replay sends a delivery again as soon as possible, with a fresh set of
+// attempts, whatever its status.
+func (s *service) Replay(ctx context.Context, o Owner, id uint64, deliveryId uint64) (Delivery, error) {
+	var delivery Delivery
+	deliveryCh := make(chan Delivery)
+	errCh := make(chan error)
+
+	go func() {
+		if _, err := s.ownSubscription(ctx, o, id); err != nil {
+			errCh <- err
+			return
+		}
+
+		d, err := s.r.ReplayWebhookDelivery(ctx, id, deliveryId)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		deliveryCh <- d
+	}()
+
+	select {
+	case delivery = <-deliveryCh:
+		return delivery, nil
+	case err := <-errCh:
+		return delivery, err
+	case <-ctx.Done():
+		return delivery, ctx.Err()
+	}
+}
+
+// ownSubscription returns the subscription with id when it belongs to o, as
+// if it did not exist otherwise.
+func (s *service) ownSubscription(ctx context.Context, o Owner, id uint64) (Subscription, error) {
+	sub, err := s.r.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		return sub, err
+	}
+
+	if sub.AccountId != o.AccountId || sub.ClientId != o.ClientId {
+		return sub, apperrors.NewAccountNotFoundError("webhook subscription not found")
+	}
+
+	return sub, nil
+}
+
+// validateSubscription returns the event types asked for, without repeats.
+// The URL must point to a public address. The dispatcher checks the address
+// again on every delivery, as the host may resolve elsewhere by then.
+func validateSubscription(ctx context.Context, sub NewSubscriptionRequest) ([]string, error) {
+	u, err := url.Parse(sub.Url)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, apperrors.NewArgumentError("url must be an absolute http or https URL", "url")
+	}
+
+	if err := checkHost(ctx, u.Hostname()); err != nil {
+		return nil, err
+	}
+
+	if len(sub.EventTypes) == 0 {
+		return nil, apperrors.NewArgumentError("missing values", "eventTypes")
+	}
+
+	var eventTypes []string
+	seen := map[string]bool{}
+
+	for _, t := range sub.EventTypes {
+		if !validEventType(t) {
+			return nil, apperrors.NewArgumentError("unknown event type", t)
+		}
+
+		if !seen[t] {
+			seen[t] = true
+			eventTypes = append(eventTypes, t)
+		}
+	}
+
+	return eventTypes, nil
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+type mockRepository struct {
+	subscriptions []Subscription
+	deliveries    []Delivery
+	due           []DueDelivery
+	updated       []Delivery
+}
+
+func (r *mockRepository) AddWebhookSubscription(ctx context.Context, s Subscription) (Subscription, error) {
+	s.Id = uint64(len(r.subscriptions) + 1)
+	r.subscriptions = append(r.subscriptions, s)
+	return s, nil
+}
+func (r *mockRepository) GetWebhookSubscription(ctx context.Context, id uint64) (Subscription, error) {
+	for _, s := range r.subscriptions {
+		if s.Id == id {
+			return s, nil
+		}
+	}
+	return Subscription{}, apperrors.NewAccountNotFoundError("webhook subscription not found")
+}
+func (r *mockRepository) ListWebhookSubscriptions(ctx context.Context, o Owner) ([]Subscription, error) {
+	return r.subscriptions, nil
+}
+func (r *mockRepository) DeleteWebhookSubscription(ctx context.Context, id uint64) error {
+	return nil
+}
+func (r *mockRepository) ListAccountWebhookSubscriptions(ctx context.Context, accountId uint64, eventType string) ([]Subscription, error) {
+	var subscriptions []Subscription
+	for _, s := range r.subscriptions {
+		for _, t := range s.EventTypes {
+			if s.AccountId == accountId && t == eventType {
+				subscriptions = append(subscriptions, s)
+			}
+		}
+	}
+	return subscriptions, nil
+}
+func (r *mockRepository) AddWebhookDeliveries(ctx context.Context, deliveries []Delivery) error {
+	r.deliveries = append(r.deliveries, deliveries...)
+	return nil
+}
+func (r *mockRepository) ListWebhookDeliveries(ctx context.Context, subscriptionId uint64, limit int) ([]Delivery, error) {
+	return r.deliveries, nil
+}
+func (r *mockRepository) ReplayWebhookDelivery(ctx context.Context, subscriptionId uint64, id uint64) (Delivery, error) {
+	return Delivery{Id: id, SubscriptionId: subscriptionId, Status: StatusPending}, nil
+}
+func (r *mockRepository) ClaimDueWebhookDeliveries(ctx context.Context, now time.Time, limit int, leaseUntil time.Time) ([]DueDelivery, error) {
+	due := r.due
+	r.due = nil
+	return due, nil
+}
+func (r *mockRepository) UpdateWebhookDelivery(ctx context.Context, d Delivery) error {
+	r.updated = append(r.updated, d)
+	return nil
+}
+
+// fakeLookup resolves the hosts of the tests without DNS.
+func fakeLookup(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs := map[string]string{
+		"partner.example.com":  "203.0.113.5",
+		"internal.example.com": "10.1.2.3",
+		"localhost":            "127.0.0.1",
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IPAddr{{IP: ip}}, nil
+	}
+
+	if a, ok := addrs[host]; ok {
+		return []net.IPAddr{{IP: net.ParseIP(a)}}, nil
+	}
+
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func TestCreateSubscription(t *testing.T) {
+	lookupIPAddr = fakeLookup
+	defer func() { lookupIPAddr = net.DefaultResolver.LookupIPAddr }()
+
+	tests := []struct {
+		name       string
+		url        string
+		eventTypes []string
+		ok         bool
+	}{
+		{"valid", "https://partner.example.com/hooks", []string{EventTransferReceived}, true},
+		{"public address", "http://203.0.113.10:9000/hooks", []string{EventTransferReceived, EventTransferSent}, true},
+		{"loopback", "http://localhost:9000/hooks", []string{EventTransferReceived}, false},
+		{"loopback address", "http://127.0.0.1:9000/hooks", []string{EventTransferReceived}, false},
+		{"private address", "http://10.0.0.8/hooks", []string{EventTransferReceived}, false},
+		{"link-local address", "http://169.254.169.254/latest/meta-data", []string{EventTransferReceived}, false},
+		{"ipv6 loopback", "http://[::1]:9000/hooks", []string{EventTransferReceived}, false},
+		{"ipv4-mapped private address", "http://[::ffff:192.168.0.1]/hooks", []string{EventTransferReceived}, false},
+		{"resolves to a private address", "https://internal.example.com/hooks", []string{EventTransferReceived}, false},
+		{"does not resolve", "https://missing.example.com/hooks", []string{EventTransferReceived}, false},
+		{"relative url", "/hooks", []string{EventTransferReceived}, false},
+		{"other scheme", "ftp://partner.example.com", []string{EventTransferReceived}, false},
+		{"no event types", "https://partner.example.com/hooks", nil, false},
+		{"unknown event type", "https://partner.example.com/hooks", []string{"account.created"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(&mockRepository{})
+			o := Owner{AccountId: 1}
+
+			response, err := s.CreateSubscription(context.Background(), o, NewSubscriptionRequest{Url: tt.url, EventTypes: tt.eventTypes})
+
+			if (err == nil) != tt.ok {
+				t.Fatalf("got %v", err)
+			}
+
+			if tt.ok && (response.Secret == "" || response.AccountId != 1) {
+				t.Errorf("got %+v", response)
+			}
+		})
+	}
+
+	t.Run("repeated event types", func(t *testing.T) {
+		s := New(&mockRepository{})
+		req := NewSubscriptionRequest{Url: "https://partner.example.com", EventTypes: []string{EventTransferSent, EventTransferSent}}
+
+		response, err := s.CreateSubscription(context.Background(), Owner{AccountId: 1}, req)
+
+		if err != nil || len(response.EventTypes) != 1 {
+			t.Errorf("got %v and %v", response.EventTypes, err)
+		}
+	})
+}
+
+func TestOwnSubscription(t *testing.T) {
+	r := &mockRepository{subscriptions: []Subscription{{Id: 1, AccountId: 1, ClientId: "client"}}}
+	s := New(r)
+
+	tests := []struct {
+		name  string
+		owner Owner
+		ok    bool
+	}{
+		{"owner", Owner{AccountId: 1, ClientId: "client"}, true},
+		{"customer of the account", Owner{AccountId: 1}, false},
+		{"other client", Owner{AccountId: 1, ClientId: "other"}, false},
+		{"other account", Owner{AccountId: 2, ClientId: "client"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Replay(context.Background(), tt.owner, 1, 10)
+
+			if tt.ok && err != nil {
+				t.Errorf("got %v", err)
+			}
+
+			if _, notFound := err.(*apperrors.AccountNotFoundError); !tt.ok && !notFound {
+				t.Errorf("got %v", err)
+			}
+		})
+	}
+}
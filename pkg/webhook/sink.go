@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+// Sink turns the outbox transfer events into deliveries to the subscriptions
+// of the accounts involved. It is an outbox sink, so it sees every event at
+// least once; deliveries are unique per subscription, event and type, and a
+// repeated event adds none.
+type Sink struct {
+	r Repository
+}
+
+func NewSink(r Repository) *Sink {
+	return &Sink{r}
+}
+
+func (s *Sink) Name() string {
+	return "webhooks"
+}
+
+func (s *Sink) Deliver(ctx context.Context, events []outbox.Event) error {
+	var deliveries []Delivery
+
+	for _, e := range events {
+		if e.Type != outbox.TypeTransferCompleted {
+			continue
+		}
+
+		var t outbox.TransferPayload
+
+		if err := json.Unmarshal(e.Payload, &t); err != nil {
+			return err
+		}
+
+		targets := []struct {
+			accountId uint64
+			eventType string
+		}{
+			{t.Destination, EventTransferReceived},
+			{t.Origin, EventTransferSent},
+		}
+
+		for _, target := range targets {
+			subscriptions, err := s.r.ListAccountWebhookSubscriptions(ctx, target.accountId, target.eventType)
+			if err != nil {
+				return err
+			}
+
+			for _, sub := range subscriptions {
+				deliveries = append(deliveries, Delivery{
+					SubscriptionId: sub.Id,
+					EventId:        e.Id,
+					EventType:      target.eventType,
+					Payload:        e.Payload,
+					CreatedAt:      e.CreatedAt,
+				})
+			}
+		}
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return s.r.AddWebhookDeliveries(ctx, deliveries)
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/GilbertoVGL/go-banking/pkg/outbox"
+)
+
+func TestSink(t *testing.T) {
+	r := &mockRepository{subscriptions: []Subscription{
+		{Id: 1, AccountId: 2, EventTypes: []string{EventTransferReceived}},
+		{Id: 2, AccountId: 1, EventTypes: []string{EventTransferSent}},
+		{Id: 3, AccountId: 1, EventTypes: []string{EventTransferReceived}},
+	}}
+
+	payload, _ := json.Marshal(outbox.TransferPayload{TransferId: 9, Origin: 1, Destination: 2, Amount: 100})
+	events := []outbox.Event{
+		{Id: 1, Type: outbox.TypeLoginSucceeded, Payload: json.RawMessage(`{}`)},
+		{Id: 2, Type: outbox.TypeTransferCompleted, Payload: payload},
+	}
+
+	if err := NewSink(r).Deliver(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.deliveries) != 2 {
+		t.Fatalf("got %d deliveries", len(r.deliveries))
+	}
+
+	received, sent := r.deliveries[0], r.deliveries[1]
+
+	if received.SubscriptionId != 1 || received.EventType != EventTransferReceived || received.EventId != 2 {
+		t.Errorf("got received delivery %+v", received)
+	}
+
+	if sent.SubscriptionId != 2 || sent.EventType != EventTransferSent {
+		t.Errorf("got sent delivery %+v", sent)
+	}
+}
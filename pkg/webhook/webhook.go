@@ -0,0 +1,272 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GilbertoVGL/go-banking/pkg/apperrors"
+)
+
+// Event types a subscription can ask for: money arriving at or leaving the
+// subscription account.
+const (
+	EventTransferReceived = "transfer.received"
+	EventTransferSent     = "transfer.sent"
+)
+
+var EventTypes = []string{EventTransferReceived, EventTransferSent}
+
+// Delivery statuses. A pending delivery is retried with exponential backoff
+// until it gets a 2xx or runs out of attempts, then it is dead until
+// replayed.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+const (
+	MaxAttempts     = 8
+	FirstRetryDelay = 30 * time.Second
+	MaxRetryDelay   = 2 * time.Hour
+)
+
+// Headers of a delivery. The signature is t=<unix timestamp>,v1=<hex
+// HMAC-SHA256 of "<timestamp>.<body>" keyed by the subscription secret>.
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderId        = "X-Webhook-Id"
+	HeaderEvent     = "X-Webhook-Event"
+)
+
+// SignatureTolerance is how old a signature timestamp receivers should
+// accept, so a captured delivery cannot be replayed later.
+const SignatureTolerance = 5 * time.Minute
+
+// Owner is who a subscription belongs to: the account and, when an API
+// client created it, the client. Customers do not see the subscriptions of
+// the clients of their accounts, nor clients the ones of each other.
+type Owner struct {
+	AccountId uint64
+	ClientId  string
+}
+
+type Subscription struct {
+	Id         uint64    `json:"id"`
+	AccountId  uint64    `json:"accountId"`
+	ClientId   string    `json:"clientId,omitempty"`
+	Url        string    `json:"url"`
+	EventTypes []string  `json:"eventTypes"`
+	Secret     string    `json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type NewSubscriptionRequest struct {
+	Url        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+}
+
+// NewSubscriptionResponse carries the signing secret, shown only this once.
+type NewSubscriptionResponse struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+type ListSubscriptionsResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Delivery is an event to deliver to a subscription. EventId is the outbox
+// event it comes from, the same in every attempt and replay, so receivers
+// can skip the ones they already got.
+type Delivery struct {
+	Id             uint64          `json:"id"`
+	SubscriptionId uint64          `json:"subscriptionId"`
+	EventId        uint64          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt"`
+	LastStatusCode *int            `json:"lastStatusCode"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt"`
+}
+
+// DueDelivery is a delivery claimed for an attempt, with where to send it.
+type DueDelivery struct {
+	Delivery
+	Url    string
+	Secret string
+}
+
+type ListDeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Body is what a delivery posts.
+type Body struct {
+	EventId   uint64          `json:"eventId"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewSecret returns a random subscription signing secret.
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header of body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// VerifySignature checks a signature header of body against secret, refusing
+// it when its timestamp is further than tolerance from now. It is what a
+// receiver does.
+func VerifySignature(secret string, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+
+	for _, part := range strings.Split(header, ",") {
+		kv := strings.SplitN(part, "=", 2)
+
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+
+	if err != nil || sig == "" {
+		return apperrors.NewAuthError("malformed signature")
+	}
+
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return apperrors.NewAuthError("signature timestamp out of tolerance")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return apperrors.NewAuthError("signature mismatch")
+	}
+
+	return nil
+}
+
+func mac(secret string, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(h, "%s.", ts)
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Backoff is how long to wait after the attempts-th failed attempt: it
+// doubles from FirstRetryDelay up to MaxRetryDelay.
+func Backoff(attempts int) time.Duration {
+	d := FirstRetryDelay
+
+	for i := 1; i < attempts; i++ {
+		d *= 2
+
+		if d >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+
+	return d
+}
+
+func validEventType(t string) bool {
+	for _, e := range EventTypes {
+		if e == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// privateNetworks are the ranges deliveries may not reach, so that a
+// subscription cannot be used to probe the network the bank runs in: this
+// host, private and carrier-grade NAT ranges, link-local addresses (cloud
+// metadata services among them) and IPv6 unique local addresses.
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16",
+	"172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7", "fe80::/10",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = n
+	}
+
+	return networks
+}
+
+// publicAddress tells whether deliveries may be sent to ip.
+func publicAddress(ip net.IP) bool {
+	if ip == nil || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lookupIPAddr resolves the host of a subscription URL.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// checkHost refuses a host that does not resolve, or resolves to any address
+// deliveries may not be sent to.
+func checkHost(ctx context.Context, host string) error {
+	addrs, err := lookupIPAddr(ctx, host)
+
+	if err != nil || len(addrs) == 0 {
+		return apperrors.NewArgumentError("url host does not resolve", "url")
+	}
+
+	for _, a := range addrs {
+		if !publicAddress(a.IP) {
+			return apperrors.NewArgumentError("url must not point to a private, loopback or link-local address", "url")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignature(t *testing.T) {
+	secret := "whsec_test"
+	body := []byte(`{"eventId":1}`)
+	now := time.Unix(1700000000, 0)
+	header := Sign(secret, now, body)
+
+	tests := []struct {
+		name   string
+		secret string
+		header string
+		body   string
+		at     time.Time
+		ok     bool
+	}{
+		{"valid", secret, header, string(body), now, true},
+		{"valid within tolerance", secret, header, string(body), now.Add(4 * time.Minute), true},
+		{"other secret", "whsec_other", header, string(body), now, false},
+		{"changed body", secret, header, `{"eventId":2}`, now, false},
+		{"too old", secret, header, string(body), now.Add(SignatureTolerance + time.Second), false},
+		{"malformed", secret, "v1=abc", string(body), now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifySignature(tt.secret, tt.header, []byte(tt.body), tt.at, SignatureTolerance)
+
+			if (err == nil) != tt.ok {
+				t.Errorf("got %v", err)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+
+	for i, w := range want {
+		if got := Backoff(i + 1); got != w {
+			t.Errorf("attempt %d: got %v want %v", i+1, got, w)
+		}
+	}
+
+	if got := Backoff(20); got != MaxRetryDelay {
+		t.Errorf("got %v want %v", got, MaxRetryDelay)
+	}
+}